@@ -0,0 +1,200 @@
+// Package client is a Go client for the file API. It mirrors the HTTP
+// endpoints as methods and turns the API's JSON error responses into
+// *Error values that match the sentinel errors below with errors.Is.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// FileInfo describes a file or directory as returned by List and Stat.
+type FileInfo struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	IsDir    bool      `json:"is_dir"`
+	ModTime  time.Time `json:"mod_time"`
+	Checksum string    `json:"checksum,omitempty"`
+
+	// ChildCount and TotalSize are filled in by Stat for directories.
+	ChildCount int   `json:"child_count,omitempty"`
+	TotalSize  int64 `json:"total_size,omitempty"`
+}
+
+// Client calls the API at a base URL such as http://localhost:8080. It is
+// safe for concurrent use.
+type Client struct {
+	base   *url.URL
+	http   *http.Client
+	apiKey string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends requests through hc instead of http.DefaultClient,
+// for custom timeouts, transports or TLS settings.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithAPIKey sends key as a bearer token on every request. The server
+// expects a JWT signed with its AUTH_JWT_SECRET.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// New returns a Client for the API served at baseURL.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("base URL %q must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	c := &Client{base: u, http: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// List returns the entries of the directory at p.
+func (c *Client) List(ctx context.Context, p string) ([]FileInfo, error) {
+	var files []FileInfo
+	err := c.doJSON(ctx, http.MethodGet, "/api/v1/files", url.Values{"path": {p}}, &files)
+	return files, err
+}
+
+// Stat returns information about the file or directory at p.
+func (c *Client) Stat(ctx context.Context, p string) (*FileInfo, error) {
+	var info FileInfo
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/files/stat", url.Values{"path": {p}}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Download streams the contents of the file at p. The caller must close
+// the returned reader.
+func (c *Client) Download(ctx context.Context, p string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/files/download", url.Values{"path": {p}}, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Upload stores the contents of r at p, streaming them as a multipart form,
+// and returns the path the file was stored at, which differs from p when p
+// names a directory. An existing file is only replaced when overwrite is set; otherwise the
+// error matches ErrExist.
+func (c *Client) Upload(ctx context.Context, p string, r io.Reader, overwrite bool) (string, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile("file", path.Base(p))
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	defer pr.Close()
+
+	q := url.Values{"path": {p}}
+	if overwrite {
+		q.Set("overwrite", "true")
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/files/upload", q, pr, mw.FormDataContentType())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode upload response: %w", err)
+	}
+	return out.Path, nil
+}
+
+// Delete removes the file or empty directory at p. A directory with
+// entries gives an error matching ErrNotEmpty; see DeleteAll.
+func (c *Client) Delete(ctx context.Context, p string) error {
+	return c.delete(ctx, url.Values{"path": {p}})
+}
+
+// DeleteAll removes p and, for a directory, everything below it.
+func (c *Client) DeleteAll(ctx context.Context, p string) error {
+	return c.delete(ctx, url.Values{"path": {p}, "recursive": {"true"}})
+}
+
+func (c *Client) delete(ctx context.Context, q url.Values) error {
+	resp, err := c.do(ctx, http.MethodDelete, "/api/v1/files", q, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// doJSON sends a request without a body and decodes the JSON response into
+// out.
+func (c *Client) doJSON(ctx context.Context, method, endpoint string, q url.Values, out any) error {
+	resp, err := c.do(ctx, method, endpoint, q, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// do sends a request to endpoint and returns the response if it succeeded.
+// Any other status is read into an *Error and the body closed.
+func (c *Client) do(ctx context.Context, method, endpoint string, q url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	u := *c.base
+	u.Path += endpoint
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	return nil, readError(resp)
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-storage-api/internal/api"
+	"go-storage-api/internal/storage/local"
+)
+
+// newTestClient returns a Client for the real router over a local store
+// rooted in t.TempDir().
+func newTestClient(t *testing.T, apiOpts api.Options, opts ...Option) *Client {
+	t.Helper()
+	store, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("create local storage: %v", err)
+	}
+	if apiOpts.MaxUploadSize == 0 {
+		apiOpts.MaxUploadSize = 10 << 20
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	srv := httptest.NewServer(api.NewRouter(store, apiOpts, logger))
+	t.Cleanup(srv.Close)
+
+	c, err := New(srv.URL+"/", opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func TestClient_RoundTrip(t *testing.T) {
+	c := newTestClient(t, api.Options{})
+	ctx := context.Background()
+
+	stored, err := c.Upload(ctx, "docs/a.txt", strings.NewReader("hello"), false)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if stored != "docs/a.txt" {
+		t.Errorf("expected the file stored at docs/a.txt, got %q", stored)
+	}
+
+	info, err := c.Stat(ctx, "docs/a.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Name != "a.txt" || info.Size != 5 || info.IsDir || info.ModTime.IsZero() {
+		t.Errorf("unexpected Stat result: %+v", info)
+	}
+
+	files, err := c.List(ctx, "docs")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "docs/a.txt" {
+		t.Errorf("expected docs/a.txt listed, got %+v", files)
+	}
+
+	rc, err := c.Download(ctx, "docs/a.txt")
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(body) != "hello" {
+		t.Errorf("expected hello, got %q", body)
+	}
+
+	if err := c.Delete(ctx, "docs/a.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := c.Stat(ctx, "docs/a.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestClient_UploadOverwrite(t *testing.T) {
+	c := newTestClient(t, api.Options{})
+	ctx := context.Background()
+
+	if _, err := c.Upload(ctx, "a.txt", strings.NewReader("one"), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Upload(ctx, "a.txt", strings.NewReader("two"), false); !errors.Is(err, ErrExist) {
+		t.Errorf("expected ErrExist without overwrite, got %v", err)
+	}
+	if _, err := c.Upload(ctx, "a.txt", strings.NewReader("two"), true); err != nil {
+		t.Fatalf("Upload with overwrite: %v", err)
+	}
+	rc, err := c.Download(ctx, "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if body, _ := io.ReadAll(rc); string(body) != "two" {
+		t.Errorf("expected the overwritten contents, got %q", body)
+	}
+}
+
+func TestClient_DeleteDirectory(t *testing.T) {
+	c := newTestClient(t, api.Options{})
+	ctx := context.Background()
+	if _, err := c.Upload(ctx, "dir/a.txt", strings.NewReader("x"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.Delete(ctx, "dir")
+	if !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("expected ErrNotEmpty, got %v", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict || apiErr.RequestID == "" {
+		t.Errorf("expected a 409 *Error with a request ID, got %#v", err)
+	}
+
+	if err := c.DeleteAll(ctx, "dir"); err != nil {
+		t.Fatalf("DeleteAll: %v", err)
+	}
+	if _, err := c.Stat(ctx, "dir"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after DeleteAll, got %v", err)
+	}
+}
+
+func TestClient_DownloadMissing(t *testing.T) {
+	c := newTestClient(t, api.Options{})
+	if _, err := c.Download(context.Background(), "missing.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClient_APIKey(t *testing.T) {
+	secret := []byte("client-test-secret")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "alice",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anon := newTestClient(t, api.Options{JWTSecret: secret})
+	if _, err := anon.List(context.Background(), "/"); !errors.Is(err, ErrPermission) {
+		t.Errorf("expected ErrPermission without a key, got %v", err)
+	}
+
+	authed := newTestClient(t, api.Options{JWTSecret: secret}, WithAPIKey(token))
+	if _, err := authed.List(context.Background(), "/"); err != nil {
+		t.Errorf("expected List to succeed with a key, got %v", err)
+	}
+}
+
+func TestClient_CustomHTTPClientAndContext(t *testing.T) {
+	var calls int
+	hc := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	c := newTestClient(t, api.Options{}, WithHTTPClient(hc))
+
+	if _, err := c.List(context.Background(), "/"); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the request sent through the custom client, got %d calls", calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.List(ctx, "/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNew_RejectsBadBaseURL(t *testing.T) {
+	for _, u := range []string{"localhost:8080", "ftp://example.com", "http://[::1"} {
+		if _, err := New(u); err == nil {
+			t.Errorf("New(%q): expected an error", u)
+		}
+	}
+}
+
+func TestError_NonJSONBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	c, _ := New(srv.URL)
+
+	_, err := c.Stat(context.Background(), "a.txt")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway || apiErr.Code != "" {
+		t.Fatalf("expected a 502 *Error without a code, got %#v", err)
+	}
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrPermission) {
+		t.Error("expected a proxy error to match no sentinel")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrPermission = errors.New("permission denied")
+	ErrExist      = errors.New("file already exists")
+	ErrNotEmpty   = errors.New("directory not empty")
+)
+
+// Error is an error response from the API. Code is one of the API's
+// documented error codes, or empty when the response was not the API's
+// JSON error body (a proxy error page, say).
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Code == "" {
+		return fmt.Sprintf("%d: %s", e.StatusCode, msg)
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, msg)
+}
+
+// Is lets errors.Is match an *Error against the sentinel for its code.
+// Authentication and authorization failures both match ErrPermission.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Code == "not_found"
+	case ErrPermission:
+		return e.Code == "permission_denied" || e.Code == "forbidden" || e.Code == "unauthorized"
+	case ErrExist:
+		return e.Code == "conflict"
+	case ErrNotEmpty:
+		return e.Code == "not_empty"
+	}
+	return false
+}
+
+// readError builds an *Error from a failed response.
+func readError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+	var body struct {
+		Error     string `json:"error"`
+		Code      string `json:"code"`
+		RequestID string `json:"request_id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err == nil {
+		e.Code, e.Message = body.Code, body.Error
+		if body.RequestID != "" {
+			e.RequestID = body.RequestID
+		}
+	}
+	return e
+}
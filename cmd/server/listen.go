@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// listen opens addr, either tcp://host:port or unix:///path/to/socket. A
+// Unix socket gets mode as its permissions and is removed when the
+// listener closes. A socket file left behind by a process that is gone is
+// replaced; one that still accepts connections, or any other file at the
+// path, is an error.
+func listen(addr string, mode os.FileMode) (net.Listener, error) {
+	scheme, rest, _ := strings.Cut(addr, "://")
+	switch scheme {
+	case "tcp":
+		return net.Listen("tcp", rest)
+	case "unix":
+		return listenUnix(rest, mode)
+	}
+	return nil, fmt.Errorf("unsupported listen address %q: must be tcp://host:port or unix:///path/to/socket", addr)
+}
+
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("set socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// removeStaleSocket deletes the socket file at path if nothing is serving
+// on it, as happens after a crash.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
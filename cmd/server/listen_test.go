@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/api"
+	"go-storage-api/internal/storage/local"
+)
+
+// unixClient returns an HTTP client that dials the socket at path for
+// every request.
+func unixClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+}
+
+func TestListen_UnixSocketServesHealth(t *testing.T) {
+	store, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("create local storage: %v", err)
+	}
+	router := api.NewRouter(store, api.Options{}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	sock := filepath.Join(t.TempDir(), "api.sock")
+	ln, err := listen("unix://"+sock, 0o600)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	info, err := os.Stat(sock)
+	if err != nil {
+		t.Fatalf("stat socket: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected socket mode 0600, got %o", info.Mode().Perm())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, &http.Server{Handler: router}, ln, time.Second) }()
+
+	resp, err := unixClient(sock).Get("http://unix/api/v1/health")
+	if err != nil {
+		t.Fatalf("health over socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	if _, err := os.Lstat(sock); !os.IsNotExist(err) {
+		t.Errorf("expected the socket removed on shutdown, got %v", err)
+	}
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "api.sock")
+	stale, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	// Leave the file behind as a crashed process would.
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := listen("unix://"+sock, 0o660)
+	if err != nil {
+		t.Fatalf("expected a stale socket to be replaced, got %v", err)
+	}
+	defer ln.Close()
+
+	if _, err := listen("unix://"+sock, 0o660); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("expected a live socket to be refused, got %v", err)
+	}
+}
+
+func TestListen_RefusesNonSocketFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("keep me"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listen("unix://"+path, 0o660); err == nil {
+		t.Fatal("expected an existing regular file to be refused")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "keep me" {
+		t.Error("expected the file left alone")
+	}
+}
+
+func TestListen_TCP(t *testing.T) {
+	ln, err := listen("tcp://127.0.0.1:0", 0)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	if ln.Addr().Network() != "tcp" {
+		t.Errorf("expected a tcp listener, got %s", ln.Addr().Network())
+	}
+
+	if _, err := listen("udp://127.0.0.1:0", 0); err == nil {
+		t.Error("expected an unsupported scheme to fail")
+	}
+}
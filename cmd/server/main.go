@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
+	"io"
 	"log"
 	"log/slog"
-	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"go-storage-api/internal/api"
+	"go-storage-api/internal/bufpool"
 	"go-storage-api/internal/config"
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/storage"
 	"go-storage-api/internal/storage/local"
 )
 
@@ -20,18 +26,98 @@ func main() {
 		Level: level,
 	}))
 
-	store, err := local.New(cfg.Local.RootPath)
+	// One pool serves both downloads and backend writes.
+	copyBuffers := bufpool.New(cfg.CopyBufferSize)
+
+	localOpts := []local.Option{local.WithCopyBuffers(copyBuffers)}
+	if cfg.Local.StagingDir != "" {
+		localOpts = append(localOpts, local.WithStagingDir(cfg.Local.StagingDir))
+	}
+	if cfg.Local.Dedup {
+		localOpts = append(localOpts, local.WithDedup())
+	}
+	if cfg.Local.Compress {
+		localOpts = append(localOpts, local.WithCompression())
+	}
+	if !cfg.Local.FollowSymlinks {
+		localOpts = append(localOpts, local.WithoutSymlinks())
+	}
+	if cfg.Local.Fsync {
+		localOpts = append(localOpts, local.WithFsync())
+	}
+	store, err := local.New(cfg.Local.RootPath, localOpts...)
 	if err != nil {
 		log.Fatalf("create local storage backend: %v", err)
 	}
 
-	router := api.NewRouter(store, cfg.MaxUploadSize, logger)
+	router := api.NewRouter(storage.WithMetrics(store), api.Options{
+		MaxUploadSize:          cfg.MaxUploadSize,
+		MultipartMemory:        cfg.MultipartMemory,
+		CopyBuffers:            copyBuffers,
+		MaxPathSegments:        cfg.MaxPathSegments,
+		MaxNameBytes:           cfg.MaxNameBytes,
+		ListConcurrency:        cfg.ListConcurrency,
+		MaxListEntries:         cfg.MaxListEntries,
+		AssetMaxAge:            cfg.AssetCacheMaxAge,
+		MaxConcurrentUploads:   cfg.MaxUploads,
+		UploadQueueTimeout:     cfg.UploadQueueTimeout,
+		ResumableUploadDir:     cfg.ResumableDir,
+		ResumableUploadExpiry:  cfg.ResumableExpiry,
+		AllowedExtensions:      cfg.UploadAllowedExts,
+		AllowedMIMETypes:       cfg.UploadAllowedMIME,
+		HiddenPatterns:         cfg.HiddenPatterns,
+		PublicPaths:            cfg.PublicPaths,
+		HiddenMatchFullPath:    cfg.HiddenFullPath,
+		ContentTypes:           cfg.ContentTypes,
+		Inline:                 api.InlineTypes(cfg.InlineTypes...),
+		DownloadTrailers:       cfg.DownloadTrailers,
+		DownloadRateLimit:      cfg.DownloadRateLimit,
+		DownloadRateLimitTotal: cfg.DownloadRateTotal,
+		UploadQuota:            cfg.UploadQuota,
+		UploadQuotaWindow:      cfg.UploadQuotaWindow,
+		ContentPolicy:          middleware.ContentPolicy(cfg.ContentSecurity),
+		DeleteNoContent:        cfg.DeleteNoContent,
+		SoftDelete:             cfg.SoftDelete,
+		JWTSecret:              []byte(cfg.Auth.JWTSecret),
+		JWTIssuer:              cfg.Auth.JWTIssuer,
+		JWTAudience:            cfg.Auth.JWTAudience,
+		URLSigningSecret:       []byte(cfg.Auth.URLSigningSecret),
+		ScopeToUser:            cfg.Auth.UserScope,
+		ExposeBackend:          cfg.ExposeBackendHeader,
+		WebDAV:                 cfg.WebDAVEnabled,
+		IPAllow:                cfg.IPAllowlist,
+		IPDeny:                 cfg.IPDenylist,
+		TrustedProxies:         cfg.TrustedProxies,
+	}, logger)
+
+	ln, err := listen(cfg.Server.ListenAddr, cfg.Server.SocketMode)
+	if err != nil {
+		log.Fatalf("listen: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv, err := newServer(router, cfg.Server)
+	if err != nil {
+		log.Fatalf("configure server: %v", err)
+	}
+
+	logger.Info("server started", "addr", cfg.Server.ListenAddr, "backend", cfg.StorageBackend, "tls", srv.TLSConfig != nil, "h2c", cfg.Server.H2C)
 
-	logger.Info("server started", "port", cfg.Port, "backend", cfg.StorageBackend)
+	serveErr := serve(ctx, srv, ln, cfg.ShutdownTimeout)
+
+	// Backends holding connections (SMB, FTP) release them here; see DECISIONS.md.
+	if closer, ok := storage.Storage(store).(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Error("close storage backend", "error", err)
+		}
+	}
 
-	if err := http.ListenAndServe(":"+cfg.Port, router); err != nil {
-		log.Fatalf("server exited: %v", err)
+	if serveErr != nil {
+		log.Fatalf("server exited: %v", serveErr)
 	}
+	logger.Info("server stopped")
 }
 
 func parseLogLevel(s string) slog.Level {
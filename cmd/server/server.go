@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"go-storage-api/internal/config"
+)
+
+// newServer builds the http.Server for handler with cfg's timeouts and
+// header limit. HTTP/2 is served over TLS when cfg names a certificate,
+// and in cleartext (h2c, prior knowledge only) when cfg.H2C is set, for
+// proxies that speak HTTP/2 to the backend. HTTP/1.1 is always served.
+func newServer(handler http.Handler, cfg config.ServerConfig) (*http.Server, error) {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		Protocols:         new(http.Protocols),
+	}
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetUnencryptedHTTP2(cfg.H2C)
+
+	if cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS certificate: %w", err)
+		}
+		srv.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		srv.Protocols.SetHTTP2(true)
+	}
+	return srv, nil
+}
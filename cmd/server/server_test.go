@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/config"
+)
+
+var protoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, r.Proto)
+})
+
+func testServerConfig() config.ServerConfig {
+	return config.ServerConfig{
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       time.Minute,
+		MaxHeaderBytes:    1 << 16,
+	}
+}
+
+func TestNewServer_AppliesLimits(t *testing.T) {
+	cfg := config.ServerConfig{
+		ReadHeaderTimeout: 3 * time.Second,
+		ReadTimeout:       time.Minute,
+		WriteTimeout:      2 * time.Minute,
+		IdleTimeout:       90 * time.Second,
+		MaxHeaderBytes:    8192,
+	}
+	srv, err := newServer(protoHandler, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.ReadHeaderTimeout != cfg.ReadHeaderTimeout || srv.ReadTimeout != cfg.ReadTimeout ||
+		srv.WriteTimeout != cfg.WriteTimeout || srv.IdleTimeout != cfg.IdleTimeout || srv.MaxHeaderBytes != cfg.MaxHeaderBytes {
+		t.Errorf("server limits not applied: %+v", srv)
+	}
+	if srv.TLSConfig != nil {
+		t.Error("expected no TLS without a certificate")
+	}
+}
+
+func TestNewServer_H2C(t *testing.T) {
+	for _, h2c := range []bool{true, false} {
+		cfg := testServerConfig()
+		cfg.H2C = h2c
+		srv, err := newServer(protoHandler, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		url, _, _ := startServer(t, srv, time.Second)
+
+		client := &http.Client{Transport: &http.Transport{Protocols: new(http.Protocols)}}
+		client.Transport.(*http.Transport).Protocols.SetUnencryptedHTTP2(true)
+		resp, err := client.Get(url)
+		if h2c {
+			if err != nil {
+				t.Fatalf("h2c request: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "HTTP/2.0" {
+				t.Errorf("expected HTTP/2.0 with h2c enabled, got %q", body)
+			}
+		} else if err == nil {
+			resp.Body.Close()
+			t.Error("expected a prior-knowledge HTTP/2 request to fail with h2c disabled")
+		}
+
+		// HTTP/1.1 keeps working either way.
+		resp, err = http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "HTTP/1.1" {
+			t.Errorf("expected HTTP/1.1 from a plain client, got %q", body)
+		}
+	}
+}
+
+func TestNewServer_TLSNegotiatesHTTP2(t *testing.T) {
+	cfg := testServerConfig()
+	cfg.TLSCertFile, cfg.TLSKeyFile = writeTestCert(t)
+	srv, err := newServer(protoHandler, cfg)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	url, _, _ := startServer(t, srv, time.Second)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2.0 over TLS, got %q", body)
+	}
+}
+
+func TestNewServer_BadCertificate(t *testing.T) {
+	cfg := testServerConfig()
+	cfg.TLSCertFile = filepath.Join(t.TempDir(), "missing.pem")
+	cfg.TLSKeyFile = cfg.TLSCertFile
+	if _, err := newServer(protoHandler, cfg); err == nil {
+		t.Error("expected an error for a missing certificate")
+	}
+}
+
+func TestNewServer_ReadHeaderTimeoutDropsSlowClients(t *testing.T) {
+	cfg := testServerConfig()
+	cfg.ReadHeaderTimeout = 100 * time.Millisecond
+	srv, err := newServer(protoHandler, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, _, _ := startServer(t, srv, time.Second)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: x\r\n") // headers never finish
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+	io.ReadAll(conn)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the connection dropped after the header timeout, took %s", elapsed)
+	}
+}
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and its key,
+// returning their paths.
+func writeTestCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
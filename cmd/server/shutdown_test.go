@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// startServe runs serve with handler on a loopback listener and returns its
+// base URL, a cancel func that triggers shutdown, and serve's result channel.
+func startServe(t *testing.T, handler http.Handler, grace time.Duration) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	return startServer(t, &http.Server{Handler: handler}, grace)
+}
+
+// startServer is startServe for a configured srv. The URL is https when srv
+// has a TLSConfig.
+func startServer(t *testing.T, srv *http.Server, grace time.Duration) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// Pick the scheme before serve starts; it sets up TLSConfig for HTTP/2.
+	scheme := "http://"
+	if srv.TLSConfig != nil {
+		scheme = "https://"
+	}
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, srv, ln, grace) }()
+	return scheme + ln.Addr().String(), cancel, done
+}
+
+func TestServe_InFlightRequestFinishes(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(w, "done")
+	})
+	url, cancel, done := startServe(t, handler, 5*time.Second)
+
+	type result struct {
+		body string
+		err  error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			resc <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		resc <- result{string(b), err}
+	}()
+
+	<-started
+	cancel()
+
+	res := <-resc
+	if res.err != nil || res.body != "done" {
+		t.Errorf("in-flight request: body %q, err %v", res.body, res.err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("serve returned %v, want nil", err)
+	}
+
+	if _, err := http.Get(url); err == nil {
+		t.Error("expected new connections to be refused after shutdown")
+	}
+}
+
+func TestServe_ForceClosesAfterGrace(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	url, cancel, done := startServe(t, handler, 50*time.Millisecond)
+
+	go http.Get(url)
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("serve returned %v, want DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after the grace period")
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"mime"
+	"path"
+	"strings"
+)
+
+// allowlist is a case-insensitive set of permitted values. An empty
+// allowlist permits everything.
+type allowlist map[string]bool
+
+func newAllowlist(values []string, normalize func(string) string) allowlist {
+	a := make(allowlist, len(values))
+	for _, v := range values {
+		if v = normalize(v); v != "" {
+			a[v] = true
+		}
+	}
+	return a
+}
+
+func (a allowlist) allows(v string) bool {
+	return len(a) == 0 || a[v]
+}
+
+// normalizeExt lowercases an extension and ensures a leading dot, so "PDF",
+// ".pdf" and "pdf" all compare equal.
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext == "" || ext == "." {
+		return ""
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// normalizeMediaType reduces a Content-Type value to its lowercase media type
+// without parameters, e.g. "Text/Plain; charset=utf-8" becomes "text/plain".
+func normalizeMediaType(ct string) string {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(ct))
+	}
+	return mt
+}
+
+// filenameExt returns the normalized extension of a multipart filename.
+func filenameExt(name string) string {
+	return normalizeExt(path.Ext(strings.ReplaceAll(name, "\\", "/")))
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/storage"
+)
+
+// maxArchivePaths caps how many files one archive request may name.
+const maxArchivePaths = 1000
+
+// maxArchiveRequestSize caps the JSON body of an archive request.
+const maxArchiveRequestSize = 1 << 20
+
+// headerArchiveSkipped lists, URL-escaped and comma-separated, the requested
+// paths a lenient archive left out.
+const headerArchiveSkipped = "X-Archive-Skipped"
+
+// ArchiveRequest is the body of POST /api/v1/files/archive.
+type ArchiveRequest struct {
+	Paths  []string `json:"paths"`
+	Format string   `json:"format"`
+	Strict bool     `json:"strict"`
+}
+
+// Archive streams a zip of the requested files, each stored under its path
+// relative to the storage root. Every path is validated like the "path"
+// query parameter. Paths that do not exist or are directories fail the
+// request with 404 when Strict is set; otherwise they are left out and named
+// in the X-Archive-Skipped header. Files are copied straight from storage
+// into the response, so once streaming starts a read error can only truncate
+// the archive.
+func (h *Handler) Archive(w http.ResponseWriter, r *http.Request) {
+	var req ArchiveRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveRequestSize)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if writeTooLarge(w, err) {
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.Format != "" && req.Format != "zip" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "unsupported archive format: only zip is available")
+		return
+	}
+	if len(req.Paths) == 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "paths must not be empty")
+		return
+	}
+	if len(req.Paths) > maxArchivePaths {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "too many paths in one archive")
+		return
+	}
+
+	paths, err := h.cleanArchivePaths(req.Paths)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, pathErrorCode(err), err.Error())
+		return
+	}
+
+	var files []storage.FileInfo
+	var skipped []string
+	for _, p := range paths {
+		info, err := h.store.Stat(r.Context(), p)
+		switch {
+		case err == nil && !info.IsDir:
+			info.Path = p
+			files = append(files, *info)
+		case err == nil || errors.Is(err, storage.ErrNotFound):
+			skipped = append(skipped, p)
+		default:
+			handleStorageError(w, err)
+			return
+		}
+	}
+
+	if req.Strict && len(skipped) > 0 {
+		writeError(w, http.StatusNotFound, CodeNotFound, "files not found: "+strings.Join(skipped, ", "))
+		return
+	}
+
+	if len(skipped) > 0 {
+		escaped := make([]string, len(skipped))
+		for i, p := range skipped {
+			escaped[i] = url.PathEscape(p)
+		}
+		w.Header().Set(headerArchiveSkipped, strings.Join(escaped, ","))
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="archive.zip"`)
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		if err := h.addToArchive(r, zw, f); err != nil {
+			// Headers are already sent; abandon the archive so the client
+			// sees a truncated zip rather than a silently short one.
+			return
+		}
+	}
+	zw.Close()
+}
+
+// cleanArchivePaths validates each requested path and drops duplicates.
+func (h *Handler) cleanArchivePaths(raw []string) ([]string, error) {
+	seen := make(map[string]bool, len(raw))
+	paths := make([]string, 0, len(raw))
+	for _, p := range raw {
+		if p == "" {
+			return nil, errors.New("paths must not contain empty entries")
+		}
+		cleaned, err := middleware.CleanPath(p, h.pathLimits)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[cleaned] {
+			seen[cleaned] = true
+			paths = append(paths, cleaned)
+		}
+	}
+	return paths, nil
+}
+
+func (h *Handler) addToArchive(r *http.Request, zw *zip.Writer, f storage.FileInfo) error {
+	rc, err := h.store.Read(r.Context(), f.Path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	entry, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     strings.TrimPrefix(f.Path, "/"),
+		Method:   zip.Deflate,
+		Modified: f.ModTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = h.copyBuffers.Copy(entry, rc)
+	return err
+}
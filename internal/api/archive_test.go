@@ -0,0 +1,149 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// newArchiveStore serves the given files and treats "/docs" as a directory.
+func newArchiveStore(files map[string]string) *mockStorage {
+	return &mockStorage{
+		statFn: func(_ context.Context, path string) (*storage.FileInfo, error) {
+			if path == "/docs" {
+				return &storage.FileInfo{Name: "docs", IsDir: true}, nil
+			}
+			content, ok := files[path]
+			if !ok {
+				return nil, storage.ErrNotFound
+			}
+			return &storage.FileInfo{Name: path, Size: int64(len(content))}, nil
+		},
+		readFn: func(_ context.Context, path string) (io.ReadCloser, error) {
+			content, ok := files[path]
+			if !ok {
+				return nil, storage.ErrNotFound
+			}
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+	}
+}
+
+func archiveRequest(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/api/v1/files/archive", strings.NewReader(body))
+}
+
+func readArchive(t *testing.T, body []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	entries := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		entries[f.Name] = string(data)
+	}
+	return entries
+}
+
+func TestArchive_LenientSkipsMissing(t *testing.T) {
+	h := newTestHandler(newArchiveStore(map[string]string{
+		"/docs/a.txt":     "alpha",
+		"/docs/sub/b.txt": "beta",
+		"/other.txt":      "unrequested",
+	}))
+
+	req := archiveRequest(`{"paths":["/docs/a.txt","/docs/sub/b.txt","/missing.txt","/docs"],"format":"zip"}`)
+	rr := httptest.NewRecorder()
+	h.Archive(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("expected application/zip, got %q", ct)
+	}
+	if got := rr.Header().Get(headerArchiveSkipped); got != "%2Fmissing.txt,%2Fdocs" {
+		t.Errorf("unexpected %s header %q", headerArchiveSkipped, got)
+	}
+
+	entries := readArchive(t, rr.Body.Bytes())
+	want := map[string]string{"docs/a.txt": "alpha", "docs/sub/b.txt": "beta"}
+	if len(entries) != len(want) {
+		t.Errorf("expected %d entries, got %v", len(want), entries)
+	}
+	for name, content := range want {
+		if entries[name] != content {
+			t.Errorf("entry %s = %q, want %q", name, entries[name], content)
+		}
+	}
+}
+
+func TestArchive_StrictFailsOnMissing(t *testing.T) {
+	h := newTestHandler(newArchiveStore(map[string]string{"/docs/a.txt": "alpha"}))
+
+	req := archiveRequest(`{"paths":["/docs/a.txt","/missing.txt"],"strict":true}`)
+	rr := httptest.NewRecorder()
+	h.Archive(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "/missing.txt") {
+		t.Errorf("expected error to name the missing path, got %s", rr.Body.String())
+	}
+}
+
+func TestArchive_StrictAllPresent(t *testing.T) {
+	h := newTestHandler(newArchiveStore(map[string]string{"/a.txt": "alpha", "/b.txt": "beta"}))
+
+	req := archiveRequest(`{"paths":["/a.txt","/b.txt","/a.txt"],"strict":true}`)
+	rr := httptest.NewRecorder()
+	h.Archive(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	entries := readArchive(t, rr.Body.Bytes())
+	if len(entries) != 2 || entries["a.txt"] != "alpha" || entries["b.txt"] != "beta" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+}
+
+func TestArchive_BadRequests(t *testing.T) {
+	h := newTestHandler(newArchiveStore(map[string]string{}))
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid json", `{"paths":`},
+		{"no paths", `{"paths":[]}`},
+		{"unsupported format", `{"paths":["/a.txt"],"format":"tar"}`},
+		{"traversal", `{"paths":["/a.txt","/../etc/passwd"]}`},
+		{"control characters", `{"paths":["/a\u0000.txt"]}`},
+		{"empty path", `{"paths":[""]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.Archive(rr, archiveRequest(tt.body))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rr.Code)
+			}
+		})
+	}
+}
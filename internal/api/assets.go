@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/storage"
+)
+
+// Asset serves the file named by the rest of the URL path inline, for
+// browsers loading uploaded images, stylesheets and scripts directly.
+// Responses carry Cache-Control with Options.AssetMaxAge (private when
+// authentication is on), Last-Modified and a weak ETag built from the
+// size and modification time, and http.ServeContent answers conditional
+// and Range requests from them.
+func (h *Handler) Asset(w http.ResponseWriter, r *http.Request) {
+	p, err := middleware.CleanPath(r.PathValue("path"), h.pathLimits)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, pathErrorCode(err), err.Error())
+		return
+	}
+
+	info, err := h.store.Stat(r.Context(), p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	if info.IsDir {
+		handleStorageError(w, storage.ErrIsDirectory)
+		return
+	}
+	rs, err := storage.ReadSeeker(r.Context(), h.store, p)
+	if errors.Is(err, storage.ErrNotSupported) {
+		rs, err = h.streamSeeker(r, p, info.Size)
+	}
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	defer rs.Close()
+
+	scope := "public"
+	if h.privateAssets {
+		scope = "private"
+	}
+	w.Header().Set("Cache-Control", scope+", max-age="+strconv.Itoa(int(h.assetMaxAge.Seconds())))
+	if !info.ModTime.IsZero() {
+		w.Header().Set("ETag", fmt.Sprintf(`W/"%x-%x"`, info.Size, info.ModTime.UnixNano()))
+	}
+	if ct := h.contentTypes.byName(p); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	http.ServeContent(w, r, info.Name, info.ModTime, rs)
+}
+
+// streamSeeker opens the file at p for a backend that cannot seek, as an
+// assetReader.
+func (h *Handler) streamSeeker(r *http.Request, p string, size int64) (io.ReadSeekCloser, error) {
+	rc, err := h.store.Read(r.Context(), p)
+	if err != nil {
+		return nil, err
+	}
+	return &assetReader{
+		src: &rangeSource{
+			open:        func() (io.ReadCloser, error) { return h.store.Read(r.Context(), p) },
+			copyBuffers: h.copyBuffers,
+			rc:          rc,
+			body:        rc,
+		},
+		size: size,
+	}, nil
+}
+
+// assetReader is the io.ReadSeeker http.ServeContent needs, over a
+// rangeSource, for backends without storage.SeekableReader. Seeks only record the offset; the next Read moves the
+// stream there, so the size probe ServeContent starts with costs nothing
+// and backends that cannot seek are reopened only to go backwards.
+type assetReader struct {
+	src  *rangeSource
+	size int64
+	off  int64
+}
+
+func (a *assetReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += a.off
+	case io.SeekEnd:
+		offset += a.size
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("seek to negative offset %d", offset)
+	}
+	a.off = offset
+	return offset, nil
+}
+
+func (a *assetReader) Close() error {
+	return a.src.Close()
+}
+
+func (a *assetReader) Read(b []byte) (int, error) {
+	if a.off != a.src.pos {
+		if err := a.src.seek(a.off); err != nil {
+			return 0, err
+		}
+	}
+	n, err := a.src.body.Read(b)
+	a.src.pos += int64(n)
+	a.off += int64(n)
+	return n, err
+}
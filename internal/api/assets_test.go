@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+var assetModTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newAssetRouter serves files from a map, counting reads in *reads. With
+// seekable set the readers it hands out can seek.
+func newAssetRouter(files map[string]string, seekable bool, reads *int) http.Handler {
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			if p == "docs" {
+				return &storage.FileInfo{Name: p, Path: p, IsDir: true}, nil
+			}
+			b, ok := files[p]
+			if !ok {
+				return nil, storage.ErrNotFound
+			}
+			return &storage.FileInfo{Name: p, Path: p, Size: int64(len(b)), ModTime: assetModTime}, nil
+		},
+		readFn: func(_ context.Context, p string) (io.ReadCloser, error) {
+			*reads++
+			if seekable {
+				return readSeekNopCloser{strings.NewReader(files[p])}, nil
+			}
+			return io.NopCloser(strings.NewReader(files[p])), nil
+		},
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	return NewRouter(store, Options{AssetMaxAge: time.Hour}, logger)
+}
+
+func getAsset(h http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAsset_ServesWithCachingHeaders(t *testing.T) {
+	var reads int
+	h := newAssetRouter(map[string]string{"site/app.css": "body { color: red }"}, false, &reads)
+
+	rr := getAsset(h, "/api/v1/assets/site/app.css", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr.Body.String() != "body { color: red }" {
+		t.Errorf("unexpected body %q", rr.Body.String())
+	}
+	if cc := rr.Header().Get("Cache-Control"); cc != "public, max-age=3600" {
+		t.Errorf("expected public, max-age=3600, got %q", cc)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("expected text/css, got %q", ct)
+	}
+	if lm := rr.Header().Get("Last-Modified"); lm != assetModTime.Format(http.TimeFormat) {
+		t.Errorf("unexpected Last-Modified %q", lm)
+	}
+	if etag := rr.Header().Get("ETag"); !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("expected a weak ETag, got %q", etag)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); cd != "" {
+		t.Errorf("expected the file served inline, got Content-Disposition %q", cd)
+	}
+}
+
+func TestAsset_NotModified(t *testing.T) {
+	var reads int
+	h := newAssetRouter(map[string]string{"logo.svg": "<svg/>"}, false, &reads)
+	etag := getAsset(h, "/api/v1/assets/logo.svg", nil).Header().Get("ETag")
+
+	tests := []struct {
+		name   string
+		header http.Header
+	}{
+		{"If-None-Match", http.Header{"If-None-Match": {etag}}},
+		{"If-Modified-Since", http.Header{"If-Modified-Since": {assetModTime.Format(http.TimeFormat)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := getAsset(h, "/api/v1/assets/logo.svg", tt.header)
+			if rr.Code != http.StatusNotModified {
+				t.Fatalf("expected 304, got %d", rr.Code)
+			}
+			if rr.Body.Len() != 0 {
+				t.Errorf("expected no body, got %q", rr.Body.String())
+			}
+			if cc := rr.Header().Get("Cache-Control"); cc == "" {
+				t.Error("expected Cache-Control on the 304")
+			}
+		})
+	}
+
+	rr := getAsset(h, "/api/v1/assets/logo.svg", http.Header{"If-None-Match": {`W/"stale"`}})
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200 for a stale ETag, got %d", rr.Code)
+	}
+}
+
+func TestAsset_Range(t *testing.T) {
+	for _, seekable := range []bool{false, true} {
+		var reads int
+		h := newAssetRouter(map[string]string{"data.txt": "0123456789"}, seekable, &reads)
+
+		rr := getAsset(h, "/api/v1/assets/data.txt", http.Header{"Range": {"bytes=2-5"}})
+		if rr.Code != http.StatusPartialContent {
+			t.Fatalf("seekable=%v: expected 206, got %d", seekable, rr.Code)
+		}
+		if rr.Body.String() != "2345" {
+			t.Errorf("seekable=%v: expected 2345, got %q", seekable, rr.Body.String())
+		}
+		if cr := rr.Header().Get("Content-Range"); cr != "bytes 2-5/10" {
+			t.Errorf("seekable=%v: unexpected Content-Range %q", seekable, cr)
+		}
+		if reads != 1 {
+			t.Errorf("seekable=%v: expected one read for a forward range, got %d", seekable, reads)
+		}
+	}
+}
+
+func TestAsset_MultipleRangesOutOfOrder(t *testing.T) {
+	var reads int
+	h := newAssetRouter(map[string]string{"data.txt": "0123456789"}, false, &reads)
+
+	rr := getAsset(h, "/api/v1/assets/data.txt", http.Header{"Range": {"bytes=6-7,0-1"}})
+	if rr.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", rr.Code)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "67") || !strings.Contains(body, "01") {
+		t.Errorf("expected both ranges in the body, got %q", body)
+	}
+	if reads != 2 {
+		t.Errorf("expected the file reopened to go back, got %d reads", reads)
+	}
+}
+
+func TestAsset_Errors(t *testing.T) {
+	var reads int
+	h := newAssetRouter(map[string]string{"a.txt": "a"}, false, &reads)
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/api/v1/assets/missing.txt", http.StatusNotFound},
+		{"/api/v1/assets/docs", http.StatusBadRequest},
+		{"/api/v1/assets/a%5C..%5Cb", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rr := getAsset(h, tt.target, nil); rr.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d: %s", tt.target, tt.want, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestAssetReader_SeekEnd(t *testing.T) {
+	src := &rangeSource{
+		open:        func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("hello")), nil },
+		copyBuffers: NewHandler(&mockStorage{}, Options{}).copyBuffers,
+	}
+	src.rc, _ = src.open()
+	src.body = src.rc
+	a := &assetReader{src: src, size: 5}
+
+	if n, err := a.Seek(0, io.SeekEnd); err != nil || n != 5 {
+		t.Fatalf("Seek(0, SeekEnd) = %d, %v", n, err)
+	}
+	if _, err := a.Seek(-3, io.SeekCurrent); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, a); err != nil || out.String() != "llo" {
+		t.Errorf("expected llo, got %q (%v)", out.String(), err)
+	}
+	if _, err := a.Seek(-1, io.SeekStart); err == nil {
+		t.Error("expected an error seeking before the start")
+	}
+}
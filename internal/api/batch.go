@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+
+	"go-storage-api/internal/middleware"
+)
+
+// batchField is the multipart field name that always selects a batch upload,
+// even with a single part.
+const batchField = "files"
+
+// UploadResult reports the outcome for one part of a batch upload.
+type UploadResult struct {
+	Filename string `json:"filename"`
+	Path     string `json:"path,omitempty"`
+	Status   int    `json:"status"`
+	Code     string `json:"code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// uploadBatch writes each part to prefix joined with the part's filename.
+// Each destination gets the same validation as a "path" parameter, and each part is checked and
+// written independently, so one bad file does not fail the others. The
+// response is always 207 Multi-Status with one UploadResult per part, in
+// request order. The whole body is still bounded by maxUploadSize.
+func (h *Handler) uploadBatch(w http.ResponseWriter, r *http.Request, prefix string, parts []*multipart.FileHeader, overwrite bool) {
+	results := make([]UploadResult, len(parts))
+	for i, part := range parts {
+		results[i] = h.uploadPart(r.Context(), prefix, part, overwrite)
+	}
+	writeJSON(w, http.StatusMultiStatus, results)
+}
+
+func (h *Handler) uploadPart(ctx context.Context, prefix string, part *multipart.FileHeader, overwrite bool) UploadResult {
+	res := UploadResult{Filename: part.Filename}
+	fail := func(status int, code, msg string) UploadResult {
+		res.Status, res.Code, res.Error = status, code, msg
+		return res
+	}
+
+	// Like single uploads, keep only the final element; Windows clients may
+	// send the full C:\dir\name path.
+	name := path.Base(strings.ReplaceAll(part.Filename, "\\", "/"))
+	if name == "." || name == "/" {
+		return fail(http.StatusBadRequest, CodePathInvalid, "part has no filename")
+	}
+	dest, err := middleware.CleanPath(prefix+"/"+name, h.pathLimits)
+	if err != nil {
+		return fail(http.StatusBadRequest, pathErrorCode(err), err.Error())
+	}
+	res.Path = dest
+
+	if reason := h.disallowed(name, part.Header.Get("Content-Type")); reason != "" {
+		return fail(http.StatusUnsupportedMediaType, CodeUnsupportedType, reason)
+	}
+
+	file, err := part.Open()
+	if err != nil {
+		return fail(http.StatusBadRequest, CodeInvalidRequest, "unreadable part: "+err.Error())
+	}
+	defer file.Close()
+
+	if err := h.write(ctx, dest, file, overwrite); err != nil {
+		return fail(classifyStorageError(err))
+	}
+	res.Status = http.StatusCreated
+	return res
+}
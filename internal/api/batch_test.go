@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// batchPart is one file part of a batch upload request.
+type batchPart struct {
+	field, filename, content string
+}
+
+func createBatchRequest(t *testing.T, path string, parts ...batchPart) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		fw, err := w.CreateFormFile(p.field, p.filename)
+		if err != nil {
+			t.Fatalf("CreateFormFile: %v", err)
+		}
+		fw.Write([]byte(p.content))
+	}
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload?path="+path, &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func newBatchStore(written map[string]string) *mockStorage {
+	return &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, path string, r io.Reader) error {
+			data, _ := io.ReadAll(r)
+			written[path] = string(data)
+			return nil
+		},
+	}
+}
+
+func TestUploadBatch_PartialFailure(t *testing.T) {
+	written := map[string]string{}
+	h := newTestHandler(newBatchStore(written))
+
+	req := createBatchRequest(t, "/inbox",
+		batchPart{"file", "good.txt", "good"},
+		batchPart{"file", "..", "evil"},
+	)
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var results []UploadResult
+	json.NewDecoder(rr.Body).Decode(&results)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+
+	if results[0].Status != http.StatusCreated || results[0].Path != "/inbox/good.txt" {
+		t.Errorf("good.txt: unexpected result %+v", results[0])
+	}
+	if results[1].Status != http.StatusBadRequest || results[1].Code != CodePathInvalid {
+		t.Errorf("..: expected 400 %s, got %+v", CodePathInvalid, results[1])
+	}
+
+	if written["/inbox/good.txt"] != "good" {
+		t.Errorf("expected good.txt written, got %v", written)
+	}
+	if len(written) != 1 {
+		t.Errorf("expected only one write, got %v", written)
+	}
+}
+
+func TestUploadBatch_FilesFieldAndWindowsFilename(t *testing.T) {
+	written := map[string]string{}
+	h := newTestHandler(newBatchStore(written))
+
+	req := createBatchRequest(t, "/", batchPart{"files", `C:\photos\a.jpg`, "jpeg"})
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d", rr.Code)
+	}
+	if written["/a.jpg"] != "jpeg" {
+		t.Errorf("expected /a.jpg written, got %v", written)
+	}
+}
+
+func TestUploadBatch_PerFileConflict(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, path string) (*storage.FileInfo, error) {
+			if path == "/b.txt" {
+				return &storage.FileInfo{Name: "b.txt"}, nil
+			}
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, _ string, r io.Reader) error {
+			io.Copy(io.Discard, r)
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := createBatchRequest(t, "/", batchPart{"files", "a.txt", "a"}, batchPart{"files", "b.txt", "b"})
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	var results []UploadResult
+	json.NewDecoder(rr.Body).Decode(&results)
+	if len(results) != 2 || results[0].Status != http.StatusCreated || results[1].Code != CodeConflict {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestUploadBatch_CombinedSizeLimit(t *testing.T) {
+	h := NewHandler(newBatchStore(map[string]string{}), Options{MaxUploadSize: 1024})
+
+	req := createBatchRequest(t, "/",
+		batchPart{"files", "a.bin", strings.Repeat("a", 700)},
+		batchPart{"files", "b.bin", strings.Repeat("b", 700)},
+	)
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", rr.Code)
+	}
+}
+
+func TestUploadBatch_RejectsContentMD5(t *testing.T) {
+	h := newTestHandler(newBatchStore(map[string]string{}))
+
+	req := createBatchRequest(t, "/", batchPart{"files", "a.txt", "a"}, batchPart{"files", "b.txt", "b"})
+	req.Header.Set("Content-MD5", contentMD5("a"))
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/storage"
+)
+
+// maxBatchStatPaths caps how many paths one batch-stat request may name.
+const maxBatchStatPaths = 1000
+
+// maxBatchStatRequestSize caps the JSON body of a batch-stat request.
+const maxBatchStatRequestSize = 1 << 20
+
+// batchStatWorkers bounds how many Stat calls one request runs at once, so a
+// large batch cannot monopolize a backend's connections.
+const batchStatWorkers = 8
+
+// BatchStatRequest is the body of POST /api/v1/files/batch-stat.
+type BatchStatRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// StatResult reports the metadata, or the failure, for one requested path.
+// Path echoes the request so clients can match results to their input.
+type StatResult struct {
+	Path  string            `json:"path"`
+	Info  *storage.FileInfo `json:"info,omitempty"`
+	Code  string            `json:"code,omitempty"`
+	Error string            `json:"error,omitempty"`
+}
+
+// BatchStat returns metadata for many paths in one round trip. Each path is
+// validated like the "path" query parameter; invalid, missing or otherwise
+// failing paths get a per-entry code and error instead of failing the
+// request. Results come back in request order.
+func (h *Handler) BatchStat(w http.ResponseWriter, r *http.Request) {
+	var req BatchStatRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchStatRequestSize)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if writeTooLarge(w, err) {
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if len(req.Paths) == 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "paths must not be empty")
+		return
+	}
+	if len(req.Paths) > maxBatchStatPaths {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "too many paths in one batch")
+		return
+	}
+
+	results := make([]StatResult, len(req.Paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(batchStatWorkers, len(req.Paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = h.statOne(r.Context(), req.Paths[i])
+			}
+		}()
+	}
+
+feed:
+	for i := range req.Paths {
+		select {
+		case jobs <- i:
+		case <-r.Context().Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if r.Context().Err() != nil {
+		// The client is gone; nobody is left to read a response.
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (h *Handler) statOne(ctx context.Context, raw string) StatResult {
+	res := StatResult{Path: raw}
+	if raw == "" {
+		res.Code, res.Error = CodePathInvalid, "path must not be empty"
+		return res
+	}
+	p, err := middleware.CleanPath(raw, h.pathLimits)
+	if err != nil {
+		res.Code, res.Error = pathErrorCode(err), err.Error()
+		return res
+	}
+
+	info, err := h.store.Stat(ctx, p)
+	if err != nil {
+		_, res.Code, res.Error = classifyStorageError(err)
+		return res
+	}
+	res.Info = info
+	return res
+}
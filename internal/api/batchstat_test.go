@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+func newBatchStatRequest(t *testing.T, paths ...string) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(BatchStatRequest{Paths: paths})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return httptest.NewRequest(http.MethodPost, "/api/v1/files/batch-stat", strings.NewReader(string(body)))
+}
+
+func TestBatchStat_MixedResults(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			if p == "/docs/a.txt" {
+				return &storage.FileInfo{Name: "a.txt", Path: "docs/a.txt", Size: 3}, nil
+			}
+			return nil, storage.ErrNotFound
+		},
+	}
+	h := newTestHandler(store)
+
+	rr := httptest.NewRecorder()
+	h.BatchStat(rr, newBatchStatRequest(t, "/docs/a.txt", "/docs/missing.txt", "/../etc/passwd", ""))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var results []StatResult
+	if err := json.NewDecoder(rr.Body).Decode(&results); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %+v", results)
+	}
+
+	if results[0].Path != "/docs/a.txt" || results[0].Info == nil || results[0].Info.Size != 3 || results[0].Error != "" {
+		t.Errorf("existing file: unexpected result %+v", results[0])
+	}
+	if results[1].Path != "/docs/missing.txt" || results[1].Info != nil || results[1].Code != CodeNotFound {
+		t.Errorf("missing file: unexpected result %+v", results[1])
+	}
+	if results[2].Code != CodePathInvalid || results[2].Info != nil {
+		t.Errorf("traversal: unexpected result %+v", results[2])
+	}
+	if results[3].Code != CodePathInvalid {
+		t.Errorf("empty path: unexpected result %+v", results[3])
+	}
+}
+
+func TestBatchStat_BoundedConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			mu.Lock()
+			active++
+			peak = max(peak, active)
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			active--
+			mu.Unlock()
+			return &storage.FileInfo{Name: p}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	paths := make([]string, 40)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/f%d", i)
+	}
+	rr := httptest.NewRecorder()
+	h.BatchStat(rr, newBatchStatRequest(t, paths...))
+
+	var results []StatResult
+	json.NewDecoder(rr.Body).Decode(&results)
+	for i, res := range results {
+		if res.Path != paths[i] || res.Info == nil {
+			t.Fatalf("result %d out of order or missing: %+v", i, res)
+		}
+	}
+	if peak > batchStatWorkers {
+		t.Errorf("expected at most %d concurrent Stat calls, saw %d", batchStatWorkers, peak)
+	}
+}
+
+func TestBatchStat_InvalidRequests(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not json", "{"},
+		{"no paths", `{"paths":[]}`},
+		{"too many paths", `{"paths":[` + strings.Repeat(`"/a",`, maxBatchStatPaths) + `"/a"]}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/batch-stat", strings.NewReader(tt.body))
+		rr := httptest.NewRecorder()
+		h.BatchStat(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", tt.name, rr.Code)
+		}
+	}
+}
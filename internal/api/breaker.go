@@ -0,0 +1,45 @@
+package api
+
+import (
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go-storage-api/internal/storage"
+)
+
+// registerBreakerMetrics exports the state of each of store's circuit
+// breakers as storage_circuit_breaker_state{operation}: 0 closed, 1
+// half-open, 2 open. Stores without breakers export nothing.
+func registerBreakerMetrics(reg prometheus.Registerer, store storage.Storage) {
+	br, ok := store.(storage.BreakerReporter)
+	if !ok {
+		return
+	}
+	for op := range br.BreakerStates() {
+		reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "storage_circuit_breaker_state",
+			Help:        "Storage circuit breaker state by operation: 0 closed, 1 half-open, 2 open.",
+			ConstLabels: prometheus.Labels{"operation": op},
+		}, func() float64 {
+			return float64(br.BreakerStates()[op])
+		}))
+	}
+}
+
+// openBreakers returns the operations whose circuit breaker in store is
+// open, sorted, or nil when there are none or store has no breakers.
+func openBreakers(store storage.Storage) []string {
+	br, ok := store.(storage.BreakerReporter)
+	if !ok {
+		return nil
+	}
+	var open []string
+	for op, state := range br.BreakerStates() {
+		if state == storage.BreakerOpen {
+			open = append(open, op)
+		}
+	}
+	slices.Sort(open)
+	return open
+}
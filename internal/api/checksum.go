@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"log/slog"
+
+	"go-storage-api/internal/storage"
+)
+
+// errChecksumMismatch means the uploaded bytes did not hash to the
+// client's Content-MD5 value; the upload was not stored.
+var errChecksumMismatch = errors.New("content md5 mismatch")
+
+// errInvalidContentMD5 means the Content-MD5 header is not a base64-encoded
+// 16-byte digest.
+var errInvalidContentMD5 = errors.New("invalid Content-MD5 header")
+
+// parseContentMD5 decodes a Content-MD5 header value (RFC 1864). An empty
+// header returns (nil, nil), meaning no verification is requested.
+func parseContentMD5(header string) ([]byte, error) {
+	if header == "" {
+		return nil, nil
+	}
+	sum, err := base64.StdEncoding.DecodeString(header)
+	if err != nil || len(sum) != md5.Size {
+		return nil, errInvalidContentMD5
+	}
+	return sum, nil
+}
+
+// writeVerified is write with an optional integrity check: when want is
+// non-nil the MD5 of the bytes handed to storage is computed in the same
+// pass, and a mismatch fails the final read, so the backend aborts the
+// write before it replaces anything and errChecksumMismatch is returned.
+// For multipart uploads the hash covers the decoded file part only, not the
+// multipart envelope.
+func (h *Handler) writeVerified(ctx context.Context, path string, r io.Reader, overwrite bool, want []byte) error {
+	if want == nil {
+		return h.write(ctx, path, r, overwrite)
+	}
+
+	v := &md5Verifier{r: r, sum: md5.New(), want: want}
+	err := h.write(ctx, path, v, overwrite)
+	if !v.mismatch {
+		return err
+	}
+	// Backends that do not stage writes may have kept the partial file.
+	// Only remove one this request created, never one it was replacing.
+	if !overwrite && !errors.Is(err, storage.ErrExist) {
+		if derr := h.store.Delete(ctx, path); derr != nil && !errors.Is(derr, storage.ErrNotFound) {
+			h.logger.Warn("remove upload after checksum mismatch",
+				slog.String("path", path), slog.String("error", derr.Error()))
+		}
+	}
+	return errChecksumMismatch
+}
+
+// md5Verifier hashes what is read through it and, at EOF, returns
+// errChecksumMismatch instead of io.EOF if the digest is not want.
+type md5Verifier struct {
+	r        io.Reader
+	sum      hash.Hash
+	want     []byte
+	mismatch bool
+}
+
+func (v *md5Verifier) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.sum.Write(p[:n])
+	if err == io.EOF && !bytes.Equal(v.sum.Sum(nil), v.want) {
+		v.mismatch = true
+		return n, errChecksumMismatch
+	}
+	return n, err
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"mime"
+	"path/filepath"
+)
+
+// defaultContentTypes pins the types of common web files whose
+// mime.TypeByExtension result depends on the platform's mime.types, or that
+// it does not know at all.
+var defaultContentTypes = map[string]string{
+	".md":          "text/markdown; charset=utf-8",
+	".markdown":    "text/markdown; charset=utf-8",
+	".yaml":        "application/yaml",
+	".yml":         "application/yaml",
+	".toml":        "application/toml",
+	".csv":         "text/csv; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".webmanifest": "application/manifest+json",
+	".wasm":        "application/wasm",
+	".svg":         "image/svg+xml",
+	".webp":        "image/webp",
+	".avif":        "image/avif",
+}
+
+// contentTypes maps lowercase extensions to the Content-Type served for
+// them ahead of mime.TypeByExtension.
+type contentTypes map[string]string
+
+// newContentTypes returns defaultContentTypes with overrides applied on
+// top. Override keys are normalized like allowlist extensions.
+func newContentTypes(overrides map[string]string) contentTypes {
+	c := make(contentTypes, len(defaultContentTypes)+len(overrides))
+	for ext, ct := range defaultContentTypes {
+		c[ext] = ct
+	}
+	for ext, ct := range overrides {
+		if ext = normalizeExt(ext); ext != "" && ct != "" {
+			c[ext] = ct
+		}
+	}
+	return c
+}
+
+// byName returns the Content-Type for name's extension, or "" if neither
+// the map nor mime.TypeByExtension knows it.
+func (c contentTypes) byName(name string) string {
+	ext := normalizeExt(filepath.Ext(name))
+	if ct, ok := c[ext]; ok {
+		return ct
+	}
+	return mime.TypeByExtension(ext)
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/storage"
+)
+
+// errIntoItself refuses a copy or move of a directory to itself or below
+// itself, which would never finish.
+var errIntoItself = errors.New("cannot copy or move a directory into itself")
+
+// errDirOntoFile refuses an overwrite that would merge a directory into an
+// existing file.
+var errDirOntoFile = errors.New("cannot overwrite a file with a directory")
+
+// TransferResponse is returned by Copy and Move.
+type TransferResponse struct {
+	Message string `json:"message"`
+	Path    string `json:"path"`
+
+	// Files counts the files written at the destination; zero when a move
+	// renamed the source in one step.
+	Files int `json:"files"`
+}
+
+// Copy copies the file or directory at path to "to", recreating a
+// directory's whole tree with streamed file copies. Without
+// overwrite=true it answers 409 if anything exists at "to"; with it a
+// directory is merged into an existing one, replacing files of the same
+// name. Custom metadata is not copied. Copied files get the current time
+// unless preserve_times=true, which gives each the source's ModTime.
+// dry_run=true answers with the destination paths the copy would write and
+// writes nothing.
+func (h *Handler) Copy(w http.ResponseWriter, r *http.Request) {
+	from, to, overwrite, ok := h.transferParams(w, r)
+	if !ok {
+		return
+	}
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	preserveTimes, ok := parseBoolParam(w, r, "preserve_times", false)
+	if !ok {
+		return
+	}
+	info, err := h.transferSource(r.Context(), from, to, overwrite)
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+
+	steps, err := h.planTransfer(r.Context(), info, from, to)
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+	if dryRun {
+		writeDryRun(w, destinations(steps))
+		return
+	}
+	n, err := h.copyTree(r.Context(), steps, overwrite, preserveTimes)
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+	h.writeDone(w, http.StatusCreated, TransferResponse{Message: "copied", Path: to, Files: n})
+}
+
+// Move moves the file or directory at path to "to". It renames it in one
+// step when the backend can (directories included on the local backend),
+// and otherwise copies the tree and deletes the source. overwrite works
+// as for Copy; merging into an existing directory always takes the copy
+// path. dry_run=true answers with the source paths the move would remove
+// followed by the destination paths it would write, and moves nothing.
+func (h *Handler) Move(w http.ResponseWriter, r *http.Request) {
+	from, to, overwrite, ok := h.transferParams(w, r)
+	if !ok {
+		return
+	}
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	info, err := h.transferSource(ctx, from, to, overwrite)
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+
+	if dryRun {
+		steps, err := h.planTransfer(ctx, info, from, to)
+		if err != nil {
+			writeTransferError(w, err)
+			return
+		}
+		paths := make([]string, 0, 2*len(steps))
+		for _, s := range steps {
+			paths = append(paths, s.from)
+		}
+		writeDryRun(w, append(paths, destinations(steps)...))
+		return
+	}
+
+	var n int
+	_, statErr := h.store.Stat(ctx, to)
+	merge := info.IsDir && statErr == nil
+	if !merge {
+		err = storage.Rename(ctx, h.store, from, to)
+		if !info.IsDir || !errors.Is(err, storage.ErrNotSupported) && !errors.Is(err, storage.ErrIsDirectory) {
+			if err != nil {
+				writeTransferError(w, err)
+				return
+			}
+			h.writeDone(w, http.StatusOK, TransferResponse{Message: "moved", Path: to})
+			return
+		}
+	}
+
+	// No rename for directories: copy, then remove the source.
+	steps, err := h.planTree(ctx, from, to)
+	if err == nil {
+		n, err = h.copyTree(ctx, steps, overwrite, false)
+	}
+	if err == nil {
+		err = storage.DeleteAll(ctx, h.store, from)
+	}
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+	h.writeDone(w, http.StatusOK, TransferResponse{Message: "moved", Path: to, Files: n})
+}
+
+// transferParams reads Copy's and Move's path, to and overwrite
+// parameters, writing a 400 and returning ok=false if one is invalid.
+func (h *Handler) transferParams(w http.ResponseWriter, r *http.Request) (from, to string, overwrite, ok bool) {
+	from = r.URL.Query().Get("path")
+	if from == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return "", "", false, false
+	}
+	to = r.URL.Query().Get("to")
+	if to == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "to query parameter is required")
+		return "", "", false, false
+	}
+	to, err := middleware.CleanPath(to, h.pathLimits)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, pathErrorCode(err), "to: "+err.Error())
+		return "", "", false, false
+	}
+	overwrite, ok = parseOverwrite(w, r)
+	return path.Clean("/" + from), path.Clean("/" + to), overwrite, ok
+}
+
+// transferSource stats the source of a copy or move and checks that it
+// can go to "to": nothing may exist there without overwrite, and a
+// directory may only overwrite a directory.
+func (h *Handler) transferSource(ctx context.Context, from, to string, overwrite bool) (*storage.FileInfo, error) {
+	if from == "/" {
+		return nil, storage.ErrPermission
+	}
+	if from == to || strings.HasPrefix(to, from+"/") {
+		return nil, errIntoItself
+	}
+	info, err := h.store.Stat(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := h.store.Stat(ctx, to)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+	case err != nil:
+		return nil, err
+	case !overwrite:
+		return nil, storage.ErrExist
+	case info.IsDir && !dst.IsDir:
+		return nil, errDirOntoFile
+	}
+	return info, nil
+}
+
+// copyFile streams the file at from to to.
+func (h *Handler) copyFile(ctx context.Context, from, to string, overwrite bool) error {
+	rc, err := h.store.Read(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return h.put(ctx, to, rc, overwrite)
+}
+
+// transferStep is one file or directory of a tree copy or move. modTime
+// is the source's, for preserve_times.
+type transferStep struct {
+	from, to string
+	dir      bool
+	modTime  time.Time
+}
+
+// planTransfer returns the steps that copy the file or directory info at
+// from to to.
+func (h *Handler) planTransfer(ctx context.Context, info *storage.FileInfo, from, to string) ([]transferStep, error) {
+	if !info.IsDir {
+		return []transferStep{{from: from, to: to, modTime: info.ModTime}}, nil
+	}
+	return h.planTree(ctx, from, to)
+}
+
+// planTree lists the directory at from and returns the steps that
+// recreate it at to: the directory itself first, then everything below it
+// with parents before their children. Every destination path is checked
+// like a request path.
+func (h *Handler) planTree(ctx context.Context, from, to string) ([]transferStep, error) {
+	entries, err := storage.ListRecursive(ctx, h.store, from, h.listConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	// Parents sort before their children.
+	slices.SortFunc(entries, func(a, b storage.FileInfo) int { return strings.Compare(a.Path, b.Path) })
+
+	steps := []transferStep{{from: from, to: to, dir: true}}
+	for _, e := range entries {
+		src := path.Clean("/" + e.Path)
+		rel, ok := strings.CutPrefix(src, from+"/")
+		if !ok {
+			continue
+		}
+		dst, err := middleware.CleanPath(path.Join(to, rel), h.pathLimits)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, transferStep{from: src, to: dst, dir: e.IsDir, modTime: e.ModTime})
+	}
+	return steps, nil
+}
+
+// copyTree carries out steps from planTransfer, returning how many files it
+// copied. With preserveTimes each copied file gets its source's ModTime.
+// ctx is checked between files.
+func (h *Handler) copyTree(ctx context.Context, steps []transferStep, overwrite, preserveTimes bool) (int, error) {
+	n := 0
+	for _, s := range steps {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		var err error
+		if s.dir {
+			err = h.mkdir(ctx, s.to)
+		} else if err = h.copyFile(ctx, s.from, s.to, overwrite); err == nil {
+			n++
+			if preserveTimes && !s.modTime.IsZero() {
+				_, err = storage.Touch(ctx, h.store, s.to, s.modTime)
+			}
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// destinations returns the destination path of each step.
+func destinations(steps []transferStep) []string {
+	paths := make([]string, len(steps))
+	for i, s := range steps {
+		paths[i] = s.to
+	}
+	return paths
+}
+
+// mkdir creates the directory p, accepting one that already exists.
+func (h *Handler) mkdir(ctx context.Context, p string) error {
+	if err := h.store.Mkdir(ctx, p); err != nil && !errors.Is(err, storage.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// writeTransferError maps a Copy or Move failure to a response.
+func writeTransferError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errIntoItself):
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	case errors.Is(err, errDirOntoFile):
+		writeError(w, http.StatusConflict, CodeConflict, err.Error())
+	case errors.Is(err, middleware.ErrInvalidPath), errors.Is(err, middleware.ErrTooManySegments), errors.Is(err, middleware.ErrNameTooLong):
+		writeError(w, http.StatusBadRequest, pathErrorCode(err), "destination: "+err.Error())
+	default:
+		handleStorageError(w, err)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+func TestCopy_IntoItselfRefused(t *testing.T) {
+	h := NewHandler(&mockStorage{}, Options{})
+	for _, to := range []string{"/src", "/src/sub", "/src/./sub/deeper"} {
+		rr := httptest.NewRecorder()
+		h.Copy(rr, httptest.NewRequest(http.MethodPost, "/api/v1/files/copy?path=/src&to="+to, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("to=%s: expected 400, got %d", to, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	h.Move(rr, httptest.NewRequest(http.MethodPost, "/api/v1/files/move?path=/src&to=/src/sub", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("move into itself: expected 400, got %d", rr.Code)
+	}
+}
+
+func TestCopy_InvalidDestination(t *testing.T) {
+	h := NewHandler(&mockStorage{}, Options{})
+	for _, target := range []string{
+		"/api/v1/files/copy?path=/a.txt",
+		"/api/v1/files/copy?path=/a.txt&to=/../etc/passwd",
+	} {
+		rr := httptest.NewRecorder()
+		h.Copy(rr, httptest.NewRequest(http.MethodPost, target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rr.Code)
+		}
+	}
+}
+
+func TestCopy_StopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var written []string
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			if p == "/src" {
+				return &storage.FileInfo{Path: "src", IsDir: true}, nil
+			}
+			return nil, storage.ErrNotFound
+		},
+		listFn: func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+			return []storage.FileInfo{{Path: "src/a.txt"}, {Path: "src/b.txt"}}, nil
+		},
+		mkdirFn: func(_ context.Context, _ string) error { return nil },
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			cancel() // the client goes away during the first file
+			return io.NopCloser(strings.NewReader("data")), nil
+		},
+		writeFn: func(_ context.Context, p string, _ io.Reader) error {
+			written = append(written, p)
+			return nil
+		},
+	}
+	h := NewHandler(store, Options{})
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/copy?path=/src&to=/dst", nil).WithContext(ctx)
+	h.Copy(rr, req)
+	if rr.Code == http.StatusCreated {
+		t.Fatal("expected the canceled copy to fail")
+	}
+	if len(written) != 1 {
+		t.Errorf("expected the copy to stop after one file, wrote %v", written)
+	}
+}
+
+func TestCopyMove_NoContentMode(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			if p == "/a.txt" {
+				return &storage.FileInfo{Path: "a.txt"}, nil
+			}
+			return nil, storage.ErrNotFound
+		},
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("data")), nil
+		},
+		writeFn:  func(_ context.Context, _ string, _ io.Reader) error { return nil },
+		deleteFn: func(_ context.Context, _ string) error { return nil },
+	}
+
+	tests := []struct {
+		name      string
+		noContent bool
+		copyCode  int
+		moveCode  int
+	}{
+		{"default status with body", false, http.StatusCreated, http.StatusOK},
+		{"204 without body", true, http.StatusNoContent, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(store, Options{DeleteNoContent: tt.noContent})
+			for _, c := range []struct {
+				op   http.HandlerFunc
+				url  string
+				want int
+			}{
+				{h.Copy, "/api/v1/files/copy?path=/a.txt&to=/b.txt", tt.copyCode},
+				{h.Move, "/api/v1/files/move?path=/a.txt&to=/b.txt", tt.moveCode},
+			} {
+				rr := httptest.NewRecorder()
+				c.op(rr, httptest.NewRequest(http.MethodPost, c.url, nil))
+				if rr.Code != c.want {
+					t.Errorf("%s: expected %d, got %d: %s", c.url, c.want, rr.Code, rr.Body)
+				}
+				if gotBody := rr.Body.Len() > 0; gotBody == tt.noContent {
+					t.Errorf("%s: expected body present=%v, got %q", c.url, !tt.noContent, rr.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// InlineTypes returns an Options.Inline policy that serves the given media
+// types inline and everything else as an attachment. "image/*" covers a
+// whole top-level type; parameters such as charset are ignored. No types
+// returns nil, which serves everything inline.
+func InlineTypes(types ...string) func(contentType string) bool {
+	if len(types) == 0 {
+		return nil
+	}
+	set := newAllowlist(types, normalizeMediaType)
+	return func(contentType string) bool {
+		mt := normalizeMediaType(contentType)
+		if set[mt] {
+			return true
+		}
+		top, _, _ := strings.Cut(mt, "/")
+		return set[top+"/*"]
+	}
+}
+
+// parseInline reads the optional inline query parameter, which overrides
+// the Inline policy; a bare ?inline means true. set reports whether it was
+// given. It writes a 400 and returns ok=false if the value is not a
+// boolean.
+func parseInline(w http.ResponseWriter, r *http.Request) (inline, set, ok bool) {
+	inline, ok = parseBoolParam(w, r, "inline", true)
+	return inline, r.URL.Query().Has("inline"), ok
+}
+
+// setDisposition sets a download's Content-Disposition for content type
+// ct: inline or attachment as the request asked, else as h.inline decides,
+// else inline. ContentSecurity may still turn active content into an
+// attachment.
+func (h *Handler) setDisposition(w http.ResponseWriter, p, ct string, inline, set bool) {
+	if !set {
+		inline = h.inline == nil || h.inline(ct)
+	}
+	kind := "attachment"
+	if inline {
+		kind = "inline"
+	}
+	if cd := mime.FormatMediaType(kind, map[string]string{"filename": path.Base(p)}); cd != "" {
+		w.Header().Set("Content-Disposition", cd)
+	}
+}
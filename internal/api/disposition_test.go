@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// fileStore serves content for any path.
+func fileStore(content string) *mockStorage {
+	return &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: p, Path: p, Size: int64(len(content))}, nil
+		},
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+	}
+}
+
+func TestDownload_InlinePolicy(t *testing.T) {
+	const png = "\x89PNG\r\n\x1a\n"
+	const zip = "PK\x03\x04"
+	inline := InlineTypes("image/*", "application/pdf")
+
+	tests := []struct {
+		name, content, query, want string
+	}{
+		{"png inline", png, "path=/a.png", `inline; filename=a.png`},
+		{"zip attachment", zip, "path=/a.zip", `attachment; filename=a.zip`},
+		{"override to attachment", png, "path=/a.png&inline=false", `attachment; filename=a.png`},
+		{"override to inline", zip, "path=/a.zip&inline=true", `inline; filename=a.zip`},
+		{"bare override", zip, "path=/a.zip&inline", `inline; filename=a.zip`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stores := map[string]storage.Storage{
+				"streamed": fileStore(tt.content),
+				"seekable": &seekableStore{mockStorage: fileStore(tt.content), content: tt.content},
+			}
+			for name, store := range stores {
+				h := NewHandler(store, Options{Inline: inline})
+				rr := httptest.NewRecorder()
+				h.Download(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/download?"+tt.query, nil))
+				if rr.Code != http.StatusOK {
+					t.Fatalf("%s: expected 200, got %d", name, rr.Code)
+				}
+				if cd := rr.Header().Get("Content-Disposition"); cd != tt.want {
+					t.Errorf("%s: expected Content-Disposition %q, got %q", name, tt.want, cd)
+				}
+			}
+		})
+	}
+}
+
+func TestDownload_InvalidInline(t *testing.T) {
+	h := NewHandler(fileStore("data"), Options{})
+	rr := httptest.NewRecorder()
+	h.Download(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=/a.txt&inline=maybe", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestInlineTypes(t *testing.T) {
+	if InlineTypes() != nil {
+		t.Error("expected no types to give a nil policy")
+	}
+	inline := InlineTypes("image/*", "Application/PDF")
+	for ct, want := range map[string]bool{
+		"image/png":                 true,
+		"image/svg+xml":             true,
+		"application/pdf":           true,
+		"application/zip":           false,
+		"text/plain; charset=utf-8": false,
+	} {
+		if got := inline(ct); got != want {
+			t.Errorf("%s: expected %v, got %v", ct, want, got)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"path"
+
+	"go-storage-api/internal/storage"
+)
+
+// DryRunResponse is returned instead of performing a Delete, Copy or Move
+// called with dry_run=true. Paths lists everything the request would
+// create, replace or remove, parents before their children.
+type DryRunResponse struct {
+	WouldAffect int      `json:"would_affect"`
+	Paths       []string `json:"paths"`
+}
+
+// parseDryRun reads the optional dry_run parameter, writing a 400 and
+// returning ok=false if it is not a boolean.
+func parseDryRun(w http.ResponseWriter, r *http.Request) (dryRun, ok bool) {
+	return parseBoolParam(w, r, "dry_run", false)
+}
+
+// writeDryRun answers a dry run that would touch paths.
+func writeDryRun(w http.ResponseWriter, paths []string) {
+	if paths == nil {
+		paths = []string{}
+	}
+	writeJSON(w, http.StatusOK, DryRunResponse{WouldAffect: len(paths), Paths: paths})
+}
+
+// deleteTargets returns the paths deleting p would remove: p itself and,
+// for a directory, everything below it. A non-empty directory without
+// recursive fails with storage.ErrNotEmpty, as the delete would.
+func (h *Handler) deleteTargets(ctx context.Context, p string, recursive bool) ([]string, error) {
+	p = path.Clean("/" + p)
+	info, err := h.store.Stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{p}
+	if !info.IsDir {
+		return paths, nil
+	}
+	entries, err := storage.ListRecursive(ctx, h.store, p, h.listConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 && !recursive {
+		return nil, storage.ErrNotEmpty
+	}
+	for _, e := range entries {
+		paths = append(paths, path.Clean("/"+e.Path))
+	}
+	return paths, nil
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// readOnlyTree is a store holding /dir/a.txt and /dir/sub/b.txt that fails
+// the test on any write.
+func readOnlyTree(t *testing.T) *mockStorage {
+	fail := func(op, p string) error {
+		t.Errorf("dry run called %s(%s)", op, p)
+		return nil
+	}
+	return &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			switch p {
+			case "/dir", "/dir/sub":
+				return &storage.FileInfo{Path: p[1:], IsDir: true}, nil
+			case "/dir/a.txt", "/dir/sub/b.txt":
+				return &storage.FileInfo{Path: p[1:]}, nil
+			}
+			return nil, storage.ErrNotFound
+		},
+		listFn: func(_ context.Context, p string) ([]storage.FileInfo, error) {
+			switch path.Clean("/" + p) {
+			case "/dir":
+				return []storage.FileInfo{{Path: "dir/a.txt"}, {Path: "dir/sub", IsDir: true}}, nil
+			case "/dir/sub":
+				return []storage.FileInfo{{Path: "dir/sub/b.txt"}}, nil
+			}
+			return nil, nil
+		},
+		writeFn:  func(_ context.Context, p string, _ io.Reader) error { return fail("Write", p) },
+		deleteFn: func(_ context.Context, p string) error { return fail("Delete", p) },
+		mkdirFn:  func(_ context.Context, p string) error { return fail("Mkdir", p) },
+	}
+}
+
+func TestDryRun_ReportsPathsWithoutWriting(t *testing.T) {
+	h := NewHandler(readOnlyTree(t), Options{})
+
+	tests := []struct {
+		name   string
+		handle http.HandlerFunc
+		target string
+		want   []string
+	}{
+		{"delete", h.Delete, "/api/v1/files?path=/dir&recursive=true&dry_run=true",
+			[]string{"/dir", "/dir/a.txt", "/dir/sub", "/dir/sub/b.txt"}},
+		{"copy", h.Copy, "/api/v1/files/copy?path=/dir&to=/out&dry_run=true",
+			[]string{"/out", "/out/a.txt", "/out/sub", "/out/sub/b.txt"}},
+		{"move file", h.Move, "/api/v1/files/move?path=/dir/a.txt&to=/a.txt&dry_run=true",
+			[]string{"/dir/a.txt", "/a.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.handle(rr, httptest.NewRequest(http.MethodPost, tt.target, nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", rr.Code)
+			}
+			var body DryRunResponse
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !slices.Equal(body.Paths, tt.want) || body.WouldAffect != len(tt.want) {
+				t.Errorf("expected %d paths %v, got %d %v", len(tt.want), tt.want, body.WouldAffect, body.Paths)
+			}
+		})
+	}
+}
@@ -1,24 +1,146 @@
 package api
 
 import (
+	"bufio"
+	"cmp"
+	"context"
 	"errors"
+	"fmt"
 	"io"
-	"mime"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
-	"path/filepath"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"go-storage-api/internal/bufpool"
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/ratelimit"
 	"go-storage-api/internal/storage"
+	"go-storage-api/internal/version"
 )
 
-// Handler holds dependencies for HTTP handlers.
+// sniffLen is the number of leading bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// readyTimeout bounds the backend check in Ready so a hung backend fails the
+// probe instead of stalling it. The deep check makes three round trips and
+// gets longer.
+const (
+	readyTimeout     = 2 * time.Second
+	deepReadyTimeout = 5 * time.Second
+)
+
+// healthProbePath is the file the deep readiness check writes, reads back
+// and deletes at the backend's root.
+const healthProbePath = "/.health-probe"
+
+// Preview reads defaultPreviewBytes unless the bytes parameter asks for more,
+// up to maxPreviewBytes.
+const (
+	defaultPreviewBytes = 4096
+	maxPreviewBytes     = 1 << 20
+)
+
+// Download trailers sent after the body when Options.DownloadTrailers is set.
+const (
+	trailerBytesSent      = "X-Bytes-Sent"
+	trailerDownloadStatus = "X-Download-Status"
+)
+
+// headerResultTruncated marks a listing cut to Options.MaxListEntries.
+const headerResultTruncated = "X-Result-Truncated"
+
+// Handler holds dependencies for HTTP handlers. store serves requests and
+// may be scoped per user; backend is the unwrapped storage.
 type Handler struct {
 	store         storage.Storage
+	backend       storage.Storage
 	maxUploadSize int64
+	multipartMem  int64
+	pathLimits    middleware.PathLimits
+	allowedExts   allowlist
+	allowedMIME   allowlist
+	contentTypes  contentTypes
+	scan          ScanFunc
+	signingKey    []byte
+	hidden        func(p string) bool
+	inline        func(contentType string) bool
+	logger        *slog.Logger
+
+	downloadTrailers bool
+	deleteNoContent  bool
+	softDelete       bool
+
+	progress        *progressRegistry
+	copyBuffers     *bufpool.Pool
+	listConcurrency int
+	maxListEntries  int
+	uploadSlots     *uploadSlots
+	uploads         *tusStore
+	thumbnails      *thumbnailCache
+	scopeToUser     bool
+
+	// assetMaxAge is the Cache-Control max-age of Asset responses, which
+	// are marked private when requests are authenticated.
+	assetMaxAge   time.Duration
+	privateAssets bool
+
+	// downloadRate caps each download in bytes per second; downloadLimit
+	// is shared by all downloads. Zero and nil mean unlimited.
+	downloadRate  int64
+	downloadLimit *ratelimit.Limiter
+
+	// probeMu serializes deep readiness checks, which share one file.
+	probeMu sync.Mutex
 }
 
-// NewHandler creates a Handler with the given storage backend and upload limit.
-func NewHandler(store storage.Storage, maxUploadSize int64) *Handler {
-	return &Handler{store: store, maxUploadSize: maxUploadSize}
+// NewHandler creates a Handler with the given storage backend and options.
+func NewHandler(store storage.Storage, opts Options) *Handler {
+	hidden := hiddenPaths(opts)
+	h := &Handler{
+		store:         storage.WithHidden(scopedStore(store, opts), hidden),
+		backend:       store,
+		maxUploadSize: opts.MaxUploadSize,
+		multipartMem:  cmp.Or(opts.MultipartMemory, DefaultMultipartMemory),
+		pathLimits:    opts.pathLimits(),
+		allowedExts:   newAllowlist(opts.AllowedExtensions, normalizeExt),
+		allowedMIME:   newAllowlist(opts.AllowedMIMETypes, normalizeMediaType),
+		contentTypes:  newContentTypes(opts.ContentTypes),
+		scan:          opts.Scan,
+		signingKey:    opts.URLSigningSecret,
+		hidden:        hidden,
+		inline:        opts.Inline,
+		logger:        cmp.Or(opts.Logger, slog.Default()),
+
+		downloadTrailers: opts.DownloadTrailers,
+		deleteNoContent:  opts.DeleteNoContent,
+		softDelete:       opts.SoftDelete,
+
+		progress:    newProgressRegistry(),
+		copyBuffers: opts.CopyBuffers,
+
+		listConcurrency: opts.ListConcurrency,
+		maxListEntries:  opts.MaxListEntries,
+		uploadSlots:     newUploadSlots(opts.MaxConcurrentUploads, opts.UploadQueueTimeout),
+		uploads:         newTusStore(opts.ResumableUploadDir, cmp.Or(opts.ResumableUploadExpiry, defaultUploadExpiry)),
+		thumbnails:      newThumbnailCache(thumbnailCacheBytes),
+		scopeToUser:     opts.ScopeToUser,
+
+		assetMaxAge:   opts.AssetMaxAge,
+		privateAssets: len(opts.JWTSecret) > 0,
+
+		downloadRate:  opts.DownloadRateLimit,
+		downloadLimit: ratelimit.New(opts.DownloadRateLimitTotal),
+	}
+	if h.copyBuffers == nil {
+		h.copyBuffers = bufpool.New(bufpool.DefaultSize)
+	}
+	return h
 }
 
 // Health returns a simple health check response.
@@ -26,97 +148,856 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, SuccessResponse{Message: "ok"})
 }
 
-// List returns the contents of a directory.
+// Ready reports whether the storage backend is reachable by statting its
+// root. Unlike Health it fails with 503 when the backend is broken, so
+// orchestrators stop routing traffic to this instance. deep=true also
+// checks the backend is writable; see probeWrite.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	deep, ok := parseBoolParam(w, r, "deep", false)
+	if !ok {
+		return
+	}
+
+	timeout := readyTimeout
+	if deep {
+		timeout = deepReadyTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	// An open breaker already knows the backend is down; once its cooldown
+	// ends, the Stat below may be the probe that closes it.
+	if open := openBreakers(h.backend); len(open) > 0 {
+		writeError(w, http.StatusServiceUnavailable, CodeUnavailable, "storage circuit breaker open for "+strings.Join(open, ", "))
+		return
+	}
+
+	// Probe the unscoped backend: readiness checks carry no user identity.
+	// The probe is public, so the cause is logged rather than returned.
+	if _, err := h.backend.Stat(ctx, "/"); err != nil {
+		h.logReadyFailure(r, "stat", err)
+		writeError(w, http.StatusServiceUnavailable, CodeUnavailable, "storage backend unavailable")
+		return
+	}
+	if deep {
+		if err := h.probeWrite(ctx); err != nil {
+			h.logReadyFailure(r, "write", err)
+			writeError(w, http.StatusServiceUnavailable, CodeUnavailable, "storage backend not writable")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, SuccessResponse{Message: "ready"})
+}
+
+// logReadyFailure logs why the readiness check failed.
+func (h *Handler) logReadyFailure(r *http.Request, check string, err error) {
+	h.logger.Warn("readiness check failed",
+		slog.String("check", check),
+		slog.String("error", err.Error()),
+		slog.String("request_id", middleware.RequestIDFromContext(r.Context())),
+	)
+}
+
+// probeWrite writes a small file to healthProbePath, reads it back, checks
+// the contents and deletes it, catching read-only mounts, full disks and
+// permission problems a Stat misses. The delete is attempted even if an
+// earlier step failed, and even once ctx has ended.
+func (h *Handler) probeWrite(ctx context.Context) (err error) {
+	h.probeMu.Lock()
+	defer h.probeMu.Unlock()
+
+	// A timestamp keeps a stale probe file from passing the check.
+	want := "ready " + time.Now().UTC().Format(time.RFC3339Nano)
+	defer func() {
+		cleanup, cancel := context.WithTimeout(context.WithoutCancel(ctx), readyTimeout)
+		defer cancel()
+		if derr := h.backend.Delete(cleanup, healthProbePath); err == nil && derr != nil {
+			err = fmt.Errorf("delete: %w", derr)
+		}
+	}()
+
+	if err := h.backend.Write(ctx, healthProbePath, strings.NewReader(want)); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	rc, err := h.backend.Read(ctx, healthProbePath)
+	if err != nil {
+		return fmt.Errorf("read back: %w", err)
+	}
+	got, err := io.ReadAll(io.LimitReader(rc, int64(len(want))+1))
+	rc.Close()
+	if err != nil {
+		return fmt.Errorf("read back: %w", err)
+	}
+	if string(got) != want {
+		return errors.New("read back different contents than written")
+	}
+	return nil
+}
+
+// Version reports the running build's version, commit, build time and Go
+// version. Like Health it needs no authentication.
+func (h *Handler) Version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, version.Get())
+}
+
+// Favicon answers browser favicon requests with 204 No Content.
+func (h *Handler) Favicon(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List returns the contents of a directory. recursive=true returns the
+// whole subtree sorted by path, listing subdirectories concurrently.
+// hideTrash=true leaves the trash directory out of a root listing, and
+// dirs_only=true returns only directories, for folder pickers. stream=true
+// or Accept: application/x-ndjson streams entries one per line as they are
+// found instead of building one sorted array; see streamList. Other
+// listings are cut to Options.MaxListEntries, with X-Result-Truncated set,
+// so a huge directory cannot exhaust a client; streaming is not capped.
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	p := r.URL.Query().Get("path")
 	if p == "" {
 		p = "/"
 	}
 
-	files, err := h.store.List(r.Context(), p)
+	v := newValidator(r)
+	hideTrash := v.bool("hideTrash", false)
+	recursive := v.bool("recursive", false)
+	dirsOnly := v.bool("dirs_only", false)
+	stream := v.bool("stream", false) || acceptsNDJSON(r)
+	if !v.ok(w) {
+		return
+	}
+
+	if stream {
+		hide := hideTrash && path.Clean("/"+p) == "/"
+		h.streamList(w, r, p, recursive, dirsOnly, func(f storage.FileInfo) bool {
+			if dirsOnly && !f.IsDir {
+				return false
+			}
+			return !hide || !isTrashEntry(f)
+		})
+		return
+	}
+
+	var files []storage.FileInfo
+	var err error
+	switch {
+	case recursive:
+		files, err = storage.ListRecursive(r.Context(), h.store, p, h.listConcurrency)
+		if dirsOnly {
+			files = slices.DeleteFunc(files, func(f storage.FileInfo) bool { return !f.IsDir })
+		}
+	case dirsOnly:
+		files, err = storage.ListDirs(r.Context(), h.store, p)
+	default:
+		files, err = h.store.List(r.Context(), p)
+	}
 	if err != nil {
 		handleStorageError(w, err)
 		return
 	}
+	if hideTrash && path.Clean("/"+p) == "/" {
+		files = withoutTrash(files)
+	}
+	if h.maxListEntries > 0 && len(files) > h.maxListEntries {
+		files = files[:h.maxListEntries]
+		w.Header().Set(headerResultTruncated, "true")
+	}
 
 	writeJSON(w, http.StatusOK, files)
 }
 
-// Download streams a file to the client.
+// errDownloadDirectory explains why Download refused a directory and where
+// to go instead.
+const errDownloadDirectory = "path is a directory; list it with GET /api/v1/files or download it as a zip with POST /api/v1/files/archive"
+
+// Download streams a file to the client with a Last-Modified header when
+// the backend knows the modification time. Directories are refused with 400.
+// Content-Disposition follows Options.Inline unless inline=true or
+// inline=false is given.
+// Backends that can seek are served by http.ServeContent (see
+// serveSeekable); others are streamed, skipping forward to range starts.
 func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
 	p := r.URL.Query().Get("path")
 	if p == "" {
-		writeError(w, http.StatusBadRequest, "path query parameter is required")
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
 		return
 	}
 
-	rc, err := h.store.Read(r.Context(), p)
+	h.download(w, r, h.store, p)
+}
+
+// download serves the file at p in store; see Download.
+func (h *Handler) download(w http.ResponseWriter, r *http.Request, store storage.Storage, p string) {
+	inline, inlineSet, ok := parseInline(w, r)
+	if !ok {
+		return
+	}
+	info, err := store.Stat(r.Context(), p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	if info.IsDir {
+		writeError(w, http.StatusBadRequest, CodeIsDirectory, errDownloadDirectory)
+		return
+	}
+	size := info.Size
+
+	var ranges []byteRange
+	if header := r.Header.Get("Range"); header != "" {
+		ranges, err = parseRanges(header, size)
+		if err != nil {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			writeError(w, http.StatusRequestedRangeNotSatisfiable, CodeRangeNotSatisfiable, "requested range not satisfiable")
+			return
+		}
+	}
+
+	rs, err := storage.ReadSeeker(r.Context(), store, p)
+	switch {
+	case err == nil:
+		if ranges == nil && r.Header.Get("Range") != "" {
+			// A Range we ignore must not reach ServeContent, which would
+			// answer it with a plain-text 416.
+			r = r.Clone(r.Context())
+			r.Header.Del("Range")
+		}
+		h.serveSeekable(w, r, p, info, rs, inline, inlineSet)
+		return
+	case !errors.Is(err, storage.ErrNotSupported):
+		handleStorageError(w, err)
+		return
+	}
+
+	rc, err := store.Read(r.Context(), p)
 	if err != nil {
 		handleStorageError(w, err)
 		return
 	}
-	defer rc.Close()
+	src := &rangeSource{
+		open:        func() (io.ReadCloser, error) { return store.Read(r.Context(), p) },
+		copyBuffers: h.copyBuffers,
+		rc:          rc,
+		body:        rc,
+	}
+	defer src.Close()
 
-	ct := mime.TypeByExtension(filepath.Ext(p))
+	ct := h.contentTypes.byName(p)
 	if ct == "" {
-		ct = "application/octet-stream"
+		// Peek rather than read so the sniffed prefix is still delivered.
+		br := bufio.NewReaderSize(rc, sniffLen)
+		prefix, _ := br.Peek(sniffLen)
+		ct = http.DetectContentType(prefix)
+		src.body = br
 	}
 	w.Header().Set("Content-Type", ct)
+	h.setDisposition(w, p, ct, inline, inlineSet)
+	w.Header().Set("Accept-Ranges", "bytes")
+	// Backends that do not track modification times report the zero time.
+	if !info.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if h.downloadTrailers {
+		w.Header().Set("Trailer", trailerBytesSent+", "+trailerDownloadStatus)
+	}
+
+	throttle := func(body io.Reader) io.Reader { return h.throttle(r.Context(), body) }
+	var n int64
+	switch len(ranges) {
+	case 0:
+		n, err = h.copyBuffers.Copy(w, throttle(src.body))
+	case 1:
+		rng := ranges[0]
+		if err := src.seek(rng.start); err != nil {
+			handleStorageError(w, err)
+			return
+		}
+		w.Header().Set("Content-Range", rng.contentRange(size))
+		w.Header().Set("Content-Length", strconv.FormatInt(rng.length, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		n, err = src.copyRange(w, rng, throttle)
+	default:
+		mw := multipart.NewWriter(w)
+		length, lerr := multipartLength(ranges, ct, size, mw.Boundary())
+		if lerr != nil {
+			handleStorageError(w, lerr)
+			return
+		}
+		w.Header().Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+		w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		n, err = src.writeRanges(mw, ranges, ct, size, throttle)
+	}
+
+	if h.downloadTrailers {
+		status := "complete"
+		if err != nil {
+			status = "incomplete"
+		}
+		w.Header().Set(trailerBytesSent, strconv.FormatInt(n, 10))
+		w.Header().Set(trailerDownloadStatus, status)
+	}
+}
+
+// throttle applies the per-download and global download rate limits to
+// body. Bytes skipped to reach a range start are not throttled.
+func (h *Handler) throttle(ctx context.Context, body io.Reader) io.Reader {
+	return ratelimit.NewReader(ctx, body, ratelimit.New(h.downloadRate), h.downloadLimit)
+}
+
+// Preview returns at most the first "bytes" bytes of a file, capped at
+// maxPreviewBytes, so clients can show a snippet without a full download.
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
 
-	io.Copy(w, rc)
+	limit := int64(defaultPreviewBytes)
+	if raw := r.URL.Query().Get("bytes"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "bytes must be a positive integer")
+			return
+		}
+		limit = min(n, maxPreviewBytes)
+	}
+
+	rc, err := h.store.Read(r.Context(), p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	// Closing as soon as the snippet is read lets the backend abandon the
+	// rest of the stream.
+	buf, err := io.ReadAll(io.LimitReader(rc, limit))
+	rc.Close()
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+
+	ct := h.contentTypes.byName(p)
+	if ct == "" {
+		ct = http.DetectContentType(buf)
+	}
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+	w.Write(buf)
 }
 
-// Upload receives a multipart file and writes it to storage.
+// Upload receives a multipart file and writes it to storage. If the request
+// carries a Content-MD5 header, the stored file must match it. Requests with
+// several "file" parts, or any "files" parts, are handled by uploadBatch. An
+// uploadId parameter publishes progress to UploadProgress. An If-Match or
+// If-Unmodified-Since header makes the write conditional on the current
+// file's ETag or modification time; see checkPreconditions. Up to
+// Options.MultipartMemory bytes of the form are kept in memory and the rest
+// is spooled to temporary files.
 func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 	p := r.URL.Query().Get("path")
 	if p == "" {
-		writeError(w, http.StatusBadRequest, "path query parameter is required")
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
 		return
 	}
 
+	overwrite, ok := parseOverwrite(w, r)
+	if !ok {
+		return
+	}
+	useFilename, ok := parseBoolParam(w, r, "use_filename", false)
+	if !ok {
+		return
+	}
+	wantMD5, ok := parseChecksum(w, r)
+	if !ok {
+		return
+	}
+	release, ok := h.trackProgress(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	done, ok := h.acquireUpload(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
 	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
 
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid multipart form: "+err.Error())
+	if err := r.ParseMultipartForm(h.multipartMem); err != nil {
+		if writeTooLarge(w, err) {
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid multipart form: "+err.Error())
 		return
 	}
 
-	file, _, err := r.FormFile("file")
+	if batch := r.MultipartForm.File[batchField]; len(batch) > 0 || len(r.MultipartForm.File["file"]) > 1 {
+		if wantMD5 != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Content-MD5 is not supported for multi-file uploads")
+			return
+		}
+		if r.Header.Get("If-Match") != "" || r.Header.Get("If-Unmodified-Since") != "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "If-Match and If-Unmodified-Since are not supported for multi-file uploads")
+			return
+		}
+		h.uploadBatch(w, r, p, append(r.MultipartForm.File["file"], batch...), overwrite)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "file field is required: "+err.Error())
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "file field is required: "+err.Error())
 		return
 	}
 	defer file.Close()
 
-	if err := h.store.Write(r.Context(), p, file); err != nil {
+	if containsControl(header.Filename) {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "filename contains control characters")
+		return
+	}
+	if !h.checkAllowed(w, header.Filename, header.Header.Get("Content-Type")) {
+		return
+	}
+
+	// A trailing slash, use_filename=true or an existing directory at path
+	// stores the file under its multipart filename.
+	toDir := useFilename || strings.HasSuffix(p, "/")
+	if !toDir {
+		info, err := h.store.Stat(r.Context(), p)
+		switch {
+		case err == nil && info.IsDir:
+			toDir = true
+		case err != nil && !errors.Is(err, storage.ErrNotFound):
+			handleStorageError(w, err)
+			return
+		}
+	}
+	if toDir {
+		name := sanitizeFilename(header.Filename)
+		if name == "" {
+			writeError(w, http.StatusBadRequest, CodePathInvalid, "path is a directory and the upload has no usable filename")
+			return
+		}
+		p, err = middleware.CleanPath(path.Join(p, name), h.pathLimits)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, pathErrorCode(err), err.Error())
+			return
+		}
+	}
+
+	if err := h.checkPreconditions(r.Context(), p, r.Header); err != nil {
 		handleStorageError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, SuccessResponse{Message: "file uploaded"})
+	if err := h.writeVerified(r.Context(), p, file, overwrite, wantMD5); err != nil {
+		if writeChecksumMismatch(w, err) {
+			return
+		}
+		handleStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, SuccessResponse{Message: "file uploaded", Path: p})
 }
 
-// Delete removes a file from storage.
+// Put stores the raw request body at path, for clients that would rather not
+// build a multipart form (e.g. curl -T). It shares Upload's size limit,
+// allowlists and overwrite protection; the extension check uses path and the
+// MIME check uses the request Content-Type. Content-MD5, uploadId, If-Match
+// and If-Unmodified-Since work the same way.
+func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+
+	overwrite, ok := parseOverwrite(w, r)
+	if !ok {
+		return
+	}
+	wantMD5, ok := parseChecksum(w, r)
+	if !ok {
+		return
+	}
+
+	if r.ContentLength > h.maxUploadSize {
+		writeTooLarge(w, &http.MaxBytesError{Limit: h.maxUploadSize})
+		return
+	}
+
+	if !h.checkAllowed(w, p, r.Header.Get("Content-Type")) {
+		return
+	}
+	if err := h.checkPreconditions(r.Context(), p, r.Header); err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	release, ok := h.trackProgress(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	done, ok := h.acquireUpload(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+
+	if err := h.writeVerified(r.Context(), p, r.Body, overwrite, wantMD5); err != nil {
+		if writeTooLarge(w, err) || writeChecksumMismatch(w, err) {
+			return
+		}
+		handleStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, SuccessResponse{Message: "file uploaded", Path: p})
+}
+
+// Patch replaces the contents of an existing file with the raw request
+// body and returns its new FileInfo. Unlike Put it never creates a file:
+// a missing path gets 404. Backends that implement storage.Replacer swap
+// the contents atomically. If-Match and If-Unmodified-Since work as for
+// Upload.
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+
+	if r.ContentLength > h.maxUploadSize {
+		writeTooLarge(w, &http.MaxBytesError{Limit: h.maxUploadSize})
+		return
+	}
+
+	if !h.checkAllowed(w, p, r.Header.Get("Content-Type")) {
+		return
+	}
+	if err := h.checkPreconditions(r.Context(), p, r.Header); err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	done, ok := h.acquireUpload(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+
+	body, cleanup, err := h.scanned(r.Context(), p, r.Body)
+	if err == nil {
+		defer cleanup()
+		err = storage.Replace(r.Context(), h.store, p, body)
+	}
+	if err != nil {
+		if writeTooLarge(w, err) {
+			return
+		}
+		handleStorageError(w, err)
+		return
+	}
+
+	info, err := h.store.Stat(r.Context(), p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// parseOverwrite reads the optional overwrite query parameter, defaulting to
+// false. It writes a 400 and returns ok=false if the value is not a boolean.
+func parseOverwrite(w http.ResponseWriter, r *http.Request) (overwrite, ok bool) {
+	return parseBoolParam(w, r, "overwrite", false)
+}
+
+// parseBoolParam reads the optional boolean query parameter name, returning
+// def when it is absent. It writes a 400 and returns ok=false if the value
+// is not a boolean.
+func parseBoolParam(w http.ResponseWriter, r *http.Request, name string, def bool) (val, ok bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, true
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, name+" must be true or false")
+		return false, false
+	}
+	return b, true
+}
+
+// parseChecksum reads the optional Content-MD5 header. It writes a 400 and
+// returns ok=false if the header is present but malformed.
+func parseChecksum(w http.ResponseWriter, r *http.Request) (sum []byte, ok bool) {
+	sum, err := parseContentMD5(r.Header.Get("Content-MD5"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return nil, false
+	}
+	return sum, true
+}
+
+// writeChecksumMismatch writes a 400 if err is errChecksumMismatch, and
+// reports whether it did.
+func writeChecksumMismatch(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, errChecksumMismatch) {
+		return false
+	}
+	writeError(w, http.StatusBadRequest, CodeChecksumMismatch, "upload does not match Content-MD5")
+	return true
+}
+
+// writeTooLarge writes a 413 naming the limit if err came from
+// http.MaxBytesReader, and reports whether it did.
+func writeTooLarge(w http.ResponseWriter, err error) bool {
+	var maxErr *http.MaxBytesError
+	if !errors.As(err, &maxErr) {
+		return false
+	}
+	writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge,
+		fmt.Sprintf("upload exceeds maximum size of %d bytes", maxErr.Limit))
+	return true
+}
+
+// checkAllowed applies the extension and MIME allowlists to an upload,
+// writing a 415 and returning false if either rejects it.
+func (h *Handler) checkAllowed(w http.ResponseWriter, filename, contentType string) bool {
+	if reason := h.disallowed(filename, contentType); reason != "" {
+		writeError(w, http.StatusUnsupportedMediaType, CodeUnsupportedType, reason)
+		return false
+	}
+	return true
+}
+
+// disallowed returns why the allowlists reject an upload, or "" if they
+// accept it.
+func (h *Handler) disallowed(filename, contentType string) string {
+	if !h.allowedExts.allows(filenameExt(filename)) {
+		return "file extension is not allowed"
+	}
+	if !h.allowedMIME.allows(normalizeMediaType(contentType)) {
+		return "content type is not allowed"
+	}
+	return ""
+}
+
+// sanitizeFilename reduces a client-supplied multipart filename to a single
+// safe path element, returning "" if nothing usable remains.
+func sanitizeFilename(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Base(name)
+	if name == "." || name == ".." || name == "/" || containsControl(name) {
+		return ""
+	}
+	return name
+}
+
+// containsControl reports whether s contains an ASCII control character
+// (0x00-0x1F or 0x7F), which must never reach logs or response headers.
+func containsControl(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool {
+		return r < 0x20 || r == 0x7f
+	}) >= 0
+}
+
+// write stores r at path. Unless overwrite is set it refuses to replace an
+// existing file, using the backend's atomic Create when available and a
+// Stat-then-Write check otherwise.
+func (h *Handler) write(ctx context.Context, path string, r io.Reader, overwrite bool) error {
+	r, cleanup, err := h.scanned(ctx, path, r)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return h.put(ctx, path, r, overwrite)
+}
+
+// put is write without the scan, for bytes already in storage.
+func (h *Handler) put(ctx context.Context, path string, r io.Reader, overwrite bool) error {
+	if overwrite {
+		return h.store.Write(ctx, path, r)
+	}
+	return storage.Create(ctx, h.store, path, r)
+}
+
+// Delete removes a file or an empty directory from storage. A directory
+// with entries needs recursive=true, which removes everything below it,
+// and otherwise gets 409. With soft=true, or by default when
+// Options.SoftDelete is set, it is moved into the trash instead;
+// purge=true always removes it permanently. The 200 response carries the
+// FileInfo of what was removed when it could be read first. An If-Match or
+// If-Unmodified-Since header makes the delete conditional on the file's
+// current ETag or modification time. dry_run=true answers with the paths
+// that would be removed and removes nothing.
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	p := r.URL.Query().Get("path")
 	if p == "" {
-		writeError(w, http.StatusBadRequest, "path query parameter is required")
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+
+	soft, ok := parseBoolParam(w, r, "soft", h.softDelete)
+	if !ok {
+		return
+	}
+	purge, ok := parseBoolParam(w, r, "purge", false)
+	if !ok {
+		return
+	}
+	if purge && r.URL.Query().Get("soft") != "" && soft {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "soft and purge cannot both be true")
+		return
+	}
+	recursive, ok := parseBoolParam(w, r, "recursive", false)
+	if !ok {
+		return
+	}
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	if err := h.checkPreconditions(r.Context(), p, r.Header); err != nil {
+		handleStorageError(w, err)
+		return
+	}
+
+	if dryRun {
+		paths, err := h.deleteTargets(r.Context(), p, recursive)
+		if err != nil {
+			writeDeleteError(w, err)
+			return
+		}
+		writeDryRun(w, paths)
+		return
+	}
+
+	// Stat first so the response can say what was removed. A failed Stat
+	// only leaves that out; the delete itself reports real problems.
+	var info *storage.FileInfo
+	if !h.deleteNoContent {
+		info, _ = h.store.Stat(r.Context(), p)
+	}
+
+	if soft && !purge {
+		if err := h.moveToTrash(r.Context(), p, recursive); err != nil {
+			writeDeleteError(w, err)
+			return
+		}
+		h.writeDone(w, http.StatusOK, SuccessResponse{Message: "file moved to trash", File: info})
+		return
+	}
+
+	if err := h.remove(r.Context(), p, recursive); err != nil {
+		writeDeleteError(w, err)
+		return
+	}
+
+	h.writeDone(w, http.StatusOK, SuccessResponse{Message: "file deleted", File: info})
+}
+
+// remove deletes p for good, with everything below it when recursive is
+// set.
+func (h *Handler) remove(ctx context.Context, p string, recursive bool) error {
+	if recursive {
+		return storage.DeleteAll(ctx, h.store, p)
+	}
+	return h.store.Delete(ctx, p)
+}
+
+// writeDeleteError is handleStorageError with a hint for a directory that
+// needs recursive=true.
+func writeDeleteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotEmpty):
+		writeError(w, http.StatusConflict, CodeNotEmpty, "directory is not empty; pass recursive=true to delete it and everything in it")
+	case errors.Is(err, errTrashDirectory):
+		writeError(w, http.StatusConflict, CodeConflict, err.Error())
+	default:
+		handleStorageError(w, err)
+	}
+}
+
+// writeDone reports success for a delete, copy or move, either as status
+// with body or, when deleteNoContent is set, as a bare 204.
+func (h *Handler) writeDone(w http.ResponseWriter, status int, body any) {
+	if h.deleteNoContent {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, status, body)
+}
+
+// Mkdir creates an empty directory, including any missing parents.
+func (h *Handler) Mkdir(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+
+	if err := h.store.Mkdir(r.Context(), p); err != nil {
+		handleStorageError(w, err)
 		return
 	}
 
-	if err := h.store.Delete(r.Context(), p); err != nil {
+	writeJSON(w, http.StatusCreated, SuccessResponse{Message: "directory created"})
+}
+
+// Usage returns space consumption for the subtree at path.
+func (h *Handler) Usage(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		p = "/"
+	}
+
+	u, err := h.store.Usage(r.Context(), p)
+	if err != nil {
 		handleStorageError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, SuccessResponse{Message: "file deleted"})
+	writeJSON(w, http.StatusOK, u)
 }
 
-// Stat returns metadata for a file or directory.
+// DiskInfo reports the total, free and used bytes of the filesystem
+// holding the storage root, so clients can check for room before a large
+// upload. Backends without a filesystem limit, such as object stores, get
+// 501.
+func (h *Handler) DiskInfo(w http.ResponseWriter, r *http.Request) {
+	d, err := storage.DiskInfo(r.Context(), h.store)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Stat returns metadata for a file or directory. With ?checksum=sha256 it
+// also hashes a file's contents into the checksum field.
 func (h *Handler) Stat(w http.ResponseWriter, r *http.Request) {
 	p := r.URL.Query().Get("path")
 	if p == "" {
-		writeError(w, http.StatusBadRequest, "path query parameter is required")
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+
+	algo := r.URL.Query().Get("checksum")
+	if algo != "" && algo != "sha256" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "checksum must be sha256")
 		return
 	}
 
@@ -126,17 +1007,61 @@ func (h *Handler) Stat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if algo != "" && !info.IsDir {
+		info.Checksum, err = storage.SHA256Of(r.Context(), h.store, p)
+		if err != nil {
+			handleStorageError(w, err)
+			return
+		}
+		w.Header().Set("ETag", etagOf(info.Checksum))
+	}
+
 	writeJSON(w, http.StatusOK, info)
 }
 
 // handleStorageError maps storage sentinel errors to HTTP status codes.
 func handleStorageError(w http.ResponseWriter, err error) {
+	status, code, msg := classifyStorageError(err)
+	writeError(w, status, code, msg)
+}
+
+// classifyStorageError maps a storage error to its HTTP status, error code
+// and client-safe message.
+func classifyStorageError(err error) (status int, code, msg string) {
 	switch {
 	case errors.Is(err, storage.ErrNotFound):
-		writeError(w, http.StatusNotFound, "not found")
+		return http.StatusNotFound, CodeNotFound, "not found"
+	case errors.Is(err, storage.ErrReadOnly):
+		return http.StatusForbidden, CodePermissionDenied, "storage backend is read-only"
 	case errors.Is(err, storage.ErrPermission):
-		writeError(w, http.StatusForbidden, "permission denied")
+		return http.StatusForbidden, CodePermissionDenied, "permission denied"
+	case errors.Is(err, storage.ErrExist):
+		return http.StatusConflict, CodeConflict, "file already exists"
+	case errors.Is(err, storage.ErrIsDirectory):
+		return http.StatusBadRequest, CodeIsDirectory, "path is a directory"
+	case errors.Is(err, storage.ErrNotEmpty):
+		return http.StatusConflict, CodeNotEmpty, "directory is not empty"
+	case errors.Is(err, storage.ErrNotSupported):
+		return http.StatusNotImplemented, CodeNotSupported, "not supported by this storage backend"
+	case errors.Is(err, storage.ErrUnavailable):
+		return http.StatusServiceUnavailable, CodeUnavailable, "storage backend unavailable"
+	case errors.Is(err, ErrUploadRejected):
+		return http.StatusUnprocessableEntity, CodeUploadRejected, err.Error()
+	case errors.Is(err, errPreconditionFailed), errors.Is(err, errModifiedSince):
+		return http.StatusPreconditionFailed, CodePreconditionFailed, err.Error()
+	default:
+		return http.StatusInternalServerError, CodeInternal, "internal server error"
+	}
+}
+
+// pathErrorCode maps a middleware.CleanPath error to its error code.
+func pathErrorCode(err error) string {
+	switch {
+	case errors.Is(err, middleware.ErrTooManySegments):
+		return CodePathTooDeep
+	case errors.Is(err, middleware.ErrNameTooLong):
+		return CodeNameTooLong
 	default:
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		return CodePathInvalid
 	}
 }
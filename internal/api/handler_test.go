@@ -3,11 +3,20 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -22,6 +31,8 @@ type mockStorage struct {
 	writeFn  func(ctx context.Context, path string, r io.Reader) error
 	deleteFn func(ctx context.Context, path string) error
 	statFn   func(ctx context.Context, path string) (*storage.FileInfo, error)
+	mkdirFn  func(ctx context.Context, path string) error
+	usageFn  func(ctx context.Context, path string) (*storage.Usage, error)
 }
 
 func (m *mockStorage) List(ctx context.Context, path string) ([]storage.FileInfo, error) {
@@ -36,12 +47,24 @@ func (m *mockStorage) Write(ctx context.Context, path string, r io.Reader) error
 func (m *mockStorage) Delete(ctx context.Context, path string) error {
 	return m.deleteFn(ctx, path)
 }
+
+// Stat defaults to reporting a plain file, so tests that only care about
+// reads need not stub it.
 func (m *mockStorage) Stat(ctx context.Context, path string) (*storage.FileInfo, error) {
+	if m.statFn == nil {
+		return &storage.FileInfo{Name: path, Path: path}, nil
+	}
 	return m.statFn(ctx, path)
 }
+func (m *mockStorage) Mkdir(ctx context.Context, path string) error {
+	return m.mkdirFn(ctx, path)
+}
+func (m *mockStorage) Usage(ctx context.Context, path string) (*storage.Usage, error) {
+	return m.usageFn(ctx, path)
+}
 
 func newTestHandler(store *mockStorage) *Handler {
-	return NewHandler(store, 10<<20) // 10MB
+	return NewHandler(store, Options{MaxUploadSize: 10 << 20}) // 10MB
 }
 
 // --- Health ---
@@ -64,6 +87,145 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+// --- Ready ---
+
+func TestReady_BackendReachable(t *testing.T) {
+	var capturedPath string
+	store := &mockStorage{
+		statFn: func(_ context.Context, path string) (*storage.FileInfo, error) {
+			capturedPath = path
+			return &storage.FileInfo{Name: "/", IsDir: true}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ready", nil)
+	rr := httptest.NewRecorder()
+	h.Ready(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rr.Code)
+	}
+	if capturedPath != "/" {
+		t.Errorf("expected root stat, got %q", capturedPath)
+	}
+}
+
+func TestReady_BackendDown(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, errors.New("dial tcp 10.0.0.5:445: connection refused")
+		},
+	}
+	var logs bytes.Buffer
+	h := NewHandler(store, Options{Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ready", nil)
+	rr := httptest.NewRecorder()
+	h.Ready(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rr.Code)
+	}
+
+	var body ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&body)
+	if strings.Contains(body.Error, "10.0.0.5") {
+		t.Errorf("expected no backend detail in the public response, got %q", body.Error)
+	}
+	if !strings.Contains(logs.String(), "connection refused") {
+		t.Errorf("expected the cause logged, got %q", logs.String())
+	}
+	if body.Code != CodeUnavailable {
+		t.Errorf("expected code %q, got %q", CodeUnavailable, body.Code)
+	}
+}
+
+func TestReady_UsesTimeout(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(ctx context.Context, _ string) (*storage.FileInfo, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("expected readiness check to carry a deadline")
+			}
+			return &storage.FileInfo{IsDir: true}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ready", nil)
+	h.Ready(httptest.NewRecorder(), req)
+}
+
+// probeStore is an in-memory mockStorage for the deep readiness check.
+// writeErr and readBack, when set, break the write or change what is read.
+func probeStore(files map[string]string, writeErr error, readBack string) *mockStorage {
+	return &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "/", IsDir: true}, nil
+		},
+		writeFn: func(_ context.Context, p string, r io.Reader) error {
+			if writeErr != nil {
+				return writeErr
+			}
+			b, _ := io.ReadAll(r)
+			files[p] = string(b)
+			return nil
+		},
+		readFn: func(_ context.Context, p string) (io.ReadCloser, error) {
+			if readBack != "" {
+				return io.NopCloser(strings.NewReader(readBack)), nil
+			}
+			return io.NopCloser(strings.NewReader(files[p])), nil
+		},
+		deleteFn: func(_ context.Context, p string) error {
+			delete(files, p)
+			return nil
+		},
+	}
+}
+
+func TestReady_DeepChecksWritability(t *testing.T) {
+	tests := []struct {
+		name     string
+		writeErr error
+		readBack string
+		want     int
+	}{
+		{"writable", nil, "", http.StatusOK},
+		{"read-only", storage.ErrReadOnly, "", http.StatusServiceUnavailable},
+		{"contents differ", nil, "stale", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{}
+			h := newTestHandler(probeStore(files, tt.writeErr, tt.readBack))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/ready?deep=true", nil)
+			rr := httptest.NewRecorder()
+			h.Ready(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+			if len(files) != 0 {
+				t.Errorf("expected the probe file cleaned up, got %v", files)
+			}
+		})
+	}
+}
+
+func TestReady_ShallowDoesNotWrite(t *testing.T) {
+	h := newTestHandler(probeStore(map[string]string{}, errors.New("write must not be called"), ""))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ready", nil)
+	rr := httptest.NewRecorder()
+	h.Ready(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200 without deep=true, got %d", rr.Code)
+	}
+}
+
 // --- List ---
 
 func TestList_Success(t *testing.T) {
@@ -130,6 +292,105 @@ func TestList_NotFound(t *testing.T) {
 	}
 }
 
+func TestList_DirsOnly(t *testing.T) {
+	var capturedPath string
+	store := &mockStorage{
+		listFn: func(_ context.Context, path string) ([]storage.FileInfo, error) {
+			capturedPath = path
+			return []storage.FileInfo{
+				{Name: "a.txt", Path: "a.txt"},
+				{Name: "docs", Path: "docs", IsDir: true},
+				{Name: "b.txt", Path: "b.txt"},
+				{Name: "img", Path: "img", IsDir: true},
+			}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files?dirs_only=true", nil)
+	rr := httptest.NewRecorder()
+	h.List(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if capturedPath != "/" {
+		t.Errorf("expected path %q, got %q", "/", capturedPath)
+	}
+	var files []storage.FileInfo
+	json.NewDecoder(rr.Body).Decode(&files)
+	if len(files) != 2 || files[0].Name != "docs" || files[1].Name != "img" {
+		t.Errorf("expected only docs and img, got %+v", files)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/files?dirs_only=maybe", nil)
+	rr = httptest.NewRecorder()
+	h.List(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-boolean dirs_only, got %d", rr.Code)
+	}
+}
+
+func TestList_TruncatedAtMaxEntries(t *testing.T) {
+	entries := make([]storage.FileInfo, 25)
+	for i := range entries {
+		name := fmt.Sprintf("f%02d.txt", i)
+		entries[i] = storage.FileInfo{Name: name, Path: name}
+	}
+	store := &mockStorage{
+		listFn: func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+			return entries, nil
+		},
+	}
+
+	tests := []struct {
+		name          string
+		max           int
+		wantLen       int
+		wantTruncated bool
+	}{
+		{"over the cap", 10, 10, true},
+		{"exactly the cap", 25, 25, false},
+		{"unlimited", 0, 25, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(store, Options{MaxListEntries: tt.max})
+			for _, target := range []string{"/api/v1/files?path=/", "/api/v1/files?path=/&recursive=true"} {
+				rr := httptest.NewRecorder()
+				h.List(rr, httptest.NewRequest(http.MethodGet, target, nil))
+
+				var files []storage.FileInfo
+				json.NewDecoder(rr.Body).Decode(&files)
+				if len(files) != tt.wantLen {
+					t.Errorf("%s: expected %d entries, got %d", target, tt.wantLen, len(files))
+				}
+				if got := rr.Header().Get("X-Result-Truncated") == "true"; got != tt.wantTruncated {
+					t.Errorf("%s: expected truncated=%v, got header %q", target, tt.wantTruncated, rr.Header().Get("X-Result-Truncated"))
+				}
+			}
+		})
+	}
+}
+
+func TestList_StreamNotTruncated(t *testing.T) {
+	entries := make([]storage.FileInfo, 25)
+	for i := range entries {
+		entries[i] = storage.FileInfo{Name: "f", Path: "f"}
+	}
+	h := NewHandler(&mockStorage{
+		listFn: func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+			return entries, nil
+		},
+	}, Options{MaxListEntries: 10})
+
+	rr := httptest.NewRecorder()
+	h.List(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files?path=/&stream=true", nil))
+	if lines := strings.Count(rr.Body.String(), "\n"); lines != 25 {
+		t.Errorf("expected all 25 entries streamed, got %d lines", lines)
+	}
+}
+
 // --- Download ---
 
 func TestDownload_Success(t *testing.T) {
@@ -170,187 +431,1511 @@ func TestDownload_UnknownExtension(t *testing.T) {
 	h.Download(rr, req)
 
 	ct := rr.Header().Get("Content-Type")
-	if ct != "application/octet-stream" {
-		t.Errorf("expected application/octet-stream, got %q", ct)
+	if ct != "text/plain; charset=utf-8" {
+		t.Errorf("expected sniffed text/plain, got %q", ct)
+	}
+	if rr.Body.String() != "binary" {
+		t.Errorf("expected body %q, got %q", "binary", rr.Body.String())
 	}
 }
 
-func TestDownload_MissingPath(t *testing.T) {
-	h := newTestHandler(&mockStorage{})
+func TestDownload_SniffsExtensionless(t *testing.T) {
+	content := "{\"hello\": \"world\"}\n" + strings.Repeat(" ", 1024)
+	store := &mockStorage{
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+	}
+	h := newTestHandler(store)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=README", nil)
 	rr := httptest.NewRecorder()
 	h.Download(rr, req)
 
-	if rr.Code != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", rr.Code)
+	ct := rr.Header().Get("Content-Type")
+	if ct != "text/plain; charset=utf-8" {
+		t.Errorf("expected text/plain, got %q", ct)
+	}
+	if rr.Body.String() != content {
+		t.Errorf("expected full body after sniffing (%d bytes), got %d bytes", len(content), rr.Body.Len())
 	}
 }
 
-func TestDownload_NotFound(t *testing.T) {
+func TestDownload_SniffsBinary(t *testing.T) {
 	store := &mockStorage{
 		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
-			return nil, storage.ErrNotFound
+			return io.NopCloser(bytes.NewReader([]byte{0x00, 0x01, 0x02, 0xff})), nil
 		},
 	}
 	h := newTestHandler(store)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=gone.txt", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=blob", nil)
 	rr := httptest.NewRecorder()
 	h.Download(rr, req)
 
-	if rr.Code != http.StatusNotFound {
-		t.Errorf("expected 404, got %d", rr.Code)
-	}
-}
-
-// --- Upload ---
-
-func createMultipartRequest(t *testing.T, path, filename, content string) *http.Request {
-	t.Helper()
-	var buf bytes.Buffer
-	w := multipart.NewWriter(&buf)
-	part, err := w.CreateFormFile("file", filename)
-	if err != nil {
-		t.Fatalf("CreateFormFile: %v", err)
+	ct := rr.Header().Get("Content-Type")
+	if ct != "application/octet-stream" {
+		t.Errorf("expected application/octet-stream, got %q", ct)
 	}
-	part.Write([]byte(content))
-	w.Close()
-
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload?path="+path, &buf)
-	req.Header.Set("Content-Type", w.FormDataContentType())
-	return req
 }
 
-func TestUpload_Success(t *testing.T) {
-	var writtenContent string
+func TestDownload_KnownExtensionNotSniffed(t *testing.T) {
 	store := &mockStorage{
-		writeFn: func(_ context.Context, _ string, r io.Reader) error {
-			data, _ := io.ReadAll(r)
-			writtenContent = string(data)
-			return nil
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("plain words, not css")), nil
 		},
 	}
 	h := newTestHandler(store)
 
-	req := createMultipartRequest(t, "upload.txt", "upload.txt", "uploaded data")
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=site.css", nil)
 	rr := httptest.NewRecorder()
-	h.Upload(rr, req)
+	h.Download(rr, req)
 
-	if rr.Code != http.StatusCreated {
-		t.Errorf("expected 201, got %d", rr.Code)
-	}
-	if writtenContent != "uploaded data" {
-		t.Errorf("expected written content %q, got %q", "uploaded data", writtenContent)
+	ct := rr.Header().Get("Content-Type")
+	if !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("expected text/css from extension, got %q", ct)
 	}
 }
 
-func TestUpload_MissingPath(t *testing.T) {
-	h := newTestHandler(&mockStorage{})
-
-	var buf bytes.Buffer
-	w := multipart.NewWriter(&buf)
-	w.Close()
-
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload", &buf)
-	req.Header.Set("Content-Type", w.FormDataContentType())
-	rr := httptest.NewRecorder()
-	h.Upload(rr, req)
-
-	if rr.Code != http.StatusBadRequest {
-		t.Errorf("expected 400, got %d", rr.Code)
+func TestDownload_ContentTypeOverrides(t *testing.T) {
+	store := &mockStorage{
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("contents")), nil
+		},
+	}
+	h := NewHandler(store, Options{ContentTypes: map[string]string{
+		"LOG": "text/x-log",
+		".md": "text/plain; charset=utf-8",
+	}})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"app.LOG", "text/x-log"},                 // configured, case-insensitive
+		{"notes.md", "text/plain; charset=utf-8"}, // configured over the built-in entry
+		{"module.wasm", "application/wasm"},       // built-in
+		{"site.css", "text/css; charset=utf-8"},   // falls through to mime
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path="+tt.path, nil)
+		rr := httptest.NewRecorder()
+		h.Download(rr, req)
+
+		if ct := rr.Header().Get("Content-Type"); ct != tt.want {
+			t.Errorf("%s: expected Content-Type %q, got %q", tt.path, tt.want, ct)
+		}
 	}
 }
 
-func TestUpload_MissingFile(t *testing.T) {
+func TestDownload_MissingPath(t *testing.T) {
 	h := newTestHandler(&mockStorage{})
 
-	var buf bytes.Buffer
-	w := multipart.NewWriter(&buf)
-	w.WriteField("other", "value")
-	w.Close()
-
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload?path=test.txt", &buf)
-	req.Header.Set("Content-Type", w.FormDataContentType())
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download", nil)
 	rr := httptest.NewRecorder()
-	h.Upload(rr, req)
+	h.Download(rr, req)
 
 	if rr.Code != http.StatusBadRequest {
 		t.Errorf("expected 400, got %d", rr.Code)
 	}
 }
 
-// --- Delete ---
-
-func TestDelete_Success(t *testing.T) {
+func TestDownload_Directory(t *testing.T) {
 	store := &mockStorage{
-		deleteFn: func(_ context.Context, _ string) error {
-			return nil
+		statFn: func(_ context.Context, path string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: path, Path: path, IsDir: true}, nil
+		},
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			t.Fatal("Read must not be called for a directory")
+			return nil, nil
 		},
 	}
 	h := newTestHandler(store)
 
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=trash.txt", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=docs", nil)
 	rr := httptest.NewRecorder()
-	h.Delete(rr, req)
+	h.Download(rr, req)
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("expected 200, got %d", rr.Code)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var resp ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Code != CodeIsDirectory {
+		t.Errorf("expected code %s, got %q", CodeIsDirectory, resp.Code)
 	}
 }
 
-func TestDelete_MissingPath(t *testing.T) {
-	h := newTestHandler(&mockStorage{})
+func TestDownload_ReadIsDirectory(t *testing.T) {
+	store := &mockStorage{
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return nil, storage.ErrIsDirectory
+		},
+	}
+	h := newTestHandler(store)
 
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=docs", nil)
 	rr := httptest.NewRecorder()
-	h.Delete(rr, req)
+	h.Download(rr, req)
 
 	if rr.Code != http.StatusBadRequest {
 		t.Errorf("expected 400, got %d", rr.Code)
 	}
 }
 
-func TestDelete_NotFound(t *testing.T) {
+func TestDownload_RateLimited(t *testing.T) {
+	const rate = 20000
+	payload := strings.Repeat("x", 6000)
 	store := &mockStorage{
-		deleteFn: func(_ context.Context, _ string) error {
-			return storage.ErrNotFound
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(payload)), nil
 		},
 	}
-	h := newTestHandler(store)
+	h := NewHandler(store, Options{DownloadRateLimit: rate})
 
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=gone.txt", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=data.txt", nil)
 	rr := httptest.NewRecorder()
-	h.Delete(rr, req)
+	start := time.Now()
+	h.Download(rr, req)
+	elapsed := time.Since(start)
 
-	if rr.Code != http.StatusNotFound {
-		t.Errorf("expected 404, got %d", rr.Code)
+	if rr.Body.String() != payload {
+		t.Fatalf("expected the full payload, got %d bytes", rr.Body.Len())
+	}
+	// The limiter lets a tenth of a second's worth through up front.
+	minimum := time.Duration(float64(len(payload)-rate/10) / rate * float64(time.Second))
+	if elapsed < minimum {
+		t.Errorf("expected at least %v, took %v", minimum, elapsed)
 	}
 }
 
-// --- Stat ---
-
-func TestStat_Success(t *testing.T) {
-	now := time.Now().Truncate(time.Second)
+func TestDownload_RateLimitStopsOnCancel(t *testing.T) {
 	store := &mockStorage{
-		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
-			return &storage.FileInfo{
-				Name:    "info.txt",
-				Path:    "info.txt",
-				Size:    42,
-				IsDir:   false,
-				ModTime: now,
-			}, nil
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(strings.Repeat("x", 100000))), nil
 		},
 	}
-	h := newTestHandler(store)
+	h := NewHandler(store, Options{DownloadRateLimitTotal: 1000})
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=info.txt", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=data.txt", nil).WithContext(ctx)
 	rr := httptest.NewRecorder()
-	h.Stat(rr, req)
+	start := time.Now()
+	h.Download(rr, req)
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("expected 200, got %d", rr.Code)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the download to stop with the request, took %v", elapsed)
+	}
+	if rr.Body.Len() >= 100000 {
+		t.Error("expected a partial body after cancellation")
+	}
+}
+
+func TestDownload_NotFound(t *testing.T) {
+	store := &mockStorage{
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return nil, storage.ErrNotFound
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=gone.txt", nil)
+	rr := httptest.NewRecorder()
+	h.Download(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rr.Code)
+	}
+}
+
+func newRangeStore(content string) *mockStorage {
+	return &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "data.txt", Size: int64(len(content))}, nil
+		},
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+	}
+}
+
+func TestDownload_ValidRange(t *testing.T) {
+	h := newTestHandler(newRangeStore("0123456789"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=data.txt", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	rr := httptest.NewRecorder()
+	h.Download(rr, req)
+
+	if rr.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", rr.Code)
+	}
+	if rr.Body.String() != "2345" {
+		t.Errorf("expected body %q, got %q", "2345", rr.Body.String())
+	}
+	if cr := rr.Header().Get("Content-Range"); cr != "bytes 2-5/10" {
+		t.Errorf("expected Content-Range %q, got %q", "bytes 2-5/10", cr)
+	}
+}
+
+func TestDownload_MultipleRanges(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		store *mockStorage
+	}{
+		{"stream", newRangeStore("0123456789")},
+		{"seeker", &mockStorage{
+			statFn: newRangeStore("0123456789").statFn,
+			readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+				return readSeekNopCloser{strings.NewReader("0123456789")}, nil
+			},
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(tt.store)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=data.txt", nil)
+			req.Header.Set("Range", "bytes=7-8,1-2")
+			rr := httptest.NewRecorder()
+			h.Download(rr, req)
+
+			if rr.Code != http.StatusPartialContent {
+				t.Fatalf("expected 206, got %d", rr.Code)
+			}
+			if cl := rr.Header().Get("Content-Length"); cl != strconv.Itoa(rr.Body.Len()) {
+				t.Errorf("expected Content-Length %d, got %s", rr.Body.Len(), cl)
+			}
+			mt, params, err := mime.ParseMediaType(rr.Header().Get("Content-Type"))
+			if err != nil || mt != "multipart/byteranges" {
+				t.Fatalf("expected multipart/byteranges, got %q", rr.Header().Get("Content-Type"))
+			}
+
+			mr := multipart.NewReader(rr.Body, params["boundary"])
+			for _, want := range []struct{ contentRange, body string }{
+				{"bytes 7-8/10", "78"},
+				{"bytes 1-2/10", "12"},
+			} {
+				part, err := mr.NextPart()
+				if err != nil {
+					t.Fatalf("next part: %v", err)
+				}
+				if cr := part.Header.Get("Content-Range"); cr != want.contentRange {
+					t.Errorf("expected Content-Range %q, got %q", want.contentRange, cr)
+				}
+				if ct := part.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+					t.Errorf("expected part Content-Type text/plain, got %q", ct)
+				}
+				if b, _ := io.ReadAll(part); string(b) != want.body {
+					t.Errorf("expected part body %q, got %q", want.body, b)
+				}
+			}
+			if _, err := mr.NextPart(); err != io.EOF {
+				t.Errorf("expected 2 parts, got more (%v)", err)
+			}
+		})
+	}
+}
+
+// readSeekNopCloser lets a test store hand out a seekable reader.
+type readSeekNopCloser struct{ io.ReadSeeker }
+
+func (readSeekNopCloser) Close() error { return nil }
+
+func TestDownload_LastModified(t *testing.T) {
+	modTime := time.Date(2024, 3, 9, 14, 30, 15, 500, time.FixedZone("CET", 3600))
+	store := newRangeStore("hello")
+	store.statFn = func(_ context.Context, _ string) (*storage.FileInfo, error) {
+		return &storage.FileInfo{Name: "data.txt", Size: 5, ModTime: modTime}, nil
+	}
+	h := newTestHandler(store)
+
+	rr := httptest.NewRecorder()
+	h.Download(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=data.txt", nil))
+
+	want := "Sat, 09 Mar 2024 13:30:15 GMT"
+	if got := rr.Header().Get("Last-Modified"); got != want {
+		t.Errorf("expected Last-Modified %q, got %q", want, got)
+	}
+}
+
+func TestDownload_NoLastModifiedWithoutModTime(t *testing.T) {
+	h := newTestHandler(newRangeStore("hello"))
+
+	rr := httptest.NewRecorder()
+	h.Download(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=data.txt", nil))
+
+	if got := rr.Header().Get("Last-Modified"); got != "" {
+		t.Errorf("expected no Last-Modified for a zero ModTime, got %q", got)
+	}
+}
+
+func TestDownload_MalformedRange(t *testing.T) {
+	h := newTestHandler(newRangeStore("0123456789"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=data.txt", nil)
+	req.Header.Set("Range", "bytes=abc-xyz")
+	rr := httptest.NewRecorder()
+	h.Download(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if rr.Body.String() != "0123456789" {
+		t.Errorf("expected full body, got %q", rr.Body.String())
+	}
+}
+
+func TestDownload_EmptyRange(t *testing.T) {
+	h := newTestHandler(newRangeStore("0123456789"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=data.txt", nil)
+	req.Header.Set("Range", "bytes=")
+	rr := httptest.NewRecorder()
+	h.Download(rr, req)
+
+	if rr.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("expected 416, got %d", rr.Code)
+	}
+	if cr := rr.Header().Get("Content-Range"); cr != "bytes */10" {
+		t.Errorf("expected Content-Range %q, got %q", "bytes */10", cr)
+	}
+}
+
+func TestDownload_Trailers(t *testing.T) {
+	content := strings.Repeat("chunk", 1000)
+	store := &mockStorage{
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+	}
+	h := NewHandler(store, Options{DownloadTrailers: true})
+	srv := httptest.NewServer(http.HandlerFunc(h.Download))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/files/download?path=big.txt")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if _, ok := resp.Trailer["X-Bytes-Sent"]; !ok {
+		t.Fatalf("expected X-Bytes-Sent to be announced, got %v", resp.Trailer)
+	}
+
+	data, _ := io.ReadAll(resp.Body)
+	if len(data) != len(content) {
+		t.Fatalf("expected %d bytes, got %d", len(content), len(data))
+	}
+	if got := resp.Trailer.Get("X-Bytes-Sent"); got != strconv.Itoa(len(content)) {
+		t.Errorf("expected X-Bytes-Sent %d, got %q", len(content), got)
+	}
+	if got := resp.Trailer.Get("X-Download-Status"); got != "complete" {
+		t.Errorf("expected X-Download-Status complete, got %q", got)
+	}
+}
+
+func TestDownload_NoTrailersByDefault(t *testing.T) {
+	h := newTestHandler(newRangeStore("0123456789"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=data.txt", nil)
+	rr := httptest.NewRecorder()
+	h.Download(rr, req)
+
+	if tr := rr.Header().Get("Trailer"); tr != "" {
+		t.Errorf("expected no Trailer header, got %q", tr)
+	}
+}
+
+// --- Preview ---
+
+// closeTracker records whether Close was called on a read stream.
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestPreview_ReturnsPrefix(t *testing.T) {
+	body := &closeTracker{Reader: strings.NewReader(strings.Repeat("line of text\n", 10000))}
+	store := &mockStorage{
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return body, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/preview?path=CHANGELOG&bytes=100", nil)
+	rr := httptest.NewRecorder()
+	h.Preview(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if rr.Body.Len() != 100 {
+		t.Errorf("expected 100 bytes, got %d", rr.Body.Len())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("expected sniffed text/plain, got %q", ct)
+	}
+	if !body.closed {
+		t.Error("expected the storage reader to be closed")
+	}
+}
+
+func TestPreview_CapsAtMaximum(t *testing.T) {
+	store := &mockStorage{
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(make([]byte, 2*maxPreviewBytes))), nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/preview?path=huge.bin&bytes=999999999", nil)
+	rr := httptest.NewRecorder()
+	h.Preview(rr, req)
+
+	if rr.Body.Len() != maxPreviewBytes {
+		t.Errorf("expected %d bytes, got %d", maxPreviewBytes, rr.Body.Len())
+	}
+}
+
+func TestPreview_InvalidBytes(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	for _, v := range []string{"0", "-5", "abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/files/preview?path=a.txt&bytes="+v, nil)
+		rr := httptest.NewRecorder()
+		h.Preview(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("bytes=%s: expected 400, got %d", v, rr.Code)
+		}
+	}
+}
+
+func TestPreview_NotFound(t *testing.T) {
+	store := &mockStorage{
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return nil, storage.ErrNotFound
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/preview?path=missing.txt", nil)
+	rr := httptest.NewRecorder()
+	h.Preview(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rr.Code)
+	}
+}
+
+// --- Upload ---
+
+func createMultipartRequest(t *testing.T, path, filename, content string) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write([]byte(content))
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload?path="+path, &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestUpload_Success(t *testing.T) {
+	var writtenContent string
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, _ string, r io.Reader) error {
+			data, _ := io.ReadAll(r)
+			writtenContent = string(data)
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := createMultipartRequest(t, "upload.txt", "upload.txt", "uploaded data")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", rr.Code)
+	}
+	if writtenContent != "uploaded data" {
+		t.Errorf("expected written content %q, got %q", "uploaded data", writtenContent)
+	}
+}
+
+func TestUpload_MultipartMemorySpillsToDisk(t *testing.T) {
+	content := strings.Repeat("x", 4096)
+	for name, tt := range map[string]struct {
+		memory int64
+		spill  bool
+	}{
+		"small threshold": {memory: 64, spill: true},
+		"default":         {memory: 0, spill: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// mime/multipart spools parts past the threshold into TMPDIR.
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+
+			var spooled int
+			store := &mockStorage{
+				statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+					return nil, storage.ErrNotFound
+				},
+				writeFn: func(_ context.Context, _ string, r io.Reader) error {
+					entries, _ := os.ReadDir(tmp)
+					spooled = len(entries)
+					io.Copy(io.Discard, r)
+					return nil
+				},
+			}
+			h := NewHandler(store, Options{MaxUploadSize: 10 << 20, MultipartMemory: tt.memory})
+
+			rr := httptest.NewRecorder()
+			h.Upload(rr, createMultipartRequest(t, "big.txt", "big.txt", content))
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("expected 201, got %d", rr.Code)
+			}
+			if got := spooled > 0; got != tt.spill {
+				t.Errorf("expected spilled to disk %v, found %d temp files", tt.spill, spooled)
+			}
+		})
+	}
+}
+
+func TestUpload_ConflictWithoutOverwrite(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "exists.txt"}, nil
+		},
+		writeFn: func(_ context.Context, _ string, _ io.Reader) error {
+			t.Error("existing file should not be overwritten")
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := createMultipartRequest(t, "exists.txt", "exists.txt", "new data")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", rr.Code)
+	}
+}
+
+func TestUpload_OverwriteTrue(t *testing.T) {
+	written := false
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "exists.txt"}, nil
+		},
+		writeFn: func(_ context.Context, _ string, _ io.Reader) error {
+			written = true
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := createMultipartRequest(t, "exists.txt&overwrite=true", "exists.txt", "new data")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", rr.Code)
+	}
+	if !written {
+		t.Error("expected Write to be called")
+	}
+}
+
+func TestUpload_DirectoryTarget(t *testing.T) {
+	var writtenPath string
+	store := &mockStorage{
+		statFn: func(_ context.Context, path string) (*storage.FileInfo, error) {
+			if path == "docs" {
+				return &storage.FileInfo{Name: "docs", IsDir: true}, nil
+			}
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, path string, _ io.Reader) error {
+			writtenPath = path
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := createMultipartRequest(t, "docs", "report.pdf", "pdf bytes")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rr.Code)
+	}
+	if writtenPath != "docs/report.pdf" {
+		t.Errorf("expected write to %q, got %q", "docs/report.pdf", writtenPath)
+	}
+}
+
+func TestUpload_UseFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		filename string
+		wantCode int
+		wantPath string
+	}{
+		{"trailing slash", "/inbox/", "report.pdf", http.StatusCreated, "/inbox/report.pdf"},
+		{"explicit flag", "/inbox&use_filename=true", "report.pdf", http.StatusCreated, "/inbox/report.pdf"},
+		{"flag off keeps path", "/inbox/named.pdf&use_filename=false", "report.pdf", http.StatusCreated, "/inbox/named.pdf"},
+		{"traversal stripped", "/inbox/", "../../evil.sh", http.StatusCreated, "/inbox/evil.sh"},
+		{"windows path stripped", "/inbox/", `C:\Users\me\evil.sh`, http.StatusCreated, "/inbox/evil.sh"},
+		{"dot-dot rejected", "/inbox/", "..", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writtenPath string
+			store := &mockStorage{
+				statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+					return nil, storage.ErrNotFound
+				},
+				writeFn: func(_ context.Context, p string, r io.Reader) error {
+					io.Copy(io.Discard, r)
+					writtenPath = p
+					return nil
+				},
+			}
+			h := newTestHandler(store)
+
+			req := createMultipartRequest(t, tt.query, tt.filename, "data")
+			rr := httptest.NewRecorder()
+			h.Upload(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if writtenPath != tt.wantPath {
+				t.Errorf("expected write to %q, got %q", tt.wantPath, writtenPath)
+			}
+			if tt.wantCode == http.StatusCreated {
+				var resp SuccessResponse
+				json.NewDecoder(rr.Body).Decode(&resp)
+				if resp.Path != tt.wantPath {
+					t.Errorf("expected response path %q, got %q", tt.wantPath, resp.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestUpload_DirectoryTargetSanitizesFilename(t *testing.T) {
+	var writtenPath string
+	store := &mockStorage{
+		statFn: func(_ context.Context, path string) (*storage.FileInfo, error) {
+			if path == "docs" {
+				return &storage.FileInfo{Name: "docs", IsDir: true}, nil
+			}
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, path string, _ io.Reader) error {
+			writtenPath = path
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := createMultipartRequest(t, "docs", `..\..\evil.txt`, "data")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rr.Code)
+	}
+	if writtenPath != "docs/evil.txt" {
+		t.Errorf("expected write to %q, got %q", "docs/evil.txt", writtenPath)
+	}
+}
+
+func TestUpload_DirectoryTargetWithoutFilename(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "docs", IsDir: true}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := createMultipartRequest(t, "docs", "", "data")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestUpload_DirectoryTargetFilenameLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		length   int
+		wantCode int
+	}{
+		{"at limit", 16, http.StatusCreated},
+		{"over limit", 17, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockStorage{
+				statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+					if p == "docs" {
+						return &storage.FileInfo{Name: "docs", IsDir: true}, nil
+					}
+					return nil, storage.ErrNotFound
+				},
+				writeFn: func(_ context.Context, _ string, r io.Reader) error {
+					io.Copy(io.Discard, r)
+					return nil
+				},
+			}
+			h := NewHandler(store, Options{MaxUploadSize: 10 << 20, MaxNameBytes: 16})
+
+			req := createMultipartRequest(t, "docs", strings.Repeat("n", tt.length), "data")
+			rr := httptest.NewRecorder()
+			h.Upload(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if tt.wantCode == http.StatusBadRequest {
+				var resp ErrorResponse
+				json.NewDecoder(rr.Body).Decode(&resp)
+				if resp.Code != CodeNameTooLong {
+					t.Errorf("expected code %s, got %q", CodeNameTooLong, resp.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestUpload_FilenameWithControlCharacters(t *testing.T) {
+	store := &mockStorage{
+		writeFn: func(_ context.Context, _ string, _ io.Reader) error {
+			t.Error("storage should not be called")
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	// mime/multipart rejects most raw control bytes in headers, but a tab
+	// passes through and RFC 2231 encoding (filename*=) decodes to raw CR/LF.
+	dispositions := []string{
+		`form-data; name="file"; filename="tab` + "\t" + `.txt"`,
+		`form-data; name="file"; filename*=UTF-8''evil%0D%0AX-Injected%3A%201.txt`,
+	}
+	for _, cd := range dispositions {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		hdr := make(textproto.MIMEHeader)
+		hdr.Set("Content-Disposition", cd)
+		part, err := mw.CreatePart(hdr)
+		if err != nil {
+			t.Fatalf("CreatePart: %v", err)
+		}
+		part.Write([]byte("data"))
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload?path=docs", &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rr := httptest.NewRecorder()
+		h.Upload(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("disposition %q: expected 400, got %d", cd, rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), "control characters") {
+			t.Errorf("disposition %q: expected control character error, got %s", cd, rr.Body.String())
+		}
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{"a/b/c.txt", "c.txt"},
+		{`C:\Users\me\c.txt`, "c.txt"},
+		{"..", ""},
+		{"", ""},
+		{"/", ""},
+		{"line\nbreak.txt", ""},
+	}
+	for _, tt := range tests {
+		if got := sanitizeFilename(tt.in); got != tt.want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func createTypedMultipartRequest(t *testing.T, path, filename, contentType, content string) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	hdr := make(textproto.MIMEHeader)
+	hdr.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	hdr.Set("Content-Type", contentType)
+	part, err := w.CreatePart(hdr)
+	if err != nil {
+		t.Fatalf("CreatePart: %v", err)
+	}
+	part.Write([]byte(content))
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload?path="+path, &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func newAllowlistStore() *mockStorage {
+	return &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, _ string, _ io.Reader) error {
+			return nil
+		},
+	}
+}
+
+func TestUpload_AllowedExtension(t *testing.T) {
+	h := NewHandler(newAllowlistStore(), Options{
+		MaxUploadSize:     10 << 20,
+		AllowedExtensions: []string{"pdf", ".PNG"},
+	})
+
+	req := createMultipartRequest(t, "scan.png", "scan.PNG", "png bytes")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", rr.Code)
+	}
+}
+
+func TestUpload_BlockedExtension(t *testing.T) {
+	h := NewHandler(newAllowlistStore(), Options{
+		MaxUploadSize:     10 << 20,
+		AllowedExtensions: []string{".pdf"},
+	})
+
+	req := createMultipartRequest(t, "run.exe", "run.exe", "MZ")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415, got %d", rr.Code)
+	}
+}
+
+func TestUpload_MIMEAllowlist(t *testing.T) {
+	h := NewHandler(newAllowlistStore(), Options{
+		MaxUploadSize:    10 << 20,
+		AllowedMIMETypes: []string{"application/pdf"},
+	})
+
+	tests := []struct {
+		name        string
+		contentType string
+		want        int
+	}{
+		{"allowed", "application/pdf", http.StatusCreated},
+		{"allowed with params", "Application/PDF; charset=binary", http.StatusCreated},
+		{"blocked", "application/x-msdownload", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createTypedMultipartRequest(t, "doc.pdf", "doc.pdf", tt.contentType, "data")
+			rr := httptest.NewRecorder()
+			h.Upload(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestUpload_UnrestrictedByDefault(t *testing.T) {
+	h := newTestHandler(newAllowlistStore())
+
+	req := createTypedMultipartRequest(t, "anything.xyz", "anything.xyz", "application/x-whatever", "data")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", rr.Code)
+	}
+}
+
+func TestUpload_InvalidOverwrite(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	req := createMultipartRequest(t, "a.txt&overwrite=maybe", "a.txt", "data")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestUpload_TooLarge(t *testing.T) {
+	store := &mockStorage{
+		writeFn: func(_ context.Context, _ string, _ io.Reader) error {
+			t.Error("storage should not be called for oversized upload")
+			return nil
+		},
+	}
+	h := NewHandler(store, Options{MaxUploadSize: 1024})
+
+	req := createMultipartRequest(t, "big.bin", "big.bin", strings.Repeat("x", 4096))
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", rr.Code)
+	}
+
+	var body ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&body)
+	if !strings.Contains(body.Error, "1024") {
+		t.Errorf("expected error to name the limit, got %q", body.Error)
+	}
+	if body.Code != CodeTooLarge {
+		t.Errorf("expected code %q, got %q", CodeTooLarge, body.Code)
+	}
+}
+
+func TestUpload_MalformedMultipart(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload?path=test.txt", strings.NewReader("not multipart"))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=xyz")
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestUpload_MissingPath(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload", &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestUpload_MissingFile(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.WriteField("other", "value")
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload?path=test.txt", &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+// --- Put ---
+
+func TestPut_Success(t *testing.T) {
+	var writtenPath, writtenContent string
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, path string, r io.Reader) error {
+			data, _ := io.ReadAll(r)
+			writtenPath, writtenContent = path, string(data)
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=dest.txt", strings.NewReader("raw data"))
+	rr := httptest.NewRecorder()
+	h.Put(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rr.Code)
+	}
+	if writtenPath != "dest.txt" || writtenContent != "raw data" {
+		t.Errorf("expected write of %q to dest.txt, got %q to %q", "raw data", writtenContent, writtenPath)
+	}
+}
+
+// --- Patch ---
+
+func TestPatch_ReplacesExistingFile(t *testing.T) {
+	var writtenPath, writtenContent string
+	store := &mockStorage{
+		statFn: func(_ context.Context, path string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "app.json", Path: path, Size: int64(len(writtenContent))}, nil
+		},
+		writeFn: func(_ context.Context, path string, r io.Reader) error {
+			data, _ := io.ReadAll(r)
+			writtenPath, writtenContent = path, string(data)
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/files?path=conf/app.json", strings.NewReader(`{"debug":true}`))
+	rr := httptest.NewRecorder()
+	h.Patch(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if writtenPath != "conf/app.json" || writtenContent != `{"debug":true}` {
+		t.Errorf("expected new contents written to conf/app.json, got %q to %q", writtenContent, writtenPath)
+	}
+	var info storage.FileInfo
+	json.NewDecoder(rr.Body).Decode(&info)
+	if info.Path != "conf/app.json" || info.Size != 14 {
+		t.Errorf("expected the updated FileInfo, got %+v", info)
+	}
+}
+
+func TestPatch_MissingFile(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, _ string, _ io.Reader) error {
+			t.Fatal("Patch must not create a missing file")
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/files?path=missing.json", strings.NewReader("{}"))
+	rr := httptest.NewRecorder()
+	h.Patch(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestPatch_TooLarge(t *testing.T) {
+	h := NewHandler(&mockStorage{}, Options{MaxUploadSize: 8})
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/files?path=a.json", strings.NewReader("way more than eight bytes"))
+	rr := httptest.NewRecorder()
+	h.Patch(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", rr.Code)
+	}
+}
+
+func TestPatch_Directory(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, path string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: path, IsDir: true}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/files?path=docs", strings.NewReader("{}"))
+	rr := httptest.NewRecorder()
+	h.Patch(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestPut_MissingPath(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files", strings.NewReader("data"))
+	rr := httptest.NewRecorder()
+	h.Put(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestPut_TooLarge(t *testing.T) {
+	h := NewHandler(&mockStorage{}, Options{MaxUploadSize: 8})
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=big.bin", strings.NewReader("way more than eight bytes"))
+	rr := httptest.NewRecorder()
+	h.Put(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", rr.Code)
+	}
+}
+
+func TestPut_Conflict(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "dest.txt"}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=dest.txt", strings.NewReader("data"))
+	rr := httptest.NewRecorder()
+	h.Put(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", rr.Code)
+	}
+}
+
+// --- Content-MD5 ---
+
+func contentMD5(s string) string {
+	sum := md5.Sum([]byte(s))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// newChecksumStore stores writes in files and records deletes.
+func newChecksumStore(files map[string]string) *mockStorage {
+	return &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, path string, r io.Reader) error {
+			data, _ := io.ReadAll(r)
+			files[path] = string(data)
+			return nil
+		},
+		deleteFn: func(_ context.Context, path string) error {
+			delete(files, path)
+			return nil
+		},
+	}
+}
+
+func TestUpload_ContentMD5Match(t *testing.T) {
+	files := map[string]string{}
+	h := newTestHandler(newChecksumStore(files))
+
+	req := createMultipartRequest(t, "a.txt", "a.txt", "hello world")
+	req.Header.Set("Content-MD5", contentMD5("hello world"))
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if files["a.txt"] != "hello world" {
+		t.Errorf("expected stored content, got %q", files["a.txt"])
+	}
+}
+
+func TestUpload_ContentMD5MismatchDeletesFile(t *testing.T) {
+	files := map[string]string{}
+	h := newTestHandler(newChecksumStore(files))
+
+	req := createMultipartRequest(t, "a.txt", "a.txt", "hello world")
+	req.Header.Set("Content-MD5", contentMD5("something else"))
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	if _, ok := files["a.txt"]; ok {
+		t.Error("expected mismatched upload to be deleted")
+	}
+}
+
+func TestPut_ContentMD5MismatchDeletesFile(t *testing.T) {
+	files := map[string]string{}
+	h := newTestHandler(newChecksumStore(files))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=b.txt", strings.NewReader("payload"))
+	req.Header.Set("Content-MD5", contentMD5("payloaD"))
+	rr := httptest.NewRecorder()
+	h.Put(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	if _, ok := files["b.txt"]; ok {
+		t.Error("expected mismatched upload to be deleted")
+	}
+}
+
+func TestPut_InvalidContentMD5(t *testing.T) {
+	files := map[string]string{}
+	h := newTestHandler(newChecksumStore(files))
+
+	for _, v := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=b.txt", strings.NewReader("payload"))
+		req.Header.Set("Content-MD5", v)
+		rr := httptest.NewRecorder()
+		h.Put(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Content-MD5 %q: expected 400, got %d", v, rr.Code)
+		}
+	}
+	if len(files) != 0 {
+		t.Errorf("expected nothing written, got %v", files)
+	}
+}
+
+// --- Delete ---
+
+func TestDelete_Success(t *testing.T) {
+	store := &mockStorage{
+		deleteFn: func(_ context.Context, _ string) error {
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=trash.txt", nil)
+	rr := httptest.NewRecorder()
+	h.Delete(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rr.Code)
+	}
+}
+
+func TestDelete_ReturnsDeletedFileInfo(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	deleted := false
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			if deleted {
+				return nil, storage.ErrNotFound
+			}
+			return &storage.FileInfo{Name: "report.pdf", Path: "docs/report.pdf", Size: 4096, ModTime: modTime}, nil
+		},
+		deleteFn: func(_ context.Context, _ string) error {
+			deleted = true
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	rr := httptest.NewRecorder()
+	h.Delete(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=docs/report.pdf", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp SuccessResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Message != "file deleted" {
+		t.Errorf("expected the usual message, got %q", resp.Message)
+	}
+	if resp.File == nil || resp.File.Size != 4096 || resp.File.Name != "report.pdf" || !resp.File.ModTime.Equal(modTime) {
+		t.Errorf("expected the pre-deletion FileInfo, got %+v", resp.File)
+	}
+}
+
+func TestDelete_StatFailureStillDeletes(t *testing.T) {
+	deleted := false
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, errors.New("stat unavailable")
+		},
+		deleteFn: func(_ context.Context, _ string) error {
+			deleted = true
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	rr := httptest.NewRecorder()
+	h.Delete(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=a.txt", nil))
+
+	if rr.Code != http.StatusOK || !deleted {
+		t.Fatalf("expected the delete to go ahead, got %d (deleted=%v)", rr.Code, deleted)
+	}
+	if body := rr.Body.String(); strings.Contains(body, `"file"`) || !strings.Contains(body, "file deleted") {
+		t.Errorf("expected only the message, got %s", body)
+	}
+}
+
+func TestDelete_NoContentMode(t *testing.T) {
+	store := &mockStorage{
+		deleteFn: func(_ context.Context, _ string) error {
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name      string
+		noContent bool
+		wantCode  int
+		wantBody  bool
+	}{
+		{"default 200 with body", false, http.StatusOK, true},
+		{"204 without body", true, http.StatusNoContent, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(store, Options{DeleteNoContent: tt.noContent})
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=trash.txt", nil)
+			rr := httptest.NewRecorder()
+			h.Delete(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("expected %d, got %d", tt.wantCode, rr.Code)
+			}
+			if gotBody := rr.Body.Len() > 0; gotBody != tt.wantBody {
+				t.Errorf("expected body present=%v, got %q", tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestDelete_MissingPath(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files", nil)
+	rr := httptest.NewRecorder()
+	h.Delete(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestDelete_NotFound(t *testing.T) {
+	store := &mockStorage{
+		deleteFn: func(_ context.Context, _ string) error {
+			return storage.ErrNotFound
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=gone.txt", nil)
+	rr := httptest.NewRecorder()
+	h.Delete(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestDelete_NonEmptyDirectoryNeedsRecursive(t *testing.T) {
+	deleted := map[string]bool{}
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Path: p, IsDir: p == "docs"}, nil
+		},
+		listFn: func(_ context.Context, p string) ([]storage.FileInfo, error) {
+			if deleted["docs/a.txt"] {
+				return []storage.FileInfo{}, nil
+			}
+			return []storage.FileInfo{{Name: "a.txt", Path: "docs/a.txt"}}, nil
+		},
+		deleteFn: func(_ context.Context, p string) error {
+			if p == "docs" && !deleted["docs/a.txt"] {
+				return storage.ErrNotEmpty
+			}
+			deleted[p] = true
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	rr := httptest.NewRecorder()
+	h.Delete(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=docs", nil))
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("without recursive: expected 409, got %d", rr.Code)
+	}
+	var resp ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Code != CodeNotEmpty || !strings.Contains(resp.Error, "recursive=true") {
+		t.Errorf("expected a not_empty error mentioning recursive=true, got %+v", resp)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("expected nothing deleted, got %v", deleted)
+	}
+
+	rr = httptest.NewRecorder()
+	h.Delete(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=docs&recursive=true", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("with recursive: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !deleted["docs/a.txt"] || !deleted["docs"] {
+		t.Errorf("expected the file and the directory deleted, got %v", deleted)
+	}
+}
+
+// --- Stat ---
+
+func TestStat_Success(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{
+				Name:    "info.txt",
+				Path:    "info.txt",
+				Size:    42,
+				IsDir:   false,
+				ModTime: now,
+			}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=info.txt", nil)
+	rr := httptest.NewRecorder()
+	h.Stat(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rr.Code)
 	}
 
 	var info storage.FileInfo
@@ -363,6 +1948,88 @@ func TestStat_Success(t *testing.T) {
 	}
 }
 
+func TestStat_Checksum(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "info.txt", Size: 11}, nil
+		},
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("hello world")), nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=info.txt&checksum=sha256", nil)
+	rr := httptest.NewRecorder()
+	h.Stat(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var info storage.FileInfo
+	json.NewDecoder(rr.Body).Decode(&info)
+	want := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if info.Checksum != want {
+		t.Errorf("expected checksum %s, got %q", want, info.Checksum)
+	}
+}
+
+func TestStat_ChecksumOmittedByDefault(t *testing.T) {
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "info.txt"}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=info.txt", nil)
+	rr := httptest.NewRecorder()
+	h.Stat(rr, req)
+
+	if strings.Contains(rr.Body.String(), "checksum") {
+		t.Errorf("expected no checksum field, got %s", rr.Body.String())
+	}
+}
+
+func TestStat_DirectoryChildCounts(t *testing.T) {
+	infos := map[string]*storage.FileInfo{
+		"docs":     {Name: "docs", IsDir: true, ChildCount: 3, TotalSize: 42},
+		"info.txt": {Name: "info.txt", Size: 5},
+	}
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			return infos[p], nil
+		},
+	}
+	h := newTestHandler(store)
+
+	rr := httptest.NewRecorder()
+	h.Stat(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=docs", nil))
+	var info storage.FileInfo
+	json.NewDecoder(rr.Body).Decode(&info)
+	if info.ChildCount != 3 || info.TotalSize != 42 {
+		t.Errorf("expected childCount 3 and totalSize 42, got %+v", info)
+	}
+
+	rr = httptest.NewRecorder()
+	h.Stat(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=info.txt", nil))
+	if body := rr.Body.String(); strings.Contains(body, "childCount") || strings.Contains(body, "totalSize") {
+		t.Errorf("expected file stats without directory fields, got %s", body)
+	}
+}
+
+func TestStat_UnsupportedChecksum(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=info.txt&checksum=md5", nil)
+	rr := httptest.NewRecorder()
+	h.Stat(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
 func TestStat_MissingPath(t *testing.T) {
 	h := newTestHandler(&mockStorage{})
 
@@ -391,3 +2058,245 @@ func TestStat_PermissionDenied(t *testing.T) {
 		t.Errorf("expected 403, got %d", rr.Code)
 	}
 }
+
+// --- Mkdir ---
+
+func TestMkdir_Success(t *testing.T) {
+	var capturedPath string
+	store := &mockStorage{
+		mkdirFn: func(_ context.Context, path string) error {
+			capturedPath = path
+			return nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/mkdir?path=/new/dir", nil)
+	rr := httptest.NewRecorder()
+	h.Mkdir(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d", rr.Code)
+	}
+	if capturedPath != "/new/dir" {
+		t.Errorf("expected path %q, got %q", "/new/dir", capturedPath)
+	}
+}
+
+func TestMkdir_MissingPath(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/mkdir", nil)
+	rr := httptest.NewRecorder()
+	h.Mkdir(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestMkdir_FileExists(t *testing.T) {
+	store := &mockStorage{
+		mkdirFn: func(_ context.Context, _ string) error {
+			return storage.ErrExist
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/mkdir?path=file.txt", nil)
+	rr := httptest.NewRecorder()
+	h.Mkdir(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", rr.Code)
+	}
+}
+
+// --- Usage ---
+
+func TestUsage_Success(t *testing.T) {
+	var capturedPath string
+	store := &mockStorage{
+		usageFn: func(_ context.Context, path string) (*storage.Usage, error) {
+			capturedPath = path
+			return &storage.Usage{TotalBytes: 300, FileCount: 3, DirCount: 1}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/usage", nil)
+	rr := httptest.NewRecorder()
+	h.Usage(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if capturedPath != "/" {
+		t.Errorf("expected path %q, got %q", "/", capturedPath)
+	}
+
+	var body map[string]int64
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body["total_bytes"] != 300 || body["file_count"] != 3 || body["dir_count"] != 1 {
+		t.Errorf("unexpected usage body: %v", body)
+	}
+}
+
+func TestUsage_NotFound(t *testing.T) {
+	store := &mockStorage{
+		usageFn: func(_ context.Context, _ string) (*storage.Usage, error) {
+			return nil, storage.ErrNotFound
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/usage?path=/nope", nil)
+	rr := httptest.NewRecorder()
+	h.Usage(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rr.Code)
+	}
+}
+
+// diskStore is a mockStorage that reports fixed disk space.
+type diskStore struct{ *mockStorage }
+
+func (diskStore) DiskInfo(context.Context) (*storage.DiskSpace, error) {
+	return &storage.DiskSpace{TotalBytes: 1000, FreeBytes: 400, UsedBytes: 550}, nil
+}
+
+func TestDiskInfo_Success(t *testing.T) {
+	h := NewHandler(diskStore{&mockStorage{}}, Options{})
+
+	rr := httptest.NewRecorder()
+	h.DiskInfo(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/diskinfo", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var body map[string]uint64
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body["total_bytes"] != 1000 || body["free_bytes"] != 400 || body["used_bytes"] != 550 {
+		t.Errorf("unexpected disk info body: %v", body)
+	}
+}
+
+func TestDiskInfo_NotSupported(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	rr := httptest.NewRecorder()
+	h.DiskInfo(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/diskinfo", nil))
+
+	if rr.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501, got %d", rr.Code)
+	}
+}
+
+// --- Errors ---
+
+func TestHandleStorageError_Codes(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{storage.ErrNotFound, http.StatusNotFound, CodeNotFound},
+		{storage.ErrPermission, http.StatusForbidden, CodePermissionDenied},
+		{storage.ErrExist, http.StatusConflict, CodeConflict},
+		{errors.New("boom"), http.StatusInternalServerError, CodeInternal},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		handleStorageError(rr, tt.err)
+
+		var body ErrorResponse
+		json.NewDecoder(rr.Body).Decode(&body)
+		if rr.Code != tt.status || body.Code != tt.code {
+			t.Errorf("%v: expected %d %q, got %d %q", tt.err, tt.status, tt.code, rr.Code, body.Code)
+		}
+	}
+}
+
+func TestWriteError_EchoesRequestID(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rr.Header().Set("X-Request-ID", "req-123")
+	writeError(rr, http.StatusBadRequest, CodeInvalidRequest, "nope")
+
+	var body ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body.RequestID != "req-123" {
+		t.Errorf("expected requestId req-123, got %q", body.RequestID)
+	}
+	if body.Code != CodeInvalidRequest || body.Error != "nope" {
+		t.Errorf("unexpected body %+v", body)
+	}
+}
+
+// discardResponseWriter is a minimal http.ResponseWriter that drops the body
+// and, like the middleware wrappers, offers no ReaderFrom fast path.
+type discardResponseWriter struct{ header http.Header }
+
+func (d *discardResponseWriter) Header() http.Header         { return d.header }
+func (d *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardResponseWriter) WriteHeader(int)             {}
+
+// BenchmarkDownload compares a pooled download copy with the per-request
+// buffer io.Copy allocates.
+func BenchmarkDownload(b *testing.B) {
+	data := bytes.Repeat([]byte("x"), 1<<20)
+	store := &mockStorage{
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			// Hide bytes.Reader's WriterTo so the copy needs a buffer, as
+			// it does for network-backed readers.
+			return io.NopCloser(struct{ io.Reader }{bytes.NewReader(data)}), nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=big.txt", nil)
+
+	for _, bc := range []struct {
+		name   string
+		pooled bool
+	}{{"io.Copy", false}, {"pooled", true}} {
+		b.Run(bc.name, func(b *testing.B) {
+			h := newTestHandler(store)
+			if !bc.pooled {
+				h.copyBuffers = nil
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				h.Download(&discardResponseWriter{header: http.Header{}}, req)
+			}
+		})
+	}
+}
+
+func TestReady_BreakerOpen(t *testing.T) {
+	var stats int
+	store := storage.WithCircuitBreaker(&mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			stats++
+			return nil, errors.New("connection reset by peer")
+		},
+	}, storage.BreakerOptions{MinRequests: 1, Cooldown: time.Hour})
+	store.Stat(context.Background(), "/")
+	h := NewHandler(store, Options{})
+
+	rr := httptest.NewRecorder()
+	h.Ready(rr, httptest.NewRequest(http.MethodGet, "/api/v1/ready", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "circuit breaker open for stat") {
+		t.Errorf("expected the open breaker named, got %s", rr.Body.String())
+	}
+	if stats != 1 {
+		t.Errorf("expected no backend call while the breaker is open, got %d", stats)
+	}
+
+	rr = httptest.NewRecorder()
+	h.Stat(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=a.txt", nil))
+	if rr.Code != http.StatusServiceUnavailable || !strings.Contains(rr.Body.String(), CodeUnavailable) {
+		t.Errorf("expected 503 unavailable from a file request, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
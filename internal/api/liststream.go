@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go-storage-api/internal/storage"
+)
+
+// ndjsonType is the media type of a streamed listing: one FileInfo JSON
+// object per line.
+const ndjsonType = "application/x-ndjson"
+
+// streamFlushEvery is how many entries a streamed listing writes between
+// flushes.
+const streamFlushEvery = 256
+
+// acceptsNDJSON reports whether r's Accept header names ndjsonType, which
+// asks for a streamed listing like stream=true.
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mt, _, err := mime.ParseMediaType(accept); err == nil && mt == ndjsonType {
+			return true
+		}
+	}
+	return false
+}
+
+// ndjsonWriter writes FileInfo lines, sending the 200 status with the first
+// one so a listing that fails at once can still get a proper error status.
+type ndjsonWriter struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	rc      *http.ResponseController
+	written int
+}
+
+func newNDJSONWriter(w http.ResponseWriter) *ndjsonWriter {
+	return &ndjsonWriter{w: w, enc: json.NewEncoder(w), rc: http.NewResponseController(w)}
+}
+
+func (n *ndjsonWriter) start() {
+	if n.written == 0 {
+		n.w.Header().Set("Content-Type", ndjsonType)
+		n.w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (n *ndjsonWriter) write(f storage.FileInfo) error {
+	n.start()
+	if err := n.enc.Encode(f); err != nil {
+		return err
+	}
+	n.written++
+	if n.written%streamFlushEvery == 0 {
+		return n.rc.Flush()
+	}
+	return nil
+}
+
+// finish ends the stream. An error before any entry gets a normal error
+// response. Once entries have gone out the status cannot change, so the
+// error is sent as a final ErrorResponse line instead, which clients tell
+// apart from entries by its "code" field.
+func (n *ndjsonWriter) finish(err error) {
+	if err != nil && n.written == 0 {
+		handleStorageError(n.w, err)
+		return
+	}
+	n.start()
+	if err != nil {
+		_, code, msg := classifyStorageError(err)
+		n.enc.Encode(ErrorResponse{Error: msg, Code: code, RequestID: n.w.Header().Get(headerRequestID)})
+	}
+	n.rc.Flush()
+}
+
+// streamList writes the listing of p as NDJSON, walking the whole subtree
+// when recursive is set. Only entries keep accepts are written.
+func (h *Handler) streamList(w http.ResponseWriter, r *http.Request, p string, recursive, dirsOnly bool, keep func(storage.FileInfo) bool) {
+	out := newNDJSONWriter(w)
+	emit := func(f storage.FileInfo) error {
+		if !keep(f) {
+			return nil
+		}
+		return out.write(f)
+	}
+
+	var err error
+	if recursive {
+		err = storage.Walk(r.Context(), h.store, p, h.listConcurrency, emit)
+	} else {
+		var files []storage.FileInfo
+		if dirsOnly {
+			files, err = storage.ListDirs(r.Context(), h.store, p)
+		} else {
+			files, err = h.store.List(r.Context(), p)
+		}
+		for _, f := range files {
+			if err = emit(f); err != nil {
+				break
+			}
+		}
+	}
+	out.finish(err)
+}
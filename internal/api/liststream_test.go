@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// treeStore serves List from a fixed map of directory listings; a missing
+// directory fails with failErr, or ErrNotFound if failErr is nil.
+func treeStore(tree map[string][]storage.FileInfo, failErr error) *mockStorage {
+	return &mockStorage{
+		listFn: func(_ context.Context, p string) ([]storage.FileInfo, error) {
+			files, ok := tree[p]
+			if !ok {
+				if failErr != nil {
+					return nil, failErr
+				}
+				return nil, storage.ErrNotFound
+			}
+			return slices.Clone(files), nil
+		},
+	}
+}
+
+var streamTree = map[string][]storage.FileInfo{
+	"/": {
+		{Name: "a.txt", Path: "a.txt"},
+		{Name: "docs", Path: "docs", IsDir: true},
+	},
+	"docs": {
+		{Name: "b.txt", Path: "docs/b.txt"},
+		{Name: "img", Path: "docs/img", IsDir: true},
+	},
+	"docs/img": {
+		{Name: "c.png", Path: "docs/img/c.png"},
+	},
+}
+
+// readLines decodes each line of an NDJSON body on its own.
+func readLines(t *testing.T, body string) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	sc := bufio.NewScanner(strings.NewReader(body))
+	for sc.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", sc.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestList_StreamsNDJSON(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		url    string
+		accept string
+	}{
+		{"stream param", "/api/v1/files?recursive=true&stream=true", ""},
+		{"accept header", "/api/v1/files?recursive=true", "application/json;q=0.5, application/x-ndjson"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(treeStore(streamTree, nil))
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			h.List(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != ndjsonType {
+				t.Errorf("expected Content-Type %s, got %q", ndjsonType, ct)
+			}
+			if !strings.HasSuffix(rr.Body.String(), "\n") {
+				t.Error("expected the body to end with a newline")
+			}
+			var paths []string
+			for _, line := range readLines(t, rr.Body.String()) {
+				paths = append(paths, line["path"].(string))
+			}
+			slices.Sort(paths)
+			want := []string{"a.txt", "docs", "docs/b.txt", "docs/img", "docs/img/c.png"}
+			if !slices.Equal(paths, want) {
+				t.Errorf("expected %v, got %v", want, paths)
+			}
+		})
+	}
+}
+
+func TestList_StreamDirsOnly(t *testing.T) {
+	h := newTestHandler(treeStore(streamTree, nil))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files?recursive=true&stream=true&dirs_only=true", nil)
+	rr := httptest.NewRecorder()
+	h.List(rr, req)
+
+	lines := readLines(t, rr.Body.String())
+	if len(lines) != 2 {
+		t.Fatalf("expected docs and docs/img, got %v", lines)
+	}
+	for _, line := range lines {
+		if line["is_dir"] != true {
+			t.Errorf("expected only directories, got %v", line)
+		}
+	}
+}
+
+func TestList_StreamErrors(t *testing.T) {
+	// A missing root still gets a proper status.
+	h := newTestHandler(treeStore(nil, nil))
+	rr := httptest.NewRecorder()
+	h.List(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files?path=/nope&recursive=true&stream=true", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 before any entry, got %d", rr.Code)
+	}
+
+	// A failure deeper down ends the stream with an error line.
+	tree := map[string][]storage.FileInfo{"/": streamTree["/"]}
+	h = newTestHandler(treeStore(tree, storage.ErrPermission))
+	rr = httptest.NewRecorder()
+	h.List(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files?recursive=true&stream=true", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 once entries were sent, got %d", rr.Code)
+	}
+	lines := readLines(t, rr.Body.String())
+	last := lines[len(lines)-1]
+	if last["code"] != CodePermissionDenied {
+		t.Errorf("expected a final %s error line, got %v", CodePermissionDenied, last)
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go-storage-api/internal/storage"
+)
+
+// maxMetadataRequestSize caps the JSON body of a metadata update. It leaves
+// room for quoting and whitespace around storage.MaxMetadataBytes of tags.
+const maxMetadataRequestSize = 16 << 10
+
+// GetMetadata returns the custom tags of a file as a JSON object of
+// strings; a file without tags gets {}.
+func (h *Handler) GetMetadata(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+
+	tags, err := storage.GetMetadata(r.Context(), h.store, p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+// SetMetadata replaces the custom tags of a file with the JSON object of
+// strings in the body and returns them. {} removes every tag. Keys and the
+// total size are checked with storage.ValidateMetadata.
+func (h *Handler) SetMetadata(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+
+	var tags map[string]string
+	r.Body = http.MaxBytesReader(w, r.Body, maxMetadataRequestSize)
+	if err := json.NewDecoder(r.Body).Decode(&tags); err != nil {
+		if writeTooLarge(w, err) {
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "body must be a JSON object of string values: "+err.Error())
+		return
+	}
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	if err := storage.ValidateMetadata(tags); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := storage.SetMetadata(r.Context(), h.store, p, tags); err != nil {
+		if errors.Is(err, storage.ErrInvalidMetadata) {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+		handleStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// metaStore adds an in-memory storage.MetadataStore to mockStorage.
+type metaStore struct {
+	*mockStorage
+	tags map[string]map[string]string
+}
+
+func (s *metaStore) GetMetadata(_ context.Context, p string) (map[string]string, error) {
+	tags := maps.Clone(s.tags[p])
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	return tags, nil
+}
+
+func (s *metaStore) SetMetadata(_ context.Context, p string, tags map[string]string) error {
+	s.tags[p] = maps.Clone(tags)
+	return nil
+}
+
+func newMetaHandler() (*Handler, *metaStore) {
+	store := &metaStore{mockStorage: &mockStorage{}, tags: map[string]map[string]string{}}
+	return NewHandler(store, Options{MaxUploadSize: 10 << 20}), store
+}
+
+func putMetadata(h *Handler, p, body string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	h.SetMetadata(rr, httptest.NewRequest(http.MethodPut, "/api/v1/files/metadata?path="+p, strings.NewReader(body)))
+	return rr
+}
+
+func TestMetadata_SetAndGet(t *testing.T) {
+	h, store := newMetaHandler()
+
+	rr := putMetadata(h, "a.txt", `{"owner":"alice","label":"draft"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	want := map[string]string{"owner": "alice", "label": "draft"}
+	if !maps.Equal(store.tags["a.txt"], want) {
+		t.Errorf("expected stored tags %v, got %v", want, store.tags["a.txt"])
+	}
+
+	rr = httptest.NewRecorder()
+	h.GetMetadata(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/metadata?path=a.txt", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var got map[string]string
+	json.NewDecoder(rr.Body).Decode(&got)
+	if !maps.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMetadata_GetWithoutTags(t *testing.T) {
+	h, _ := newMetaHandler()
+
+	rr := httptest.NewRecorder()
+	h.GetMetadata(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/metadata?path=a.txt", nil))
+	if body := strings.TrimSpace(rr.Body.String()); body != "{}" {
+		t.Errorf("expected {}, got %s", body)
+	}
+}
+
+func TestMetadata_InvalidRequests(t *testing.T) {
+	h, store := newMetaHandler()
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		code string
+	}{
+		{"missing path", "", `{}`, CodePathInvalid},
+		{"not an object", "a.txt", `["owner"]`, CodeInvalidRequest},
+		{"non-string value", "a.txt", `{"size":3}`, CodeInvalidRequest},
+		{"reserved key", "a.txt", `{"etag":"x"}`, CodeInvalidRequest},
+		{"bad key", "a.txt", `{"Owner Name":"x"}`, CodeInvalidRequest},
+		{"too large", "a.txt", `{"k":"` + strings.Repeat("v", storage.MaxMetadataBytes) + `"}`, CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := putMetadata(h, tt.path, tt.body)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", rr.Code)
+			}
+			var body ErrorResponse
+			json.NewDecoder(rr.Body).Decode(&body)
+			if body.Code != tt.code {
+				t.Errorf("expected code %q, got %q", tt.code, body.Code)
+			}
+		})
+	}
+	if len(store.tags) != 0 {
+		t.Errorf("expected nothing stored, got %v", store.tags)
+	}
+}
+
+func TestMetadata_NotSupported(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	rr := putMetadata(h, "a.txt", `{"owner":"alice"}`)
+	if rr.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501, got %d", rr.Code)
+	}
+	var body ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body.Code != CodeNotSupported {
+		t.Errorf("expected code %q, got %q", CodeNotSupported, body.Code)
+	}
+}
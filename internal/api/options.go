@@ -0,0 +1,191 @@
+package api
+
+import (
+	"log/slog"
+	"net/netip"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"go-storage-api/internal/bufpool"
+	"go-storage-api/internal/middleware"
+)
+
+// DefaultMultipartMemory is the in-memory share of a multipart upload
+// when Options.MultipartMemory is zero.
+const DefaultMultipartMemory = 10 << 20
+
+// Options configures handler and router behavior beyond the storage backend.
+type Options struct {
+	// MaxUploadSize caps the request body size for uploads, in bytes.
+	MaxUploadSize int64
+
+	// MultipartMemory caps how many bytes of a multipart upload are held
+	// in memory; the rest of its files spill to temporary files on disk.
+	// Zero uses DefaultMultipartMemory.
+	MultipartMemory int64
+
+	// CopyBuffers supplies the buffers for streaming file contents to
+	// clients. Nil gives the handler its own pool of bufpool.DefaultSize
+	// buffers; sharing one pool with the storage backend saves memory.
+	CopyBuffers *bufpool.Pool
+
+	// MaxPathSegments caps how many segments a "path" parameter may have.
+	// Zero uses middleware.DefaultMaxPathSegments.
+	MaxPathSegments int
+
+	// MaxNameBytes caps the byte length of each path segment, including
+	// names taken from upload filenames. Zero uses
+	// middleware.DefaultMaxNameBytes.
+	MaxNameBytes int
+
+	// ListConcurrency caps how many directories a recursive listing fetches
+	// at once. Zero uses storage.DefaultListConcurrency.
+	ListConcurrency int
+
+	// MaxListEntries caps the entries a non-streamed listing returns; a
+	// listing cut short carries X-Result-Truncated: true. Zero means
+	// unlimited.
+	MaxListEntries int
+
+	// AssetMaxAge is the max-age /api/v1/assets responses tell browsers and
+	// caches to keep a file for. Zero makes them revalidate every time.
+	AssetMaxAge time.Duration
+
+	// MaxConcurrentUploads caps how many uploads (POST upload, PUT and
+	// PATCH) run at once. Uploads over the cap wait up to UploadQueueTimeout
+	// for a slot and then fail with 503. Zero means unlimited.
+	MaxConcurrentUploads int
+	UploadQueueTimeout   time.Duration
+
+	// ResumableUploadDir enables tus resumable uploads under
+	// /api/v1/uploads, staging partial uploads in this local directory.
+	// Unfinished uploads are removed ResumableUploadExpiry after their last
+	// chunk; zero means 24 hours. Empty disables resumable uploads.
+	ResumableUploadDir    string
+	ResumableUploadExpiry time.Duration
+
+	// Scan inspects every upload (POST upload, PUT, PATCH, finished tus
+	// uploads and WebDAV PUT) before it is stored, e.g. with a virus
+	// scanner; see ScanFunc. Streams are staged to a temporary file for it.
+	// Nil, the default, accepts every upload unscanned.
+	Scan ScanFunc
+
+	// HiddenPatterns hides matching paths (e.g. ".DS_Store", "*.tmp", ".*")
+	// from the API: listings omit them and requests naming them get 404,
+	// or 403 for writes. Patterns match each name along a path, or the
+	// whole path when HiddenMatchFullPath is set; see
+	// storage.MatchPatterns. Empty hides nothing.
+	HiddenPatterns      []string
+	HiddenMatchFullPath bool
+
+	// AllowedExtensions restricts uploads by multipart filename extension,
+	// e.g. []string{".pdf", ".png"}. Empty allows every extension.
+	AllowedExtensions []string
+
+	// AllowedMIMETypes restricts uploads by the multipart part's declared
+	// Content-Type, e.g. []string{"application/pdf"}. Empty allows all.
+	AllowedMIMETypes []string
+
+	// ContentTypes maps file extensions such as ".md" to the Content-Type
+	// downloads and previews are served with, ahead of
+	// mime.TypeByExtension. Entries are added to a built-in set covering
+	// common web types, replacing built-in entries for the same extension.
+	ContentTypes map[string]string
+
+	// Inline decides from a download's Content-Type whether it is served
+	// inline or as an attachment, e.g. InlineTypes("image/*",
+	// "application/pdf"). A request's inline parameter overrides it, and
+	// the strict ContentPolicy still makes active content an attachment.
+	// Nil serves everything inline.
+	Inline func(contentType string) bool
+
+	// DownloadTrailers announces and sends X-Bytes-Sent and
+	// X-Download-Status trailers after each download body so clients can
+	// confirm they received the whole stream.
+	DownloadTrailers bool
+
+	// DownloadRateLimit caps each download at this many bytes per second,
+	// and DownloadRateLimitTotal caps all downloads together. Zero means
+	// unlimited.
+	DownloadRateLimit      int64
+	DownloadRateLimitTotal int64
+
+	// UploadQuota caps the request body bytes each client address may
+	// upload within UploadQuotaWindow, answering 429 quota_exceeded once it
+	// is used up; see middleware.UploadQuota. Zero means unlimited.
+	UploadQuota       int64
+	UploadQuotaWindow time.Duration
+
+	// ContentPolicy controls the security headers on downloads and
+	// previews; see middleware.ContentSecurity. Empty means
+	// middleware.ContentStrict.
+	ContentPolicy middleware.ContentPolicy
+
+	// DeleteNoContent answers successful deletes, copies and moves with 204
+	// and no body instead of their usual status and JSON body.
+	DeleteNoContent bool
+
+	// SoftDelete makes deletes move files into /.trash/ by default, where
+	// POST /api/v1/files/restore can bring them back. Clients can still
+	// pass purge=true to delete permanently, or soft=true per request when
+	// this is off.
+	SoftDelete bool
+
+	// JWTSecret enables bearer-token authentication with HMAC-signed JWTs
+	// when non-empty. JWTIssuer and JWTAudience, if set, must match the
+	// token's iss and aud claims. Public routes stay unauthenticated; see
+	// PublicPaths.
+	JWTSecret   []byte
+	JWTIssuer   string
+	JWTAudience string
+
+	// PublicPaths lists URL path prefixes, on top of the health, readiness
+	// and version endpoints (and signed links when enabled), that skip
+	// authentication and the upload quota. OPTIONS requests always do.
+	// Without ScopeToUser a public file route exposes the whole store.
+	PublicPaths []string
+
+	// IPAllow and IPDeny restrict which client addresses may use the API;
+	// see middleware.IPFilter. Empty IPAllow allows every address not in
+	// IPDeny. TrustedProxies lists the proxies whose X-Forwarded-For header
+	// is believed when working out the client address, both for IPFilter
+	// and for the access log's client_ip.
+	IPAllow        []netip.Prefix
+	IPDeny         []netip.Prefix
+	TrustedProxies []netip.Prefix
+
+	// URLSigningSecret enables POST /api/v1/files/sign, which hands out
+	// time-limited download links signed with it, and GET
+	// /api/v1/files/signed, which serves them without other credentials.
+	// Empty disables both.
+	URLSigningSecret []byte
+
+	// ScopeToUser confines each request to /users/<sub>/, where sub is the
+	// verified JWT subject. Clients keep using paths relative to their own
+	// subtree and see them that way in responses. Requires JWTSecret.
+	ScopeToUser bool
+
+	// ExposeBackend adds an X-Storage-Backend response header naming the
+	// storage backend. Off by default to avoid disclosing internals.
+	ExposeBackend bool
+
+	// Logger receives server-side detail that responses leave out, such as
+	// why a readiness check failed. NewRouter defaults it to its logger,
+	// NewHandler to slog.Default().
+	Logger *slog.Logger
+
+	// WebDAV mounts a WebDAV surface at /webdav/ over the same store, size
+	// limit, path validation and user scoping as the JSON API.
+	WebDAV bool
+
+	// TracerProvider enables OpenTelemetry request spans when non-nil.
+	// Incoming traceparent headers are honored so spans join the caller's
+	// trace.
+	TracerProvider trace.TracerProvider
+}
+
+// pathLimits returns the path validation limits set in o.
+func (o Options) pathLimits() middleware.PathLimits {
+	return middleware.PathLimits{MaxSegments: o.MaxPathSegments, MaxNameBytes: o.MaxNameBytes}
+}
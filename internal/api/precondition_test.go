@@ -0,0 +1,249 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// helloETag is the ETag of the "hello" contents served by newTrashStore.
+func helloETag(t *testing.T) string {
+	t.Helper()
+	sum, err := storage.HashSHA256(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("HashSHA256: %v", err)
+	}
+	return etagOf(sum)
+}
+
+func TestDelete_IfMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		ifMatch    string
+		wantStatus int
+	}{
+		{"absent", "", http.StatusOK},
+		{"matching", "HELLO", http.StatusOK},
+		{"one of several", `"abc", HELLO`, http.StatusOK},
+		{"wildcard", "*", http.StatusOK},
+		{"mismatching", `"abc"`, http.StatusPreconditionFailed},
+		{"weak", "W/HELLO", http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written := map[string]string{}
+			var deleted []string
+			h := newTestHandler(newTrashStore(written, &deleted))
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=/docs/a.txt", nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", strings.ReplaceAll(tt.ifMatch, "HELLO", helloETag(t)))
+			}
+			rr := httptest.NewRecorder()
+			h.Delete(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && len(deleted) != 1 {
+				t.Errorf("expected the file deleted, got %v", deleted)
+			}
+			if tt.wantStatus == http.StatusPreconditionFailed {
+				if len(deleted) != 0 {
+					t.Errorf("expected nothing deleted, got %v", deleted)
+				}
+				var resp ErrorResponse
+				json.NewDecoder(rr.Body).Decode(&resp)
+				if resp.Code != CodePreconditionFailed {
+					t.Errorf("expected code %s, got %q", CodePreconditionFailed, resp.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete_IfMatchMissingFile(t *testing.T) {
+	written := map[string]string{}
+	var deleted []string
+	h := newTestHandler(newTrashStore(written, &deleted))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=/docs/other.txt", nil)
+	req.Header.Set("If-Match", "*")
+	rr := httptest.NewRecorder()
+	h.Delete(rr, req)
+
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412, got %d", rr.Code)
+	}
+}
+
+func TestPut_IfMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		ifMatch    string
+		wantStatus int
+	}{
+		{"absent", "", http.StatusCreated},
+		{"matching", "HELLO", http.StatusCreated},
+		{"mismatching", `"abc"`, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written := map[string]string{}
+			var deleted []string
+			h := newTestHandler(newTrashStore(written, &deleted))
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=/docs/a.txt&overwrite=true", strings.NewReader("new"))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", strings.ReplaceAll(tt.ifMatch, "HELLO", helloETag(t)))
+			}
+			rr := httptest.NewRecorder()
+			h.Put(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			_, wrote := written["/docs/a.txt"]
+			if wrote != (tt.wantStatus == http.StatusCreated) {
+				t.Errorf("unexpected write state %v for status %d", written, rr.Code)
+			}
+		})
+	}
+}
+
+func TestUpload_IfMatchMismatch(t *testing.T) {
+	written := map[string]string{}
+	var deleted []string
+	h := newTestHandler(newTrashStore(written, &deleted))
+
+	req := createBatchRequest(t, "/docs/a.txt&overwrite=true", batchPart{"file", "a.txt", "new"})
+	req.Header.Set("If-Match", `"abc"`)
+	rr := httptest.NewRecorder()
+	h.Upload(rr, req)
+
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(written) != 0 {
+		t.Errorf("expected nothing written, got %v", written)
+	}
+}
+
+func TestIfUnmodifiedSince(t *testing.T) {
+	// The file was modified at 10:00:00.5; HTTP dates drop the fraction.
+	before := trashStoreModTime.Add(-time.Second).Format(http.TimeFormat)
+	same := trashStoreModTime.Format(http.TimeFormat)
+	after := trashStoreModTime.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name       string
+		header     string
+		ifMatch    string
+		wantStatus int
+	}{
+		{"absent", "", "", 0},
+		{"unmodified since", after, "", 0},
+		{"modified in the same second", same, "", 0},
+		{"modified since", before, "", http.StatusPreconditionFailed},
+		{"unparseable date ignored", "yesterday", "", 0},
+		{"If-Match takes precedence", before, "*", 0},
+	}
+	requests := map[string]func(h *Handler, hdr http.Header) *httptest.ResponseRecorder{
+		"delete": func(h *Handler, hdr http.Header) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=/docs/a.txt", nil)
+			req.Header = hdr
+			rr := httptest.NewRecorder()
+			h.Delete(rr, req)
+			return rr
+		},
+		"put": func(h *Handler, hdr http.Header) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=/docs/a.txt&overwrite=true", strings.NewReader("new"))
+			req.Header = hdr
+			rr := httptest.NewRecorder()
+			h.Put(rr, req)
+			return rr
+		},
+		"upload": func(h *Handler, hdr http.Header) *httptest.ResponseRecorder {
+			req := createBatchRequest(t, "/docs/a.txt&overwrite=true", batchPart{"file", "a.txt", "new"})
+			for k, v := range hdr {
+				req.Header[k] = v
+			}
+			rr := httptest.NewRecorder()
+			h.Upload(rr, req)
+			return rr
+		},
+		"patch": func(h *Handler, hdr http.Header) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/files?path=/docs/a.txt", strings.NewReader("new"))
+			req.Header = hdr
+			rr := httptest.NewRecorder()
+			h.Patch(rr, req)
+			return rr
+		},
+	}
+	for op, do := range requests {
+		for _, tt := range tests {
+			t.Run(op+"/"+tt.name, func(t *testing.T) {
+				written := map[string]string{}
+				var deleted []string
+				h := newTestHandler(newTrashStore(written, &deleted))
+
+				hdr := http.Header{}
+				if tt.header != "" {
+					hdr.Set("If-Unmodified-Since", tt.header)
+				}
+				if tt.ifMatch != "" {
+					hdr.Set("If-Match", tt.ifMatch)
+				}
+				rr := do(h, hdr)
+
+				changed := len(written)+len(deleted) > 0
+				if tt.wantStatus == http.StatusPreconditionFailed {
+					if rr.Code != tt.wantStatus {
+						t.Fatalf("expected 412, got %d: %s", rr.Code, rr.Body.String())
+					}
+					if changed {
+						t.Errorf("expected no changes, got writes %v and deletes %v", written, deleted)
+					}
+					return
+				}
+				if rr.Code >= 300 || !changed {
+					t.Errorf("expected the request to proceed, got %d: %s", rr.Code, rr.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestIfUnmodifiedSince_NewFile(t *testing.T) {
+	written := map[string]string{}
+	var deleted []string
+	h := newTestHandler(newTrashStore(written, &deleted))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=/docs/new.txt", strings.NewReader("new"))
+	req.Header.Set("If-Unmodified-Since", trashStoreModTime.Add(-time.Hour).Format(http.TimeFormat))
+	rr := httptest.NewRecorder()
+	h.Put(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected a new file to be created, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestStat_ETag(t *testing.T) {
+	written := map[string]string{}
+	var deleted []string
+	h := newTestHandler(newTrashStore(written, &deleted))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=/docs/a.txt&checksum=sha256", nil)
+	rr := httptest.NewRecorder()
+	h.Stat(rr, req)
+
+	if got := rr.Header().Get("ETag"); got != helloETag(t) {
+		t.Errorf("expected ETag %s, got %q", helloETag(t), got)
+	}
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-storage-api/internal/middleware"
+)
+
+// uploadIDParam names the query parameter an upload uses to publish its
+// progress under an ID of the client's choosing.
+const uploadIDParam = "uploadId"
+
+// maxUploadIDLen bounds client-chosen upload IDs.
+const maxUploadIDLen = 128
+
+// progressInterval is how often UploadProgress checks for new bytes.
+const progressInterval = 250 * time.Millisecond
+
+var errUploadInProgress = errors.New("an upload with this id is already in progress")
+
+// progressEvent is the JSON payload of each upload-progress event. Total is
+// the request's Content-Length, or -1 if the client did not send one.
+type progressEvent struct {
+	Bytes int64 `json:"bytes"`
+	Total int64 `json:"total"`
+}
+
+// uploadProgress tracks one upload. read and total are updated by the upload
+// and polled by watchers; done is closed when the upload finishes.
+type uploadProgress struct {
+	read  atomic.Int64
+	total atomic.Int64
+	done  chan struct{}
+
+	// Guarded by progressRegistry.mu.
+	active   bool
+	watchers int
+}
+
+func (p *uploadProgress) snapshot() progressEvent {
+	return progressEvent{Bytes: p.read.Load(), Total: p.total.Load()}
+}
+
+// progressRegistry holds in-flight uploads by key. An entry exists while an
+// upload is running or someone is watching it, and is removed when the
+// upload finishes or the last watcher leaves before it started.
+type progressRegistry struct {
+	mu      sync.Mutex
+	entries map[string]*uploadProgress
+}
+
+func newProgressRegistry() *progressRegistry {
+	return &progressRegistry{entries: make(map[string]*uploadProgress)}
+}
+
+// entry returns the entry for key, creating it if needed. mu must be held.
+func (reg *progressRegistry) entry(key string) *uploadProgress {
+	p, ok := reg.entries[key]
+	if !ok {
+		p = &uploadProgress{done: make(chan struct{})}
+		p.total.Store(-1)
+		reg.entries[key] = p
+	}
+	return p
+}
+
+// start marks the upload for key as running. It fails if another upload is
+// already using the key.
+func (reg *progressRegistry) start(key string, total int64) (*uploadProgress, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	p := reg.entry(key)
+	if p.active {
+		return nil, errUploadInProgress
+	}
+	p.active = true
+	p.total.Store(total)
+	return p, nil
+}
+
+// finish wakes p's watchers and forgets key.
+func (reg *progressRegistry) finish(key string, p *uploadProgress) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	close(p.done)
+	if reg.entries[key] == p {
+		delete(reg.entries, key)
+	}
+}
+
+// watch returns the entry for key, so a watcher may connect before the
+// upload starts.
+func (reg *progressRegistry) watch(key string) *uploadProgress {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	p := reg.entry(key)
+	p.watchers++
+	return p
+}
+
+// unwatch drops a watcher, removing the entry if nothing else needs it.
+func (reg *progressRegistry) unwatch(key string, p *uploadProgress) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	p.watchers--
+	if p.watchers == 0 && !p.active && reg.entries[key] == p {
+		delete(reg.entries, key)
+	}
+}
+
+// countingReader adds the bytes read through it to n.
+type countingReader struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (c countingReader) Read(b []byte) (int, error) {
+	n, err := c.ReadCloser.Read(b)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// trackProgress publishes the progress of r's body if the request carries
+// an upload ID. The returned func must be called when the upload is done.
+// It writes an error and returns ok=false if the ID is invalid or taken.
+func (h *Handler) trackProgress(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	id := r.URL.Query().Get(uploadIDParam)
+	if id == "" {
+		return func() {}, true
+	}
+	if !validUploadID(id) {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid "+uploadIDParam)
+		return nil, false
+	}
+
+	key := progressKey(r, id)
+	p, err := h.progress.start(key, r.ContentLength)
+	if err != nil {
+		writeError(w, http.StatusConflict, CodeConflict, err.Error())
+		return nil, false
+	}
+	r.Body = countingReader{ReadCloser: r.Body, n: &p.read}
+	return func() { h.progress.finish(key, p) }, true
+}
+
+// UploadProgress streams the progress of the upload whose uploadId matches
+// the id parameter as Server-Sent Events. It may be opened before the upload
+// starts. Each change is sent as a progressEvent; a final "done" event is
+// sent when the upload ends, successfully or not.
+func (h *Handler) UploadProgress(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if !validUploadID(id) {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "id query parameter is required and must be a valid upload id")
+		return
+	}
+
+	key := progressKey(r, id)
+	p := h.progress.watch(key)
+	defer h.progress.unwatch(key, p)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	last := progressEvent{Bytes: -1}
+	for {
+		select {
+		case <-p.done:
+			writeEvent(w, "done", p.snapshot())
+			rc.Flush()
+			return
+		default:
+		}
+
+		if ev := p.snapshot(); ev != last {
+			if writeEvent(w, "", ev) != nil || rc.Flush() != nil {
+				return
+			}
+			last = ev
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-p.done:
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeEvent writes one SSE event. An empty name sends an unnamed "message"
+// event.
+func writeEvent(w io.Writer, name string, ev progressEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		if _, err := fmt.Fprintf(w, "event: %s\n", name); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+// progressKey namespaces id by the JWT subject, if any, so one user cannot
+// watch another's uploads by guessing IDs.
+func progressKey(r *http.Request, id string) string {
+	if sub := requestSubject(r); sub != "" {
+		return sub + "\x00" + id
+	}
+	return id
+}
+
+// requestSubject returns the JWT subject of r, or "" if it has none.
+func requestSubject(r *http.Request) string {
+	if claims, ok := middleware.ClaimsFromContext(r.Context()); ok {
+		if sub, err := claims.GetSubject(); err == nil {
+			return sub
+		}
+	}
+	return ""
+}
+
+// validUploadID reports whether id is 1-128 characters of letters, digits,
+// '-', '_' or '.'.
+func validUploadID(id string) bool {
+	if id == "" || len(id) > maxUploadIDLen {
+		return false
+	}
+	for _, c := range []byte(id) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvent reads one SSE event and returns its name and decoded data.
+func readEvent(t *testing.T, br *bufio.Reader) (string, progressEvent) {
+	t.Helper()
+	var name string
+	var ev progressEvent
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			return name, ev
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+				t.Fatalf("decode event data %q: %v", line, err)
+			}
+		}
+	}
+}
+
+// waitForEmpty fails the test if the registry still has entries after a
+// short wait.
+func waitForEmpty(t *testing.T, reg *progressRegistry) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		reg.mu.Lock()
+		n := len(reg.entries)
+		reg.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected registry to be empty, has %d entries", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestUploadProgress_StreamsUntilDone(t *testing.T) {
+	written := map[string]string{}
+	h := newTestHandler(newBatchStore(written))
+	srv := httptest.NewServer(http.HandlerFunc(h.UploadProgress))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?id=up-1")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+	br := bufio.NewReader(resp.Body)
+
+	if name, ev := readEvent(t, br); name != "" || ev != (progressEvent{Bytes: 0, Total: -1}) {
+		t.Fatalf("expected initial {0 -1} event, got %q %+v", name, ev)
+	}
+
+	body := strings.Repeat("x", 1000)
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=/big.bin&uploadId=up-1", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	h.Put(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	for {
+		name, ev := readEvent(t, br)
+		if name != "done" {
+			continue
+		}
+		if ev != (progressEvent{Bytes: 1000, Total: 1000}) {
+			t.Errorf("expected final {1000 1000}, got %+v", ev)
+		}
+		break
+	}
+	waitForEmpty(t, h.progress)
+}
+
+func TestUploadProgress_WatcherDisconnectCleansUp(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+	srv := httptest.NewServer(http.HandlerFunc(h.UploadProgress))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?id=never-started", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	readEvent(t, bufio.NewReader(resp.Body))
+	cancel()
+	resp.Body.Close()
+
+	waitForEmpty(t, h.progress)
+}
+
+func TestUploadProgress_InvalidID(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	for _, id := range []string{"", "a/b", strings.Repeat("a", maxUploadIDLen+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/files/upload-progress?id="+id, nil)
+		rr := httptest.NewRecorder()
+		h.UploadProgress(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("id %q: expected 400, got %d", id, rr.Code)
+		}
+	}
+}
+
+func TestPut_UploadIDInUse(t *testing.T) {
+	written := map[string]string{}
+	h := newTestHandler(newBatchStore(written))
+	if _, err := h.progress.start("busy", -1); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=/a.txt&uploadId=busy", strings.NewReader("data"))
+	rr := httptest.NewRecorder()
+	h.Put(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(written) != 0 {
+		t.Errorf("expected nothing written, got %v", written)
+	}
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-storage-api/internal/bufpool"
+)
+
+// errUnsatisfiableRange means the Range header was well-formed but selects
+// no bytes of the file, which maps to 416 Range Not Satisfiable.
+var errUnsatisfiableRange = errors.New("range not satisfiable")
+
+// maxRanges caps how many ranges one request may ask for. Longer range
+// sets are ignored and the full content served, as RFC 9110 allows, so a
+// client cannot make a tiny header expand into thousands of parts.
+const maxRanges = 64
+
+// byteRange is a contiguous slice of a file starting at start.
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+func (r byteRange) end() int64 {
+	return r.start + r.length
+}
+
+// contentRange formats the Content-Range header value for r within size.
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// parseRanges interprets a "bytes=" Range header against a file of the
+// given size. Headers that cannot be parsed, or that ask for more than
+// maxRanges ranges, return (nil, nil) so the caller serves the full content
+// as RFC 9110 allows. Ranges that start past the end of the file are
+// dropped; if none are left, or the range set is empty ("bytes="), the
+// result is errUnsatisfiableRange.
+//
+// Ranges keep their requested order unless some overlap, in which case the
+// set is sorted and overlapping ranges are coalesced, so no byte is sent
+// twice.
+func parseRanges(header string, size int64) ([]byteRange, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return nil, nil
+	}
+	if strings.TrimSpace(spec) == "" {
+		return nil, errUnsatisfiableRange
+	}
+
+	specs := strings.Split(spec, ",")
+	if len(specs) > maxRanges {
+		return nil, nil
+	}
+	var ranges []byteRange
+	for _, s := range specs {
+		r, ok, satisfiable := parseRangeSpec(strings.TrimSpace(s), size)
+		if !ok {
+			return nil, nil
+		}
+		if satisfiable {
+			ranges = append(ranges, r)
+		}
+	}
+	if len(ranges) == 0 {
+		return nil, errUnsatisfiableRange
+	}
+	return coalesceRanges(ranges), nil
+}
+
+// parseRangeSpec parses one range-spec such as "0-4", "7-" or "-3". ok is
+// false if it is malformed; satisfiable is false if it selects no bytes.
+func parseRangeSpec(spec string, size int64) (r byteRange, ok, satisfiable bool) {
+	first, last, found := strings.Cut(spec, "-")
+	if !found {
+		return byteRange{}, false, false
+	}
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+	// Suffix range: "-N" selects the final N bytes.
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return byteRange{}, false, false
+		}
+		if n == 0 || size == 0 {
+			return byteRange{}, true, false
+		}
+		n = min(n, size)
+		return byteRange{start: size - n, length: n}, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return byteRange{}, false, false
+	}
+
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return byteRange{}, false, false
+		}
+		end = min(end, size-1)
+	}
+
+	if start >= size {
+		return byteRange{}, true, false
+	}
+	return byteRange{start: start, length: end - start + 1}, true, true
+}
+
+// coalesceRanges returns ranges unchanged if none overlap, and otherwise
+// sorted with overlapping ranges merged.
+func coalesceRanges(ranges []byteRange) []byteRange {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b byteRange) int {
+		return cmp.Compare(a.start, b.start)
+	})
+	overlap := false
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].start < sorted[i-1].end() {
+			overlap = true
+			break
+		}
+	}
+	if !overlap {
+		return ranges
+	}
+
+	merged := sorted[:1]
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.start < last.end() {
+			last.length = max(last.end(), r.end()) - last.start
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// rangeSource reads byte ranges of one file in any order. It skips forward
+// through the current stream and, to go back, seeks it if it can or opens
+// the file again.
+type rangeSource struct {
+	open        func() (io.ReadCloser, error)
+	copyBuffers *bufpool.Pool
+
+	rc   io.ReadCloser
+	body io.Reader // rc, or a reader buffering ahead of it
+	pos  int64     // offset of body's next byte
+}
+
+func (s *rangeSource) Close() error {
+	return s.rc.Close()
+}
+
+// seek positions body at offset start.
+func (s *rangeSource) seek(start int64) error {
+	if start < s.pos {
+		if seeker, ok := s.rc.(io.Seeker); ok {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return err
+			}
+			s.body, s.pos = s.rc, start
+			return nil
+		}
+		rc, err := s.open()
+		if err != nil {
+			return err
+		}
+		s.rc.Close()
+		s.rc, s.body, s.pos = rc, rc, 0
+	}
+	n, err := s.copyBuffers.CopyN(io.Discard, s.body, start-s.pos)
+	s.pos += n
+	return err
+}
+
+// copyRange copies r to w, passing the file through wrap on the way.
+func (s *rangeSource) copyRange(w io.Writer, r byteRange, wrap func(io.Reader) io.Reader) (int64, error) {
+	if err := s.seek(r.start); err != nil {
+		return 0, err
+	}
+	n, err := s.copyBuffers.CopyN(w, wrap(s.body), r.length)
+	s.pos += n
+	return n, err
+}
+
+// writeRanges writes ranges as the parts of a multipart/byteranges body,
+// each with its own Content-Type and Content-Range, and returns the number
+// of file bytes sent.
+func (s *rangeSource) writeRanges(mw *multipart.Writer, ranges []byteRange, contentType string, size int64, wrap func(io.Reader) io.Reader) (int64, error) {
+	var sent int64
+	for _, r := range ranges {
+		part, err := mw.CreatePart(rangePartHeader(r, contentType, size))
+		if err != nil {
+			return sent, err
+		}
+		n, err := s.copyRange(part, r, wrap)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, mw.Close()
+}
+
+func rangePartHeader(r byteRange, contentType string, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Type":  {contentType},
+		"Content-Range": {r.contentRange(size)},
+	}
+}
+
+// multipartLength returns the exact length of the body writeRanges produces
+// with boundary, for the Content-Length header.
+func multipartLength(ranges []byteRange, contentType string, size int64, boundary string) (int64, error) {
+	var cw countingWriter
+	mw := multipart.NewWriter(&cw)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return 0, err
+	}
+	for _, r := range ranges {
+		if _, err := mw.CreatePart(rangePartHeader(r, contentType, size)); err != nil {
+			return 0, err
+		}
+		cw += countingWriter(r.length)
+	}
+	if err := mw.Close(); err != nil {
+		return 0, err
+	}
+	return int64(cw), nil
+}
+
+// countingWriter discards what is written to it, counting the bytes.
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseRanges(t *testing.T) {
+	tooMany := "bytes=" + strings.Repeat("0-0,", maxRanges) + "0-0"
+
+	tests := []struct {
+		name    string
+		header  string
+		size    int64
+		want    []byteRange
+		wantErr error
+	}{
+		{"closed range", "bytes=0-4", 10, []byteRange{{0, 5}}, nil},
+		{"open ended", "bytes=7-", 10, []byteRange{{7, 3}}, nil},
+		{"suffix", "bytes=-3", 10, []byteRange{{7, 3}}, nil},
+		{"suffix larger than file", "bytes=-50", 10, []byteRange{{0, 10}}, nil},
+		{"end clamped to size", "bytes=5-100", 10, []byteRange{{5, 5}}, nil},
+		{"wrong unit", "items=0-4", 10, nil, nil},
+		{"non-numeric", "bytes=a-b", 10, nil, nil},
+		{"missing dash", "bytes=5", 10, nil, nil},
+		{"end before start", "bytes=5-2", 10, nil, nil},
+		{"multiple ranges", "bytes=0-1, 4-5", 10, []byteRange{{0, 2}, {4, 2}}, nil},
+		{"request order kept", "bytes=6-7,0-1", 10, []byteRange{{6, 2}, {0, 2}}, nil},
+		{"overlapping merged", "bytes=4-6,0-2,1-3", 10, []byteRange{{0, 4}, {4, 3}}, nil},
+		{"contained merged", "bytes=0-9,2-3", 10, []byteRange{{0, 10}}, nil},
+		{"unsatisfiable dropped", "bytes=20-30,0-1", 10, []byteRange{{0, 2}}, nil},
+		{"one malformed ignores all", "bytes=0-1,x-2", 10, nil, nil},
+		{"too many ranges", tooMany, 10, nil, nil},
+		{"empty range set", "bytes=", 10, nil, errUnsatisfiableRange},
+		{"start past end", "bytes=10-", 10, nil, errUnsatisfiableRange},
+		{"zero suffix", "bytes=-0", 10, nil, errUnsatisfiableRange},
+		{"all unsatisfiable", "bytes=10-11,-0", 10, nil, errUnsatisfiableRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRanges(tt.header, tt.size)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected ranges %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
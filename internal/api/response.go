@@ -3,22 +3,116 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"go-storage-api/internal/storage"
+)
+
+// Error codes returned in ErrorResponse.Code. They are part of the API
+// contract: clients switch on them, so existing values must not change.
+const (
+	CodeInvalidRequest      = "invalid_request"
+	CodeValidationFailed    = "validation_failed"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodePathInvalid         = "path_invalid"
+	CodePathTooDeep         = "path_too_deep"
+	CodeNameTooLong         = "name_too_long"
+	CodeNotFound            = "not_found"
+	CodeIsDirectory         = "is_directory"
+	CodePermissionDenied    = "permission_denied"
+	CodeConflict            = "conflict"
+	CodeNotEmpty            = "not_empty"
+	CodeTooLarge            = "too_large"
+	CodeUnsupportedType     = "unsupported_type"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeRangeNotSatisfiable = "range_not_satisfiable"
+	CodeChecksumMismatch    = "checksum_mismatch"
+	CodePreconditionFailed  = "precondition_failed"
+	CodeUploadRejected      = "upload_rejected"
+	CodeQuotaExceeded       = "quota_exceeded"
+	CodeUnavailable         = "unavailable"
+	CodeNotSupported        = "not_supported"
+	CodeInternal            = "internal"
 )
 
+// headerRequestID is set on the response by middleware.RequestID before any
+// handler runs.
+const headerRequestID = "X-Request-ID"
+
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 type SuccessResponse struct {
 	Message string `json:"message"`
+
+	// Path is where an upload was stored, which may differ from the
+	// requested path when the multipart filename was appended.
+	Path string `json:"path,omitempty"`
+
+	// File describes what a delete removed, as it was just before.
+	File *storage.FileInfo `json:"file,omitempty"`
 }
 
+// writeJSON writes data as the response body, indented with two spaces
+// when the request asked for ?pretty=true and compact otherwise.
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+	enc := json.NewEncoder(w)
+	if isPretty(w) {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(data)
+}
+
+// prettyJSON marks the response writer of requests with ?pretty=true so
+// writeJSON indents its output, for reading responses by hand.
+func prettyJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pretty, ok := parseBoolParam(w, r, "pretty", false)
+		if !ok {
+			return
+		}
+		if pretty {
+			w = &prettyWriter{w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// prettyWriter marks a response for indented JSON.
+type prettyWriter struct {
+	http.ResponseWriter
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (pw *prettyWriter) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
+
+// isPretty reports whether w, or a writer it wraps, is a prettyWriter.
+func isPretty(w http.ResponseWriter) bool {
+	for {
+		switch v := w.(type) {
+		case *prettyWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return false
+		}
+	}
 }
 
-func writeError(w http.ResponseWriter, status int, msg string) {
-	writeJSON(w, status, ErrorResponse{Error: msg})
+// writeError writes an ErrorResponse, echoing the request ID already set on
+// the response so clients can quote it in bug reports.
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, ErrorResponse{
+		Error:     msg,
+		Code:      code,
+		RequestID: w.Header().Get(headerRequestID),
+	})
 }
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/storage"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	router := newTestRouter()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", `{"message":"ok"}` + "\n"},
+		{"?pretty=false", `{"message":"ok"}` + "\n"},
+		{"?pretty=true", "{\n  \"message\": \"ok\"\n}\n"},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/health"+tt.query, nil))
+		if rr.Body.String() != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.query, tt.want, rr.Body.String())
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/health?pretty=yes", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad pretty value, got %d", rr.Code)
+	}
+}
+
+func TestPrettyJSON_ThroughWrappers(t *testing.T) {
+	rr := httptest.NewRecorder()
+	h := prettyJSON(middleware.ContentSecurity(middleware.ContentStrict)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "not found")
+	})))
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?pretty=1", nil))
+	if !strings.Contains(rr.Body.String(), "\n  \"code\": \"not_found\"") {
+		t.Errorf("expected an indented error, got %q", rr.Body.String())
+	}
+}
+
+func TestJSONFieldNames(t *testing.T) {
+	mt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "docs", Path: p, IsDir: true, ModTime: mt, Checksum: "ab", ChildCount: 2, TotalSize: 7}, nil
+		},
+	}
+	rr := httptest.NewRecorder()
+	newTestHandler(store).Stat(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=docs", nil))
+
+	var got map[string]any
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"name", "path", "size", "is_dir", "mod_time", "checksum", "child_count", "total_size"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("expected field %q in %v", name, got)
+		}
+	}
+
+	b, _ := json.Marshal(ErrorResponse{Error: "x", Code: CodeInternal, RequestID: "r1"})
+	if string(b) != `{"error":"x","code":"internal","request_id":"r1"}` {
+		t.Errorf("unexpected error response %s", b)
+	}
+}
@@ -4,28 +4,166 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	"go-storage-api/internal/middleware"
 	"go-storage-api/internal/storage"
+	"go-storage-api/internal/webdav"
 )
 
+// webdavPrefix is where the WebDAV surface is mounted when Options.WebDAV
+// is set.
+const webdavPrefix = "/webdav"
+
+// headerStorageBackend names the backend that served a request when
+// Options.ExposeBackend is enabled.
+const headerStorageBackend = "X-Storage-Backend"
+
 // NewRouter creates a fully wired http.Handler with middleware and routes.
-func NewRouter(store storage.Storage, maxUploadSize int64, logger *slog.Logger) http.Handler {
-	h := NewHandler(store, maxUploadSize)
+func NewRouter(store storage.Storage, opts Options, logger *slog.Logger) http.Handler {
+	if opts.Logger == nil {
+		opts.Logger = logger
+	}
+	h := NewHandler(store, opts)
 
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("GET /api/v1/health", h.Health)
-	mux.HandleFunc("GET /api/v1/files", h.List)
-	mux.HandleFunc("GET /api/v1/files/download", h.Download)
-	mux.HandleFunc("POST /api/v1/files/upload", h.Upload)
-	mux.HandleFunc("DELETE /api/v1/files", h.Delete)
-	mux.HandleFunc("GET /api/v1/files/stat", h.Stat)
-
-	stack := middleware.Chain(
-		middleware.RequestID,
-		middleware.Logging(logger),
-		middleware.PathGuard,
+	routes := newRouteTable(mux)
+	routes.handleFunc(http.MethodGet, "/api/v1/health", h.Health)
+	routes.handleFunc(http.MethodGet, "/api/v1/ready", h.Ready)
+	routes.handleFunc(http.MethodGet, "/api/v1/version", h.Version)
+	routes.handleFunc(http.MethodGet, "/api/v1/files", h.List)
+	// File contents are served through ContentSecurity so uploaded HTML
+	// or SVG cannot run scripts in the API's origin.
+	secure := middleware.ContentSecurity(opts.ContentPolicy)
+	routes.handle(http.MethodGet, "/api/v1/files/download", secure(http.HandlerFunc(h.Download)))
+	routes.handle(http.MethodGet, "/api/v1/files/preview", secure(http.HandlerFunc(h.Preview)))
+	routes.handle(http.MethodGet, "/api/v1/assets/{path...}", secure(http.HandlerFunc(h.Asset)))
+	if len(opts.URLSigningSecret) > 0 {
+		routes.handleFunc(http.MethodPost, "/api/v1/files/sign", h.SignURL)
+		routes.handle(http.MethodGet, signedPath, secure(http.HandlerFunc(h.SignedDownload)))
+	}
+	routes.handleFunc(http.MethodGet, "/api/v1/files/thumbnail", h.Thumbnail)
+	routes.handleFunc(http.MethodPost, "/api/v1/files/upload", h.Upload)
+	routes.handleFunc(http.MethodGet, "/api/v1/files/upload-progress", h.UploadProgress)
+	routes.handleFunc(http.MethodPut, "/api/v1/files", h.Put)
+	routes.handleFunc(http.MethodPatch, "/api/v1/files", h.Patch)
+	routes.handleFunc(http.MethodPost, "/api/v1/files/archive", h.Archive)
+	routes.handleFunc(http.MethodDelete, "/api/v1/files", h.Delete)
+	routes.handleFunc(http.MethodPost, "/api/v1/files/restore", h.Restore)
+	routes.handleFunc(http.MethodGet, "/api/v1/files/stat", h.Stat)
+	routes.handleFunc(http.MethodPost, "/api/v1/files/batch-stat", h.BatchStat)
+	routes.handleFunc(http.MethodPost, "/api/v1/files/verify", h.Verify)
+	routes.handleFunc(http.MethodPost, "/api/v1/files/mkdir", h.Mkdir)
+	routes.handleFunc(http.MethodPost, "/api/v1/files/copy", h.Copy)
+	routes.handleFunc(http.MethodPost, "/api/v1/files/move", h.Move)
+	routes.handleFunc(http.MethodGet, "/api/v1/files/usage", h.Usage)
+	routes.handleFunc(http.MethodGet, "/api/v1/files/diskinfo", h.DiskInfo)
+	routes.handleFunc(http.MethodGet, "/api/v1/files/search", h.Search)
+	routes.handleFunc(http.MethodGet, "/api/v1/files/tree", h.Tree)
+	routes.handleFunc(http.MethodGet, "/api/v1/files/metadata", h.GetMetadata)
+	routes.handleFunc(http.MethodPut, "/api/v1/files/metadata", h.SetMetadata)
+	routes.handleFunc(http.MethodPut, "/api/v1/files/touch", h.Touch)
+	if opts.ResumableUploadDir != "" {
+		routes.handleFunc(http.MethodOptions, uploadsPath, h.UploadOptions)
+		routes.handleFunc(http.MethodPost, uploadsPath, h.CreateUpload)
+		routes.handleFunc(http.MethodHead, uploadsPath+"/{id}", h.UploadStatus)
+		routes.handleFunc(http.MethodPatch, uploadsPath+"/{id}", h.AppendUpload)
+		routes.handleFunc(http.MethodDelete, uploadsPath+"/{id}", h.TerminateUpload)
+	}
+	if opts.WebDAV {
+		mux.Handle(webdavPrefix+"/", webdav.New(h.davStore(), webdavPrefix, opts.MaxUploadSize, opts.pathLimits(), h.copyBuffers))
+	}
+
+	// Each router gets its own registry so repeated construction (tests,
+	// multiple servers) never trips duplicate-registration panics.
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	registerBreakerMetrics(reg, store)
+	if c, ok := store.(prometheus.Collector); ok {
+		// Set by storage.WithMetrics.
+		reg.MustRegister(c)
+	}
+	routes.handle(http.MethodGet, "/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	routes.finish()
+
+	route := func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}
+
+	public := publicRoutes(opts)
+	mws := []middleware.Middleware{middleware.RequestID}
+	if opts.TracerProvider != nil {
+		mws = append(mws, middleware.Tracing(opts.TracerProvider, route))
+	}
+	mws = append(mws,
+		middleware.Logging(logger, middleware.LogTrustedProxies(opts.TrustedProxies)),
+		middleware.Metrics(reg, route),
 	)
+	if len(opts.IPAllow) > 0 || len(opts.IPDeny) > 0 {
+		mws = append(mws, middleware.IPFilter(opts.IPAllow, opts.IPDeny, middleware.WithTrustedProxies(opts.TrustedProxies)))
+	}
+	if opts.UploadQuota > 0 && opts.UploadQuotaWindow > 0 {
+		mws = append(mws, middleware.UploadQuota(opts.UploadQuota, opts.UploadQuotaWindow,
+			middleware.QuotaTrustedProxies(opts.TrustedProxies), middleware.QuotaPublicRoutes(public)))
+	}
+	if len(opts.JWTSecret) > 0 {
+		mws = append(mws, jwtMiddleware(opts, public))
+	}
+	mws = append(mws, middleware.PathGuardWithLimits(opts.pathLimits()))
+	if opts.ExposeBackend {
+		if name := storage.NameOf(store); name != "" {
+			mws = append(mws, middleware.SetHeader(headerStorageBackend, name))
+		}
+	}
+	mws = append(mws, prettyJSON)
+	stack := middleware.Chain(mws...)
+
+	// Browsers request /favicon.ico unprompted. Answer it outside the
+	// middleware stack so it never reaches logging or path checks.
+	root := http.NewServeMux()
+	root.HandleFunc("GET /favicon.ico", h.Favicon)
+	root.Handle("/", stack(mux))
+
+	// Outermost, so the muxes route the normalized path and logs and
+	// metrics record it.
+	return middleware.NormalizeSlashes(webdavPrefix)(root)
+}
+
+// publicRoutes registers the routes that need no credentials: the probes,
+// signed links, which carry their own authorization, and opts.PublicPaths.
+func publicRoutes(opts Options) *middleware.PublicRoutes {
+	public := middleware.NewPublicRoutes("/api/v1/health", "/api/v1/ready", "/api/v1/version")
+	if len(opts.URLSigningSecret) > 0 {
+		public.Add(signedPath)
+	}
+	public.Add(opts.PublicPaths...)
+	return public
+}
+
+// jwtMiddleware builds HMAC bearer-token authentication from opts, letting
+// public routes through.
+func jwtMiddleware(opts Options, public *middleware.PublicRoutes) middleware.Middleware {
+	keyfunc := func(*jwt.Token) (interface{}, error) {
+		return opts.JWTSecret, nil
+	}
 
-	return stack(mux)
+	jwtOpts := []middleware.JWTOption{
+		middleware.WithValidMethods("HS256", "HS384", "HS512"),
+		middleware.WithPublicRoutes(public),
+	}
+	if opts.JWTIssuer != "" {
+		jwtOpts = append(jwtOpts, middleware.WithIssuer(opts.JWTIssuer))
+	}
+	if opts.JWTAudience != "" {
+		jwtOpts = append(jwtOpts, middleware.WithAudience(opts.JWTAudience))
+	}
+	return middleware.JWT(keyfunc, jwtOpts...)
 }
@@ -3,14 +3,23 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"go-storage-api/internal/middleware"
 	"go-storage-api/internal/storage"
+	"go-storage-api/internal/version"
 )
 
 func newTestRouter() http.Handler {
@@ -33,7 +42,7 @@ func newTestRouter() http.Handler {
 	}
 
 	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
-	return NewRouter(store, 10<<20, logger)
+	return NewRouter(store, Options{MaxUploadSize: 10 << 20}, logger)
 }
 
 func TestRouter_HealthRoute(t *testing.T) {
@@ -54,6 +63,26 @@ func TestRouter_HealthRoute(t *testing.T) {
 	}
 }
 
+func TestRouter_VersionRoute(t *testing.T) {
+	router := newTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/version", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var body version.Info
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body.GoVersion == "" {
+		t.Error("expected a non-empty go_version")
+	}
+	if body.Version != "dev" || body.Commit != "unknown" {
+		t.Errorf("expected unstamped defaults, got %+v", body)
+	}
+}
+
 func TestRouter_ListRoute(t *testing.T) {
 	router := newTestRouter()
 
@@ -66,6 +95,27 @@ func TestRouter_ListRoute(t *testing.T) {
 	}
 }
 
+func TestRouter_NormalizesSlashes(t *testing.T) {
+	router := newTestRouter()
+
+	for _, target := range []string{"/api/v1/files/?path=/", "//api/v1/files?path=/"} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		var files []storage.FileInfo
+		if rr.Code != http.StatusOK || json.NewDecoder(rr.Body).Decode(&files) != nil {
+			t.Errorf("%s: expected the List response, got %d", target, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1//health", nil))
+	var body SuccessResponse
+	json.NewDecoder(rr.Body).Decode(&body)
+	if rr.Code != http.StatusOK || body.Message != "ok" {
+		t.Errorf("/api/v1//health: expected the Health response, got %d %+v", rr.Code, body)
+	}
+}
+
 func TestRouter_StatRoute(t *testing.T) {
 	router := newTestRouter()
 
@@ -105,7 +155,7 @@ func TestRouter_DeleteRoute(t *testing.T) {
 func TestRouter_WrongMethod(t *testing.T) {
 	router := newTestRouter()
 
-	req := httptest.NewRequest(http.MethodPatch, "/api/v1/files", nil)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files", nil)
 	rr := httptest.NewRecorder()
 	router.ServeHTTP(rr, req)
 
@@ -138,3 +188,432 @@ func TestRouter_NotFoundRoute(t *testing.T) {
 		t.Errorf("expected 404, got %d", rr.Code)
 	}
 }
+
+func TestRouter_FaviconNoContent(t *testing.T) {
+	router := newTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/favicon.ico", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", rr.Body.String())
+	}
+	if rr.Header().Get("X-Request-ID") != "" {
+		t.Error("expected favicon to bypass the middleware stack")
+	}
+}
+
+func TestRouter_MetricsRoute(t *testing.T) {
+	router := newTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=test", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	want := `http_requests_total{method="GET",route="/api/v1/files/stat",status="200"} 1`
+	if !strings.Contains(rr.Body.String(), want) {
+		t.Errorf("expected metrics output to contain %q", want)
+	}
+}
+
+// namedStorage gives mockStorage a backend name for header tests.
+type namedStorage struct {
+	*mockStorage
+	name string
+}
+
+func (n namedStorage) Name() string { return n.name }
+
+func TestRouter_BackendHeader(t *testing.T) {
+	store := namedStorage{mockStorage: &mockStorage{}, name: "cache(local)"}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	tests := []struct {
+		name   string
+		expose bool
+		want   string
+	}{
+		{"disabled by default", false, ""},
+		{"enabled", true, "cache(local)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter(store, Options{MaxUploadSize: 10 << 20, ExposeBackend: tt.expose}, logger)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if got := rr.Header().Get("X-Storage-Backend"); got != tt.want {
+				t.Errorf("expected X-Storage-Backend %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRouter_ContentPolicy(t *testing.T) {
+	store := &mockStorage{
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("<h1>hi</h1>")), nil
+		},
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	tests := []struct {
+		policy middleware.ContentPolicy
+		want   string
+	}{
+		{"", "attachment; filename=page.html"},
+		{middleware.ContentStrict, "attachment; filename=page.html"},
+		{middleware.ContentPermissive, "inline; filename=page.html"},
+	}
+	for _, tt := range tests {
+		router := NewRouter(store, Options{MaxUploadSize: 10 << 20, ContentPolicy: tt.policy}, logger)
+		for _, route := range []string{"/api/v1/files/download?path=page.html", "/api/v1/files/preview?path=page.html"} {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, route, nil))
+			if got := rr.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("%q %s: expected nosniff, got %q", tt.policy, route, got)
+			}
+			if strings.Contains(route, "download") && rr.Header().Get("Content-Disposition") != tt.want {
+				t.Errorf("%q: expected Content-Disposition %q, got %q", tt.policy, tt.want, rr.Header().Get("Content-Disposition"))
+			}
+		}
+	}
+}
+
+func TestRouter_JWTProtectsFilesButNotHealth(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	store := &mockStorage{
+		listFn: func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+			return []storage.FileInfo{}, nil
+		},
+	}
+	router := NewRouter(store, Options{MaxUploadSize: 10 << 20, JWTSecret: []byte("secret")}, logger)
+
+	for _, public := range []string{"/api/v1/health", "/api/v1/version"} {
+		req := httptest.NewRequest(http.MethodGet, public, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", public, rr.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("list without token: expected 401, got %d", rr.Code)
+	}
+
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/files", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("list with token: expected 200, got %d", rr.Code)
+	}
+}
+
+func TestRouter_PublicPathsSkipAuth(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	router := NewRouter(fileStore("logo"), Options{
+		MaxUploadSize: 10 << 20,
+		JWTSecret:     []byte("secret"),
+		PublicPaths:   []string{"/api/v1/assets"},
+	}, logger)
+
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodGet, "/api/v1/assets/logo.txt", http.StatusOK},
+		{http.MethodGet, "/api/v1/ready", http.StatusOK},
+		{http.MethodOptions, "/api/v1/files", http.StatusNoContent},
+		{http.MethodGet, "/api/v1/files/download?path=/logo.txt", http.StatusUnauthorized},
+		{http.MethodDelete, "/api/v1/files?path=/logo.txt", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.target, nil))
+		if rr.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.target, tt.want, rr.Code)
+		}
+	}
+}
+
+func TestRouter_ErrorBodyCarriesCodeAndRequestID(t *testing.T) {
+	router := newTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=../etc/passwd", nil)
+	req.Header.Set("X-Request-ID", "trace-abc")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var body ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&body)
+	if rr.Code != http.StatusBadRequest || body.Code != CodePathInvalid {
+		t.Errorf("expected 400 %q, got %d %q", CodePathInvalid, rr.Code, body.Code)
+	}
+	if body.RequestID != "trace-abc" {
+		t.Errorf("expected requestId trace-abc, got %q", body.RequestID)
+	}
+}
+
+func TestRouter_TracingUsesRoutePattern(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	store := &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "a.txt"}, nil
+		},
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	router := NewRouter(store, Options{MaxUploadSize: 10 << 20, TracerProvider: tp}, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=a.txt", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 1 || spans[0].Name() != "GET /api/v1/files/stat" {
+		names := make([]string, len(spans))
+		for i, s := range spans {
+			names[i] = s.Name()
+		}
+		t.Errorf("expected one span named %q, got %v", "GET /api/v1/files/stat", names)
+	}
+}
+
+func TestRouter_WebDAVMountedWhenEnabled(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	for _, enabled := range []bool{false, true} {
+		router := NewRouter(&mockStorage{}, Options{MaxUploadSize: 10 << 20, WebDAV: enabled}, logger)
+		req := httptest.NewRequest(http.MethodOptions, "/webdav/", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("DAV") != ""; got != enabled {
+			t.Errorf("WebDAV=%v: DAV header present = %v", enabled, got)
+		}
+	}
+}
+
+func TestRouter_OptionsListsAllowedMethods(t *testing.T) {
+	router := newTestRouter()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/files", "DELETE, GET, HEAD, OPTIONS, PATCH, PUT"},
+		{"/api/v1/files/download", "GET, HEAD, OPTIONS"},
+		{"/api/v1/files/upload", "OPTIONS, POST"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNoContent {
+			t.Errorf("OPTIONS %s: expected 204, got %d", tt.path, rr.Code)
+		}
+		if got := rr.Header().Get("Allow"); got != tt.want {
+			t.Errorf("OPTIONS %s: Allow = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRouter_MethodNotAllowedSetsAllow(t *testing.T) {
+	router := newTestRouter()
+
+	tests := []struct {
+		method, path, want string
+	}{
+		{http.MethodPost, "/api/v1/files", "DELETE, GET, HEAD, OPTIONS, PATCH, PUT"},
+		{http.MethodDelete, "/api/v1/files/download", "GET, HEAD, OPTIONS"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("%s %s: expected 405, got %d", tt.method, tt.path, rr.Code)
+		}
+		if got := rr.Header().Get("Allow"); got != tt.want {
+			t.Errorf("%s %s: Allow = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+		var body ErrorResponse
+		json.NewDecoder(rr.Body).Decode(&body)
+		if body.Code != CodeMethodNotAllowed {
+			t.Errorf("%s %s: expected code %s, got %q", tt.method, tt.path, CodeMethodNotAllowed, body.Code)
+		}
+	}
+}
+
+func TestRouter_IPFilter(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	store := &mockStorage{
+		listFn: func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+			return []storage.FileInfo{}, nil
+		},
+	}
+	router := NewRouter(store, Options{
+		MaxUploadSize: 10 << 20,
+		IPAllow:       []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	}, logger)
+
+	tests := []struct {
+		remoteAddr string
+		wantStatus int
+	}{
+		{"10.1.2.3:5000", http.StatusOK},
+		{"192.0.2.1:5000", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/files", nil)
+		req.RemoteAddr = tt.remoteAddr
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s: expected %d, got %d", tt.remoteAddr, tt.wantStatus, rr.Code)
+		}
+		if rr.Code == http.StatusForbidden {
+			var resp ErrorResponse
+			json.NewDecoder(rr.Body).Decode(&resp)
+			if resp.Code != CodeForbidden {
+				t.Errorf("expected code %s, got %q", CodeForbidden, resp.Code)
+			}
+		}
+	}
+}
+
+func TestRouter_BreakerMetrics(t *testing.T) {
+	store := storage.WithCircuitBreaker(&mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, errors.New("connection reset by peer")
+		},
+	}, storage.BreakerOptions{MinRequests: 1, Cooldown: time.Hour})
+	store.Stat(context.Background(), "/")
+	router := NewRouter(store, Options{}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`storage_circuit_breaker_state{operation="stat"} 2`,
+		`storage_circuit_breaker_state{operation="read"} 0`,
+	} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("expected metrics output to contain %q", want)
+		}
+	}
+}
+
+func TestRouter_StorageMetrics(t *testing.T) {
+	store := storage.WithMetrics(&mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, storage.ErrNotFound
+		},
+	})
+	router := NewRouter(store, Options{}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=missing.txt", nil))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`storage_operation_duration_seconds_count{operation="stat"} 1`,
+		`storage_operation_errors_total{kind="not_found",operation="stat"} 1`,
+	} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("expected metrics output to contain %q", want)
+		}
+	}
+}
+
+func TestRouter_HiddenPatterns(t *testing.T) {
+	var deleted []string
+	store := &mockStorage{
+		listFn: func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+			return []storage.FileInfo{
+				{Name: ".DS_Store", Path: ".DS_Store"},
+				{Name: "a.txt", Path: "a.txt"},
+				{Name: "b.tmp", Path: "b.tmp"},
+			}, nil
+		},
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("data")), nil
+		},
+		deleteFn: func(_ context.Context, p string) error {
+			deleted = append(deleted, p)
+			return nil
+		},
+	}
+	router := NewRouter(store, Options{HiddenPatterns: []string{".DS_Store", "*.tmp"}}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files?path=/", nil))
+	var files []storage.FileInfo
+	json.NewDecoder(rr.Body).Decode(&files)
+	if len(files) != 1 || files[0].Name != "a.txt" {
+		t.Errorf("expected only a.txt listed, got %+v", files)
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=.DS_Store", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/files/stat?path=/b.tmp", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/files/preview?path=b.tmp", nil),
+		httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=.DS_Store", nil),
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected 404, got %d", req.Method, req.URL, rr.Code)
+		}
+	}
+	if len(deleted) != 0 {
+		t.Errorf("expected nothing deleted, got %v", deleted)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=a.txt", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected visible files served, got %d", rr.Code)
+	}
+}
+
+func TestHiddenPaths_SparesTrash(t *testing.T) {
+	hidden := hiddenPaths(Options{HiddenPatterns: []string{".*"}})
+	for p, want := range map[string]bool{
+		"/.trash":                 false,
+		"/.trash/docs/a.txt~2024": false,
+		"/.trash/.env~2024":       true,
+		"/docs/.trash/a.txt":      true,
+		"/.env":                   true,
+		"/docs/a.txt":             false,
+	} {
+		if got := hidden(p); got != want {
+			t.Errorf("%s: expected %v, got %v", p, want, got)
+		}
+	}
+	if hiddenPaths(Options{}) != nil {
+		t.Error("expected no patterns to hide nothing")
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// routeTable registers method-specific handlers on a ServeMux and remembers
+// which methods each path accepts, so OPTIONS and 405 responses can list
+// them in an Allow header.
+type routeTable struct {
+	mux     *http.ServeMux
+	methods map[string][]string
+	paths   []string
+}
+
+func newRouteTable(mux *http.ServeMux) *routeTable {
+	return &routeTable{mux: mux, methods: make(map[string][]string)}
+}
+
+// handle registers h for method and path.
+func (t *routeTable) handle(method, path string, h http.Handler) {
+	t.mux.Handle(method+" "+path, h)
+	if _, ok := t.methods[path]; !ok {
+		t.paths = append(t.paths, path)
+	}
+	t.methods[path] = append(t.methods[path], method)
+}
+
+func (t *routeTable) handleFunc(method, path string, h http.HandlerFunc) {
+	t.handle(method, path, h)
+}
+
+// finish registers a method-less pattern for every path. The mux prefers
+// the method-specific patterns, so this only sees OPTIONS, which gets 204,
+// and unsupported methods, which get 405. Both carry Allow.
+func (t *routeTable) finish() {
+	for _, path := range t.paths {
+		allow := allowHeader(t.methods[path])
+		t.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", allow)
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+		})
+	}
+}
+
+// allowHeader lists methods plus the HEAD the mux serves for every GET and
+// OPTIONS, sorted like net/http's own Allow values.
+func allowHeader(methods []string) string {
+	all := append([]string{http.MethodOptions}, methods...)
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		all = append(all, http.MethodHead)
+	}
+	slices.Sort(all)
+	return strings.Join(slices.Compact(all), ", ")
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"go-storage-api/internal/storage"
+)
+
+// ScanFunc inspects an upload before it is stored, for example by passing
+// it to ClamAV. filename is the storage path the upload is headed for and
+// r yields its complete contents. An error wrapping ErrUploadRejected
+// refuses the upload with 422; any other error fails it with 500. Either
+// way nothing is written.
+type ScanFunc func(ctx context.Context, filename string, r io.Reader) error
+
+// ErrUploadRejected is wrapped by a ScanFunc's error to refuse an upload,
+// e.g. fmt.Errorf("%w: Eicar-Test-Signature", api.ErrUploadRejected). The
+// full message is returned to the client.
+var ErrUploadRejected = errors.New("upload rejected by scanner")
+
+// scanned runs h.scan over r, bound for path, and returns a reader for
+// the same bytes to write instead. Seekable sources (multipart files, tus
+// staging files) are scanned in place and rewound; streams are staged to
+// a temporary file first, which cleanup removes. With no ScanFunc r is
+// returned as is.
+func (h *Handler) scanned(ctx context.Context, path string, r io.Reader) (_ io.Reader, cleanup func(), err error) {
+	cleanup = func() {}
+	if h.scan == nil {
+		return r, cleanup, nil
+	}
+
+	var start int64
+	rs, ok := r.(io.ReadSeeker)
+	if ok {
+		if start, err = rs.Seek(0, io.SeekCurrent); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		f, err := os.CreateTemp("", "upload-scan-*")
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() {
+			f.Close()
+			os.Remove(f.Name())
+		}
+		if _, err := h.copyBuffers.Copy(f, r); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		rs = f
+	}
+
+	// The scanner gets a plain reader so it cannot move the offset.
+	if err := h.scan(ctx, path, struct{ io.Reader }{rs}); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := rs.Seek(start, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return rs, cleanup, nil
+}
+
+// davStore returns the store for the WebDAV handler, which scans writes
+// when a ScanFunc is set.
+func (h *Handler) davStore() storage.Storage {
+	if h.scan == nil {
+		return h.store
+	}
+	return scanStore{Storage: h.store, h: h}
+}
+
+// scanStore applies the handler's ScanFunc to writes that bypass it, i.e.
+// WebDAV PUTs. Rename is forwarded for MOVE.
+type scanStore struct {
+	storage.Storage
+	h *Handler
+}
+
+func (s scanStore) Write(ctx context.Context, path string, r io.Reader) error {
+	r, cleanup, err := s.h.scanned(ctx, path, r)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return s.Storage.Write(ctx, path, r)
+}
+
+func (s scanStore) Rename(ctx context.Context, from, to string) error {
+	return storage.Rename(ctx, s.Storage, from, to)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// rejectEICAR is a ScanFunc that refuses the EICAR test signature and
+// records what it saw.
+func rejectEICAR(seen *string) ScanFunc {
+	return func(_ context.Context, filename string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		*seen = filename
+		if bytes.Contains(data, []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")) {
+			return fmt.Errorf("%w: Eicar-Test-Signature", ErrUploadRejected)
+		}
+		return nil
+	}
+}
+
+// recordingStore accepts writes, keeping the bytes of the last one.
+func recordingStore(written *string) *mockStorage {
+	return &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, _ string, r io.Reader) error {
+			data, err := io.ReadAll(r)
+			*written = string(data)
+			return err
+		},
+	}
+}
+
+func TestScan_Upload(t *testing.T) {
+	var written, seen string
+	h := NewHandler(recordingStore(&written), Options{MaxUploadSize: 1 << 20, Scan: rejectEICAR(&seen)})
+
+	rr := httptest.NewRecorder()
+	h.Upload(rr, createMultipartRequest(t, "clean.txt", "clean.txt", "hello"))
+	if rr.Code != http.StatusCreated || written != "hello" {
+		t.Fatalf("expected a clean upload stored whole, got %d with %q", rr.Code, written)
+	}
+	if seen != "clean.txt" {
+		t.Errorf("expected the scanner to see clean.txt, got %q", seen)
+	}
+
+	written = ""
+	rr = httptest.NewRecorder()
+	h.Upload(rr, createMultipartRequest(t, "bad.com", "bad.com", eicar))
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), CodeUploadRejected) || !strings.Contains(rr.Body.String(), "Eicar-Test-Signature") {
+		t.Errorf("expected the scanner's reason, got %s", rr.Body.String())
+	}
+	if written != "" {
+		t.Errorf("expected nothing written, got %q", written)
+	}
+}
+
+func TestScan_PutStagesStream(t *testing.T) {
+	var written, seen string
+	h := NewHandler(recordingStore(&written), Options{MaxUploadSize: 1 << 20, Scan: rejectEICAR(&seen)})
+
+	put := func(body string) *httptest.ResponseRecorder {
+		// A plain reader, so the body cannot be rewound and must be staged.
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/files?path=a.txt", struct{ io.Reader }{strings.NewReader(body)})
+		rr := httptest.NewRecorder()
+		h.Put(rr, req)
+		return rr
+	}
+
+	if rr := put("clean data"); rr.Code != http.StatusCreated || written != "clean data" {
+		t.Fatalf("expected the staged copy written, got %d with %q", rr.Code, written)
+	}
+	written = ""
+	if rr := put(eicar); rr.Code != http.StatusUnprocessableEntity || written != "" {
+		t.Errorf("expected 422 and nothing written, got %d with %q", rr.Code, written)
+	}
+}
+
+func TestScan_ScannerFailure(t *testing.T) {
+	var written string
+	h := NewHandler(recordingStore(&written), Options{
+		MaxUploadSize: 1 << 20,
+		Scan: func(context.Context, string, io.Reader) error {
+			return errors.New("clamd: connection refused")
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	h.Upload(rr, createMultipartRequest(t, "a.txt", "a.txt", "hello"))
+	if rr.Code != http.StatusInternalServerError || written != "" {
+		t.Errorf("expected an unscanned upload to fail with 500, got %d with %q", rr.Code, written)
+	}
+}
+
+func TestScan_Patch(t *testing.T) {
+	var written, seen string
+	store := recordingStore(&written)
+	store.statFn = nil
+	h := NewHandler(store, Options{MaxUploadSize: 1 << 20, Scan: rejectEICAR(&seen)})
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/files?path=a.txt", strings.NewReader(eicar))
+	rr := httptest.NewRecorder()
+	h.Patch(rr, req)
+	if rr.Code != http.StatusUnprocessableEntity || written != "" {
+		t.Errorf("expected 422 and nothing written, got %d with %q", rr.Code, written)
+	}
+}
+
+func TestScan_WebDAV(t *testing.T) {
+	var written, seen string
+	router := NewRouter(recordingStore(&written), Options{MaxUploadSize: 1 << 20, WebDAV: true, Scan: rejectEICAR(&seen)}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/webdav/bad.com", strings.NewReader(eicar)))
+	if rr.Code < 400 || written != "" {
+		t.Errorf("expected the WebDAV PUT refused, got %d with %q", rr.Code, written)
+	}
+	if seen != "/bad.com" {
+		t.Errorf("expected the scanner to see /bad.com, got %q", seen)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/storage"
+)
+
+// usersRoot is the directory holding one subtree per authenticated subject
+// when Options.ScopeToUser is set.
+const usersRoot = "/users"
+
+// scopedStore wraps store so each request only sees its user's subtree when
+// opts.ScopeToUser is set, and returns store unchanged otherwise.
+func scopedStore(store storage.Storage, opts Options) storage.Storage {
+	if opts.ScopeToUser {
+		return storage.WithPrefix(store, userRoot)
+	}
+	return store
+}
+
+// hiddenPaths matches Options.HiddenPatterns against paths, or returns nil
+// without patterns. Trash entries are matched by their original paths, so
+// a pattern such as ".*" does not hide the trash itself.
+func hiddenPaths(opts Options) func(p string) bool {
+	match := storage.MatchPatterns(opts.HiddenPatterns, opts.HiddenMatchFullPath)
+	if match == nil {
+		return nil
+	}
+	return func(p string) bool {
+		clean := strings.TrimPrefix(path.Clean("/"+p), "/")
+		if clean == trashDir {
+			return false
+		}
+		if orig, ok := strings.CutPrefix(clean, trashDir+"/"); ok {
+			p = orig
+		}
+		return match(p)
+	}
+}
+
+// userRoot returns the storage subtree of the JWT subject in ctx. Requests
+// without a usable subject get storage.ErrPermission, so they can never fall
+// through to the shared root.
+func userRoot(ctx context.Context) (string, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return "", storage.ErrPermission
+	}
+	sub, err := claims.GetSubject()
+	if err != nil || !validSubject(sub) {
+		return "", storage.ErrPermission
+	}
+	return usersRoot + "/" + sub, nil
+}
+
+// validSubject reports whether sub can be used as a single directory name.
+func validSubject(sub string) bool {
+	return sub != "" && sub != "." && sub != ".." &&
+		!strings.ContainsAny(sub, `/\`) && !containsControl(sub)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"go-storage-api/internal/storage"
+)
+
+// Search returns defaultSearchLimit matches unless the limit parameter asks
+// for a different number, up to maxSearchLimit.
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 1000
+)
+
+// Search finds entries below path whose base name contains q, ignoring
+// case, optionally narrowed to one extension with ext. Results are sorted
+// by path and cut to limit. The trash is skipped unless path is inside it.
+// A client disconnect cancels the walk.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	p := query.Get("path")
+	if p == "" {
+		p = "/"
+	}
+
+	v := newValidator(r)
+	q := strings.ToLower(v.required("q"))
+	limit := min(v.positiveInt("limit", defaultSearchLimit), maxSearchLimit)
+	if !v.ok(w) {
+		return
+	}
+
+	ext := normalizeExt(query.Get("ext"))
+	skipTrash := !inTrash(p)
+
+	match := func(f storage.FileInfo) bool {
+		if skipTrash && inTrash(f.Path) {
+			return false
+		}
+		if ext != "" && (f.IsDir || normalizeExt(path.Ext(f.Name)) != ext) {
+			return false
+		}
+		return strings.Contains(strings.ToLower(f.Name), q)
+	}
+
+	files, err := storage.Search(r.Context(), h.store, p, match, limit, h.listConcurrency)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, files)
+}
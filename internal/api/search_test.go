@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// newSearchStore serves List from a fixture tree of the given file paths,
+// creating their parent directories.
+func newSearchStore(paths ...string) *mockStorage {
+	tree := map[string][]storage.FileInfo{}
+	seen := map[string]bool{}
+	add := func(p string, dir bool) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		parent := path.Dir(p)
+		tree[parent] = append(tree[parent], storage.FileInfo{Name: path.Base(p), Path: strings.TrimPrefix(p, "/"), IsDir: dir})
+	}
+	for _, p := range paths {
+		p = path.Clean("/" + p)
+		for d := path.Dir(p); d != "/"; d = path.Dir(d) {
+			add(d, true)
+		}
+		add(p, false)
+	}
+	return &mockStorage{
+		listFn: func(_ context.Context, p string) ([]storage.FileInfo, error) {
+			return tree[path.Clean("/"+p)], nil
+		},
+	}
+}
+
+var searchFixture = []string{
+	"docs/Report-2023.pdf",
+	"docs/report-2024.PDF",
+	"docs/notes.txt",
+	"docs/reports/summary.txt",
+	"archive/old-report.doc",
+	"images/photo.png",
+	".trash/docs/report-2022.pdf~20240101T000000.000000000Z",
+}
+
+func searchPaths(t *testing.T, h *Handler, target string) []string {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.Search(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("%s: expected 200, got %d: %s", target, rr.Code, rr.Body.String())
+	}
+	var files []storage.FileInfo
+	json.NewDecoder(rr.Body).Decode(&files)
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+func TestSearch_MatchesNameCaseInsensitively(t *testing.T) {
+	h := newTestHandler(newSearchStore(searchFixture...))
+
+	got := searchPaths(t, h, "/api/v1/files/search?q=REPORT")
+	want := []string{"archive/old-report.doc", "docs/Report-2023.pdf", "docs/report-2024.PDF", "docs/reports"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSearch_Filters(t *testing.T) {
+	h := newTestHandler(newSearchStore(searchFixture...))
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"/api/v1/files/search?q=report&ext=.pdf", []string{"docs/Report-2023.pdf", "docs/report-2024.PDF"}},
+		{"/api/v1/files/search?q=report&ext=pdf", []string{"docs/Report-2023.pdf", "docs/report-2024.PDF"}},
+		{"/api/v1/files/search?q=report&limit=2", []string{"archive/old-report.doc", "docs/Report-2023.pdf"}},
+		{"/api/v1/files/search?q=.txt&path=/docs/reports", []string{"docs/reports/summary.txt"}},
+		{"/api/v1/files/search?q=2022", []string{}},
+		{"/api/v1/files/search?q=2022&path=/.trash", []string{".trash/docs/report-2022.pdf~20240101T000000.000000000Z"}},
+	}
+	for _, tt := range tests {
+		got := searchPaths(t, h, tt.target)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.target, tt.want, got)
+		}
+	}
+}
+
+func TestSearch_BadParameters(t *testing.T) {
+	h := newTestHandler(newSearchStore(searchFixture...))
+
+	for _, target := range []string{
+		"/api/v1/files/search",
+		"/api/v1/files/search?q=",
+		"/api/v1/files/search?q=%20%20",
+		"/api/v1/files/search?q=a&limit=0",
+		"/api/v1/files/search?q=a&limit=many",
+	} {
+		rr := httptest.NewRecorder()
+		h.Search(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rr.Code)
+		}
+	}
+}
+
+func TestSearch_StopsWhenClientGoesAway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lists := 0
+	store := &mockStorage{
+		listFn: func(ctx context.Context, p string) ([]storage.FileInfo, error) {
+			lists++
+			cancel()
+			return []storage.FileInfo{{Name: "sub", Path: path.Join(strings.TrimPrefix(p, "/"), "sub"), IsDir: true}}, nil
+		},
+	}
+	h := NewHandler(store, Options{ListConcurrency: 1})
+
+	rr := httptest.NewRecorder()
+	h.Search(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/search?q=sub", nil).WithContext(ctx))
+	if lists != 1 {
+		t.Errorf("expected the walk to stop after the first listing, got %d", lists)
+	}
+	if rr.Code == http.StatusOK {
+		t.Errorf("expected an error status, got 200")
+	}
+}
+
+func TestSearch_NotFound(t *testing.T) {
+	store := &mockStorage{
+		listFn: func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+			return nil, storage.ErrNotFound
+		},
+	}
+	h := newTestHandler(store)
+
+	rr := httptest.NewRecorder()
+	h.Search(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/search?q=a&path=/missing", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rr.Code)
+	}
+}
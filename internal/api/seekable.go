@@ -0,0 +1,97 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+
+	"go-storage-api/internal/storage"
+)
+
+// serveSeekable answers a download from a file the backend opened for
+// random access. http.ServeContent handles Range (including multiple
+// ranges and If-Range) and the If-Modified-Since and If-Unmodified-Since
+// preconditions; the headers and trailers are the ones Download sets on
+// the streaming path. Download has already answered unsatisfiable ranges
+// and removed Range headers it ignores.
+func (h *Handler) serveSeekable(w http.ResponseWriter, r *http.Request, p string, info *storage.FileInfo, rs io.ReadSeekCloser, inline, inlineSet bool) {
+	defer rs.Close()
+
+	ct := h.contentTypes.byName(p)
+	if ct == "" {
+		prefix := make([]byte, sniffLen)
+		n, _ := io.ReadFull(rs, prefix)
+		ct = http.DetectContentType(prefix[:n])
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			handleStorageError(w, err)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", ct)
+	h.setDisposition(w, p, ct, inline, inlineSet)
+
+	// Throttling wraps only reads; seeks still go straight to the file.
+	body := io.ReadSeeker(rs)
+	if h.downloadRate > 0 || h.downloadLimit != nil {
+		body = struct {
+			io.Reader
+			io.Seeker
+		}{h.throttle(r.Context(), rs), rs}
+	}
+
+	if !h.downloadTrailers {
+		http.ServeContent(w, r, path.Base(p), info.ModTime, body)
+		return
+	}
+	w.Header().Set("Trailer", trailerBytesSent+", "+trailerDownloadStatus)
+	sw := &sentCounter{ResponseWriter: w, want: -1}
+	http.ServeContent(sw, r, path.Base(p), info.ModTime, body)
+
+	status := "complete"
+	if sw.want >= 0 && sw.n < sw.want {
+		status = "incomplete"
+	}
+	w.Header().Set(trailerBytesSent, strconv.FormatInt(sw.n, 10))
+	w.Header().Set(trailerDownloadStatus, status)
+}
+
+// sentCounter counts the body bytes written through it, for the
+// X-Bytes-Sent trailer. It takes the Content-Length http.ServeContent sets
+// off the response as want, since trailers need a chunked body.
+type sentCounter struct {
+	http.ResponseWriter
+	n, want int64
+}
+
+func (c *sentCounter) WriteHeader(code int) {
+	if n, err := strconv.ParseInt(c.Header().Get("Content-Length"), 10, 64); err == nil {
+		c.want = n
+		c.Header().Del("Content-Length")
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *sentCounter) Write(b []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadFrom keeps the underlying writer's ReaderFrom copy path.
+func (c *sentCounter) ReadFrom(src io.Reader) (int64, error) {
+	var n int64
+	var err error
+	if rf, ok := c.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{c.ResponseWriter}, src)
+	}
+	c.n += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *sentCounter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+var seekModTime = time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+// seekableStore is a mockStorage whose files can also be opened for
+// random access, counting how often each way is used.
+type seekableStore struct {
+	*mockStorage
+	content      string
+	seeks, reads int
+	seekErr      error
+}
+
+func newSeekableStore(content string) *seekableStore {
+	s := &seekableStore{content: content}
+	s.mockStorage = &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: p, Path: p, Size: int64(len(content)), ModTime: seekModTime}, nil
+		},
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			s.reads++
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+	}
+	return s
+}
+
+func (s *seekableStore) ReadSeeker(_ context.Context, _ string) (io.ReadSeekCloser, error) {
+	s.seeks++
+	if s.seekErr != nil {
+		return nil, s.seekErr
+	}
+	return readSeekNopCloser{strings.NewReader(s.content)}, nil
+}
+
+func download(h *Handler, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=data.txt", nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rr := httptest.NewRecorder()
+	h.Download(rr, req)
+	return rr
+}
+
+func TestDownload_Seekable(t *testing.T) {
+	store := newSeekableStore("0123456789")
+	h := NewHandler(store, Options{})
+
+	rr := download(h, nil)
+	if rr.Code != http.StatusOK || rr.Body.String() != "0123456789" {
+		t.Fatalf("expected the whole file, got %d %q", rr.Code, rr.Body.String())
+	}
+	if store.seeks != 1 || store.reads != 0 {
+		t.Errorf("expected the seekable path, got %d seeks and %d reads", store.seeks, store.reads)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); cd != `inline; filename=data.txt` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	if rr.Header().Get("Accept-Ranges") != "bytes" || rr.Header().Get("Last-Modified") != seekModTime.Format(http.TimeFormat) {
+		t.Errorf("expected Accept-Ranges and Last-Modified, got %v", rr.Header())
+	}
+}
+
+func TestDownload_SeekableRange(t *testing.T) {
+	h := NewHandler(newSeekableStore("0123456789"), Options{})
+
+	rr := download(h, http.Header{"Range": {"bytes=7-"}})
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "789" {
+		t.Fatalf("expected 206 with 789, got %d %q", rr.Code, rr.Body.String())
+	}
+	if cr := rr.Header().Get("Content-Range"); cr != "bytes 7-9/10" {
+		t.Errorf("unexpected Content-Range %q", cr)
+	}
+
+	rr = download(h, http.Header{"Range": {"bytes=6-7,0-1"}})
+	if rr.Code != http.StatusPartialContent || !strings.HasPrefix(rr.Header().Get("Content-Type"), "multipart/byteranges") {
+		t.Errorf("expected a multipart 206, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	rr = download(h, http.Header{"Range": {"bytes=20-30"}})
+	if rr.Code != http.StatusRequestedRangeNotSatisfiable || !strings.Contains(rr.Body.String(), CodeRangeNotSatisfiable) {
+		t.Errorf("expected a JSON 416, got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDownload_SeekableConditional(t *testing.T) {
+	h := NewHandler(newSeekableStore("0123456789"), Options{})
+	lastMod := seekModTime.Format(http.TimeFormat)
+
+	rr := download(h, http.Header{"If-Modified-Since": {lastMod}})
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Errorf("expected an empty 304, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	// A validator that no longer matches turns a ranged request into a
+	// full one.
+	stale := seekModTime.Add(-time.Hour).Format(http.TimeFormat)
+	rr = download(h, http.Header{"Range": {"bytes=0-1"}, "If-Range": {stale}})
+	if rr.Code != http.StatusOK || rr.Body.String() != "0123456789" {
+		t.Errorf("expected the whole file for a stale If-Range, got %d %q", rr.Code, rr.Body.String())
+	}
+	rr = download(h, http.Header{"Range": {"bytes=0-1"}, "If-Range": {lastMod}})
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "01" {
+		t.Errorf("expected 206 for a matching If-Range, got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDownload_SeekableFallsBack(t *testing.T) {
+	store := newSeekableStore("0123456789")
+	store.seekErr = storage.ErrNotSupported
+	h := NewHandler(store, Options{})
+
+	rr := download(h, http.Header{"Range": {"bytes=2-3"}})
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "23" {
+		t.Fatalf("expected 206 with 23 from the stream, got %d %q", rr.Code, rr.Body.String())
+	}
+	if store.reads != 1 {
+		t.Errorf("expected a streaming read, got %d", store.reads)
+	}
+
+	store.seekErr = storage.ErrPermission
+	if rr := download(h, nil); rr.Code != http.StatusForbidden {
+		t.Errorf("expected other errors to be reported, got %d", rr.Code)
+	}
+}
+
+func TestDownload_SeekableTrailers(t *testing.T) {
+	content := strings.Repeat("chunk", 1000)
+	h := NewHandler(newSeekableStore(content), Options{DownloadTrailers: true})
+	srv := httptest.NewServer(http.HandlerFunc(h.Download))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/files/download?path=big.txt")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if len(data) != len(content) {
+		t.Fatalf("expected %d bytes, got %d", len(content), len(data))
+	}
+	if got := resp.Trailer.Get("X-Bytes-Sent"); got != strconv.Itoa(len(content)) {
+		t.Errorf("expected X-Bytes-Sent %d, got %q", len(content), got)
+	}
+	if got := resp.Trailer.Get("X-Download-Status"); got != "complete" {
+		t.Errorf("expected X-Download-Status complete, got %q", got)
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// signedPath serves the links SignURL hands out. It is exempt from JWT
+// auth: the signature is the credential.
+const signedPath = "/api/v1/files/signed"
+
+const (
+	defaultSignedExpiry = 5 * time.Minute
+	maxSignedExpiry     = 7 * 24 * time.Hour
+)
+
+// SignedURLResponse is returned by SignURL.
+type SignedURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SignURL returns a link that downloads the file at path without
+// credentials until it expires, "expires" seconds from now (default 300,
+// at most 7 days). The URL is relative to the API's base URL. With
+// per-user scoping it names the file's backend path, so it works without
+// the signer's token.
+func (h *Handler) SignURL(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+	expires := defaultSignedExpiry
+	if v := r.URL.Query().Get("expires"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || time.Duration(n)*time.Second > maxSignedExpiry {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest,
+				"expires must be a number of seconds from 1 to "+strconv.Itoa(int(maxSignedExpiry/time.Second)))
+			return
+		}
+		expires = time.Duration(n) * time.Second
+	}
+
+	info, err := h.store.Stat(r.Context(), p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	if info.IsDir {
+		writeError(w, http.StatusBadRequest, CodeIsDirectory, "path is a directory")
+		return
+	}
+	if h.scopeToUser {
+		root, err := userRoot(r.Context())
+		if err != nil {
+			handleStorageError(w, err)
+			return
+		}
+		p = path.Join("/"+root, path.Clean("/"+p))
+	}
+
+	exp := time.Now().Add(expires).Truncate(time.Second)
+	q := url.Values{
+		"path": {p},
+		"exp":  {strconv.FormatInt(exp.Unix(), 10)},
+		"sig":  {base64.RawURLEncoding.EncodeToString(h.signature(p, exp.Unix()))},
+	}
+	writeJSON(w, http.StatusOK, SignedURLResponse{URL: signedPath + "?" + q.Encode(), ExpiresAt: exp.UTC()})
+}
+
+// SignedDownload serves a link made by SignURL like Download, once its
+// signature and expiry check out. Tampered and expired links get 403.
+func (h *Handler) SignedDownload(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	p := q.Get("path")
+	exp, expErr := strconv.ParseInt(q.Get("exp"), 10, 64)
+	sig, sigErr := base64.RawURLEncoding.DecodeString(q.Get("sig"))
+	if p == "" || expErr != nil || sigErr != nil || !hmac.Equal(sig, h.signature(p, exp)) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "invalid signature")
+		return
+	}
+	if time.Now().Unix() >= exp {
+		writeError(w, http.StatusForbidden, CodeForbidden, "signed URL has expired")
+		return
+	}
+	h.download(w, r, storage.WithHidden(h.backend, h.hidden), p)
+}
+
+// signature is the HMAC-SHA256 of a signed link's path and expiry.
+func (h *Handler) signature(p string, exp int64) []byte {
+	mac := hmac.New(sha256.New, h.signingKey)
+	mac.Write([]byte(p + "\n" + strconv.FormatInt(exp, 10)))
+	return mac.Sum(nil)
+}
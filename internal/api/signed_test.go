@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-storage-api/internal/storage"
+)
+
+// newSignedRouter serves "content" from every file path it is asked for,
+// behind JWT auth, recording the backend paths read.
+func newSignedRouter(opts Options, reads *[]string) http.Handler {
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: p, Path: p, Size: int64(len("content"))}, nil
+		},
+		readFn: func(_ context.Context, p string) (io.ReadCloser, error) {
+			*reads = append(*reads, p)
+			return io.NopCloser(strings.NewReader("content")), nil
+		},
+	}
+	opts.JWTSecret = []byte("jwt-secret")
+	opts.URLSigningSecret = []byte("link-secret")
+	return NewRouter(store, opts, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+}
+
+// signURL asks router, as alice, for a signed link to target.
+func signURL(t *testing.T, router http.Handler, target string) SignedURLResponse {
+	t.Helper()
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("jwt-secret"))
+	req := httptest.NewRequest(http.MethodPost, target, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("sign: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var res SignedURLResponse
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func fetch(router http.Handler, target string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	return rr
+}
+
+func TestSignedURL_Valid(t *testing.T) {
+	var reads []string
+	router := newSignedRouter(Options{}, &reads)
+
+	res := signURL(t, router, "/api/v1/files/sign?path=docs/report.pdf&expires=60")
+	if !strings.HasPrefix(res.URL, signedPath+"?") {
+		t.Fatalf("unexpected URL %q", res.URL)
+	}
+	if d := time.Until(res.ExpiresAt); d <= 0 || d > time.Minute {
+		t.Errorf("expected expiry within a minute, got %s", res.ExpiresAt)
+	}
+
+	rr := fetch(router, res.URL)
+	if rr.Code != http.StatusOK || rr.Body.String() != "content" {
+		t.Fatalf("expected the file without a token, got %d %q", rr.Code, rr.Body.String())
+	}
+	if len(reads) != 1 || reads[0] != "docs/report.pdf" {
+		t.Errorf("expected docs/report.pdf read, got %v", reads)
+	}
+}
+
+func TestSignedURL_Expired(t *testing.T) {
+	var reads []string
+	router := newSignedRouter(Options{}, &reads)
+	h := NewHandler(&mockStorage{}, Options{URLSigningSecret: []byte("link-secret")})
+
+	exp := time.Now().Add(-time.Second).Unix()
+	q := url.Values{
+		"path": {"a.txt"},
+		"exp":  {strconv.FormatInt(exp, 10)},
+		"sig":  {encodeSig(h.signature("a.txt", exp))},
+	}
+	rr := fetch(router, signedPath+"?"+q.Encode())
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "expired") {
+		t.Errorf("expected 403 for an expired link, got %d %s", rr.Code, rr.Body.String())
+	}
+	if len(reads) != 0 {
+		t.Errorf("expected nothing read, got %v", reads)
+	}
+}
+
+func TestSignedURL_Tampered(t *testing.T) {
+	var reads []string
+	router := newSignedRouter(Options{}, &reads)
+	res := signURL(t, router, "/api/v1/files/sign?path=a.txt")
+	u, _ := url.Parse(res.URL)
+
+	tamper := map[string]func(q url.Values){
+		"path": func(q url.Values) { q.Set("path", "b.txt") },
+		"exp":  func(q url.Values) { q.Set("exp", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)) },
+		"sig":  func(q url.Values) { q.Set("sig", strings.Repeat("A", len(q.Get("sig")))) },
+		"none": func(q url.Values) { q.Del("sig") },
+	}
+	for name, change := range tamper {
+		q := u.Query()
+		change(q)
+		if rr := fetch(router, signedPath+"?"+q.Encode()); rr.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", name, rr.Code)
+		}
+	}
+	if len(reads) != 0 {
+		t.Errorf("expected nothing read, got %v", reads)
+	}
+}
+
+func TestSignedURL_ScopedToUser(t *testing.T) {
+	var reads []string
+	router := newSignedRouter(Options{ScopeToUser: true}, &reads)
+
+	res := signURL(t, router, "/api/v1/files/sign?path=a.txt")
+	if rr := fetch(router, res.URL); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(reads) != 1 || reads[0] != "/users/alice/a.txt" {
+		t.Errorf("expected alice's file read, got %v", reads)
+	}
+}
+
+func TestSignedURL_InvalidExpiry(t *testing.T) {
+	h := NewHandler(&mockStorage{}, Options{URLSigningSecret: []byte("link-secret")})
+	for _, v := range []string{"0", "-5", "soon", "604801"} {
+		rr := httptest.NewRecorder()
+		h.SignURL(rr, httptest.NewRequest(http.MethodPost, "/api/v1/files/sign?path=a.txt&expires="+v, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expires=%s: expected 400, got %d", v, rr.Code)
+		}
+	}
+}
+
+func TestSignedURL_DisabledWithoutSecret(t *testing.T) {
+	router := NewRouter(&mockStorage{}, Options{}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	if rr := fetch(router, signedPath+"?path=a.txt&exp=1&sig=x"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a signing secret, got %d", rr.Code)
+	}
+}
+
+func encodeSig(sig []byte) string {
+	return base64.RawURLEncoding.EncodeToString(sig)
+}
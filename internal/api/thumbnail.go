@@ -0,0 +1,284 @@
+package api
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	_ "image/png" // registers the PNG decoder
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"go-storage-api/internal/storage"
+)
+
+const (
+	// defaultThumbnailDim is the box side used when w or h is absent;
+	// larger requests are clamped to maxThumbnailDim.
+	defaultThumbnailDim = 200
+	maxThumbnailDim     = 1024
+
+	// maxThumbnailPixels bounds the images Thumbnail will decode. The
+	// dimensions are read from the header first, so a small file that
+	// claims to be enormous is refused before any pixels are allocated.
+	maxThumbnailPixels = 50_000_000
+
+	// thumbnailCacheBytes bounds the encoded thumbnails kept in memory.
+	thumbnailCacheBytes = 32 << 20
+
+	thumbnailQuality = 85
+)
+
+var (
+	// errNotImage means a file is not an image Thumbnail can decode.
+	errNotImage = errors.New("not a JPEG, PNG or GIF image")
+
+	// errImageTooLarge means an image has more than maxThumbnailPixels.
+	errImageTooLarge = errors.New("image is too large to thumbnail")
+)
+
+// Thumbnail returns a JPEG of the image at path scaled to fit inside a w×h
+// box (200×200 by default), keeping its aspect ratio and never enlarging
+// it. Files that are not JPEG, PNG or GIF images, or that have more than
+// maxThumbnailPixels, get 415. Results are
+// cached by path, size and modification time, so a changed file gets a
+// fresh thumbnail.
+func (h *Handler) Thumbnail(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+	boxW, ok := parseThumbnailDim(w, r, "w")
+	if !ok {
+		return
+	}
+	boxH, ok := parseThumbnailDim(w, r, "h")
+	if !ok {
+		return
+	}
+
+	info, err := h.store.Stat(r.Context(), p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	if info.IsDir {
+		handleStorageError(w, storage.ErrIsDirectory)
+		return
+	}
+
+	key, err := h.thumbnailKey(r, p, info.Size, info.ModTime.UnixNano(), boxW, boxH)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	thumb, ok := h.thumbnails.get(key)
+	if !ok {
+		thumb, err = h.makeThumbnail(r, p, boxW, boxH)
+		if errors.Is(err, errNotImage) || errors.Is(err, errImageTooLarge) {
+			writeError(w, http.StatusUnsupportedMediaType, CodeUnsupportedType, err.Error())
+			return
+		}
+		if err != nil {
+			handleStorageError(w, err)
+			return
+		}
+		h.thumbnails.put(key, thumb)
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(len(thumb)))
+	w.Write(thumb)
+}
+
+// parseThumbnailDim reads the optional box side name, clamped to
+// maxThumbnailDim. It writes a 400 and returns ok=false if the value is not
+// a positive integer.
+func parseThumbnailDim(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return defaultThumbnailDim, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, name+" must be a positive integer")
+		return 0, false
+	}
+	return min(n, maxThumbnailDim), true
+}
+
+// thumbnailKey identifies a thumbnail of one version of a file. With
+// per-user scoping the same path names different files for different
+// users, so the user's root is part of the key.
+func (h *Handler) thumbnailKey(r *http.Request, p string, size, modTime int64, boxW, boxH int) (string, error) {
+	root := ""
+	if h.scopeToUser {
+		var err error
+		if root, err = userRoot(r.Context()); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%d\x00%dx%d", root, p, size, modTime, boxW, boxH), nil
+}
+
+// makeThumbnail decodes the image at p and encodes it as a JPEG that fits
+// the box.
+func (h *Handler) makeThumbnail(r *http.Request, p string, boxW, boxH int) ([]byte, error) {
+	rc, err := h.store.Read(r.Context(), p)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	src := &errRecorder{r: rc}
+
+	// Keep the bytes DecodeConfig consumes so Decode can start over.
+	var head bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(src, &head))
+	if err != nil {
+		return nil, src.or(errNotImage)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxThumbnailPixels {
+		return nil, errImageTooLarge
+	}
+	img, _, err := image.Decode(io.MultiReader(&head, src))
+	if err != nil {
+		return nil, src.or(errNotImage)
+	}
+
+	var out bytes.Buffer
+	tw, th := fitBox(img.Bounds().Dx(), img.Bounds().Dy(), boxW, boxH)
+	if err := jpeg.Encode(&out, scaleImage(img, tw, th), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// errRecorder remembers the first error other than io.EOF returned by r,
+// so a failed read is not mistaken for a file that is not an image.
+type errRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (e *errRecorder) Read(b []byte) (int, error) {
+	n, err := e.r.Read(b)
+	if err != nil && err != io.EOF && e.err == nil {
+		e.err = err
+	}
+	return n, err
+}
+
+// or returns the recorded read error, or fallback if there was none.
+func (e *errRecorder) or(fallback error) error {
+	if e.err != nil {
+		return e.err
+	}
+	return fallback
+}
+
+// fitBox returns the size of a w×h image scaled down to fit inside a
+// boxW×boxH box with its aspect ratio kept. Images that already fit are
+// left alone.
+func fitBox(w, h, boxW, boxH int) (int, int) {
+	if w <= boxW && h <= boxH {
+		return max(w, 1), max(h, 1)
+	}
+	if w*boxH > h*boxW {
+		return boxW, max(1, (h*boxW+w/2)/w)
+	}
+	return max(1, (w*boxH+h/2)/h), boxH
+}
+
+// thumbnailSamples is how many source pixels per axis scaleImage averages
+// for each output pixel, which keeps the cost tied to the output size.
+const thumbnailSamples = 4
+
+// scaleImage resizes src to w×h by averaging a grid of samples from the
+// area each output pixel covers. Transparent areas are drawn over white,
+// since JPEG has no alpha channel.
+func scaleImage(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			dst.SetRGBA(x, y, averageArea(src, x0, max(x1, x0+1), y0, max(y1, y0+1)))
+		}
+	}
+	return dst
+}
+
+// averageArea averages up to thumbnailSamples² pixels spread over
+// [x0,x1)×[y0,y1), composited over white.
+func averageArea(src image.Image, x0, x1, y0, y1 int) color.RGBA {
+	nx, ny := min(x1-x0, thumbnailSamples), min(y1-y0, thumbnailSamples)
+	var r, g, bl uint64
+	for j := 0; j < ny; j++ {
+		sy := y0 + (2*j+1)*(y1-y0)/(2*ny)
+		for i := 0; i < nx; i++ {
+			sx := x0 + (2*i+1)*(x1-x0)/(2*nx)
+			pr, pg, pb, pa := src.At(sx, sy).RGBA()
+			r += uint64(pr + 0xffff - pa)
+			g += uint64(pg + 0xffff - pa)
+			bl += uint64(pb + 0xffff - pa)
+		}
+	}
+	n := uint64(nx*ny) * 0x101
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), 0xff}
+}
+
+// thumbnailCache keeps encoded thumbnails up to a total size, evicting the
+// least recently used.
+type thumbnailCache struct {
+	mu      sync.Mutex
+	max     int
+	size    int
+	lru     *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+type thumbnailEntry struct {
+	key  string
+	data []byte
+}
+
+func newThumbnailCache(maxBytes int) *thumbnailCache {
+	return &thumbnailCache{max: maxBytes, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *thumbnailCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*thumbnailEntry).data, true
+}
+
+func (c *thumbnailCache) put(key string, data []byte) {
+	if len(data) > c.max {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&thumbnailEntry{key, data})
+	c.size += len(data)
+	for c.size > c.max {
+		oldest := c.lru.Remove(c.lru.Back()).(*thumbnailEntry)
+		delete(c.entries, oldest.key)
+		c.size -= len(oldest.data)
+	}
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// newThumbnailRouter serves files from a map, counting reads in *reads.
+// Each file's modification time is taken from modTimes, defaulting to a
+// fixed instant.
+func newThumbnailRouter(files map[string][]byte, modTimes map[string]time.Time, reads *int) http.Handler {
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			b, ok := files[p]
+			if !ok {
+				return nil, storage.ErrNotFound
+			}
+			mt, ok := modTimes[p]
+			if !ok {
+				mt = time.Unix(1700000000, 0)
+			}
+			return &storage.FileInfo{Name: p, Path: p, Size: int64(len(b)), ModTime: mt}, nil
+		},
+		readFn: func(_ context.Context, p string) (io.ReadCloser, error) {
+			*reads++
+			return io.NopCloser(bytes.NewReader(files[p])), nil
+		},
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	return NewRouter(store, Options{MaxUploadSize: 1 << 20}, logger)
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func getThumbnail(h http.Handler, query string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/thumbnail?"+query, nil))
+	return rr
+}
+
+func TestThumbnail_ScalesToFit(t *testing.T) {
+	var reads int
+	// half-red.png is 40×20: opaque red on the left, transparent on the right.
+	h := newThumbnailRouter(map[string][]byte{"a.png": readFixture(t, "half-red.png")}, nil, &reads)
+
+	rr := getThumbnail(h, "path=a.png&w=10&h=10")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("expected image/jpeg, got %q", ct)
+	}
+	img, err := jpeg.Decode(rr.Body)
+	if err != nil {
+		t.Fatalf("decode thumbnail: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 10 || b.Dy() != 5 {
+		t.Fatalf("expected a 10x5 thumbnail, got %dx%d", b.Dx(), b.Dy())
+	}
+	if r, g, _, _ := img.At(1, 2).RGBA(); r < 0xe000 || g > 0x2000 {
+		t.Errorf("expected the left half red, got r=%#x g=%#x", r, g)
+	}
+	if r, g, b, _ := img.At(8, 2).RGBA(); r < 0xe000 || g < 0xe000 || b < 0xe000 {
+		t.Errorf("expected the transparent half drawn over white, got %#x %#x %#x", r, g, b)
+	}
+}
+
+func TestThumbnail_NeverEnlarges(t *testing.T) {
+	var reads int
+	h := newThumbnailRouter(map[string][]byte{"a.png": readFixture(t, "half-red.png")}, nil, &reads)
+
+	rr := getThumbnail(h, "path=a.png&w=5000&h=5000")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	cfg, err := jpeg.DecodeConfig(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 40 || cfg.Height != 20 {
+		t.Errorf("expected the original 40x20, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestThumbnail_Cached(t *testing.T) {
+	var reads int
+	files := map[string][]byte{"a.png": readFixture(t, "half-red.png")}
+	modTimes := map[string]time.Time{}
+	h := newThumbnailRouter(files, modTimes, &reads)
+
+	first := getThumbnail(h, "path=a.png")
+	second := getThumbnail(h, "path=a.png")
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("expected 200s, got %d and %d", first.Code, second.Code)
+	}
+	if reads != 1 {
+		t.Errorf("expected the second request served from the cache, got %d reads", reads)
+	}
+	if !bytes.Equal(first.Body.Bytes(), second.Body.Bytes()) {
+		t.Error("expected the cached thumbnail to match")
+	}
+
+	getThumbnail(h, "path=a.png&w=20")
+	if reads != 2 {
+		t.Errorf("expected a different size to be generated, got %d reads", reads)
+	}
+
+	modTimes["a.png"] = time.Unix(1800000000, 0)
+	getThumbnail(h, "path=a.png")
+	if reads != 3 {
+		t.Errorf("expected a modified file to be read again, got %d reads", reads)
+	}
+}
+
+func TestThumbnail_Refused(t *testing.T) {
+	var reads int
+	h := newThumbnailRouter(map[string][]byte{
+		"notes.txt": []byte("just some text"),
+		"huge.png":  hugePNGHeader(t, readFixture(t, "half-red.png")),
+		"a.png":     readFixture(t, "half-red.png"),
+	}, nil, &reads)
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"not an image", "path=notes.txt", http.StatusUnsupportedMediaType},
+		{"too many pixels", "path=huge.png", http.StatusUnsupportedMediaType},
+		{"missing", "path=gone.png", http.StatusNotFound},
+		{"no path", "w=10", http.StatusBadRequest},
+		{"zero width", "path=a.png&w=0", http.StatusBadRequest},
+		{"bad height", "path=a.png&h=tall", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := getThumbnail(h, tt.query); rr.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+// hugePNGHeader rewrites the dimensions in png's IHDR chunk to 100000×100000,
+// like a decompression bomb, fixing up the chunk's CRC.
+func hugePNGHeader(t *testing.T, png []byte) []byte {
+	t.Helper()
+	b := bytes.Clone(png)
+	const ihdr = 8 // after the signature: length, type, data, CRC
+	if string(b[ihdr+4:ihdr+8]) != "IHDR" {
+		t.Fatal("fixture does not start with IHDR")
+	}
+	binary.BigEndian.PutUint32(b[ihdr+8:], 100000)
+	binary.BigEndian.PutUint32(b[ihdr+12:], 100000)
+	binary.BigEndian.PutUint32(b[ihdr+8+13:], crc32.ChecksumIEEE(b[ihdr+4:ihdr+8+13]))
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(b)); err != nil || cfg.Width != 100000 {
+		t.Fatalf("patched header does not decode: %v", err)
+	}
+	return b
+}
+
+func TestFitBox(t *testing.T) {
+	tests := []struct {
+		w, h, boxW, boxH int
+		wantW, wantH     int
+	}{
+		{40, 20, 10, 10, 10, 5},
+		{20, 40, 10, 10, 5, 10},
+		{40, 20, 100, 100, 40, 20},
+		{1000, 1, 10, 10, 10, 1},
+		{3, 2, 2, 2, 2, 1},
+	}
+	for _, tt := range tests {
+		if w, h := fitBox(tt.w, tt.h, tt.boxW, tt.boxH); w != tt.wantW || h != tt.wantH {
+			t.Errorf("fitBox(%d, %d, %d, %d) = %dx%d, want %dx%d", tt.w, tt.h, tt.boxW, tt.boxH, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestParseThumbnailDim_Clamps(t *testing.T) {
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/?w=99999", nil)
+	if n, ok := parseThumbnailDim(rr, r, "w"); !ok || n != maxThumbnailDim {
+		t.Errorf("expected %d, got %d (ok=%v)", maxThumbnailDim, n, ok)
+	}
+	if n, ok := parseThumbnailDim(rr, r, "h"); !ok || n != defaultThumbnailDim {
+		t.Errorf("expected the default %d, got %d (ok=%v)", defaultThumbnailDim, n, ok)
+	}
+	if strings.TrimSpace(rr.Body.String()) != "" {
+		t.Errorf("expected nothing written, got %q", rr.Body.String())
+	}
+}
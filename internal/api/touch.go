@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// Touch sets the modification time of a file or directory to now, or to
+// the RFC 3339 timestamp in mtime, creating an empty file if nothing exists
+// at path, subject to the upload extension allowlist. It returns the
+// updated FileInfo: 201 when the file was created, 200 otherwise.
+func (h *Handler) Touch(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+
+	var mtime time.Time
+	if raw := r.URL.Query().Get("mtime"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "mtime must be an RFC 3339 timestamp")
+			return
+		}
+		mtime = t
+	}
+
+	// Creating a file is an upload as far as the extension allowlist goes.
+	if _, err := h.store.Stat(r.Context(), p); errors.Is(err, storage.ErrNotFound) && !h.allowedExts.allows(filenameExt(p)) {
+		writeError(w, http.StatusUnsupportedMediaType, CodeUnsupportedType, "file extension is not allowed")
+		return
+	}
+
+	created, err := storage.Touch(r.Context(), h.store, p, mtime)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+
+	info, err := h.store.Stat(r.Context(), p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, info)
+}
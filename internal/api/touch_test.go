@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// touchStore adds a storage.ModTimeSetter to mockStorage and tracks which
+// paths exist.
+type touchStore struct {
+	*mockStorage
+	times map[string]time.Time
+}
+
+func (s *touchStore) SetModTime(_ context.Context, p string, t time.Time) error {
+	s.times[p] = t
+	return nil
+}
+
+func newTouchStore(existing ...string) *touchStore {
+	s := &touchStore{times: map[string]time.Time{}}
+	for _, p := range existing {
+		s.times[p] = time.Unix(0, 0)
+	}
+	s.mockStorage = &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			mt, ok := s.times[p]
+			if !ok {
+				return nil, storage.ErrNotFound
+			}
+			return &storage.FileInfo{Name: p, Path: p, ModTime: mt}, nil
+		},
+		writeFn: func(_ context.Context, p string, r io.Reader) error {
+			s.times[p] = time.Now()
+			return nil
+		},
+	}
+	return s
+}
+
+func touch(h *Handler, query string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	h.Touch(rr, httptest.NewRequest(http.MethodPut, "/api/v1/files/touch?"+query, nil))
+	return rr
+}
+
+func TestTouch_SetsSuppliedTime(t *testing.T) {
+	store := newTouchStore("a.txt")
+	h := NewHandler(store, Options{})
+
+	rr := touch(h, "path=a.txt&mtime=2023-04-05T06:07:08Z")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	want := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	var info storage.FileInfo
+	json.NewDecoder(rr.Body).Decode(&info)
+	if !info.ModTime.Equal(want) || !store.times["a.txt"].Equal(want) {
+		t.Errorf("expected ModTime %s, got %s", want, info.ModTime)
+	}
+}
+
+func TestTouch_DefaultsToNow(t *testing.T) {
+	store := newTouchStore("a.txt")
+	h := NewHandler(store, Options{})
+
+	before := time.Now()
+	if rr := touch(h, "path=a.txt"); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got := store.times["a.txt"]; got.Before(before) {
+		t.Errorf("expected the current time, got %s", got)
+	}
+}
+
+func TestTouch_CreatesMissingFile(t *testing.T) {
+	store := newTouchStore()
+	h := NewHandler(store, Options{})
+
+	if rr := touch(h, "path=new.txt"); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rr.Code)
+	}
+	if _, ok := store.times["new.txt"]; !ok {
+		t.Error("expected new.txt to be created")
+	}
+}
+
+func TestTouch_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		h      *Handler
+		query  string
+		status int
+	}{
+		{"missing path", NewHandler(newTouchStore(), Options{}), "", http.StatusBadRequest},
+		{"bad mtime", NewHandler(newTouchStore("a.txt"), Options{}), "path=a.txt&mtime=yesterday", http.StatusBadRequest},
+		{"extension not allowed", NewHandler(newTouchStore(), Options{AllowedExtensions: []string{".pdf"}}), "path=new.txt", http.StatusUnsupportedMediaType},
+		{"backend cannot set times", newTestHandler(&mockStorage{}), "path=a.txt", http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := touch(tt.h, tt.query); rr.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// trashDir holds soft-deleted files at their original relative paths, each
+// with trashSep and a deletion timestamp appended to its name.
+const (
+	trashDir        = ".trash"
+	trashSep        = "~"
+	trashTimeFormat = "20060102T150405.000000000Z"
+)
+
+// trashPath returns where p is kept when soft-deleted at t.
+func trashPath(p string, t time.Time) string {
+	return path.Join("/", trashDir, path.Clean("/"+p)) + trashSep + t.UTC().Format(trashTimeFormat)
+}
+
+// inTrash reports whether p lies inside the trash area.
+func inTrash(p string) bool {
+	clean := strings.TrimPrefix(path.Clean("/"+p), "/")
+	return clean == trashDir || strings.HasPrefix(clean, trashDir+"/")
+}
+
+// errTrashDirectory refuses a soft delete of a directory on a backend
+// that cannot move one, rather than deleting it for good.
+var errTrashDirectory = errors.New("this backend cannot move a directory to the trash; pass purge=true to delete it permanently")
+
+// moveToTrash soft-deletes the file or directory at p. A directory moves
+// whole, so it needs recursive=true unless it is empty, and a backend that
+// cannot rename directories gets errTrashDirectory. Anything already in
+// the trash is removed for good, as by remove.
+func (h *Handler) moveToTrash(ctx context.Context, p string, recursive bool) error {
+	if inTrash(p) {
+		return h.remove(ctx, p, recursive)
+	}
+	info, err := h.store.Stat(ctx, p)
+	if err != nil {
+		return err
+	}
+	if info.IsDir && !recursive {
+		entries, err := h.store.List(ctx, p)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return storage.ErrNotEmpty
+		}
+	}
+	err = storage.Rename(ctx, h.store, p, trashPath(p, time.Now()))
+	if info.IsDir && (errors.Is(err, storage.ErrNotSupported) || errors.Is(err, storage.ErrIsDirectory)) {
+		return errTrashDirectory
+	}
+	return err
+}
+
+// latestTrashed returns the trash path of the most recent soft delete of p,
+// or storage.ErrNotFound if p has none.
+func (h *Handler) latestTrashed(ctx context.Context, p string) (string, error) {
+	clean := path.Clean("/" + p)
+	dir := path.Join("/", trashDir, path.Dir(clean))
+	base := path.Base(clean)
+
+	files, err := h.store.List(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+
+	var latest string
+	var latestAt time.Time
+	for _, f := range files {
+		i := strings.LastIndex(f.Name, trashSep)
+		if i < 0 || f.Name[:i] != base {
+			continue
+		}
+		at, err := time.Parse(trashTimeFormat, f.Name[i+len(trashSep):])
+		if err != nil {
+			continue
+		}
+		if latest == "" || at.After(latestAt) {
+			latest, latestAt = path.Join(dir, f.Name), at
+		}
+	}
+	if latest == "" {
+		return "", storage.ErrNotFound
+	}
+	return latest, nil
+}
+
+// Restore moves the most recently soft-deleted version of path, a file or
+// a whole directory, back into place. It answers 404 if path has nothing in the trash and 409 if
+// something already occupies path, unless overwrite=true.
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+	if inTrash(p) {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path must be the file's original location, not its trash entry")
+		return
+	}
+
+	overwrite, ok := parseOverwrite(w, r)
+	if !ok {
+		return
+	}
+
+	src, err := h.latestTrashed(r.Context(), p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+
+	if !overwrite {
+		_, err := h.store.Stat(r.Context(), p)
+		switch {
+		case err == nil:
+			handleStorageError(w, storage.ErrExist)
+			return
+		case !errors.Is(err, storage.ErrNotFound):
+			handleStorageError(w, err)
+			return
+		}
+	}
+
+	if err := storage.Rename(r.Context(), h.store, src, p); err != nil {
+		handleStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SuccessResponse{Message: "file restored"})
+}
+
+// withoutTrash drops the trash directory, and anything listed inside it,
+// from a root listing.
+func withoutTrash(files []storage.FileInfo) []storage.FileInfo {
+	kept := files[:0]
+	for _, f := range files {
+		if !isTrashEntry(f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// isTrashEntry reports whether a root listing entry is the trash directory
+// or something inside it.
+func isTrashEntry(f storage.FileInfo) bool {
+	return (f.IsDir && f.Name == trashDir) || inTrash(f.Path)
+}
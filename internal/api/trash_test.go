@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// trashStoreModTime is the modification time of newTrashStore's file.
+var trashStoreModTime = time.Date(2024, 5, 1, 10, 0, 0, 500_000_000, time.UTC)
+
+// newTrashStore returns a mock holding a single file at /docs/a.txt whose
+// writes and deletes are recorded.
+func newTrashStore(written map[string]string, deleted *[]string) *mockStorage {
+	return &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			if p == "/docs/a.txt" {
+				return &storage.FileInfo{Name: "a.txt", Path: "docs/a.txt", Size: 5, ModTime: trashStoreModTime}, nil
+			}
+			return nil, storage.ErrNotFound
+		},
+		readFn: func(_ context.Context, _ string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("hello")), nil
+		},
+		writeFn: func(_ context.Context, p string, r io.Reader) error {
+			data, _ := io.ReadAll(r)
+			written[p] = string(data)
+			return nil
+		},
+		deleteFn: func(_ context.Context, p string) error {
+			*deleted = append(*deleted, p)
+			return nil
+		},
+	}
+}
+
+func TestTrashPath(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 45, 123, time.UTC)
+	got := trashPath("docs/a.txt", at)
+	want := "/.trash/docs/a.txt~20240301T123045.000000123Z"
+	if got != want {
+		t.Errorf("trashPath = %q, want %q", got, want)
+	}
+	if !inTrash(got) || inTrash("/docs/.trash/a.txt") || inTrash("/.trashy") {
+		t.Error("inTrash misclassified a path")
+	}
+}
+
+func TestDelete_Soft(t *testing.T) {
+	written := map[string]string{}
+	var deleted []string
+	h := newTestHandler(newTrashStore(written, &deleted))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=/docs/a.txt&soft=true", nil)
+	rr := httptest.NewRecorder()
+	h.Delete(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(written) != 1 {
+		t.Fatalf("expected one trash write, got %v", written)
+	}
+	for p, data := range written {
+		if !strings.HasPrefix(p, "/.trash/docs/a.txt~") || data != "hello" {
+			t.Errorf("unexpected trash write %q = %q", p, data)
+		}
+	}
+	if len(deleted) != 1 || deleted[0] != "/docs/a.txt" {
+		t.Errorf("expected original deleted, got %v", deleted)
+	}
+}
+
+func TestDelete_PurgeOverridesSoftDefault(t *testing.T) {
+	written := map[string]string{}
+	var deleted []string
+	h := NewHandler(newTrashStore(written, &deleted), Options{SoftDelete: true})
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=/docs/a.txt&purge=true", nil)
+	rr := httptest.NewRecorder()
+	h.Delete(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(written) != 0 || len(deleted) != 1 {
+		t.Errorf("expected a permanent delete only, got writes %v deletes %v", written, deleted)
+	}
+}
+
+func TestDelete_SoftAndPurge(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files?path=/docs/a.txt&soft=true&purge=true", nil)
+	rr := httptest.NewRecorder()
+	h.Delete(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestRestore_PicksLatest(t *testing.T) {
+	written := map[string]string{}
+	var deleted []string
+	store := newTrashStore(written, &deleted)
+	store.statFn = func(_ context.Context, _ string) (*storage.FileInfo, error) {
+		return nil, storage.ErrNotFound
+	}
+	store.listFn = func(_ context.Context, p string) ([]storage.FileInfo, error) {
+		if p != "/.trash/docs" {
+			return nil, storage.ErrNotFound
+		}
+		return []storage.FileInfo{
+			{Name: "a.txt~20240101T000000.000000000Z"},
+			{Name: "a.txt~20240301T000000.000000000Z"},
+			{Name: "a.txt~garbage"},
+			{Name: "ab.txt~20250101T000000.000000000Z"},
+		}, nil
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/restore?path=/docs/a.txt", nil)
+	rr := httptest.NewRecorder()
+	h.Restore(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if written["/docs/a.txt"] != "hello" {
+		t.Errorf("expected /docs/a.txt restored, got %v", written)
+	}
+	if len(deleted) != 1 || deleted[0] != "/.trash/docs/a.txt~20240301T000000.000000000Z" {
+		t.Errorf("expected newest trash entry removed, got %v", deleted)
+	}
+}
+
+func TestRestore_Conflict(t *testing.T) {
+	written := map[string]string{}
+	var deleted []string
+	store := newTrashStore(written, &deleted)
+	store.listFn = func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+		return []storage.FileInfo{{Name: "a.txt~20240101T000000.000000000Z"}}, nil
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/restore?path=/docs/a.txt", nil)
+	rr := httptest.NewRecorder()
+	h.Restore(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(written) != 0 {
+		t.Errorf("expected nothing written, got %v", written)
+	}
+}
+
+func TestList_HideTrash(t *testing.T) {
+	store := &mockStorage{
+		listFn: func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+			return []storage.FileInfo{
+				{Name: ".trash", IsDir: true},
+				{Name: "docs", IsDir: true},
+			}, nil
+		},
+	}
+	h := newTestHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files?path=/&hideTrash=true", nil)
+	rr := httptest.NewRecorder()
+	h.List(rr, req)
+
+	var files []storage.FileInfo
+	json.NewDecoder(rr.Body).Decode(&files)
+	if len(files) != 1 || files[0].Name != "docs" {
+		t.Errorf("expected only docs, got %+v", files)
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-storage-api/internal/storage"
+)
+
+// Tree expands maxTreeDepth levels below the first when depth is absent or
+// larger, and refuses subtrees with more than maxTreeEntries entries.
+const (
+	maxTreeDepth   = 10
+	maxTreeEntries = 10000
+)
+
+// errTreeTooLarge means a tree walk passed maxTreeEntries.
+var errTreeTooLarge = fmt.Errorf("tree has more than %d entries; ask for a smaller depth or a deeper path", maxTreeEntries)
+
+// treeNode is one entry in a Tree response. Children is absent for files
+// and for directories below the requested depth, and empty for empty
+// directories.
+type treeNode struct {
+	Name     string      `json:"name"`
+	IsDir    bool        `json:"is_dir"`
+	Size     int64       `json:"size"`
+	Children *[]treeNode `json:"children,omitempty"`
+}
+
+// Tree returns the subtree at path as nested JSON. depth=0 lists only its
+// immediate children, and each extra level expands the directories one
+// step further. The trash is skipped unless path is inside it. A client
+// disconnect cancels the walk.
+func (h *Handler) Tree(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		p = "/"
+	}
+
+	depth := maxTreeDepth
+	if raw := r.URL.Query().Get("depth"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "depth must be a non-negative integer")
+			return
+		}
+		depth = min(n, maxTreeDepth)
+	}
+
+	info, err := h.store.Stat(r.Context(), p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	root := treeNode{Name: info.Name, IsDir: info.IsDir, Size: info.Size}
+	if path.Clean("/"+p) == "/" {
+		root.Name = "/"
+	}
+	if info.IsDir {
+		b := &treeBuilder{store: h.store, skipTrash: !inTrash(p)}
+		children, err := b.build(r.Context(), p, depth)
+		if errors.Is(err, errTreeTooLarge) {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+		if err != nil {
+			handleStorageError(w, err)
+			return
+		}
+		root.Children = &children
+	}
+
+	writeJSON(w, http.StatusOK, root)
+}
+
+// treeBuilder lists a subtree depth first, counting entries so a huge tree
+// fails early instead of being held in memory.
+type treeBuilder struct {
+	store     storage.Storage
+	skipTrash bool
+	entries   int
+}
+
+// build returns the entries of dir sorted by name, expanding directories
+// depth more levels.
+func (b *treeBuilder) build(ctx context.Context, dir string, depth int) ([]treeNode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	files, err := b.store.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(files, func(a, b storage.FileInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	nodes := make([]treeNode, 0, len(files))
+	for _, f := range files {
+		if b.skipTrash && isTrashEntry(f) {
+			continue
+		}
+		if b.entries++; b.entries > maxTreeEntries {
+			return nil, errTreeTooLarge
+		}
+		node := treeNode{Name: f.Name, IsDir: f.IsDir, Size: f.Size}
+		if f.IsDir && depth > 0 {
+			children, err := b.build(ctx, f.Path, depth-1)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = &children
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// newTreeHandler serves the search fixture tree, with every path that Stat
+// is asked about reported as a directory.
+func newTreeHandler(paths ...string) *Handler {
+	store := newSearchStore(paths...)
+	store.statFn = func(_ context.Context, p string) (*storage.FileInfo, error) {
+		return &storage.FileInfo{Name: path.Base(p), Path: p, IsDir: true}, nil
+	}
+	return NewHandler(store, Options{})
+}
+
+func getTree(t *testing.T, h *Handler, target string) treeNode {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.Tree(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("%s: expected 200, got %d: %s", target, rr.Code, rr.Body.String())
+	}
+	var root treeNode
+	if err := json.NewDecoder(rr.Body).Decode(&root); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// names returns the names of n's children, or nil when n has none listed.
+func names(n treeNode) []string {
+	if n.Children == nil {
+		return nil
+	}
+	out := []string{}
+	for _, c := range *n.Children {
+		out = append(out, c.Name)
+	}
+	return out
+}
+
+func TestTree_TwoLevels(t *testing.T) {
+	h := newTreeHandler("docs/b.txt", "docs/a.txt", "docs/sub/c.txt", "top.txt", ".trash/old.txt")
+
+	root := getTree(t, h, "/api/v1/files/tree?path=/&depth=1")
+	if root.Name != "/" || !root.IsDir {
+		t.Errorf("unexpected root node %+v", root)
+	}
+	if got := names(root); len(got) != 2 || got[0] != "docs" || got[1] != "top.txt" {
+		t.Fatalf("expected [docs top.txt] with the trash skipped, got %v", got)
+	}
+	docs, top := (*root.Children)[0], (*root.Children)[1]
+	if top.IsDir || top.Children != nil {
+		t.Errorf("expected a file node without children, got %+v", top)
+	}
+	if got := names(docs); len(got) != 3 || got[0] != "a.txt" || got[1] != "b.txt" || got[2] != "sub" {
+		t.Fatalf("expected docs' children sorted by name, got %v", got)
+	}
+	if sub := (*docs.Children)[2]; !sub.IsDir || sub.Children != nil {
+		t.Errorf("expected sub listed but not expanded at depth 1, got %+v", sub)
+	}
+
+	root = getTree(t, h, "/api/v1/files/tree?path=/")
+	sub := (*(*root.Children)[0].Children)[2]
+	if got := names(sub); len(got) != 1 || got[0] != "c.txt" {
+		t.Errorf("expected the default depth to reach docs/sub, got %v", got)
+	}
+}
+
+func TestTree_DepthZeroListsChildrenOnly(t *testing.T) {
+	h := newTreeHandler("docs/a.txt", "top.txt")
+
+	root := getTree(t, h, "/api/v1/files/tree?path=/&depth=0")
+	if got := names(root); len(got) != 2 {
+		t.Fatalf("expected the two immediate children, got %v", got)
+	}
+	if docs := (*root.Children)[0]; docs.Children != nil {
+		t.Errorf("expected docs unexpanded at depth 0, got %+v", docs)
+	}
+}
+
+func TestTree_EmptyDirectoryHasEmptyChildren(t *testing.T) {
+	h := NewHandler(&mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "empty", Path: p, IsDir: true}, nil
+		},
+		listFn: func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+			return nil, nil
+		},
+	}, Options{})
+
+	rr := httptest.NewRecorder()
+	h.Tree(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/tree?path=/empty", nil))
+	if got := rr.Body.String(); got != `{"name":"empty","is_dir":true,"size":0,"children":[]}`+"\n" {
+		t.Errorf("unexpected body %s", got)
+	}
+}
+
+func TestTree_Errors(t *testing.T) {
+	h := newTreeHandler("a.txt")
+	for _, target := range []string{"/api/v1/files/tree?depth=-1", "/api/v1/files/tree?depth=deep"} {
+		rr := httptest.NewRecorder()
+		h.Tree(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rr.Code)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rr := httptest.NewRecorder()
+	h.Tree(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/tree", nil).WithContext(ctx))
+	if rr.Code == http.StatusOK {
+		t.Error("expected a canceled request to stop the walk")
+	}
+}
+
+func TestTree_TooManyEntries(t *testing.T) {
+	files := make([]storage.FileInfo, maxTreeEntries+1)
+	for i := range files {
+		files[i] = storage.FileInfo{Name: "f", Path: "f"}
+	}
+	h := NewHandler(&mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			return &storage.FileInfo{Name: "/", Path: p, IsDir: true}, nil
+		},
+		listFn: func(_ context.Context, _ string) ([]storage.FileInfo, error) {
+			return files, nil
+		},
+	}, Options{})
+
+	rr := httptest.NewRecorder()
+	h.Tree(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/tree", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a tree past the entry limit, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,297 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/storage"
+)
+
+// Resumable uploads follow the tus 1.0.0 protocol (https://tus.io) with the
+// creation, expiration and termination extensions.
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,expiration,termination"
+	tusContentType = "application/offset+octet-stream"
+
+	// uploadsPath is where uploads are created; each one lives at
+	// uploadsPath/<id>.
+	uploadsPath = "/api/v1/uploads"
+
+	// defaultUploadExpiry is how long an unfinished upload is kept after
+	// its last chunk when Options.ResumableUploadExpiry is zero.
+	defaultUploadExpiry = 24 * time.Hour
+)
+
+// UploadOptions advertises the tus version, extensions and maximum size.
+func (h *Handler) UploadOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.maxUploadSize, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateUpload starts a resumable upload of Upload-Length bytes to the path
+// query parameter, or to the "path" key of Upload-Metadata. It answers 201
+// with the upload's URL in Location. overwrite=true allows replacing an
+// existing file when the upload completes.
+func (h *Handler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Upload-Length must be a non-negative integer; Upload-Defer-Length is not supported")
+		return
+	}
+	if length > h.maxUploadSize {
+		writeTooLarge(w, &http.MaxBytesError{Limit: h.maxUploadSize})
+		return
+	}
+	meta, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		p = meta["path"]
+	}
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter or Upload-Metadata path is required")
+		return
+	}
+	p, err = middleware.CleanPath(p, h.pathLimits)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, pathErrorCode(err), err.Error())
+		return
+	}
+	overwrite, ok := parseOverwrite(w, r)
+	if !ok {
+		return
+	}
+	if !h.checkAllowed(w, p, meta["filetype"]) {
+		return
+	}
+
+	// Refuse up front rather than after the whole file has been sent.
+	info, err := h.store.Stat(r.Context(), p)
+	switch {
+	case err == nil && info.IsDir:
+		writeError(w, http.StatusBadRequest, CodeIsDirectory, "path is a directory")
+		return
+	case err == nil && !overwrite:
+		handleStorageError(w, storage.ErrExist)
+		return
+	case err != nil && !errors.Is(err, storage.ErrNotFound):
+		handleStorageError(w, err)
+		return
+	}
+
+	u := &tusUpload{Path: p, Length: length, Overwrite: overwrite, Owner: requestSubject(r)}
+	if err := h.uploads.create(u); err != nil {
+		handleStorageError(w, err)
+		return
+	}
+
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Location", uploadsPath+"/"+u.ID)
+	w.Header().Set("Upload-Expires", u.Expires.Format(http.TimeFormat))
+	if length == 0 {
+		if !h.finishUpload(w, r, u) {
+			return
+		}
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// UploadStatus reports how many bytes of an upload have been received in
+// Upload-Offset, so an interrupted client knows where to resume.
+func (h *Handler) UploadStatus(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	u, ok := h.loadUpload(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.Header().Set("Upload-Expires", u.Expires.Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+// AppendUpload adds the request body to an upload at Upload-Offset, which
+// must match the bytes received so far. Bytes received before a dropped
+// connection are kept. Once all Upload-Length bytes are in, the file is
+// written to storage and the staged copy removed.
+func (h *Handler) AppendUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != tusContentType {
+		writeError(w, http.StatusUnsupportedMediaType, CodeUnsupportedType, "Content-Type must be "+tusContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Upload-Offset must be a non-negative integer")
+		return
+	}
+
+	unlock, ok := h.lockUpload(w, r)
+	if !ok {
+		return
+	}
+	defer unlock()
+	u, ok := h.loadUpload(w, r)
+	if !ok {
+		return
+	}
+	if offset != u.Offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+		writeError(w, http.StatusConflict, CodeConflict, fmt.Sprintf("Upload-Offset %d does not match the %d bytes received", offset, u.Offset))
+		return
+	}
+	remaining := u.Length - u.Offset
+	if r.ContentLength > remaining {
+		writeTooLarge(w, &http.MaxBytesError{Limit: remaining})
+		return
+	}
+	done, ok := h.acquireUpload(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	n, err := h.uploads.append(u.ID, http.MaxBytesReader(w, r.Body, remaining))
+	u.Offset += n
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	if err != nil {
+		if writeTooLarge(w, err) {
+			return
+		}
+		handleStorageError(w, err)
+		return
+	}
+	if u.Offset == u.Length && !h.finishUpload(w, r, u) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TerminateUpload discards an unfinished upload.
+func (h *Handler) TerminateUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	unlock, ok := h.lockUpload(w, r)
+	if !ok {
+		return
+	}
+	defer unlock()
+	u, ok := h.loadUpload(w, r)
+	if !ok {
+		return
+	}
+
+	h.uploads.remove(u.ID)
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// finishUpload writes a complete upload to storage and removes the staged
+// copy. On failure the staged copy is kept, so an empty PATCH at the final
+// offset retries, unless the scanner rejected it. ok is false if an error was written.
+func (h *Handler) finishUpload(w http.ResponseWriter, r *http.Request, u *tusUpload) (ok bool) {
+	f, err := h.uploads.open(u.ID)
+	if err != nil {
+		handleStorageError(w, err)
+		return false
+	}
+	err = h.write(r.Context(), u.Path, f, u.Overwrite)
+	f.Close()
+	if errors.Is(err, ErrUploadRejected) {
+		// Retrying would be rejected again.
+		h.uploads.remove(u.ID)
+	}
+	if err != nil {
+		handleStorageError(w, err)
+		return false
+	}
+	h.uploads.remove(u.ID)
+	return true
+}
+
+// loadUpload returns the upload named by the id path value. Uploads that
+// expired or belong to another user get 404.
+func (h *Handler) loadUpload(w http.ResponseWriter, r *http.Request) (*tusUpload, bool) {
+	u, err := h.uploads.get(r.PathValue("id"))
+	if err == nil && u.Owner != requestSubject(r) {
+		err = errUploadNotFound
+	}
+	if errors.Is(err, errUploadNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		handleStorageError(w, err)
+		return nil, false
+	}
+	return u, true
+}
+
+// lockUpload keeps concurrent requests from writing the same upload,
+// answering 423 if another one holds it.
+func (h *Handler) lockUpload(w http.ResponseWriter, r *http.Request) (unlock func(), ok bool) {
+	unlock, ok = h.uploads.lock(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusLocked, CodeConflict, "upload is in use by another request")
+	}
+	return unlock, ok
+}
+
+// checkTusResumable answers 412 with the supported version unless the
+// request speaks tus 1.0.0.
+func checkTusResumable(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Tus-Resumable") == tusVersion {
+		return true
+	}
+	w.Header().Set("Tus-Version", tusVersion)
+	writeError(w, http.StatusPreconditionFailed, CodePreconditionFailed, "Tus-Resumable must be "+tusVersion)
+	return false
+}
+
+// parseUploadMetadata decodes an Upload-Metadata header: comma-separated
+// pairs of a key and an optional base64 value, separated by a space.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata: empty key")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata: value of %q is not base64", key)
+		}
+		meta[key] = string(decoded)
+	}
+	return meta, nil
+}
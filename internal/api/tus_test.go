@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// newTusRouter returns a router with resumable uploads staged in dir over a
+// store that records completed files in files.
+func newTusRouter(t *testing.T, dir string, files map[string]string) http.Handler {
+	t.Helper()
+	store := &mockStorage{
+		statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+			if _, ok := files[p]; !ok {
+				return nil, storage.ErrNotFound
+			}
+			return &storage.FileInfo{Name: p, Path: p}, nil
+		},
+		writeFn: func(_ context.Context, p string, r io.Reader) error {
+			b, err := io.ReadAll(r)
+			files[p] = string(b)
+			return err
+		},
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	return NewRouter(store, Options{MaxUploadSize: 100, ResumableUploadDir: dir}, logger)
+}
+
+func tusRequest(h http.Handler, method, url string, body io.Reader, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, body)
+	req.Header.Set("Tus-Resumable", tusVersion)
+	if method == http.MethodPatch {
+		req.Header.Set("Content-Type", tusContentType)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func createUpload(t *testing.T, h http.Handler, path string, length int) string {
+	t.Helper()
+	rr := tusRequest(h, http.MethodPost, uploadsPath+"?path="+path, nil, map[string]string{"Upload-Length": strconv.Itoa(length)})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	loc := rr.Header().Get("Location")
+	if !strings.HasPrefix(loc, uploadsPath+"/") {
+		t.Fatalf("expected a Location under %s, got %q", uploadsPath, loc)
+	}
+	return loc
+}
+
+func patchUpload(h http.Handler, loc string, offset int, body io.Reader) *httptest.ResponseRecorder {
+	return tusRequest(h, http.MethodPatch, loc, body, map[string]string{"Upload-Offset": strconv.Itoa(offset)})
+}
+
+// failingReader returns err after its data has been read, like a body
+// whose connection dropped.
+type failingReader struct {
+	data io.Reader
+	err  error
+}
+
+func (f failingReader) Read(b []byte) (int, error) {
+	n, err := f.data.Read(b)
+	if err == io.EOF {
+		return n, f.err
+	}
+	return n, err
+}
+
+func TestTus_InterruptedUploadResumes(t *testing.T) {
+	files := map[string]string{}
+	h := newTusRouter(t, t.TempDir(), files)
+	content := "hello, resumable world"
+	loc := createUpload(t, h, "docs/a.txt", len(content))
+
+	// The first chunk is cut off after 7 bytes.
+	cut := failingReader{data: strings.NewReader(content[:7]), err: io.ErrUnexpectedEOF}
+	if rr := patchUpload(h, loc, 0, cut); rr.Code < 400 {
+		t.Fatalf("expected the interrupted PATCH to fail, got %d", rr.Code)
+	}
+	if _, ok := files["docs/a.txt"]; ok {
+		t.Fatal("file written before the upload finished")
+	}
+
+	rr := tusRequest(h, http.MethodHead, loc, nil, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("HEAD: expected 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Upload-Offset"); got != "7" {
+		t.Fatalf("expected Upload-Offset 7 after the interruption, got %q", got)
+	}
+	if got := rr.Header().Get("Upload-Length"); got != strconv.Itoa(len(content)) {
+		t.Errorf("expected Upload-Length %d, got %q", len(content), got)
+	}
+
+	// Resuming from the wrong offset is refused.
+	if rr := patchUpload(h, loc, 0, strings.NewReader(content)); rr.Code != http.StatusConflict {
+		t.Errorf("stale offset: expected 409, got %d", rr.Code)
+	}
+
+	rr = patchUpload(h, loc, 7, strings.NewReader(content[7:]))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("resume: expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Upload-Offset"); got != strconv.Itoa(len(content)) {
+		t.Errorf("expected final Upload-Offset %d, got %q", len(content), got)
+	}
+	if files["docs/a.txt"] != content {
+		t.Errorf("expected %q stored, got %q", content, files["docs/a.txt"])
+	}
+	if rr := tusRequest(h, http.MethodHead, loc, nil, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("expected the finished upload to be gone, got %d", rr.Code)
+	}
+}
+
+func TestTus_CreateValidation(t *testing.T) {
+	files := map[string]string{"taken.txt": "x"}
+	h := newTusRouter(t, t.TempDir(), files)
+
+	tests := []struct {
+		name    string
+		url     string
+		headers map[string]string
+		want    int
+	}{
+		{"missing length", uploadsPath + "?path=a.txt", nil, http.StatusBadRequest},
+		{"too large", uploadsPath + "?path=a.txt", map[string]string{"Upload-Length": "101"}, http.StatusRequestEntityTooLarge},
+		{"no path", uploadsPath, map[string]string{"Upload-Length": "1"}, http.StatusBadRequest},
+		{"existing file", uploadsPath + "?path=taken.txt", map[string]string{"Upload-Length": "1"}, http.StatusConflict},
+		{"old protocol", uploadsPath + "?path=a.txt", map[string]string{"Upload-Length": "1", "Tus-Resumable": "0.2.2"}, http.StatusPreconditionFailed},
+		{"path in metadata", uploadsPath, map[string]string{"Upload-Length": "1", "Upload-Metadata": "path ZG9jcy9iLnR4dA=="}, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := tusRequest(h, http.MethodPost, tt.url, nil, tt.headers)
+			if rr.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestTus_PatchValidation(t *testing.T) {
+	h := newTusRouter(t, t.TempDir(), map[string]string{})
+	loc := createUpload(t, h, "a.txt", 4)
+
+	rr := tusRequest(h, http.MethodPatch, loc, strings.NewReader("abcd"), map[string]string{"Upload-Offset": "0", "Content-Type": "text/plain"})
+	if rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("wrong content type: expected 415, got %d", rr.Code)
+	}
+	if rr := patchUpload(h, loc, 0, strings.NewReader("abcdef")); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("past Upload-Length: expected 413, got %d", rr.Code)
+	}
+	if rr := patchUpload(h, uploadsPath+"/0123456789abcdef0123456789abcdef", 0, strings.NewReader("a")); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown upload: expected 404, got %d", rr.Code)
+	}
+	if rr := tusRequest(h, http.MethodDelete, loc, nil, nil); rr.Code != http.StatusNoContent {
+		t.Errorf("terminate: expected 204, got %d", rr.Code)
+	}
+	if rr := tusRequest(h, http.MethodHead, loc, nil, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("expected the terminated upload to be gone, got %d", rr.Code)
+	}
+}
+
+func TestTus_EmptyUploadFinishesOnCreate(t *testing.T) {
+	files := map[string]string{}
+	h := newTusRouter(t, t.TempDir(), files)
+
+	createUpload(t, h, "empty.txt", 0)
+	if content, ok := files["empty.txt"]; !ok || content != "" {
+		t.Errorf("expected an empty file, got %q (exists=%v)", content, ok)
+	}
+}
+
+func TestTus_StaleUploadsExpire(t *testing.T) {
+	dir := t.TempDir()
+	h := newTusRouter(t, dir, map[string]string{})
+	stale := createUpload(t, h, "stale.txt", 10)
+	patchUpload(h, stale, 0, strings.NewReader("abc"))
+
+	id := strings.TrimPrefix(stale, uploadsPath+"/")
+	old := time.Now().Add(-2 * defaultUploadExpiry)
+	if err := os.Chtimes(filepath.Join(dir, id+".part"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if rr := tusRequest(h, http.MethodHead, stale, nil, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("expected an expired upload to be gone, got %d", rr.Code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, id+".info")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the expired upload's files removed, stat err = %v", err)
+	}
+}
+
+func TestTusStore_SweepRemovesExpired(t *testing.T) {
+	dir := t.TempDir()
+	s := newTusStore(dir, time.Hour)
+	stale := &tusUpload{Path: "/a.txt", Length: 1}
+	if err := s.create(stale); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(s.partPath(stale.ID), old, old)
+
+	s.lastSweep = time.Time{}
+	if err := s.create(&tusUpload{Path: "/b.txt", Length: 1}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected only the new upload's two files, got %d entries", len(entries))
+	}
+}
+
+func TestTus_Options(t *testing.T) {
+	h := newTusRouter(t, t.TempDir(), map[string]string{})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodOptions, uploadsPath, nil))
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Tus-Version"); got != tusVersion {
+		t.Errorf("expected Tus-Version %s, got %q", tusVersion, got)
+	}
+	if got := rr.Header().Get("Tus-Max-Size"); got != "100" {
+		t.Errorf("expected Tus-Max-Size 100, got %q", got)
+	}
+}
+
+func TestTus_DisabledWithoutDir(t *testing.T) {
+	h := newTestRouter()
+	req := httptest.NewRequest(http.MethodPost, uploadsPath, bytes.NewReader(nil))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a staging dir, got %d", rr.Code)
+	}
+}
+
+func TestParseUploadMetadata(t *testing.T) {
+	got, err := parseUploadMetadata("path ZG9jcy9hLnR4dA==, filetype dGV4dC9wbGFpbg==,flag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"path": "docs/a.txt", "filetype": "text/plain", "flag": ""}
+	if !maps.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if _, err := parseUploadMetadata("path not*base64"); err == nil {
+		t.Error("expected an error for a value that is not base64")
+	}
+}
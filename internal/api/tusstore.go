@@ -0,0 +1,211 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tusSweepInterval is how often creating an upload also removes expired
+// ones from the staging directory.
+const tusSweepInterval = time.Minute
+
+// errUploadNotFound means no unexpired upload has the given id.
+var errUploadNotFound = errors.New("upload not found")
+
+// tusUpload describes a resumable upload. It is stored as <id>.info next to
+// the bytes received so far in <id>.part, so uploads survive restarts.
+type tusUpload struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	Length    int64     `json:"length"`
+	Overwrite bool      `json:"overwrite"`
+	Owner     string    `json:"owner"`
+	Created   time.Time `json:"created"`
+
+	// Offset and Expires are derived from the .part file when loaded.
+	Offset  int64     `json:"-"`
+	Expires time.Time `json:"-"`
+}
+
+// tusStore stages partial uploads on local disk until they are complete.
+// An upload expires ttl after its last chunk arrived.
+type tusStore struct {
+	dir string
+	ttl time.Duration
+
+	mu        sync.Mutex
+	busy      map[string]bool
+	lastSweep time.Time
+}
+
+// newTusStore returns a store staging uploads in dir, or nil if dir is
+// empty.
+func newTusStore(dir string, ttl time.Duration) *tusStore {
+	if dir == "" {
+		return nil
+	}
+	return &tusStore{dir: dir, ttl: ttl, busy: make(map[string]bool)}
+}
+
+func (s *tusStore) infoPath(id string) string { return filepath.Join(s.dir, id+".info") }
+func (s *tusStore) partPath(id string) string { return filepath.Join(s.dir, id+".part") }
+
+// newTusID returns a random 128-bit upload id.
+func newTusID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// validTusID reports whether id looks like one newTusID made, so it is safe
+// to use as a file name.
+func validTusID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// create assigns u an id and stages it with no bytes received.
+func (s *tusStore) create(u *tusUpload) error {
+	s.sweep()
+	id, err := newTusID()
+	if err != nil {
+		return err
+	}
+	u.ID = id
+	u.Created = time.Now().UTC()
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.partPath(id), nil, 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.infoPath(id), data, 0o600); err != nil {
+		os.Remove(s.partPath(id))
+		return err
+	}
+	u.Expires = u.Created.Add(s.ttl)
+	return nil
+}
+
+// get loads the upload with id, removing it and returning errUploadNotFound
+// if it has expired.
+func (s *tusStore) get(id string) (*tusUpload, error) {
+	if !validTusID(id) {
+		return nil, errUploadNotFound
+	}
+	data, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	u := &tusUpload{}
+	if err := json.Unmarshal(data, u); err != nil {
+		return nil, err
+	}
+	part, err := os.Stat(s.partPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		s.remove(id)
+		return nil, errUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.Offset = part.Size()
+	u.Expires = part.ModTime().Add(s.ttl)
+	if time.Now().After(u.Expires) {
+		s.remove(id)
+		return nil, errUploadNotFound
+	}
+	return u, nil
+}
+
+// lock marks id as in use by one request. It returns false if another
+// request already holds it.
+func (s *tusStore) lock(id string) (unlock func(), ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.busy[id] {
+		return nil, false
+	}
+	s.busy[id] = true
+	return func() {
+		s.mu.Lock()
+		delete(s.busy, id)
+		s.mu.Unlock()
+	}, true
+}
+
+// append adds r to the bytes received for id. Bytes read before an error
+// are kept, so the client can resume from the new offset.
+func (s *tusStore) append(id string, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.partPath(id), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// open returns the bytes received for id.
+func (s *tusStore) open(id string) (*os.File, error) {
+	return os.Open(s.partPath(id))
+}
+
+// remove deletes the staged upload id.
+func (s *tusStore) remove(id string) {
+	os.Remove(s.infoPath(id))
+	os.Remove(s.partPath(id))
+}
+
+// sweep removes expired uploads, at most once per tusSweepInterval.
+// Uploads in use by a request are left alone.
+func (s *tusStore) sweep() {
+	s.mu.Lock()
+	if time.Since(s.lastSweep) < tusSweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = time.Now()
+	s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".info")
+		if !ok || !validTusID(id) {
+			continue
+		}
+		unlock, ok := s.lock(id)
+		if !ok {
+			continue
+		}
+		s.get(id) // removes the upload if it has expired
+		unlock()
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// errUploadsBusy means no upload slot freed up within the wait timeout.
+var errUploadsBusy = errors.New("too many uploads in progress")
+
+// uploadSlots is a semaphore capping how many uploads run at once. Uploads
+// beyond the cap queue for up to wait before giving up. A nil *uploadSlots
+// never blocks.
+type uploadSlots struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// newUploadSlots returns a semaphore with n slots, or nil for n <= 0.
+func newUploadSlots(n int, wait time.Duration) *uploadSlots {
+	if n <= 0 {
+		return nil
+	}
+	return &uploadSlots{slots: make(chan struct{}, n), wait: wait}
+}
+
+// acquire takes a slot, waiting up to s.wait for one to free up. It returns
+// errUploadsBusy on timeout and ctx's error if ctx ends first. Callers must
+// call release once the upload finishes.
+func (s *uploadSlots) acquire(ctx context.Context) (release func(), err error) {
+	if s == nil {
+		return func() {}, nil
+	}
+	release = func() { <-s.slots }
+
+	select {
+	case s.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	timer := time.NewTimer(s.wait)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, errUploadsBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// acquireUpload takes an upload slot for r, writing 503 with a Retry-After
+// hint if none frees up in time. If the client goes away while waiting,
+// nothing is written. ok is false when the handler should stop.
+func (h *Handler) acquireUpload(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	release, err := h.uploadSlots.acquire(r.Context())
+	switch {
+	case err == nil:
+		return release, true
+	case errors.Is(err, errUploadsBusy):
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, int(h.uploadSlots.wait.Seconds()))))
+		writeError(w, http.StatusServiceUnavailable, CodeUnavailable, "too many uploads in progress; retry later")
+	}
+	return nil, false
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// newBlockingUploadStore returns a store whose writes signal started and
+// then block until unblock is closed.
+func newBlockingUploadStore(started chan<- struct{}, unblock <-chan struct{}) *mockStorage {
+	return &mockStorage{
+		statFn: func(_ context.Context, _ string) (*storage.FileInfo, error) {
+			return nil, storage.ErrNotFound
+		},
+		writeFn: func(_ context.Context, _ string, r io.Reader) error {
+			io.Copy(io.Discard, r)
+			started <- struct{}{}
+			<-unblock
+			return nil
+		},
+	}
+}
+
+func TestUpload_ConcurrencyLimit(t *testing.T) {
+	const limit = 2
+	started := make(chan struct{}, limit)
+	unblock := make(chan struct{})
+	h := NewHandler(newBlockingUploadStore(started, unblock), Options{
+		MaxUploadSize:        10 << 20,
+		MaxConcurrentUploads: limit,
+		UploadQueueTimeout:   50 * time.Millisecond,
+	})
+
+	var wg sync.WaitGroup
+	codes := make([]int, limit)
+	for i := range limit {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rr := httptest.NewRecorder()
+			h.Upload(rr, createMultipartRequest(t, "a.txt", "a.txt", "data"))
+			codes[i] = rr.Code
+		}()
+	}
+	for range limit {
+		<-started
+	}
+
+	begin := time.Now()
+	rr := httptest.NewRecorder()
+	h.Put(rr, httptest.NewRequest(http.MethodPut, "/api/v1/files?path=b.txt", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 with every slot taken, got %d", rr.Code)
+	}
+	if waited := time.Since(begin); waited < 50*time.Millisecond {
+		t.Errorf("expected the upload to wait for the queue timeout, gave up after %s", waited)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+	var body ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&body)
+	if body.Code != CodeUnavailable {
+		t.Errorf("expected code %q, got %q", CodeUnavailable, body.Code)
+	}
+
+	close(unblock)
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusCreated {
+			t.Errorf("upload %d: expected 201, got %d", i, code)
+		}
+	}
+
+	// Finished uploads free their slots.
+	rr = httptest.NewRecorder()
+	h.Upload(rr, createMultipartRequest(t, "c.txt", "c.txt", "data"))
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected 201 once slots are free, got %d", rr.Code)
+	}
+}
+
+func TestUploadSlots_ContextCancelStopsWaiting(t *testing.T) {
+	slots := newUploadSlots(1, time.Minute)
+	release, err := slots.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := slots.acquire(ctx); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(slots.slots) != 1 {
+		t.Errorf("expected the abandoned wait to hold no slot, %d taken", len(slots.slots))
+	}
+}
+
+func TestUploadSlots_NilIsUnlimited(t *testing.T) {
+	slots := newUploadSlots(0, time.Second)
+	if slots != nil {
+		t.Fatal("expected no semaphore for a limit of 0")
+	}
+	for range 100 {
+		if _, err := slots.acquire(context.Background()); err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes one invalid request parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse is the 400 body for a request with invalid
+// parameters, listing every one of them rather than only the first.
+type ValidationErrorResponse struct {
+	ErrorResponse
+	Errors []FieldError `json:"errors"`
+}
+
+// validator collects the problems with a request's query parameters so
+// they can be reported together. Its getters return the default for an
+// invalid value; check ok before using the results.
+type validator struct {
+	query  url.Values
+	errors []FieldError
+}
+
+func newValidator(r *http.Request) *validator {
+	return &validator{query: r.URL.Query()}
+}
+
+// fail records that field is invalid.
+func (v *validator) fail(field, message string) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: message})
+}
+
+// bool reads the optional boolean parameter name, or def when absent.
+func (v *validator) bool(name string, def bool) bool {
+	raw := v.query.Get(name)
+	if raw == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		v.fail(name, "must be true or false")
+		return def
+	}
+	return b
+}
+
+// positiveInt reads the optional parameter name as an integer above zero,
+// or def when absent.
+func (v *validator) positiveInt(name string, def int) int {
+	raw := v.query.Get(name)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		v.fail(name, "must be a positive integer")
+		return def
+	}
+	return n
+}
+
+// required reads the parameter name, which must not be blank.
+func (v *validator) required(name string) string {
+	raw := v.query.Get(name)
+	if strings.TrimSpace(raw) == "" {
+		v.fail(name, "is required")
+	}
+	return raw
+}
+
+// ok reports whether every parameter was valid. Otherwise it writes a 400
+// validation_failed response naming each invalid one.
+func (v *validator) ok(w http.ResponseWriter) bool {
+	if len(v.errors) == 0 {
+		return true
+	}
+	msgs := make([]string, len(v.errors))
+	for i, e := range v.errors {
+		msgs[i] = e.Field + " " + e.Message
+	}
+	writeJSON(w, http.StatusBadRequest, ValidationErrorResponse{
+		ErrorResponse: ErrorResponse{
+			Error:     strings.Join(msgs, "; "),
+			Code:      CodeValidationFailed,
+			RequestID: w.Header().Get(headerRequestID),
+		},
+		Errors: v.errors,
+	})
+	return false
+}
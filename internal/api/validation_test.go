@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// decodeValidation checks rr is a validation_failed 400 and returns the
+// fields it names, in order.
+func decodeValidation(t *testing.T, rr *httptest.ResponseRecorder) []string {
+	t.Helper()
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var body ValidationErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Code != CodeValidationFailed || body.Error == "" {
+		t.Errorf("expected code %q with a message, got %+v", CodeValidationFailed, body.ErrorResponse)
+	}
+	var fields []string
+	for _, e := range body.Errors {
+		if e.Message == "" {
+			t.Errorf("field %s has no message", e.Field)
+		}
+		fields = append(fields, e.Field)
+	}
+	return fields
+}
+
+func TestList_ReportsEveryInvalidParam(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	rr := httptest.NewRecorder()
+	h.List(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files?path=/&hideTrash=maybe&recursive=true&dirs_only=2x&stream=nope", nil))
+
+	want := []string{"hideTrash", "dirs_only", "stream"}
+	if got := decodeValidation(t, rr); !slices.Equal(got, want) {
+		t.Errorf("expected errors for %v, got %v", want, got)
+	}
+}
+
+func TestSearch_ReportsEveryInvalidParam(t *testing.T) {
+	h := newTestHandler(&mockStorage{})
+
+	rr := httptest.NewRecorder()
+	h.Search(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/search?q=+&limit=-3", nil))
+
+	want := []string{"q", "limit"}
+	if got := decodeValidation(t, rr); !slices.Equal(got, want) {
+		t.Errorf("expected errors for %v, got %v", want, got)
+	}
+}
+
+func TestValidator_Valid(t *testing.T) {
+	v := newValidator(httptest.NewRequest(http.MethodGet, "/?a=true&n=7&q=x", nil))
+	if !v.bool("a", false) || v.bool("missing", false) {
+		t.Error("unexpected bool values")
+	}
+	if n := v.positiveInt("n", 1); n != 7 {
+		t.Errorf("expected 7, got %d", n)
+	}
+	if q := v.required("q"); q != "x" {
+		t.Errorf("expected x, got %q", q)
+	}
+	if rr := httptest.NewRecorder(); !v.ok(rr) || rr.Body.Len() != 0 {
+		t.Error("expected valid parameters to write nothing")
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go-storage-api/internal/storage"
+)
+
+// maxVerifyRequestSize caps the JSON body of a verify request.
+const maxVerifyRequestSize = 4 << 10
+
+// verifyAlgos are the digests Verify can compute.
+var verifyAlgos = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// VerifyRequest is the body of POST /api/v1/files/verify. Expected is the
+// hex digest the client holds for the file.
+type VerifyRequest struct {
+	Algo     string `json:"algo"`
+	Expected string `json:"expected"`
+}
+
+// VerifyResponse reports whether the stored file hashes to the expected
+// digest, and the digest it actually has.
+type VerifyResponse struct {
+	Match  bool   `json:"match"`
+	Actual string `json:"actual"`
+}
+
+// Verify streams the file at path through the requested digest and
+// compares it with the expected one, so clients can check a stored file
+// without downloading it. A mismatch is still a 200, with match false.
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, CodePathInvalid, "path query parameter is required")
+		return
+	}
+
+	var req VerifyRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxVerifyRequestSize)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if writeTooLarge(w, err) {
+			return
+		}
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid request body: "+err.Error())
+		return
+	}
+	algo := strings.ToLower(req.Algo)
+	newHash, ok := verifyAlgos[algo]
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "algo must be one of "+strings.Join(slices.Sorted(maps.Keys(verifyAlgos)), ", "))
+		return
+	}
+	expected := strings.ToLower(req.Expected)
+	if b, err := hex.DecodeString(expected); err != nil || len(b) != newHash().Size() {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "expected must be a hex "+algo+" digest")
+		return
+	}
+
+	info, err := h.store.Stat(r.Context(), p)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	if info.IsDir {
+		handleStorageError(w, storage.ErrIsDirectory)
+		return
+	}
+
+	actual, err := h.digest(r.Context(), p, algo, newHash)
+	if err != nil {
+		handleStorageError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, VerifyResponse{Match: actual == expected, Actual: actual})
+}
+
+// digest returns the lowercase hex digest of the file at p. SHA-256 goes
+// through storage.SHA256Of so backends that cache it can skip the read.
+func (h *Handler) digest(ctx context.Context, p, algo string, newHash func() hash.Hash) (string, error) {
+	if algo == "sha256" {
+		return storage.SHA256Of(ctx, h.store, p)
+	}
+	rc, err := h.store.Read(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	sum := newHash()
+	if _, err := h.copyBuffers.Copy(sum, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// Digests of "hello world".
+const (
+	helloSHA256 = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	helloMD5    = "5eb63bbbe01eeed093cb22bb8f5acdc3"
+)
+
+func verify(h *Handler, p, body string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	h.Verify(rr, httptest.NewRequest(http.MethodPost, "/api/v1/files/verify?path="+p, strings.NewReader(body)))
+	return rr
+}
+
+func TestVerify(t *testing.T) {
+	h := NewHandler(fileStore("hello world"), Options{})
+
+	tests := []struct {
+		name, body string
+		match      bool
+		actual     string
+	}{
+		{"sha256 match", `{"algo":"sha256","expected":"` + helloSHA256 + `"}`, true, helloSHA256},
+		{"md5 match", `{"algo":"md5","expected":"` + helloMD5 + `"}`, true, helloMD5},
+		{"uppercase hex", `{"algo":"SHA256","expected":"` + strings.ToUpper(helloSHA256) + `"}`, true, helloSHA256},
+		{"mismatch", `{"algo":"md5","expected":"00000000000000000000000000000000"}`, false, helloMD5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := verify(h, "/a.txt", tt.body)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body)
+			}
+			var resp VerifyResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Match != tt.match || resp.Actual != tt.actual {
+				t.Errorf("expected match %v with %s, got %+v", tt.match, tt.actual, resp)
+			}
+		})
+	}
+}
+
+func TestVerify_BadRequest(t *testing.T) {
+	h := NewHandler(fileStore("hello world"), Options{})
+	for name, body := range map[string]string{
+		"unknown algo":   `{"algo":"crc32","expected":"0d4a1185"}`,
+		"missing algo":   `{"expected":"` + helloMD5 + `"}`,
+		"not hex":        `{"algo":"md5","expected":"not-a-digest"}`,
+		"wrong length":   `{"algo":"sha256","expected":"` + helloMD5 + `"}`,
+		"malformed body": `{"algo":`,
+	} {
+		if rr := verify(h, "/a.txt", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rr.Code)
+		}
+	}
+}
+
+func TestVerify_Missing(t *testing.T) {
+	missing := &mockStorage{statFn: func(context.Context, string) (*storage.FileInfo, error) {
+		return nil, storage.ErrNotFound
+	}}
+	rr := verify(NewHandler(missing, Options{}), "/missing.txt", `{"algo":"sha256","expected":"`+helloSHA256+`"}`)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rr.Code)
+	}
+
+	dir := &mockStorage{statFn: func(_ context.Context, p string) (*storage.FileInfo, error) {
+		return &storage.FileInfo{Path: p, IsDir: true}, nil
+	}}
+	rr = verify(NewHandler(dir, Options{}), "/docs", `{"algo":"md5","expected":"`+helloMD5+`"}`)
+	if rr.Code == http.StatusOK {
+		t.Error("expected a directory to be refused")
+	}
+}
@@ -0,0 +1,71 @@
+// Package bufpool recycles the byte buffers used to copy file streams, so
+// each download or upload does not allocate and discard its own.
+package bufpool
+
+import (
+	"io"
+	"sync"
+)
+
+// DefaultSize matches the buffer io.Copy would otherwise allocate.
+const DefaultSize = 32 << 10
+
+// Pool hands out reusable copy buffers of one size. It is safe for
+// concurrent use. A nil *Pool copies with plain io.Copy.
+type Pool struct {
+	size int
+	pool sync.Pool
+}
+
+// New returns a Pool of size-byte buffers. A size of zero or less uses
+// DefaultSize.
+func New(size int) *Pool {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	p := &Pool{size: size}
+	p.pool.New = func() any {
+		b := make([]byte, size)
+		return &b
+	}
+	return p
+}
+
+// Size returns the length of the pool's buffers.
+func (p *Pool) Size() int {
+	if p == nil {
+		return DefaultSize
+	}
+	return p.size
+}
+
+// Copy behaves like io.Copy but borrows its buffer from the pool. As with
+// io.CopyBuffer, a src implementing io.WriterTo or a dst implementing
+// io.ReaderFrom is used directly and no buffer is taken, which keeps OS fast
+// paths such as sendfile available.
+func (p *Pool) Copy(dst io.Writer, src io.Reader) (int64, error) {
+	if p == nil {
+		return io.Copy(dst, src)
+	}
+	if wt, ok := src.(io.WriterTo); ok {
+		return wt.WriteTo(dst)
+	}
+	if rf, ok := dst.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	bp := p.pool.Get().(*[]byte)
+	defer p.pool.Put(bp)
+	return io.CopyBuffer(dst, src, *bp)
+}
+
+// CopyN behaves like io.CopyN but borrows its buffer from the pool.
+func (p *Pool) CopyN(dst io.Writer, src io.Reader, n int64) (int64, error) {
+	written, err := p.Copy(dst, io.LimitReader(src, n))
+	if written == n {
+		return n, nil
+	}
+	if written < n && err == nil {
+		err = io.EOF
+	}
+	return written, err
+}
@@ -0,0 +1,66 @@
+package bufpool
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// plainReader and plainWriter hide WriterTo and ReaderFrom so copies have to
+// go through a buffer.
+type plainReader struct{ io.Reader }
+type plainWriter struct{ io.Writer }
+
+func TestCopy(t *testing.T) {
+	src := strings.Repeat("abcdefgh", 10_000)
+	for _, p := range []*Pool{New(0), New(7), nil} {
+		var dst bytes.Buffer
+		n, err := p.Copy(plainWriter{&dst}, plainReader{strings.NewReader(src)})
+		if err != nil || n != int64(len(src)) || dst.String() != src {
+			t.Errorf("pool size %d: Copy = %d, %v; content match %v", p.Size(), n, err, dst.String() == src)
+		}
+	}
+}
+
+func TestCopyN(t *testing.T) {
+	p := New(4)
+
+	var dst bytes.Buffer
+	n, err := p.CopyN(plainWriter{&dst}, plainReader{strings.NewReader("0123456789")}, 6)
+	if err != nil || n != 6 || dst.String() != "012345" {
+		t.Errorf("CopyN = %d, %v, %q; want 6, nil, %q", n, err, dst.String(), "012345")
+	}
+
+	n, err = p.CopyN(io.Discard, plainReader{strings.NewReader("short")}, 10)
+	if n != 5 || !errors.Is(err, io.EOF) {
+		t.Errorf("short CopyN = %d, %v; want 5, io.EOF", n, err)
+	}
+}
+
+func TestNewDefaultSize(t *testing.T) {
+	if got := New(-1).Size(); got != DefaultSize {
+		t.Errorf("Size() = %d, want %d", got, DefaultSize)
+	}
+}
+
+// The pooled copy should allocate nothing per call once warm, while
+// io.Copy allocates a fresh DefaultSize buffer each time.
+func BenchmarkCopy(b *testing.B) {
+	data := bytes.Repeat([]byte("x"), 1<<20)
+
+	b.Run("io.Copy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			io.Copy(plainWriter{io.Discard}, plainReader{bytes.NewReader(data)})
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		p := New(DefaultSize)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p.Copy(plainWriter{io.Discard}, plainReader{bytes.NewReader(data)})
+		}
+	})
+}
@@ -3,23 +3,96 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
+	"path"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Port           string
-	LogLevel       string
-	StorageBackend string
-	MaxUploadSize  int64
-	Local          LocalConfig
-	SMB            SMBConfig
-	FTP            FTPConfig
-	S3             S3Config
+	Port                string
+	LogLevel            string
+	StorageBackend      string
+	MaxUploadSize       int64
+	MultipartMemory     int64
+	MaxPathSegments     int
+	MaxNameBytes        int
+	ListConcurrency     int
+	MaxListEntries      int
+	MaxUploads          int
+	UploadQueueTimeout  time.Duration
+	ResumableDir        string
+	ResumableExpiry     time.Duration
+	CopyBufferSize      int
+	ShutdownTimeout     time.Duration
+	UploadAllowedExts   []string
+	UploadAllowedMIME   []string
+	HiddenPatterns      []string
+	PublicPaths         []string
+	HiddenFullPath      bool
+	ContentTypes        map[string]string
+	InlineTypes         []string
+	AssetCacheMaxAge    time.Duration
+	DownloadTrailers    bool
+	DownloadRateLimit   int64
+	DownloadRateTotal   int64
+	UploadQuota         int64
+	UploadQuotaWindow   time.Duration
+	ContentSecurity     string
+	DeleteNoContent     bool
+	SoftDelete          bool
+	ExposeBackendHeader bool
+	WebDAVEnabled       bool
+	IPAllowlist         []netip.Prefix
+	IPDenylist          []netip.Prefix
+	TrustedProxies      []netip.Prefix
+	Server              ServerConfig
+	Auth                AuthConfig
+	Local               LocalConfig
+	SMB                 SMBConfig
+	FTP                 FTPConfig
+	S3                  S3Config
+}
+
+// ServerConfig holds the http.Server's timeouts, header limit and
+// protocols. A zero ReadTimeout or WriteTimeout means none, which
+// streaming uploads and downloads of large files need.
+type ServerConfig struct {
+	// ListenAddr is tcp://host:port or unix:///path/to/socket.
+	ListenAddr string
+	// SocketMode is the permission set on a Unix socket.
+	SocketMode os.FileMode
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	H2C               bool
+	TLSCertFile       string
+	TLSKeyFile        string
+}
+
+type AuthConfig struct {
+	JWTSecret   string
+	JWTIssuer   string
+	JWTAudience string
+	UserScope   bool
+
+	// URLSigningSecret signs time-limited download links; empty disables
+	// them.
+	URLSigningSecret string
 }
 
 type LocalConfig struct {
-	RootPath string
+	RootPath       string
+	StagingDir     string
+	Dedup          bool
+	Compress       bool
+	FollowSymlinks bool
+	Fsync          bool
 }
 
 type SMBConfig struct {
@@ -61,13 +134,192 @@ func Load() *Config {
 		log.Fatalf("invalid MAX_UPLOAD_SIZE: %v", err)
 	}
 
+	multipartMemory, err := strconv.ParseInt(envOrDefault("MULTIPART_MEMORY", "10485760"), 10, 64)
+	if err != nil || multipartMemory < 1 {
+		log.Fatalf("invalid MULTIPART_MEMORY: %q (must be a positive number of bytes)", os.Getenv("MULTIPART_MEMORY"))
+	}
+
+	maxSegments, err := strconv.Atoi(envOrDefault("MAX_PATH_SEGMENTS", "64"))
+	if err != nil || maxSegments < 1 {
+		log.Fatalf("invalid MAX_PATH_SEGMENTS: %q (must be a positive integer)", os.Getenv("MAX_PATH_SEGMENTS"))
+	}
+
+	maxNameBytes, err := strconv.Atoi(envOrDefault("MAX_NAME_BYTES", "255"))
+	if err != nil || maxNameBytes < 1 {
+		log.Fatalf("invalid MAX_NAME_BYTES: %q (must be a positive integer)", os.Getenv("MAX_NAME_BYTES"))
+	}
+
+	listConcurrency, err := strconv.Atoi(envOrDefault("LIST_CONCURRENCY", "8"))
+	if err != nil || listConcurrency < 1 {
+		log.Fatalf("invalid LIST_CONCURRENCY: %q (must be a positive integer)", os.Getenv("LIST_CONCURRENCY"))
+	}
+
+	maxListEntries, err := strconv.Atoi(envOrDefault("MAX_LIST_ENTRIES", "10000"))
+	if err != nil || maxListEntries < 0 {
+		log.Fatalf("invalid MAX_LIST_ENTRIES: %q (must be a non-negative integer)", os.Getenv("MAX_LIST_ENTRIES"))
+	}
+
+	maxUploads, err := strconv.Atoi(envOrDefault("MAX_CONCURRENT_UPLOADS", "0"))
+	if err != nil || maxUploads < 0 {
+		log.Fatalf("invalid MAX_CONCURRENT_UPLOADS: %q (must be a non-negative integer, 0 for unlimited)", os.Getenv("MAX_CONCURRENT_UPLOADS"))
+	}
+
+	uploadQueueTimeout, err := time.ParseDuration(envOrDefault("UPLOAD_QUEUE_TIMEOUT", "30s"))
+	if err != nil || uploadQueueTimeout < 0 {
+		log.Fatalf("invalid UPLOAD_QUEUE_TIMEOUT: %q (must be a duration such as 30s)", os.Getenv("UPLOAD_QUEUE_TIMEOUT"))
+	}
+
+	resumableExpiry, err := time.ParseDuration(envOrDefault("RESUMABLE_UPLOAD_EXPIRY", "24h"))
+	if err != nil || resumableExpiry <= 0 {
+		log.Fatalf("invalid RESUMABLE_UPLOAD_EXPIRY: %q (must be a positive duration such as 24h)", os.Getenv("RESUMABLE_UPLOAD_EXPIRY"))
+	}
+
+	copyBufferSize, err := strconv.Atoi(envOrDefault("COPY_BUFFER_SIZE", "32768"))
+	if err != nil || copyBufferSize < 512 {
+		log.Fatalf("invalid COPY_BUFFER_SIZE: %q (must be an integer of at least 512)", os.Getenv("COPY_BUFFER_SIZE"))
+	}
+
+	downloadRate, err := strconv.ParseInt(envOrDefault("DOWNLOAD_RATE_LIMIT", "0"), 10, 64)
+	if err != nil || downloadRate < 0 {
+		log.Fatalf("invalid DOWNLOAD_RATE_LIMIT: %q (must be bytes per second, 0 for unlimited)", os.Getenv("DOWNLOAD_RATE_LIMIT"))
+	}
+
+	downloadRateTotal, err := strconv.ParseInt(envOrDefault("DOWNLOAD_RATE_LIMIT_TOTAL", "0"), 10, 64)
+	if err != nil || downloadRateTotal < 0 {
+		log.Fatalf("invalid DOWNLOAD_RATE_LIMIT_TOTAL: %q (must be bytes per second, 0 for unlimited)", os.Getenv("DOWNLOAD_RATE_LIMIT_TOTAL"))
+	}
+
+	uploadQuota, err := strconv.ParseInt(envOrDefault("UPLOAD_QUOTA_BYTES", "0"), 10, 64)
+	if err != nil || uploadQuota < 0 {
+		log.Fatalf("invalid UPLOAD_QUOTA_BYTES: %q (must be bytes per window, 0 for unlimited)", os.Getenv("UPLOAD_QUOTA_BYTES"))
+	}
+
+	contentSecurity := envOrDefault("CONTENT_SECURITY_MODE", "strict")
+	if contentSecurity != "strict" && contentSecurity != "permissive" {
+		log.Fatalf("invalid CONTENT_SECURITY_MODE: %q (must be strict or permissive)", contentSecurity)
+	}
+
+	contentTypes := map[string]string{}
+	for _, pair := range envList("CONTENT_TYPES") {
+		ext, ct, ok := strings.Cut(pair, "=")
+		ext, ct = strings.TrimSpace(ext), strings.TrimSpace(ct)
+		if !ok || ext == "" || ct == "" {
+			log.Fatalf("invalid CONTENT_TYPES entry: %q (must be ext=type, e.g. .md=text/markdown)", pair)
+		}
+		contentTypes[ext] = ct
+	}
+
+	inlineTypes := envList("DOWNLOAD_INLINE_TYPES")
+	for _, t := range inlineTypes {
+		if top, sub, ok := strings.Cut(t, "/"); !ok || top == "" || sub == "" {
+			log.Fatalf("invalid DOWNLOAD_INLINE_TYPES entry: %q (must be a media type such as image/* or application/pdf)", t)
+		}
+	}
+
+	hiddenPatterns := envList("HIDDEN_PATTERNS")
+	for _, pattern := range hiddenPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.Fatalf("invalid HIDDEN_PATTERNS entry: %q (%v)", pattern, err)
+		}
+	}
+
+	publicPaths := envList("PUBLIC_PATHS")
+	for _, p := range publicPaths {
+		if !strings.HasPrefix(p, "/") || strings.Trim(p, "/") == "" {
+			log.Fatalf("invalid PUBLIC_PATHS entry: %q (must be a URL path prefix such as /api/v1/assets, not the root)", p)
+		}
+	}
+
+	shutdownTimeout, err := time.ParseDuration(envOrDefault("SHUTDOWN_TIMEOUT", "30s"))
+	if err != nil || shutdownTimeout < 0 {
+		log.Fatalf("invalid SHUTDOWN_TIMEOUT: %q (must be a duration such as 30s)", os.Getenv("SHUTDOWN_TIMEOUT"))
+	}
+
+	readHeaderTimeout := envDuration("HTTP_READ_HEADER_TIMEOUT", "10s")
+	if readHeaderTimeout == 0 {
+		log.Fatalf("invalid HTTP_READ_HEADER_TIMEOUT: must be positive; without it slow clients can hold connections open")
+	}
+	maxHeaderBytes, err := strconv.Atoi(envOrDefault("HTTP_MAX_HEADER_BYTES", "65536"))
+	if err != nil || maxHeaderBytes < 4096 {
+		log.Fatalf("invalid HTTP_MAX_HEADER_BYTES: %q (must be an integer of at least 4096)", os.Getenv("HTTP_MAX_HEADER_BYTES"))
+	}
+	listenAddr := envOrDefault("LISTEN_ADDR", "tcp://:"+envOrDefault("PORT", "8080"))
+	if scheme, rest, _ := strings.Cut(listenAddr, "://"); (scheme != "tcp" && scheme != "unix") || rest == "" {
+		log.Fatalf("invalid LISTEN_ADDR: %q (must be tcp://host:port or unix:///path/to/socket)", listenAddr)
+	}
+	socketMode, err := strconv.ParseUint(envOrDefault("LISTEN_SOCKET_MODE", "0660"), 8, 32)
+	if err != nil || socketMode > 0o777 {
+		log.Fatalf("invalid LISTEN_SOCKET_MODE: %q (must be octal permissions such as 0660)", os.Getenv("LISTEN_SOCKET_MODE"))
+	}
+	tlsCert, tlsKey := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	if (tlsCert == "") != (tlsKey == "") {
+		log.Fatalf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
 	cfg := &Config{
-		Port:           envOrDefault("PORT", "8080"),
-		LogLevel:       envOrDefault("LOG_LEVEL", "info"),
-		StorageBackend: backend,
-		MaxUploadSize:  maxUpload,
+		Port:                envOrDefault("PORT", "8080"),
+		LogLevel:            envOrDefault("LOG_LEVEL", "info"),
+		StorageBackend:      backend,
+		MaxUploadSize:       maxUpload,
+		MultipartMemory:     multipartMemory,
+		MaxPathSegments:     maxSegments,
+		MaxNameBytes:        maxNameBytes,
+		ListConcurrency:     listConcurrency,
+		MaxListEntries:      maxListEntries,
+		MaxUploads:          maxUploads,
+		UploadQueueTimeout:  uploadQueueTimeout,
+		ResumableDir:        os.Getenv("RESUMABLE_UPLOAD_DIR"),
+		ResumableExpiry:     resumableExpiry,
+		CopyBufferSize:      copyBufferSize,
+		ShutdownTimeout:     shutdownTimeout,
+		UploadAllowedExts:   envList("UPLOAD_ALLOWED_EXTENSIONS"),
+		UploadAllowedMIME:   envList("UPLOAD_ALLOWED_MIME_TYPES"),
+		HiddenPatterns:      hiddenPatterns,
+		PublicPaths:         publicPaths,
+		HiddenFullPath:      envBool("HIDDEN_MATCH_FULL_PATH", false),
+		ContentTypes:        contentTypes,
+		InlineTypes:         inlineTypes,
+		AssetCacheMaxAge:    envDuration("ASSET_CACHE_MAX_AGE", "1h"),
+		DownloadTrailers:    envBool("DOWNLOAD_TRAILERS", false),
+		DownloadRateLimit:   downloadRate,
+		DownloadRateTotal:   downloadRateTotal,
+		UploadQuota:         uploadQuota,
+		UploadQuotaWindow:   envDuration("UPLOAD_QUOTA_WINDOW", "1h"),
+		ContentSecurity:     contentSecurity,
+		DeleteNoContent:     envBool("DELETE_NO_CONTENT", false),
+		SoftDelete:          envBool("SOFT_DELETE", false),
+		ExposeBackendHeader: envBool("EXPOSE_BACKEND_HEADER", false),
+		WebDAVEnabled:       envBool("WEBDAV_ENABLED", false),
+		IPAllowlist:         envPrefixes("IP_ALLOWLIST"),
+		IPDenylist:          envPrefixes("IP_DENYLIST"),
+		TrustedProxies:      envPrefixes("TRUSTED_PROXIES"),
+		Server: ServerConfig{
+			ListenAddr:        listenAddr,
+			SocketMode:        os.FileMode(socketMode),
+			ReadHeaderTimeout: readHeaderTimeout,
+			ReadTimeout:       envDuration("HTTP_READ_TIMEOUT", "0"),
+			WriteTimeout:      envDuration("HTTP_WRITE_TIMEOUT", "0"),
+			IdleTimeout:       envDuration("HTTP_IDLE_TIMEOUT", "120s"),
+			MaxHeaderBytes:    maxHeaderBytes,
+			H2C:               envBool("HTTP_H2C", true),
+			TLSCertFile:       tlsCert,
+			TLSKeyFile:        tlsKey,
+		},
+		Auth: AuthConfig{
+			JWTSecret:   os.Getenv("AUTH_JWT_SECRET"),
+			JWTIssuer:   os.Getenv("AUTH_JWT_ISSUER"),
+			JWTAudience: os.Getenv("AUTH_JWT_AUDIENCE"),
+			UserScope:   envBool("AUTH_USER_SCOPE", false),
+
+			URLSigningSecret: os.Getenv("AUTH_URL_SIGNING_SECRET"),
+		},
 		Local: LocalConfig{
-			RootPath: envOrDefault("LOCAL_ROOT_PATH", "./data"),
+			RootPath:       envOrDefault("LOCAL_ROOT_PATH", "./data"),
+			StagingDir:     os.Getenv("LOCAL_STAGING_DIR"),
+			Dedup:          envBool("LOCAL_DEDUP", false),
+			Compress:       envBool("LOCAL_COMPRESS", false),
+			FollowSymlinks: envBool("LOCAL_FOLLOW_SYMLINKS", true),
+			Fsync:          envBool("LOCAL_FSYNC", false),
 		},
 		SMB: SMBConfig{
 			Host:     os.Getenv("SMB_HOST"),
@@ -92,6 +344,9 @@ func Load() *Config {
 	if err := cfg.validateBackend(); err != nil {
 		log.Fatalf("config validation failed: %v", err)
 	}
+	if err := cfg.validateAuth(); err != nil {
+		log.Fatalf("config validation failed: %v", err)
+	}
 
 	return cfg
 }
@@ -102,6 +357,9 @@ func (c *Config) validateBackend() error {
 		if c.Local.RootPath == "" {
 			return fmt.Errorf("LOCAL_ROOT_PATH is required for local backend")
 		}
+		if c.Local.Dedup && c.Local.Compress {
+			return fmt.Errorf("LOCAL_COMPRESS cannot be combined with LOCAL_DEDUP")
+		}
 	case "smb":
 		if c.SMB.Host == "" {
 			return fmt.Errorf("SMB_HOST is required for smb backend")
@@ -121,6 +379,74 @@ func (c *Config) validateBackend() error {
 	return nil
 }
 
+func (c *Config) validateAuth() error {
+	if c.Auth.UserScope && c.Auth.JWTSecret == "" {
+		return fmt.Errorf("AUTH_USER_SCOPE requires AUTH_JWT_SECRET")
+	}
+	return nil
+}
+
+// envBool parses a boolean variable, exiting on values strconv.ParseBool
+// rejects.
+func envBool(key string, fallback bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return b
+}
+
+// envDuration parses a non-negative duration such as 30s, where 0 means
+// none.
+func envDuration(key, fallback string) time.Duration {
+	d, err := time.ParseDuration(envOrDefault(key, fallback))
+	if err != nil || d < 0 {
+		log.Fatalf("invalid %s: %q (must be a duration such as 30s, or 0 for none)", key, os.Getenv(key))
+	}
+	return d
+}
+
+// envList splits a comma-separated variable into trimmed, non-empty values.
+func envList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// envPrefixes parses a comma-separated list of CIDR prefixes, exiting on
+// invalid entries. A bare address is taken as a single-host prefix.
+func envPrefixes(key string) []netip.Prefix {
+	var out []netip.Prefix
+	for _, v := range envList(key) {
+		p, err := parsePrefix(v)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", key, err)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return p.Masked(), nil
+}
+
 func envOrDefault(key, fallback string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
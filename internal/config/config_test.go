@@ -1,7 +1,11 @@
 package config
 
 import (
+	"maps"
+	"net/netip"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestLoadDefaults(t *testing.T) {
@@ -22,6 +26,84 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.MaxUploadSize != 104857600 {
 		t.Errorf("expected default MaxUploadSize 104857600, got %d", cfg.MaxUploadSize)
 	}
+	if cfg.ShutdownTimeout != 30*time.Second {
+		t.Errorf("expected default ShutdownTimeout 30s, got %s", cfg.ShutdownTimeout)
+	}
+	if cfg.MaxPathSegments != 64 {
+		t.Errorf("expected default MaxPathSegments 64, got %d", cfg.MaxPathSegments)
+	}
+	if cfg.MaxNameBytes != 255 {
+		t.Errorf("expected default MaxNameBytes 255, got %d", cfg.MaxNameBytes)
+	}
+	if cfg.ListConcurrency != 8 {
+		t.Errorf("expected default ListConcurrency 8, got %d", cfg.ListConcurrency)
+	}
+	if cfg.MaxListEntries != 10000 {
+		t.Errorf("expected default MaxListEntries 10000, got %d", cfg.MaxListEntries)
+	}
+	if cfg.AssetCacheMaxAge != time.Hour {
+		t.Errorf("expected default AssetCacheMaxAge 1h, got %s", cfg.AssetCacheMaxAge)
+	}
+	if cfg.CopyBufferSize != 32768 {
+		t.Errorf("expected default CopyBufferSize 32768, got %d", cfg.CopyBufferSize)
+	}
+	if cfg.MultipartMemory != 10<<20 {
+		t.Errorf("expected default MultipartMemory 10MB, got %d", cfg.MultipartMemory)
+	}
+	if cfg.MaxUploads != 0 || cfg.UploadQueueTimeout != 30*time.Second {
+		t.Errorf("expected unlimited uploads with a 30s queue timeout, got %d and %s", cfg.MaxUploads, cfg.UploadQueueTimeout)
+	}
+	if cfg.ResumableDir != "" || cfg.ResumableExpiry != 24*time.Hour {
+		t.Errorf("expected resumable uploads off with a 24h expiry, got %q and %s", cfg.ResumableDir, cfg.ResumableExpiry)
+	}
+	if cfg.DownloadRateLimit != 0 || cfg.DownloadRateTotal != 0 {
+		t.Errorf("expected download rate limits to default to 0, got %d and %d", cfg.DownloadRateLimit, cfg.DownloadRateTotal)
+	}
+	if cfg.ExposeBackendHeader {
+		t.Error("expected ExposeBackendHeader to default to false")
+	}
+	want := ServerConfig{
+		ListenAddr:        "tcp://:8080",
+		SocketMode:        0o660,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		MaxHeaderBytes:    65536,
+		H2C:               true,
+	}
+	if cfg.Server != want {
+		t.Errorf("expected default Server %+v, got %+v", want, cfg.Server)
+	}
+}
+
+func TestLoadServer(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+	t.Setenv("HTTP_READ_HEADER_TIMEOUT", "5s")
+	t.Setenv("HTTP_READ_TIMEOUT", "10m")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "1h")
+	t.Setenv("HTTP_IDLE_TIMEOUT", "30s")
+	t.Setenv("HTTP_MAX_HEADER_BYTES", "8192")
+	t.Setenv("HTTP_H2C", "false")
+	t.Setenv("TLS_CERT_FILE", "/etc/storage/cert.pem")
+	t.Setenv("TLS_KEY_FILE", "/etc/storage/key.pem")
+	t.Setenv("LISTEN_ADDR", "unix:///run/storage/api.sock")
+	t.Setenv("LISTEN_SOCKET_MODE", "0600")
+
+	cfg := Load()
+
+	want := ServerConfig{
+		ListenAddr:        "unix:///run/storage/api.sock",
+		SocketMode:        0o600,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Minute,
+		WriteTimeout:      time.Hour,
+		IdleTimeout:       30 * time.Second,
+		MaxHeaderBytes:    8192,
+		TLSCertFile:       "/etc/storage/cert.pem",
+		TLSKeyFile:        "/etc/storage/key.pem",
+	}
+	if cfg.Server != want {
+		t.Errorf("expected Server %+v, got %+v", want, cfg.Server)
+	}
 }
 
 func TestLoadCustomValues(t *testing.T) {
@@ -30,21 +112,82 @@ func TestLoadCustomValues(t *testing.T) {
 	t.Setenv("STORAGE_BACKEND", "local")
 	t.Setenv("LOCAL_ROOT_PATH", "/tmp/files")
 	t.Setenv("MAX_UPLOAD_SIZE", "52428800")
+	t.Setenv("MAX_PATH_SEGMENTS", "16")
+	t.Setenv("MAX_NAME_BYTES", "100")
+	t.Setenv("COPY_BUFFER_SIZE", "65536")
+	t.Setenv("MULTIPART_MEMORY", "1048576")
+	t.Setenv("MAX_LIST_ENTRIES", "0")
+	t.Setenv("ASSET_CACHE_MAX_AGE", "24h")
+	t.Setenv("SHUTDOWN_TIMEOUT", "2m")
+	t.Setenv("MAX_CONCURRENT_UPLOADS", "4")
+	t.Setenv("UPLOAD_QUEUE_TIMEOUT", "5s")
+	t.Setenv("RESUMABLE_UPLOAD_DIR", "/var/lib/storage/uploads")
+	t.Setenv("RESUMABLE_UPLOAD_EXPIRY", "2h")
+	t.Setenv("EXPOSE_BACKEND_HEADER", "true")
+	t.Setenv("DOWNLOAD_TRAILERS", "1")
+	t.Setenv("DELETE_NO_CONTENT", "true")
+	t.Setenv("SOFT_DELETE", "true")
+	t.Setenv("WEBDAV_ENABLED", "true")
 
 	cfg := Load()
 
 	if cfg.Port != "9090" {
 		t.Errorf("expected Port 9090, got %s", cfg.Port)
 	}
+	if cfg.Server.ListenAddr != "tcp://:9090" {
+		t.Errorf("expected PORT to set ListenAddr tcp://:9090, got %s", cfg.Server.ListenAddr)
+	}
 	if cfg.LogLevel != "debug" {
 		t.Errorf("expected LogLevel debug, got %s", cfg.LogLevel)
 	}
 	if cfg.MaxUploadSize != 52428800 {
 		t.Errorf("expected MaxUploadSize 52428800, got %d", cfg.MaxUploadSize)
 	}
+	if cfg.ShutdownTimeout != 2*time.Minute {
+		t.Errorf("expected ShutdownTimeout 2m, got %s", cfg.ShutdownTimeout)
+	}
+	if cfg.MaxPathSegments != 16 {
+		t.Errorf("expected MaxPathSegments 16, got %d", cfg.MaxPathSegments)
+	}
+	if cfg.MaxNameBytes != 100 {
+		t.Errorf("expected MaxNameBytes 100, got %d", cfg.MaxNameBytes)
+	}
+	if cfg.CopyBufferSize != 65536 {
+		t.Errorf("expected CopyBufferSize 65536, got %d", cfg.CopyBufferSize)
+	}
+	if cfg.MultipartMemory != 1048576 {
+		t.Errorf("expected MultipartMemory 1048576, got %d", cfg.MultipartMemory)
+	}
+	if cfg.MaxListEntries != 0 {
+		t.Errorf("expected MaxListEntries 0, got %d", cfg.MaxListEntries)
+	}
+	if cfg.AssetCacheMaxAge != 24*time.Hour {
+		t.Errorf("expected AssetCacheMaxAge 24h, got %s", cfg.AssetCacheMaxAge)
+	}
+	if cfg.MaxUploads != 4 || cfg.UploadQueueTimeout != 5*time.Second {
+		t.Errorf("expected 4 uploads with a 5s queue timeout, got %d and %s", cfg.MaxUploads, cfg.UploadQueueTimeout)
+	}
+	if cfg.ResumableDir != "/var/lib/storage/uploads" || cfg.ResumableExpiry != 2*time.Hour {
+		t.Errorf("expected resumable uploads in /var/lib/storage/uploads for 2h, got %q and %s", cfg.ResumableDir, cfg.ResumableExpiry)
+	}
 	if cfg.Local.RootPath != "/tmp/files" {
 		t.Errorf("expected Local.RootPath /tmp/files, got %s", cfg.Local.RootPath)
 	}
+	if !cfg.ExposeBackendHeader {
+		t.Error("expected ExposeBackendHeader true")
+	}
+	if !cfg.DownloadTrailers {
+		t.Error("expected DownloadTrailers true")
+	}
+	if !cfg.DeleteNoContent {
+		t.Error("expected DeleteNoContent true")
+	}
+	if !cfg.SoftDelete {
+		t.Error("expected SoftDelete true")
+	}
+	if !cfg.WebDAVEnabled {
+		t.Error("expected WebDAVEnabled true")
+	}
 }
 
 func TestLoadSMBBackendConfig(t *testing.T) {
@@ -167,3 +310,239 @@ func TestValidateBackendLocalMissingRoot(t *testing.T) {
 		t.Error("expected error for missing LOCAL_ROOT_PATH")
 	}
 }
+
+func TestValidateBackendLocalCompressWithDedup(t *testing.T) {
+	cfg := &Config{
+		StorageBackend: "local",
+		Local:          LocalConfig{RootPath: "./data", Dedup: true, Compress: true},
+	}
+	if err := cfg.validateBackend(); err == nil {
+		t.Error("expected error combining LOCAL_COMPRESS with LOCAL_DEDUP")
+	}
+}
+
+func TestLoadHiddenPatterns(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+	t.Setenv("HIDDEN_PATTERNS", ".DS_Store, *.tmp,")
+	t.Setenv("HIDDEN_MATCH_FULL_PATH", "true")
+
+	cfg := Load()
+
+	if !slices.Equal(cfg.HiddenPatterns, []string{".DS_Store", "*.tmp"}) {
+		t.Errorf("expected HiddenPatterns [.DS_Store *.tmp], got %v", cfg.HiddenPatterns)
+	}
+	if !cfg.HiddenFullPath {
+		t.Error("expected HiddenFullPath true")
+	}
+}
+
+func TestLoadUploadAllowlists(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+	t.Setenv("UPLOAD_ALLOWED_EXTENSIONS", ".pdf, .png ,,")
+	t.Setenv("UPLOAD_ALLOWED_MIME_TYPES", "application/pdf")
+
+	cfg := Load()
+
+	if len(cfg.UploadAllowedExts) != 2 || cfg.UploadAllowedExts[0] != ".pdf" || cfg.UploadAllowedExts[1] != ".png" {
+		t.Errorf("expected UploadAllowedExts [.pdf .png], got %v", cfg.UploadAllowedExts)
+	}
+	if len(cfg.UploadAllowedMIME) != 1 || cfg.UploadAllowedMIME[0] != "application/pdf" {
+		t.Errorf("expected UploadAllowedMIME [application/pdf], got %v", cfg.UploadAllowedMIME)
+	}
+}
+
+func TestLoadInlineTypes(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+	t.Setenv("DOWNLOAD_INLINE_TYPES", "image/*, application/pdf,")
+
+	cfg := Load()
+
+	if !slices.Equal(cfg.InlineTypes, []string{"image/*", "application/pdf"}) {
+		t.Errorf("expected InlineTypes [image/* application/pdf], got %v", cfg.InlineTypes)
+	}
+}
+
+func TestLoadContentTypes(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+	t.Setenv("CONTENT_TYPES", ".md=text/markdown, .log = text/plain,")
+
+	cfg := Load()
+
+	want := map[string]string{".md": "text/markdown", ".log": "text/plain"}
+	if !maps.Equal(cfg.ContentTypes, want) {
+		t.Errorf("expected ContentTypes %v, got %v", want, cfg.ContentTypes)
+	}
+}
+
+func TestLoadPublicPaths(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+
+	if cfg := Load(); cfg.PublicPaths != nil {
+		t.Errorf("expected no public paths by default, got %v", cfg.PublicPaths)
+	}
+
+	t.Setenv("PUBLIC_PATHS", "/api/v1/assets, /docs/")
+	if cfg := Load(); !slices.Equal(cfg.PublicPaths, []string{"/api/v1/assets", "/docs/"}) {
+		t.Errorf("expected PublicPaths [/api/v1/assets /docs/], got %v", cfg.PublicPaths)
+	}
+}
+
+func TestLoadAuthConfig(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+	t.Setenv("AUTH_JWT_SECRET", "s3cret")
+	t.Setenv("AUTH_JWT_ISSUER", "https://idp.example.com")
+	t.Setenv("AUTH_JWT_AUDIENCE", "storage-api")
+	t.Setenv("AUTH_USER_SCOPE", "true")
+	t.Setenv("AUTH_URL_SIGNING_SECRET", "l1nks")
+
+	cfg := Load()
+
+	if cfg.Auth.JWTSecret != "s3cret" {
+		t.Errorf("expected Auth.JWTSecret to be loaded")
+	}
+	if cfg.Auth.JWTIssuer != "https://idp.example.com" {
+		t.Errorf("expected Auth.JWTIssuer https://idp.example.com, got %s", cfg.Auth.JWTIssuer)
+	}
+	if cfg.Auth.JWTAudience != "storage-api" {
+		t.Errorf("expected Auth.JWTAudience storage-api, got %s", cfg.Auth.JWTAudience)
+	}
+	if !cfg.Auth.UserScope {
+		t.Error("expected Auth.UserScope true")
+	}
+	if cfg.Auth.URLSigningSecret != "l1nks" {
+		t.Errorf("expected Auth.URLSigningSecret to be loaded")
+	}
+}
+
+func TestValidateAuthUserScopeWithoutSecret(t *testing.T) {
+	cfg := &Config{Auth: AuthConfig{UserScope: true}}
+	err := cfg.validateAuth()
+	if err == nil {
+		t.Error("expected error for AUTH_USER_SCOPE without AUTH_JWT_SECRET")
+	}
+}
+
+func TestLoadIPFilters(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+	t.Setenv("IP_ALLOWLIST", "10.0.0.0/8, 2001:db8::/32")
+	t.Setenv("IP_DENYLIST", "10.66.1.7")
+	t.Setenv("TRUSTED_PROXIES", "192.168.0.9/24")
+
+	cfg := Load()
+
+	want := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")}
+	if !slices.Equal(cfg.IPAllowlist, want) {
+		t.Errorf("expected IPAllowlist %v, got %v", want, cfg.IPAllowlist)
+	}
+	if len(cfg.IPDenylist) != 1 || cfg.IPDenylist[0] != netip.MustParsePrefix("10.66.1.7/32") {
+		t.Errorf("expected a bare address to become a /32, got %v", cfg.IPDenylist)
+	}
+	if len(cfg.TrustedProxies) != 1 || cfg.TrustedProxies[0] != netip.MustParsePrefix("192.168.0.0/24") {
+		t.Errorf("expected TrustedProxies masked to 192.168.0.0/24, got %v", cfg.TrustedProxies)
+	}
+}
+
+func TestLoadLocalDedup(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+
+	if cfg := Load(); cfg.Local.Dedup {
+		t.Error("expected dedup off by default")
+	}
+
+	t.Setenv("LOCAL_DEDUP", "true")
+	if cfg := Load(); !cfg.Local.Dedup {
+		t.Error("expected LOCAL_DEDUP=true to enable dedup")
+	}
+}
+
+func TestLoadLocalCompress(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+
+	if cfg := Load(); cfg.Local.Compress {
+		t.Error("expected compression off by default")
+	}
+
+	t.Setenv("LOCAL_COMPRESS", "true")
+	if cfg := Load(); !cfg.Local.Compress {
+		t.Error("expected LOCAL_COMPRESS=true to enable compression")
+	}
+}
+
+func TestLoadLocalFsync(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+
+	if cfg := Load(); cfg.Local.Fsync {
+		t.Error("expected fsync off by default")
+	}
+
+	t.Setenv("LOCAL_FSYNC", "true")
+	if cfg := Load(); !cfg.Local.Fsync {
+		t.Error("expected LOCAL_FSYNC=true to enable fsync")
+	}
+}
+
+func TestLoadLocalFollowSymlinks(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+
+	if cfg := Load(); !cfg.Local.FollowSymlinks {
+		t.Error("expected symlinks followed by default")
+	}
+
+	t.Setenv("LOCAL_FOLLOW_SYMLINKS", "false")
+	if cfg := Load(); cfg.Local.FollowSymlinks {
+		t.Error("expected LOCAL_FOLLOW_SYMLINKS=false to stop following symlinks")
+	}
+}
+
+func TestLoadLocalStagingDir(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+	t.Setenv("LOCAL_STAGING_DIR", "/var/lib/storage/.staging")
+
+	if cfg := Load(); cfg.Local.StagingDir != "/var/lib/storage/.staging" {
+		t.Errorf("expected StagingDir from LOCAL_STAGING_DIR, got %q", cfg.Local.StagingDir)
+	}
+}
+
+func TestLoadContentSecurityMode(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+
+	if cfg := Load(); cfg.ContentSecurity != "strict" {
+		t.Errorf("expected default ContentSecurity strict, got %q", cfg.ContentSecurity)
+	}
+
+	t.Setenv("CONTENT_SECURITY_MODE", "permissive")
+	if cfg := Load(); cfg.ContentSecurity != "permissive" {
+		t.Errorf("expected ContentSecurity permissive, got %q", cfg.ContentSecurity)
+	}
+}
+
+func TestLoadDownloadRateLimits(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+	t.Setenv("DOWNLOAD_RATE_LIMIT", "1048576")
+	t.Setenv("DOWNLOAD_RATE_LIMIT_TOTAL", "10485760")
+
+	cfg := Load()
+
+	if cfg.DownloadRateLimit != 1048576 {
+		t.Errorf("expected DownloadRateLimit 1048576, got %d", cfg.DownloadRateLimit)
+	}
+	if cfg.DownloadRateTotal != 10485760 {
+		t.Errorf("expected DownloadRateTotal 10485760, got %d", cfg.DownloadRateTotal)
+	}
+}
+
+func TestLoadUploadQuota(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "local")
+
+	cfg := Load()
+	if cfg.UploadQuota != 0 || cfg.UploadQuotaWindow != time.Hour {
+		t.Errorf("expected an unlimited quota over 1h by default, got %d over %v", cfg.UploadQuota, cfg.UploadQuotaWindow)
+	}
+
+	t.Setenv("UPLOAD_QUOTA_BYTES", "1073741824")
+	t.Setenv("UPLOAD_QUOTA_WINDOW", "24h")
+	cfg = Load()
+	if cfg.UploadQuota != 1073741824 || cfg.UploadQuotaWindow != 24*time.Hour {
+		t.Errorf("expected 1073741824 bytes over 24h, got %d over %v", cfg.UploadQuota, cfg.UploadQuotaWindow)
+	}
+}
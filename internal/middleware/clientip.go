@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIP returns the address of the client that sent r. X-Forwarded-For
+// is only believed when the connection comes from one of trustedProxies;
+// the client is then the rightmost address in the header that is not
+// itself a trusted proxy, so entries a client prepends cannot spoof its
+// address. A Unix domain socket peer is always trusted: only local
+// processes with write access to the socket can connect, typically the
+// proxy in front of it. Otherwise the address comes from RemoteAddr. The
+// result is invalid when the address that decides cannot be parsed, or
+// for a socket peer that sends no X-Forwarded-For.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) netip.Addr {
+	peer, ok := parseAddr(r.RemoteAddr)
+	if !unixPeer(r.RemoteAddr) && (!ok || !containsAddr(trustedProxies, peer)) {
+		return peer
+	}
+
+	hops := forwardedFor(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(hops[i])
+		if !ok {
+			return netip.Addr{}
+		}
+		if !containsAddr(trustedProxies, addr) {
+			return addr
+		}
+		peer = addr
+	}
+	// Every hop is a trusted proxy; the leftmost is as close to the client
+	// as we can get.
+	return peer
+}
+
+// unixPeer reports whether remoteAddr is a Unix domain socket peer as
+// net/http reports it: "@" for an unnamed client socket, or the client's
+// socket path.
+func unixPeer(remoteAddr string) bool {
+	return remoteAddr == "@" || strings.HasPrefix(remoteAddr, "/")
+}
+
+// forwardedFor returns the X-Forwarded-For hops in order, joining repeated
+// headers as if they were one list.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	for _, v := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(v, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// parseAddr accepts "ip", "ip:port" and "[ipv6]:port". IPv4-mapped IPv6
+// addresses are unmapped and zones dropped so they match plain prefixes.
+func parseAddr(s string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		ap, err := netip.ParseAddrPort(s)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = ap.Addr()
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := prefixes(t, "192.168.0.0/24", "fd00::/8")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct client", "203.0.113.9:5000", nil, "203.0.113.9"},
+		{"spoofed header from untrusted peer", "203.0.113.9:5000", []string{"10.1.2.3"}, "203.0.113.9"},
+		{"client behind trusted proxy", "192.168.0.5:443", []string{"10.1.2.3"}, "10.1.2.3"},
+		{"spoofed hop prepended by client", "192.168.0.5:443", []string{"6.6.6.6, 10.1.2.3"}, "10.1.2.3"},
+		{"chain of trusted proxies", "192.168.0.5:443", []string{"10.1.2.3, 192.168.0.9"}, "10.1.2.3"},
+		{"repeated headers", "192.168.0.5:443", []string{"10.1.2.3", "192.168.0.9"}, "10.1.2.3"},
+		{"every hop trusted", "192.168.0.5:443", []string{"192.168.0.7, 192.168.0.9"}, "192.168.0.7"},
+		{"trusted proxy without header", "192.168.0.5:443", nil, "192.168.0.5"},
+		{"IPv6 behind IPv6 proxy", "[fd00::1]:443", []string{"2001:db8::7"}, "2001:db8::7"},
+		{"IPv4-mapped peer", "[::ffff:203.0.113.9]:5000", nil, "203.0.113.9"},
+		{"hop with port", "192.168.0.5:443", []string{"10.1.2.3:1234"}, "10.1.2.3"},
+		{"malformed hop", "192.168.0.5:443", []string{"10.1.2.3, bogus"}, "invalid IP"},
+		{"malformed peer", "not-an-address", nil, "invalid IP"},
+		{"client behind unix socket proxy", "@", []string{"10.1.2.3"}, "10.1.2.3"},
+		{"spoofed hop behind unix socket proxy", "@", []string{"6.6.6.6, 10.1.2.3"}, "10.1.2.3"},
+		{"named unix socket peer", "/run/nginx.sock", []string{"10.1.2.3"}, "10.1.2.3"},
+		{"unix socket peer without header", "@", nil, "invalid IP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, f := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", f)
+			}
+			if got := ClientIP(req, trusted).String(); got != tt.want {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveContent(policy ContentPolicy, contentType string) *httptest.ResponseRecorder {
+	handler := ContentSecurity(policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `inline; filename="page.html"`)
+		io.Copy(w, strings.NewReader("<script>alert(1)</script>"))
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=page.html", nil))
+	return rr
+}
+
+func TestContentSecurity_StrictForcesAttachment(t *testing.T) {
+	for _, ct := range []string{"text/html; charset=utf-8", "image/svg+xml", "application/xml", "application/atom+xml", "TEXT/HTML"} {
+		rr := serveContent(ContentStrict, ct)
+		if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename=page.html` {
+			t.Errorf("%s: expected attachment keeping the filename, got %q", ct, got)
+		}
+		if got := rr.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("%s: expected nosniff, got %q", ct, got)
+		}
+		if got := rr.Header().Get("Content-Security-Policy"); !strings.Contains(got, "sandbox") {
+			t.Errorf("%s: expected a sandboxing CSP, got %q", ct, got)
+		}
+	}
+}
+
+func TestContentSecurity_StrictKeepsSafeTypesInline(t *testing.T) {
+	for _, ct := range []string{"image/png", "text/plain; charset=utf-8", "application/pdf"} {
+		rr := serveContent(ContentStrict, ct)
+		if got := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "inline") {
+			t.Errorf("%s: expected inline, got %q", ct, got)
+		}
+		if got := rr.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("%s: expected nosniff, got %q", ct, got)
+		}
+	}
+}
+
+func TestContentSecurity_EmptyPolicyIsStrict(t *testing.T) {
+	rr := serveContent("", "text/html")
+	if got := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment") {
+		t.Errorf("expected attachment, got %q", got)
+	}
+}
+
+func TestContentSecurity_Permissive(t *testing.T) {
+	rr := serveContent(ContentPermissive, "text/html")
+	if got := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "inline") {
+		t.Errorf("expected inline, got %q", got)
+	}
+	if got := rr.Header().Get("Content-Security-Policy"); got != "" {
+		t.Errorf("expected no CSP, got %q", got)
+	}
+	if got := rr.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("expected nosniff, got %q", got)
+	}
+}
+
+func TestContentSecurity_NoDispositionSet(t *testing.T) {
+	handler := ContentSecurity(ContentStrict)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.WriteHeader(http.StatusOK)
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rr.Header().Get("Content-Disposition"); got != "attachment" {
+		t.Errorf("expected attachment, got %q", got)
+	}
+}
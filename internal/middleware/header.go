@@ -0,0 +1,13 @@
+package middleware
+
+import "net/http"
+
+// SetHeader sets a fixed response header on every request before calling next.
+func SetHeader(key, value string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(key, value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+)
+
+// IPFilterOption customizes the IPFilter middleware.
+type IPFilterOption func(*ipFilterConfig)
+
+type ipFilterConfig struct {
+	trusted []netip.Prefix
+}
+
+// WithTrustedProxies makes IPFilter believe X-Forwarded-For when the
+// connection comes from one of proxies; see ClientIP.
+func WithTrustedProxies(proxies []netip.Prefix) IPFilterOption {
+	return func(c *ipFilterConfig) {
+		c.trusted = append(c.trusted, proxies...)
+	}
+}
+
+// IPFilter answers 403 to clients whose address is inside deny or, when
+// allow is non-empty, outside allow. Deny wins over allow. The client
+// address is taken from RemoteAddr unless WithTrustedProxies applies.
+// Requests whose address cannot be parsed are denied.
+func IPFilter(allow, deny []netip.Prefix, opts ...IPFilterOption) Middleware {
+	cfg := &ipFilterConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr := ClientIP(r, cfg.trusted)
+			if !addr.IsValid() || containsAddr(deny, addr) || (len(allow) > 0 && !containsAddr(allow, addr)) {
+				writeErrorJSON(w, http.StatusForbidden, "forbidden", "access from this address is not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func prefixes(t *testing.T, ss ...string) []netip.Prefix {
+	t.Helper()
+	out := make([]netip.Prefix, len(ss))
+	for i, s := range ss {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			t.Fatalf("ParsePrefix(%q): %v", s, err)
+		}
+		out[i] = p
+	}
+	return out
+}
+
+func serveIPFilter(mw Middleware, remoteAddr string, forwarded ...string) *httptest.ResponseRecorder {
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files", nil)
+	req.RemoteAddr = remoteAddr
+	for _, f := range forwarded {
+		req.Header.Add("X-Forwarded-For", f)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIPFilter_Direct(t *testing.T) {
+	allow := prefixes(t, "10.0.0.0/8", "2001:db8::/32")
+	deny := prefixes(t, "10.66.0.0/16", "2001:db8:bad::/48")
+
+	tests := []struct {
+		name       string
+		allow      []netip.Prefix
+		remoteAddr string
+		wantStatus int
+	}{
+		{"IPv4 allowed", allow, "10.1.2.3:5000", http.StatusOK},
+		{"IPv4 outside allowlist", allow, "192.168.1.1:5000", http.StatusForbidden},
+		{"IPv4 denied inside allowlist", allow, "10.66.1.1:5000", http.StatusForbidden},
+		{"IPv6 allowed", allow, "[2001:db8::1]:5000", http.StatusOK},
+		{"IPv6 outside allowlist", allow, "[2001:db9::1]:5000", http.StatusForbidden},
+		{"IPv6 denied inside allowlist", allow, "[2001:db8:bad::1]:5000", http.StatusForbidden},
+		{"IPv6 with zone", allow, "[2001:db8::1%eth0]:5000", http.StatusOK},
+		{"IPv4-mapped IPv6", allow, "[::ffff:10.1.2.3]:5000", http.StatusOK},
+		{"no port", allow, "10.1.2.3", http.StatusOK},
+		{"empty allowlist allows all", nil, "192.168.1.1:5000", http.StatusOK},
+		{"empty allowlist still denies", nil, "10.66.1.1:5000", http.StatusForbidden},
+		{"malformed", allow, "not-an-ip", http.StatusForbidden},
+		{"empty", nil, "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveIPFilter(IPFilter(tt.allow, deny), tt.remoteAddr)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestIPFilter_ForwardedFor(t *testing.T) {
+	allow := prefixes(t, "10.0.0.0/8", "2001:db8::/32")
+	deny := prefixes(t, "10.66.0.0/16")
+	trusted := prefixes(t, "192.168.0.0/24", "fd00::/8")
+	mw := IPFilter(allow, deny, WithTrustedProxies(trusted))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		wantStatus int
+	}{
+		{"client via trusted proxy", "192.168.0.5:443", []string{"10.1.2.3"}, http.StatusOK},
+		{"denied client via trusted proxy", "192.168.0.5:443", []string{"10.66.1.1"}, http.StatusForbidden},
+		{"IPv6 client via IPv6 proxy", "[fd00::1]:443", []string{"2001:db8::7"}, http.StatusOK},
+		{"proxy chain", "192.168.0.5:443", []string{"10.1.2.3, 192.168.0.9"}, http.StatusOK},
+		{"repeated headers", "192.168.0.5:443", []string{"10.1.2.3", "192.168.0.9"}, http.StatusOK},
+		{"spoofed leftmost entry", "192.168.0.5:443", []string{"10.1.2.3, 172.16.0.1"}, http.StatusForbidden},
+		{"client with port", "192.168.0.5:443", []string{"10.1.2.3:1234"}, http.StatusOK},
+		{"malformed entry", "192.168.0.5:443", []string{"10.1.2.3, bogus"}, http.StatusForbidden},
+		{"header from untrusted peer ignored", "172.16.0.1:443", []string{"10.1.2.3"}, http.StatusForbidden},
+		{"no header judges the proxy", "192.168.0.5:443", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveIPFilter(mw, tt.remoteAddr, tt.forwarded...)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestIPFilter_ForwardedIgnoredWithoutTrustedProxies(t *testing.T) {
+	mw := IPFilter(prefixes(t, "10.0.0.0/8"), nil)
+
+	rr := serveIPFilter(mw, "172.16.0.1:443", "10.1.2.3")
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rr.Code)
+	}
+}
+
+func TestIPFilter_UnixSocketProxy(t *testing.T) {
+	mw := IPFilter(prefixes(t, "10.0.0.0/8"), nil)
+
+	if rr := serveIPFilter(mw, "@", "10.1.2.3"); rr.Code != http.StatusOK {
+		t.Errorf("allowed client behind the socket: expected 200, got %d", rr.Code)
+	}
+	if rr := serveIPFilter(mw, "@", "192.168.1.1"); rr.Code != http.StatusForbidden {
+		t.Errorf("other client behind the socket: expected 403, got %d", rr.Code)
+	}
+}
+
+func TestIPFilter_ErrorBody(t *testing.T) {
+	rr := serveIPFilter(IPFilter(prefixes(t, "10.0.0.0/8"), nil), "192.168.1.1:5000")
+
+	var body errorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Code != "forbidden" {
+		t.Errorf("expected code forbidden, got %q", body.Code)
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const claimsKey contextKey = "jwt_claims"
+
+// JWTOption customizes the JWT middleware.
+type JWTOption func(*jwtConfig)
+
+type jwtConfig struct {
+	parserOpts []jwt.ParserOption
+	public     *PublicRoutes
+}
+
+// WithIssuer requires the token's iss claim to equal iss.
+func WithIssuer(iss string) JWTOption {
+	return func(c *jwtConfig) {
+		c.parserOpts = append(c.parserOpts, jwt.WithIssuer(iss))
+	}
+}
+
+// WithAudience requires the token's aud claim to contain aud.
+func WithAudience(aud string) JWTOption {
+	return func(c *jwtConfig) {
+		c.parserOpts = append(c.parserOpts, jwt.WithAudience(aud))
+	}
+}
+
+// WithValidMethods restricts the accepted signing algorithms, e.g. "HS256".
+func WithValidMethods(methods ...string) JWTOption {
+	return func(c *jwtConfig) {
+		c.parserOpts = append(c.parserOpts, jwt.WithValidMethods(methods))
+	}
+}
+
+// WithPublicRoutes lets requests that public reports as public through
+// without a token.
+func WithPublicRoutes(public *PublicRoutes) JWTOption {
+	return func(c *jwtConfig) {
+		c.public = public
+	}
+}
+
+// JWT authenticates requests with an "Authorization: Bearer <token>" header.
+// The token signature is verified with keyfunc, an exp claim is required and
+// must be in the future, and any issuer or audience set via options must
+// match. Verified claims are stored in the request context; see
+// ClaimsFromContext. Missing, invalid or expired tokens get a 401.
+func JWT(keyfunc jwt.Keyfunc, opts ...JWTOption) Middleware {
+	cfg := &jwtConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	parser := jwt.NewParser(append([]jwt.ParserOption{jwt.WithExpirationRequired()}, cfg.parserOpts...)...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.public.IsPublic(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			raw, ok := bearerToken(r)
+			if !ok {
+				writeUnauthorized(w, "missing bearer token")
+				return
+			}
+
+			claims := jwt.MapClaims{}
+			if _, err := parser.ParseWithClaims(raw, claims, keyfunc); err != nil {
+				writeUnauthorized(w, "invalid or expired token")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), claimsKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClaimsFromContext returns the claims stored by the JWT middleware.
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsKey).(jwt.MapClaims)
+	return claims, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func writeUnauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	writeErrorJSON(w, http.StatusUnauthorized, "unauthorized", msg)
+}
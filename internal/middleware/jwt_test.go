@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testJWTSecret = []byte("test-secret")
+
+func testKeyfunc(*jwt.Token) (interface{}, error) {
+	return testJWTSecret, nil
+}
+
+func signToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testJWTSecret)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "alice",
+		"iss": "https://idp.example.com",
+		"aud": "storage-api",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func serveJWT(t *testing.T, mw Middleware, path, authHeader string) (*httptest.ResponseRecorder, jwt.MapClaims) {
+	t.Helper()
+	var captured jwt.MapClaims
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured, _ = ClaimsFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr, captured
+}
+
+func TestJWT_ValidToken(t *testing.T) {
+	mw := JWT(testKeyfunc, WithIssuer("https://idp.example.com"), WithAudience("storage-api"))
+
+	rr, claims := serveJWT(t, mw, "/api/v1/files", "Bearer "+signToken(t, validClaims()))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	sub, _ := claims.GetSubject()
+	if sub != "alice" {
+		t.Errorf("expected subject alice in context, got %q", sub)
+	}
+}
+
+func TestJWT_Rejects(t *testing.T) {
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+
+	noExp := validClaims()
+	delete(noExp, "exp")
+
+	wrongIssuer := validClaims()
+	wrongIssuer["iss"] = "https://evil.example.com"
+
+	wrongAudience := validClaims()
+	wrongAudience["aud"] = "another-api"
+
+	badSig, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("other-secret"))
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"missing header", ""},
+		{"wrong scheme", "Basic dXNlcjpwYXNz"},
+		{"empty token", "Bearer "},
+		{"garbage token", "Bearer not.a.jwt"},
+		{"bad signature", "Bearer " + badSig},
+		{"expired", "Bearer " + signToken(t, expired)},
+		{"no expiry", "Bearer " + signToken(t, noExp)},
+		{"wrong issuer", "Bearer " + signToken(t, wrongIssuer)},
+		{"wrong audience", "Bearer " + signToken(t, wrongAudience)},
+	}
+
+	mw := JWT(testKeyfunc, WithIssuer("https://idp.example.com"), WithAudience("storage-api"))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, claims := serveJWT(t, mw, "/api/v1/files", tt.header)
+
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("expected 401, got %d", rr.Code)
+			}
+			if claims != nil {
+				t.Error("handler should not have been reached")
+			}
+			if rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header")
+			}
+
+			var body errorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body.Error == "" {
+				t.Error("expected non-empty error message")
+			}
+		})
+	}
+}
+
+func TestJWT_RejectsUnexpectedAlgorithm(t *testing.T) {
+	mw := JWT(testKeyfunc, WithValidMethods("HS512"))
+
+	rr, _ := serveJWT(t, mw, "/api/v1/files", "Bearer "+signToken(t, validClaims()))
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for HS256 token when only HS512 allowed, got %d", rr.Code)
+	}
+}
+
+func TestJWT_PublicRoutes(t *testing.T) {
+	mw := JWT(testKeyfunc, WithPublicRoutes(NewPublicRoutes("/api/v1/health")))
+
+	rr, _ := serveJWT(t, mw, "/api/v1/health", "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected public path to pass without token, got %d", rr.Code)
+	}
+
+	rr, _ = serveJWT(t, mw, "/api/v1/files", "")
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected protected path to need a token, got %d", rr.Code)
+	}
+}
+
+func TestClaimsFromContext_Empty(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := ClaimsFromContext(req.Context()); ok {
+		t.Error("expected no claims in bare context")
+	}
+}
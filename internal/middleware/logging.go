@@ -1,15 +1,19 @@
 package middleware
 
 import (
+	"io"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"time"
 )
 
-// responseWriter wraps http.ResponseWriter to capture the status code.
+// responseWriter wraps http.ResponseWriter to capture the status code and
+// the number of body bytes written.
 type responseWriter struct {
 	http.ResponseWriter
 	status      int
+	size        int64
 	wroteHeader bool
 }
 
@@ -26,25 +30,107 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 		rw.status = http.StatusOK
 		rw.wroteHeader = true
 	}
-	return rw.ResponseWriter.Write(b)
+	n, err := rw.ResponseWriter.Write(b)
+	rw.size += int64(n)
+	return n, err
 }
 
-// Logging records structured log entries for every HTTP request using slog.
-func Logging(logger *slog.Logger) func(http.Handler) http.Handler {
+// Flush implements http.Flusher so streamed downloads and event streams are
+// not held back by the wrapper. Flushing commits a 200 if nothing was
+// written yet, as the underlying writer does.
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.status = http.StatusOK
+		rw.wroteHeader = true
+	}
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// ReadFrom passes copies through to the underlying writer's ReaderFrom,
+// which net/http implements with sendfile for files and a pooled buffer
+// otherwise. Without it, io.Copy into the wrapper would allocate a buffer
+// per response.
+func (rw *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if !rw.wroteHeader {
+		rw.status = http.StatusOK
+		rw.wroteHeader = true
+	}
+	var n int64
+	var err error
+	if rf, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{rw.ResponseWriter}, src)
+	}
+	rw.size += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for
+// deadlines and hijacking.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// LoggingOption customizes the Logging middleware.
+type LoggingOption func(*loggingConfig)
+
+type loggingConfig struct {
+	trusted []netip.Prefix
+}
+
+// LogTrustedProxies makes Logging's client_ip believe X-Forwarded-For when
+// the connection comes from one of proxies; see ClientIP.
+func LogTrustedProxies(proxies []netip.Prefix) LoggingOption {
+	return func(c *loggingConfig) {
+		c.trusted = append(c.trusted, proxies...)
+	}
+}
+
+// Logging records a structured access log entry for every HTTP request
+// using slog: method, path, remote address, client IP, status, request
+// body bytes read by the handler, response body bytes written, duration
+// and request ID. remote_addr is always the connection's peer; client_ip
+// differs from it behind a trusted proxy.
+func Logging(logger *slog.Logger, opts ...LoggingOption) func(http.Handler) http.Handler {
+	cfg := &loggingConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 
+			body := &countingReadCloser{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
 			wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
 			next.ServeHTTP(wrapped, r)
+			elapsed := time.Since(start)
 
 			logger.Info("request",
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("client_ip", clientIPString(r, cfg.trusted)),
 				slog.Int("status", wrapped.status),
-				slog.String("duration", time.Since(start).String()),
+				slog.Int64("bytes_read", body.n),
+				slog.Int64("bytes_written", wrapped.size),
+				slog.String("duration", elapsed.String()),
+				slog.Int64("duration_ms", elapsed.Milliseconds()),
 				slog.String("request_id", RequestIDFromContext(r.Context())),
 			)
 		})
 	}
 }
+
+// clientIPString is ClientIP for logs, empty when the address cannot be
+// parsed.
+func clientIPString(r *http.Request, trusted []netip.Prefix) string {
+	if addr := ClientIP(r, trusted); addr.IsValid() {
+		return addr.String()
+	}
+	return ""
+}
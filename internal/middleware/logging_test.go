@@ -3,9 +3,11 @@ package middleware
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -67,6 +69,20 @@ func TestLogging_IncludesRequestID(t *testing.T) {
 	}
 }
 
+func TestLogging_UsesInboundRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	handler := RequestID(Logging(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(headerXRequestID, "edge-proxy-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entry := parseLogEntry(t, &buf)
+	assertLogField(t, entry, "request_id", "edge-proxy-42")
+}
+
 func TestLogging_IncludesDuration(t *testing.T) {
 	var buf bytes.Buffer
 	logger := newTestLogger(&buf)
@@ -84,6 +100,73 @@ func TestLogging_IncludesDuration(t *testing.T) {
 	}
 }
 
+func TestLogging_RecordsBytesAndRemoteAddr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	handler := Logging(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("stored"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPut, "/files", strings.NewReader("twelve bytes"))
+	req.RemoteAddr = "192.0.2.7:4321"
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	entry := parseLogEntry(t, &buf)
+	assertLogField(t, entry, "remote_addr", "192.0.2.7:4321")
+	assertLogField(t, entry, "client_ip", "192.0.2.7")
+	assertLogFieldFloat(t, entry, "bytes_read", 12)
+	assertLogFieldFloat(t, entry, "bytes_written", 6)
+	if _, ok := entry["duration_ms"].(float64); !ok {
+		t.Error("expected numeric duration_ms in log entry")
+	}
+}
+
+func TestLogging_ClientIPBehindTrustedProxy(t *testing.T) {
+	trusted := prefixes(t, "192.168.0.0/24")
+	for _, tt := range []struct {
+		remoteAddr, want string
+	}{
+		{"192.168.0.5:443", "10.1.2.3"},
+		{"203.0.113.9:5000", "203.0.113.9"},
+	} {
+		var buf bytes.Buffer
+		handler := Logging(newTestLogger(&buf), LogTrustedProxies(trusted))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest(http.MethodGet, "/files", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set("X-Forwarded-For", "10.1.2.3")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		entry := parseLogEntry(t, &buf)
+		assertLogField(t, entry, "remote_addr", tt.remoteAddr)
+		assertLogField(t, entry, "client_ip", tt.want)
+	}
+}
+
+func TestLogging_ImplementsFlusher(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	handler := Logging(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("wrapped writer does not implement http.Flusher")
+		}
+		w.Write([]byte("chunk"))
+		f.Flush()
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/download", nil))
+
+	if !rr.Flushed {
+		t.Error("expected Flush to reach the recorder")
+	}
+}
+
 // helpers
 
 func parseLogEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
@@ -118,3 +201,47 @@ func assertLogFieldFloat(t *testing.T, entry map[string]interface{}, key string,
 		t.Errorf("log field %q = %v, want %v", key, got, want)
 	}
 }
+
+func TestLogging_AllowsFlush(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	var flushErr error
+	handler := Logging(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		flushErr = http.NewResponseController(w).Flush()
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stream", nil))
+
+	if flushErr != nil {
+		t.Fatalf("expected Flush to reach the recorder, got %v", flushErr)
+	}
+	if !rr.Flushed {
+		t.Error("expected recorder to be flushed")
+	}
+}
+
+func TestLogging_ReadFromCountsBytes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	handler := Logging(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rf, ok := w.(io.ReaderFrom)
+		if !ok {
+			t.Fatal("wrapped writer does not implement io.ReaderFrom")
+		}
+		rf.ReadFrom(strings.NewReader("file contents"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/download", nil))
+
+	if rr.Body.String() != "file contents" {
+		t.Errorf("expected body passed through, got %q", rr.Body.String())
+	}
+	entry := parseLogEntry(t, &buf)
+	assertLogFieldFloat(t, entry, "status", 200)
+	assertLogFieldFloat(t, entry, "bytes_written", 13)
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unmatchedRoute labels requests that did not match any registered pattern,
+// keeping 404 scans from creating a new series per path.
+const unmatchedRoute = "unmatched"
+
+// RouteFunc resolves the registered route pattern for a request, e.g.
+// "GET /api/v1/files/download". It returns "" when no route matches.
+type RouteFunc func(r *http.Request) string
+
+// Metrics records Prometheus request metrics labeled by route pattern and
+// status code. The route label comes from route rather than the raw URL path
+// so label cardinality stays bounded. All collectors are registered with reg.
+func Metrics(reg prometheus.Registerer, route RouteFunc) Middleware {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	inFlight := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	})
+
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	responseSize := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_response_size_bytes",
+		Help:    "HTTP response body size by route, method and status code.",
+		Buckets: prometheus.ExponentialBuckets(256, 4, 10),
+	}, []string{"route", "method", "status"})
+
+	uploaded := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "storage_bytes_uploaded_total",
+		Help: "Request body bytes received by route.",
+	}, []string{"route"})
+
+	downloaded := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "storage_bytes_downloaded_total",
+		Help: "Response body bytes sent by route.",
+	}, []string{"route"})
+
+	reg.MustRegister(requests, inFlight, duration, responseSize, uploaded, downloaded)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			label := routeLabel(route(r))
+
+			var body *countingReadCloser
+			if r.Body != nil && r.Body != http.NoBody {
+				body = &countingReadCloser{ReadCloser: r.Body}
+				r.Body = body
+			}
+
+			wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			status := strconv.Itoa(wrapped.status)
+			requests.WithLabelValues(label, r.Method, status).Inc()
+			duration.WithLabelValues(label, r.Method, status).Observe(time.Since(start).Seconds())
+			responseSize.WithLabelValues(label, r.Method, status).Observe(float64(wrapped.size))
+			downloaded.WithLabelValues(label).Add(float64(wrapped.size))
+			if body != nil {
+				uploaded.WithLabelValues(label).Add(float64(body.n))
+			}
+		})
+	}
+}
+
+// routeLabel strips the method prefix from a ServeMux pattern so
+// "GET /api/v1/files" becomes "/api/v1/files".
+func routeLabel(pattern string) string {
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		return pattern[i+1:]
+	}
+	return pattern
+}
+
+// countingReadCloser counts bytes read through the wrapped body, for
+// Metrics and Logging.
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
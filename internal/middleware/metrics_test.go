@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func staticRoute(pattern string) RouteFunc {
+	return func(*http.Request) string { return pattern }
+}
+
+func TestMetrics_RecordsRouteAndStatus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	handler := Metrics(reg, staticRoute("GET /api/v1/files/download"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=a.txt", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	expected := `
+# HELP http_requests_total Total HTTP requests by route, method and status code.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/api/v1/files/download",status="200"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "http_requests_total"); err != nil {
+		t.Error(err)
+	}
+
+	expected = `
+# HELP storage_bytes_downloaded_total Response body bytes sent by route.
+# TYPE storage_bytes_downloaded_total counter
+storage_bytes_downloaded_total{route="/api/v1/files/download"} 5
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "storage_bytes_downloaded_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMetrics_CountsUploadedBytes(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	handler := Metrics(reg, staticRoute("POST /api/v1/files/upload"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 64)
+		for {
+			if _, err := r.Body.Read(buf); err != nil {
+				break
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload?path=a.txt", strings.NewReader("0123456789"))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	expected := `
+# HELP storage_bytes_uploaded_total Request body bytes received by route.
+# TYPE storage_bytes_uploaded_total counter
+storage_bytes_uploaded_total{route="/api/v1/files/upload"} 10
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "storage_bytes_uploaded_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMetrics_UnmatchedRoute(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	handler := Metrics(reg, staticRoute(""))(http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodGet, "/random/scanner/path", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	expected := `
+# HELP http_requests_total Total HTTP requests by route, method and status code.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="unmatched",status="404"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "http_requests_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMetrics_InFlightReturnsToZero(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	var during float64
+	handler := Metrics(reg, staticRoute("GET /"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = gatherGauge(t, reg, "http_requests_in_flight")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if during != 1 {
+		t.Errorf("expected 1 in-flight request during handling, got %v", during)
+	}
+	if after := gatherGauge(t, reg, "http_requests_in_flight"); after != 0 {
+		t.Errorf("expected 0 in-flight requests after handling, got %v", after)
+	}
+}
+
+func TestRouteLabel(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"GET /api/v1/files", "/api/v1/files"},
+		{"/api/v1/files", "/api/v1/files"},
+		{"", "unmatched"},
+	}
+	for _, tt := range tests {
+		if got := routeLabel(tt.pattern); got != tt.want {
+			t.Errorf("routeLabel(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func gatherGauge(t *testing.T, reg *prometheus.Registry, name string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() == name {
+			return f.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("metric %q not found", name)
+	return 0
+}
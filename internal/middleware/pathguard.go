@@ -2,22 +2,71 @@ package middleware
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"unicode/utf8"
 )
 
 // errorResponse mirrors the api.ErrorResponse JSON shape but is defined
-// locally so pathguard has no dependency on internal/api.
+// locally so pathguard has no dependency on internal/api. The codes passed to
+// writeErrorJSON must match the api.Code* values.
 type errorResponse struct {
-	Error string `json:"error"`
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Errors returned by CleanPath. Their messages are safe to show clients.
+var (
+	ErrInvalidPath     = errors.New("invalid path")
+	ErrTooManySegments = errors.New("path has too many segments")
+	ErrNameTooLong     = errors.New("path has a name that is too long")
+)
+
+// Default path limits. 255 bytes is the usual filesystem NAME_MAX.
+const (
+	DefaultMaxPathSegments = 64
+	DefaultMaxNameBytes    = 255
+)
+
+// PathLimits bounds the shape of a path: how many segments it may have and
+// how many bytes each segment may use. Zero fields use the defaults.
+type PathLimits struct {
+	MaxSegments  int
+	MaxNameBytes int
+}
+
+// withDefaults fills in zero or negative fields.
+func (l PathLimits) withDefaults() PathLimits {
+	if l.MaxSegments <= 0 {
+		l.MaxSegments = DefaultMaxPathSegments
+	}
+	if l.MaxNameBytes <= 0 {
+		l.MaxNameBytes = DefaultMaxNameBytes
+	}
+	return l
 }
 
 // PathGuard rejects requests whose "path" query parameter contains directory
-// traversal sequences (..) or null bytes. Valid paths are normalized with
-// path.Clean before the request continues.
+// traversal sequences (..) or control characters (0x00-0x1F, 0x7F), which
+// could otherwise leak into logs and response headers, or that exceeds the
+// default PathLimits. Valid paths are normalized with path.Clean, keeping
+// any trailing slash, before the request continues.
 func PathGuard(next http.Handler) http.Handler {
+	return PathGuardWithLimits(PathLimits{})(next)
+}
+
+// PathGuardWithLimits is PathGuard with custom limits.
+func PathGuardWithLimits(limits PathLimits) Middleware {
+	return func(next http.Handler) http.Handler {
+		return pathGuard(next, limits)
+	}
+}
+
+func pathGuard(next http.Handler, limits PathLimits) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		raw := r.URL.Query().Get("path")
 		if raw == "" {
@@ -28,17 +77,22 @@ func PathGuard(next http.Handler) http.Handler {
 		// Decode to catch double-encoded traversal (%252e%252e).
 		decoded, err := url.QueryUnescape(raw)
 		if err != nil {
-			writeErrorJSON(w, http.StatusBadRequest, "invalid path encoding")
+			writeErrorJSON(w, http.StatusBadRequest, "path_invalid", "invalid path encoding")
 			return
 		}
 
-		if containsTraversal(decoded) || containsNullByte(decoded) {
-			writeErrorJSON(w, http.StatusBadRequest, "invalid path")
+		cleaned, err := CleanPath(decoded, limits)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, pathErrorCode(err), err.Error())
 			return
 		}
+		// Keep a trailing slash: Upload reads it as "store under the
+		// multipart filename".
+		if strings.HasSuffix(decoded, "/") && cleaned != "/" {
+			cleaned += "/"
+		}
 
-		// Normalize and replace the query parameter.
-		cleaned := path.Clean(decoded)
+		// Replace the query parameter with the normalized path.
 		q := r.URL.Query()
 		q.Set("path", cleaned)
 		r.URL.RawQuery = q.Encode()
@@ -47,16 +101,97 @@ func PathGuard(next http.Handler) http.Handler {
 	})
 }
 
+// CleanPath applies PathGuard's checks to a single storage path and returns
+// it normalized with path.Clean. Besides traversal and control characters it
+// rejects backslashes (a separator on Windows backends), drive-letter
+// prefixes such as "C:", invalid UTF-8 including overlong encodings, and
+// percent-escapes that would still decode to ".", "/", "\\" or NUL, and
+// paths exceeding limits. Handlers that take paths from somewhere other than
+// the "path" query parameter, such as a JSON body, use it to get the same
+// validation.
+func CleanPath(p string, limits PathLimits) (string, error) {
+	limits = limits.withDefaults()
+	if containsTraversal(p) || containsControl(p) || !utf8.ValidString(p) ||
+		strings.ContainsRune(p, '\\') || hasDriveLetter(p) || containsEncodedSpecial(p) {
+		return "", ErrInvalidPath
+	}
+	cleaned := path.Clean(p)
+	if countSegments(cleaned) > limits.MaxSegments {
+		return "", ErrTooManySegments
+	}
+	if longestSegment(cleaned) > limits.MaxNameBytes {
+		return "", ErrNameTooLong
+	}
+	return cleaned, nil
+}
+
+// pathErrorCode maps a CleanPath error to its api.Code* value.
+func pathErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrTooManySegments):
+		return "path_too_deep"
+	case errors.Is(err, ErrNameTooLong):
+		return "name_too_long"
+	default:
+		return "path_invalid"
+	}
+}
+
 func containsTraversal(s string) bool {
 	return strings.Contains(s, "..")
 }
 
-func containsNullByte(s string) bool {
-	return strings.ContainsRune(s, '\x00')
+// hasDriveLetter reports whether p starts like a Windows absolute path.
+func hasDriveLetter(p string) bool {
+	return len(p) >= 2 && p[1] == ':' &&
+		(p[0] >= 'a' && p[0] <= 'z' || p[0] >= 'A' && p[0] <= 'Z')
+}
+
+// containsEncodedSpecial reports whether p, which has already been decoded,
+// still holds an escape for a dot, separator or NUL. Such paths were encoded
+// more than once and could turn into traversal if decoded again downstream.
+func containsEncodedSpecial(p string) bool {
+	lower := strings.ToLower(p)
+	for _, esc := range []string{"%2e", "%2f", "%5c", "%00"} {
+		if strings.Contains(lower, esc) {
+			return true
+		}
+	}
+	return false
+}
+
+// countSegments returns the number of non-empty elements in a cleaned path.
+func countSegments(p string) int {
+	p = strings.Trim(p, "/")
+	if p == "" || p == "." {
+		return 0
+	}
+	return strings.Count(p, "/") + 1
+}
+
+// longestSegment returns the byte length of the longest element of p.
+func longestSegment(p string) int {
+	longest := 0
+	for _, seg := range strings.Split(p, "/") {
+		longest = max(longest, len(seg))
+	}
+	return longest
 }
 
-func writeErrorJSON(w http.ResponseWriter, status int, msg string) {
+// containsControl reports whether s contains an ASCII control character,
+// including NUL, newlines and DEL.
+func containsControl(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool {
+		return r < 0x20 || r == 0x7f
+	}) >= 0
+}
+
+func writeErrorJSON(w http.ResponseWriter, status int, code, msg string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(errorResponse{Error: msg})
+	json.NewEncoder(w).Encode(errorResponse{
+		Error:     msg,
+		Code:      code,
+		RequestID: w.Header().Get(headerXRequestID),
+	})
 }
@@ -2,8 +2,11 @@ package middleware
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -62,6 +65,36 @@ func TestPathGuard_BlocksNullBytes(t *testing.T) {
 	})
 }
 
+func TestPathGuard_BlocksControlCharacters(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"newline", "file%0a.txt"},
+		{"carriage return", "file%0d%0aSet-Cookie:x.txt"},
+		{"tab", "file%09.txt"},
+		{"escape", "file%1b[31m.txt"},
+		{"delete", "file%7f.txt"},
+		{"double-encoded newline", "file%250a.txt"},
+	}
+
+	handler := PathGuard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not have been called")
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/files?path="+tt.path, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rr.Code)
+			}
+		})
+	}
+}
+
 func TestPathGuard_AllowsValidPaths(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -70,9 +103,13 @@ func TestPathGuard_AllowsValidPaths(t *testing.T) {
 	}{
 		{"simple filename", "readme.txt", "readme.txt"},
 		{"nested path", "docs/guide/intro.md", "docs/guide/intro.md"},
-		{"trailing slash cleaned", "docs/guide/", "docs/guide"},
+		{"trailing slash kept", "docs//guide/./", "docs/guide/"},
+		{"root stays root", "/", "/"},
 		{"double slash cleaned", "docs//guide", "docs/guide"},
 		{"dot current dir", "./readme.txt", "readme.txt"},
+		{"encoded space", "docs/my%20file.txt", "docs/my file.txt"},
+		{"non-ascii utf-8", "docs/r%C3%A9sum%C3%A9.pdf", "docs/résumé.pdf"},
+		{"colon later in name", "notes/10:30.txt", "notes/10:30.txt"},
 	}
 
 	for _, tt := range tests {
@@ -115,3 +152,111 @@ func TestPathGuard_NoPathParam(t *testing.T) {
 		t.Errorf("expected 200, got %d", rr.Code)
 	}
 }
+
+func TestPathGuard_BlocksTooManySegments(t *testing.T) {
+	handler := PathGuard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called for an over-segmented path")
+	}))
+
+	deep := "/" + strings.Repeat("a/", DefaultMaxPathSegments+1)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files?path="+url.QueryEscape(deep), nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestPathGuardWithLimits(t *testing.T) {
+	handler := PathGuardWithLimits(PathLimits{MaxSegments: 3, MaxNameBytes: 8})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		path     string
+		want     int
+		wantCode string
+	}{
+		{"/a/b/c", http.StatusOK, ""},
+		{"/a//b/c/", http.StatusOK, ""},
+		{"/a/b/c/d", http.StatusBadRequest, "path_too_deep"},
+		{"/a/12345678", http.StatusOK, ""},
+		{"/a/123456789", http.StatusBadRequest, "name_too_long"},
+		// Bytes, not runes: four 2-byte runes fit, five do not.
+		{"/ééééé", http.StatusBadRequest, "name_too_long"},
+		{"/éééé", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/files?path="+url.QueryEscape(tt.path), nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("path %q: expected %d, got %d", tt.path, tt.want, rr.Code)
+			continue
+		}
+		if tt.wantCode != "" {
+			var resp errorResponse
+			json.NewDecoder(rr.Body).Decode(&resp)
+			if resp.Code != tt.wantCode {
+				t.Errorf("path %q: expected code %s, got %q", tt.path, tt.wantCode, resp.Code)
+			}
+		}
+	}
+}
+
+func TestCleanPath_DefaultLimits(t *testing.T) {
+	atSegments := "/" + strings.Repeat("a/", DefaultMaxPathSegments)
+	if _, err := CleanPath(atSegments, PathLimits{}); err != nil {
+		t.Errorf("%d segments: unexpected error %v", DefaultMaxPathSegments, err)
+	}
+	if _, err := CleanPath(atSegments+"a", PathLimits{}); !errors.Is(err, ErrTooManySegments) {
+		t.Errorf("%d segments: expected ErrTooManySegments, got %v", DefaultMaxPathSegments+1, err)
+	}
+
+	atName := "/dir/" + strings.Repeat("n", DefaultMaxNameBytes)
+	if _, err := CleanPath(atName, PathLimits{}); err != nil {
+		t.Errorf("%d-byte name: unexpected error %v", DefaultMaxNameBytes, err)
+	}
+	if _, err := CleanPath(atName+"n", PathLimits{}); !errors.Is(err, ErrNameTooLong) {
+		t.Errorf("%d-byte name: expected ErrNameTooLong, got %v", DefaultMaxNameBytes+1, err)
+	}
+}
+
+func TestPathGuard_BlocksEncodingTricks(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"encoded slash after dots", "..%2F..%2Fetc%2Fpasswd"},
+		{"fully encoded traversal", "%2e%2e%2f%2e%2e%2fetc"},
+		{"double-encoded traversal", "%252e%252e%252fetc"},
+		{"triple-encoded dots", "%25252e%25252e/etc"},
+		{"embedded NUL", "docs/a%00.txt"},
+		{"double-encoded NUL", "docs/a%2500.txt"},
+		{"overlong dot", "%c0%ae%c0%ae/etc/passwd"},
+		{"overlong slash", "..%c0%afetc"},
+		{"invalid utf-8", "docs/%ff.txt"},
+		{"backslash separator", "docs%5c..%5csecret"},
+		{"backslash traversal", "..%5C..%5Cwindows"},
+		{"double-encoded backslash", "docs%255csecret"},
+		{"drive letter", "C:/Windows/system.ini"},
+		{"drive letter backslash", "c:%5cboot.ini"},
+	}
+
+	handler := PathGuard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("handler should not be called for %q", r.URL.Query().Get("path"))
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/files?path="+tt.path, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rr.Code)
+			}
+		})
+	}
+}
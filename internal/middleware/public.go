@@ -0,0 +1,38 @@
+package middleware
+
+import "net/http"
+
+// PublicRoutes is the one place that decides which requests are public:
+// every OPTIONS request, so preflights and capability probes need no
+// credentials, plus requests to registered path prefixes. Middleware that
+// authenticates or limits clients consults IsPublic instead of keeping
+// its own exemptions. Register prefixes before serving; the set is not
+// safe to change while requests are running.
+type PublicRoutes struct {
+	prefixes []string
+}
+
+// NewPublicRoutes returns a registry with prefixes registered.
+func NewPublicRoutes(prefixes ...string) *PublicRoutes {
+	p := &PublicRoutes{}
+	p.Add(prefixes...)
+	return p
+}
+
+// Add makes each prefix public along with every path below it; a prefix
+// matches whole segments, so /api/v1/health does not cover
+// /api/v1/healthz.
+func (p *PublicRoutes) Add(prefixes ...string) {
+	for _, prefix := range prefixes {
+		p.prefixes = append(p.prefixes, normalizeSlashes(prefix))
+	}
+}
+
+// IsPublic reports whether r may skip authentication and per-client
+// limits. A nil registry has no public routes.
+func (p *PublicRoutes) IsPublic(r *http.Request) bool {
+	if p == nil {
+		return false
+	}
+	return r.Method == http.MethodOptions || underAny(r.URL.Path, p.prefixes)
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublicRoutes_IsPublic(t *testing.T) {
+	public := NewPublicRoutes("/api/v1/health", "/api/v1/assets/")
+
+	tests := []struct {
+		method, target string
+		want           bool
+	}{
+		{http.MethodGet, "/api/v1/health", true},
+		{http.MethodGet, "/api/v1/health/deep", true},
+		{http.MethodGet, "/api/v1/healthz", false},
+		{http.MethodGet, "/api/v1/assets/logo.png", true},
+		{http.MethodGet, "/api/v1/assets", true},
+		{http.MethodGet, "/api/v1/files?path=/api/v1/health", false},
+		{http.MethodDelete, "/api/v1/files", false},
+		{http.MethodOptions, "/api/v1/files", true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, nil)
+		if got := public.IsPublic(r); got != tt.want {
+			t.Errorf("%s %s: expected %v, got %v", tt.method, tt.target, tt.want, got)
+		}
+	}
+
+	var none *PublicRoutes
+	if none.IsPublic(httptest.NewRequest(http.MethodOptions, "/api/v1/health", nil)) {
+		t.Error("expected a nil registry to make nothing public")
+	}
+}
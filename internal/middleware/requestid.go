@@ -13,12 +13,18 @@ const requestIDKey contextKey = "request_id"
 
 const headerXRequestID = "X-Request-ID"
 
+// maxRequestIDLen bounds inbound request IDs so a client cannot bloat every
+// log line and response with an oversized header.
+const maxRequestIDLen = 128
+
 // RequestID injects a UUID v4 request ID into the context and response header.
-// If the incoming request already has an X-Request-ID header, it is preserved.
+// If the incoming request already has a valid X-Request-ID header (for example
+// one assigned by an edge proxy), it is preserved so traces correlate across
+// services. Invalid inbound IDs are replaced with a fresh one.
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id := r.Header.Get(headerXRequestID)
-		if id == "" {
+		if !validRequestID(id) {
 			id = newUUIDv4()
 		}
 
@@ -36,6 +42,25 @@ func RequestIDFromContext(ctx context.Context) string {
 	return ""
 }
 
+// validRequestID accepts 1 to maxRequestIDLen characters drawn from letters,
+// digits and "-_.:", which covers UUIDs, ULIDs and common proxy formats while
+// keeping IDs safe to echo into headers and logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // newUUIDv4 generates a RFC 4122 version 4 UUID using crypto/rand.
 func newUUIDv4() string {
 	var uuid [16]byte
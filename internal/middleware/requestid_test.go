@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -52,6 +53,59 @@ func TestRequestID_PreservesIncomingHeader(t *testing.T) {
 	}
 }
 
+func TestRequestID_ReplacesInvalidIncomingHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+	}{
+		{"too long", strings.Repeat("a", maxRequestIDLen+1)},
+		{"spaces", "id with spaces"},
+		{"header injection", "abc\r\nSet-Cookie: x=1"},
+		{"non-ascii", "id-ü"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var captured string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				captured = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header[headerXRequestID] = []string{tt.incoming}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if captured == tt.incoming {
+				t.Errorf("expected invalid incoming ID %q to be replaced", tt.incoming)
+			}
+			if !uuidV4Re.MatchString(captured) {
+				t.Errorf("expected generated UUID v4, got %q", captured)
+			}
+			if rr.Header().Get(headerXRequestID) != captured {
+				t.Errorf("response header %q != context value %q", rr.Header().Get(headerXRequestID), captured)
+			}
+		})
+	}
+}
+
+func TestRequestID_AcceptsMaxLength(t *testing.T) {
+	incoming := strings.Repeat("a", maxRequestIDLen)
+
+	var captured string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(headerXRequestID, incoming)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if captured != incoming {
+		t.Errorf("expected %d-char ID to be preserved, got %q", maxRequestIDLen, captured)
+	}
+}
+
 func TestRequestID_ContextRoundTrip(t *testing.T) {
 	var captured string
 	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// NormalizeSlashes collapses repeated slashes in the request URL path and
+// drops a trailing one, except for the root, so /api/v1//files/ reaches
+// the same route as /api/v1/files instead of a redirect or a 404. It must
+// wrap the mux. Only the URL path changes; the query, including the
+// storage "path" parameter, is left as sent. Paths at or below any of
+// skip are left alone, for handlers such as WebDAV where a trailing slash
+// marks a collection.
+func NormalizeSlashes(skip ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := normalizeSlashes(r.URL.Path)
+			if p == r.URL.Path || underAny(p, skip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Copy like http.StripPrefix so the caller's request is untouched.
+			r2 := new(http.Request)
+			*r2 = *r
+			u := *r.URL
+			u.Path = p
+			if u.RawPath != "" {
+				u.RawPath = normalizeSlashes(u.RawPath)
+			}
+			r2.URL = &u
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// normalizeSlashes returns p without empty segments or a trailing slash.
+// Paths not starting with a slash, such as "*", are returned unchanged.
+func normalizeSlashes(p string) string {
+	if !strings.HasPrefix(p, "/") || (!strings.Contains(p, "//") && (p == "/" || !strings.HasSuffix(p, "/"))) {
+		return p
+	}
+	segs := strings.Split(p, "/")
+	kept := segs[:0]
+	for _, s := range segs {
+		if s != "" {
+			kept = append(kept, s)
+		}
+	}
+	return "/" + strings.Join(kept, "/")
+}
+
+// underAny reports whether p is one of prefixes or below one.
+func underAny(p string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
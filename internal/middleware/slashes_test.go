@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeSlashes(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/", "/"},
+		{"//", "/"},
+		{"/api/v1/files", "/api/v1/files"},
+		{"/api/v1/files/", "/api/v1/files"},
+		{"/api/v1//health", "/api/v1/health"},
+		{"//api///v1/files//", "/api/v1/files"},
+		{"/api/v1/assets/a/../b/", "/api/v1/assets/a/../b"},
+		{"/webdav/docs/", "/webdav/docs/"},
+		{"/webdav//docs", "/webdav//docs"},
+		{"*", "*"},
+	}
+	for _, tt := range tests {
+		var got, query string
+		handler := NormalizeSlashes("/webdav")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, query = r.URL.Path, r.URL.RawQuery
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path, req.URL.RawQuery = tt.in, "path=/docs//a/"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.in, tt.want, got)
+		}
+		if query != "path=/docs//a/" {
+			t.Errorf("%s: expected the query untouched, got %q", tt.in, query)
+		}
+		if req.URL.Path != tt.in {
+			t.Errorf("%s: the caller's request was modified", tt.in)
+		}
+	}
+}
+
+func TestNormalizeSlashes_EscapedPath(t *testing.T) {
+	var got string
+	handler := NormalizeSlashes()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.EscapedPath()
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/assets//a%3Fb.txt/", nil))
+	if got != "/api/v1/assets/a%3Fb.txt" {
+		t.Errorf("expected /api/v1/assets/a%%3Fb.txt, got %s", got)
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "go-storage-api/internal/middleware"
+
+// Tracing starts an OpenTelemetry server span per request, named after the
+// matched route pattern (e.g. "GET /api/v1/files/download") so span names
+// stay low-cardinality. A W3C traceparent header on the request makes the
+// span a child of the caller's trace, and the span travels in the request
+// context so storage calls made with r.Context() nest under it. The span
+// records the method, path, route, status code, response size and, when the
+// RequestID middleware ran first, the request ID. 5xx responses mark the
+// span as an error.
+func Tracing(tp trace.TracerProvider, route RouteFunc) Middleware {
+	tracer := tp.Tracer(tracerName)
+	propagator := propagation.TraceContext{}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			pattern := route(r)
+			name := pattern
+			if name == "" {
+				name = r.Method + " " + unmatchedRoute
+			}
+
+			attrs := []attribute.KeyValue{
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("http.route", routeLabel(pattern)),
+			}
+			if id := RequestIDFromContext(ctx); id != "" {
+				attrs = append(attrs, attribute.String("http.request.id", id))
+			}
+
+			ctx, span := tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+			span.SetAttributes(
+				attribute.Int("http.response.status_code", wrapped.status),
+				attribute.Int64("http.response.body.size", wrapped.size),
+			)
+			if wrapped.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(wrapped.status))
+			}
+		})
+	}
+}
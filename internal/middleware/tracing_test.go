@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newRecordingProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	rec := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)), rec
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracing_RecordsSpan(t *testing.T) {
+	tp, rec := newRecordingProvider()
+
+	var handlerSpan trace.SpanContext
+	handler := RequestID(Tracing(tp, staticRoute("GET /api/v1/files/download"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.Write([]byte("hello"))
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/download?path=a.txt", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/v1/files/download" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("expected server span, got %v", span.SpanKind())
+	}
+	if span.SpanContext().SpanID() != handlerSpan.SpanID() {
+		t.Error("expected the span to be active in the handler's context")
+	}
+
+	want := map[string]string{
+		"http.request.method":       "GET",
+		"url.path":                  "/api/v1/files/download",
+		"http.route":                "/api/v1/files/download",
+		"http.request.id":           "req-1",
+		"http.response.status_code": "200",
+		"http.response.body.size":   "5",
+	}
+	for key, val := range want {
+		got, ok := spanAttr(span, key)
+		if !ok || got.Emit() != val {
+			t.Errorf("attribute %s = %q, want %q", key, got.Emit(), val)
+		}
+	}
+}
+
+func TestTracing_JoinsIncomingTrace(t *testing.T) {
+	tp, rec := newRecordingProvider()
+
+	handler := Tracing(tp, staticRoute("GET /api/v1/files"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	span := rec.Ended()[0]
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected caller's trace ID, got %s", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("expected caller's span as parent, got %s", got)
+	}
+}
+
+func TestTracing_ServerErrorSetsStatus(t *testing.T) {
+	tp, rec := newRecordingProvider()
+
+	handler := Tracing(tp, staticRoute(""))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	span := rec.Ended()[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected error status, got %v", span.Status().Code)
+	}
+	if span.Name() != "GET unmatched" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+}
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// quotaBuckets is how many slices a quota window is tracked in; bytes
+// leave the window one slice at a time.
+const quotaBuckets = 60
+
+// UploadQuotaOption customizes the UploadQuota middleware.
+type UploadQuotaOption func(*uploadQuota)
+
+// QuotaTrustedProxies makes UploadQuota believe X-Forwarded-For when the
+// connection comes from one of proxies; see ClientIP.
+func QuotaTrustedProxies(proxies []netip.Prefix) UploadQuotaOption {
+	return func(q *uploadQuota) {
+		q.trusted = append(q.trusted, proxies...)
+	}
+}
+
+// QuotaPublicRoutes exempts requests that public reports as public from
+// the quota.
+func QuotaPublicRoutes(public *PublicRoutes) UploadQuotaOption {
+	return func(q *uploadQuota) {
+		q.public = public
+	}
+}
+
+// UploadQuota caps the request body bytes each client address may send
+// with POST, PUT and PATCH requests to limit within any window. Bytes are
+// counted as the handler reads them, so refused or abandoned uploads only
+// count what was actually received. Once a client has used its quota, or
+// a request's Content-Length would take it over, further uploads get 429
+// quota_exceeded with a Retry-After of the seconds until enough of the
+// window has passed. A request already under way is allowed to finish.
+// limit or window zero or less returns a pass-through middleware.
+func UploadQuota(limit int64, window time.Duration, opts ...UploadQuotaOption) Middleware {
+	if limit <= 0 || window <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return newUploadQuota(limit, window, opts...).middleware
+}
+
+func newUploadQuota(limit int64, window time.Duration, opts ...UploadQuotaOption) *uploadQuota {
+	q := &uploadQuota{
+		limit:   limit,
+		window:  window,
+		slice:   max(window/quotaBuckets, time.Millisecond),
+		clients: make(map[netip.Addr]*quotaUsage),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+type uploadQuota struct {
+	limit   int64
+	window  time.Duration
+	slice   time.Duration
+	trusted []netip.Prefix
+	public  *PublicRoutes
+	now     func() time.Time
+
+	mu        sync.Mutex
+	clients   map[netip.Addr]*quotaUsage
+	lastSweep time.Time
+}
+
+// quotaUsage is one client's bytes per window slice, oldest first.
+type quotaUsage struct {
+	buckets []quotaBucket
+}
+
+type quotaBucket struct {
+	start time.Time
+	bytes int64
+}
+
+func (q *uploadQuota) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || (r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodPatch) || q.public.IsPublic(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		addr := ClientIP(r, q.trusted)
+		if retry, ok := q.admit(addr, max(r.ContentLength, 0)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			writeErrorJSON(w, http.StatusTooManyRequests, "quota_exceeded", "upload quota exceeded; try again later")
+			return
+		}
+		r.Body = &quotaBody{ReadCloser: r.Body, q: q, addr: addr}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// admit reports whether addr may send want more bytes now. If not, retry
+// is the number of seconds until it may.
+func (q *uploadQuota) admit(addr netip.Addr, want int64) (retry int, ok bool) {
+	now := q.now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.sweep(now)
+
+	u := q.clients[addr]
+	if u == nil {
+		return 0, true
+	}
+	used := q.expire(u, now)
+	if used < q.limit && used+want <= q.limit {
+		return 0, true
+	}
+
+	// Find when enough of the oldest slices leave the window. A request
+	// larger than the whole quota can only wait for an empty window.
+	need := max(used-q.limit+1, used+want-q.limit)
+	at := now
+	for _, b := range u.buckets {
+		need -= b.bytes
+		at = b.start.Add(q.slice + q.window)
+		if need <= 0 {
+			break
+		}
+	}
+	return max(1, int(math.Ceil(at.Sub(now).Seconds()))), false
+}
+
+// add counts n bytes received from addr.
+func (q *uploadQuota) add(addr netip.Addr, n int64) {
+	now := q.now()
+	start := now.Truncate(q.slice)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	u := q.clients[addr]
+	if u == nil {
+		u = &quotaUsage{}
+		q.clients[addr] = u
+	}
+	if last := len(u.buckets) - 1; last >= 0 && u.buckets[last].start.Equal(start) {
+		u.buckets[last].bytes += n
+		return
+	}
+	u.buckets = append(u.buckets, quotaBucket{start: start, bytes: n})
+}
+
+// sweep forgets clients with nothing left in the window, at most once per
+// window.
+func (q *uploadQuota) sweep(now time.Time) {
+	if now.Sub(q.lastSweep) < q.window {
+		return
+	}
+	q.lastSweep = now
+	for addr, u := range q.clients {
+		if q.expire(u, now) == 0 {
+			delete(q.clients, addr)
+		}
+	}
+}
+
+// expire drops u's slices that ended a whole window before now and
+// returns the bytes left.
+func (q *uploadQuota) expire(u *quotaUsage, now time.Time) int64 {
+	i := 0
+	for i < len(u.buckets) && !u.buckets[i].start.Add(q.slice+q.window).After(now) {
+		i++
+	}
+	u.buckets = u.buckets[i:]
+	var total int64
+	for _, b := range u.buckets {
+		total += b.bytes
+	}
+	return total
+}
+
+// quotaBody counts the bytes a handler reads from a request body.
+type quotaBody struct {
+	io.ReadCloser
+	q    *uploadQuota
+	addr netip.Addr
+}
+
+func (b *quotaBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.q.add(b.addr, int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// quotaClock is a settable clock for uploadQuota.now.
+type quotaClock struct{ t time.Time }
+
+func (c *quotaClock) now() time.Time { return c.t }
+
+// upload sends body from remoteAddr through mw to a handler that reads
+// it all.
+func upload(mw Middleware, method, remoteAddr, body string) *httptest.ResponseRecorder {
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	req := httptest.NewRequest(method, "/api/v1/files?path=/a.bin", strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestUploadQuota_RejectsOnceUsedUp(t *testing.T) {
+	clock := &quotaClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	q := newUploadQuota(100, time.Minute)
+	q.now = clock.now
+	mw := q.middleware
+
+	if rr := upload(mw, http.MethodPut, "10.0.0.1:1", strings.Repeat("x", 60)); rr.Code != http.StatusCreated {
+		t.Fatalf("first upload: expected 201, got %d", rr.Code)
+	}
+	// 60 + 60 would exceed the quota, and Content-Length says so up front.
+	rr := upload(mw, http.MethodPut, "10.0.0.1:2", strings.Repeat("x", 60))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("upload past the quota: expected 429, got %d", rr.Code)
+	}
+	retry, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+	if err != nil || retry < 1 || retry > 62 {
+		t.Errorf("expected a Retry-After within the window, got %q", rr.Header().Get("Retry-After"))
+	}
+	if rr := upload(mw, http.MethodPut, "10.0.0.1:3", strings.Repeat("x", 40)); rr.Code != http.StatusCreated {
+		t.Errorf("upload filling the quota exactly: expected 201, got %d", rr.Code)
+	}
+	if rr := upload(mw, http.MethodPost, "10.0.0.1:4", "x"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("upload with the quota used up: expected 429, got %d", rr.Code)
+	}
+
+	// Other clients and reads are unaffected.
+	if rr := upload(mw, http.MethodPut, "10.0.0.2:1", strings.Repeat("x", 60)); rr.Code != http.StatusCreated {
+		t.Errorf("another client: expected 201, got %d", rr.Code)
+	}
+	if rr := upload(mw, http.MethodGet, "10.0.0.1:5", ""); rr.Code != http.StatusCreated {
+		t.Errorf("GET: expected it to pass, got %d", rr.Code)
+	}
+
+	// Once the window has passed, the client may upload again and idle
+	// clients are forgotten.
+	clock.t = clock.t.Add(time.Minute + 2*time.Second)
+	if rr := upload(mw, http.MethodPut, "10.0.0.1:6", strings.Repeat("x", 60)); rr.Code != http.StatusCreated {
+		t.Errorf("after the window: expected 201, got %d", rr.Code)
+	}
+	if _, ok := q.clients[mustAddr(t, "10.0.0.2")]; ok {
+		t.Error("expected the idle client to be evicted")
+	}
+}
+
+func TestUploadQuota_CountsBytesRead(t *testing.T) {
+	q := newUploadQuota(100, time.Minute)
+	handler := q.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.CopyN(io.Discard, r.Body, 10) // gives up early
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(strings.Repeat("x", 90)))
+	req.RemoteAddr = "10.0.0.1:1"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if used := q.expire(q.clients[mustAddr(t, "10.0.0.1")], time.Now()); used != 10 {
+		t.Errorf("expected only the 10 bytes read to count, got %d", used)
+	}
+}
+
+func TestUploadQuota_Disabled(t *testing.T) {
+	mw := UploadQuota(0, time.Minute)
+	for range 3 {
+		if rr := upload(mw, http.MethodPut, "10.0.0.1:1", strings.Repeat("x", 1000)); rr.Code != http.StatusCreated {
+			t.Fatalf("expected no quota, got %d", rr.Code)
+		}
+	}
+}
+
+func mustAddr(t *testing.T, s string) netip.Addr {
+	t.Helper()
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		t.Fatalf("ParseAddr(%q): %v", s, err)
+	}
+	return addr
+}
+
+func TestUploadQuota_PublicRoutesExempt(t *testing.T) {
+	q := newUploadQuota(10, time.Minute, QuotaPublicRoutes(NewPublicRoutes("/api/v1/files")))
+	for range 3 {
+		if rr := upload(q.middleware, http.MethodPut, "10.0.0.1:1", strings.Repeat("x", 60)); rr.Code != http.StatusCreated {
+			t.Fatalf("public upload: expected 201, got %d", rr.Code)
+		}
+	}
+	if len(q.clients) != 0 {
+		t.Errorf("expected public uploads not to be counted, got %d clients", len(q.clients))
+	}
+}
@@ -0,0 +1,136 @@
+// Package ratelimit caps the throughput of byte streams with token buckets,
+// so a few heavy downloads cannot starve everyone else of IO and network.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket holding up to a tenth of a second's worth of
+// bytes and refilling at a fixed rate. It is safe for concurrent use, so
+// one Limiter can cap the combined rate of many streams. A nil *Limiter
+// never waits.
+type Limiter struct {
+	rate  float64 // bytes per second
+	burst int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New returns a Limiter allowing bytesPerSecond bytes per second, or nil
+// (unlimited) when bytesPerSecond is zero or less.
+func New(bytesPerSecond int64) *Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	burst := max(int(bytesPerSecond/10), 1)
+	return &Limiter{
+		rate:   float64(bytesPerSecond),
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Burst returns the most bytes WaitN can grant without waiting.
+func (l *Limiter) Burst() int {
+	if l == nil {
+		return 0
+	}
+	return l.burst
+}
+
+// WaitN blocks until n bytes may pass, or until ctx is done, in which case
+// it returns ctx's error and gives the tokens back so an abandoned request
+// does not hold up others sharing the Limiter. n may exceed Burst; the
+// caller then simply waits longer.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	wait := l.reserve(n)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.refund(n)
+		return ctx.Err()
+	}
+}
+
+// reserve takes n tokens, going into debt if needed, and returns how long
+// the caller must wait for the debt to be repaid.
+func (l *Limiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.tokens = min(float64(l.burst), l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+func (l *Limiter) refund(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(float64(l.burst), l.tokens+float64(n))
+}
+
+// NewReader returns a reader that passes r's bytes on no faster than every
+// non-nil limiter allows. Reads are split into chunks no larger than the
+// smallest burst, and once ctx is done reads fail with its error. With no
+// non-nil limiters r is returned unchanged, keeping its io.WriterTo fast
+// path.
+func NewReader(ctx context.Context, r io.Reader, limiters ...*Limiter) io.Reader {
+	lr := &reader{ctx: ctx, r: r}
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		lr.limiters = append(lr.limiters, l)
+		if lr.chunk == 0 || l.burst < lr.chunk {
+			lr.chunk = l.burst
+		}
+	}
+	if len(lr.limiters) == 0 {
+		return r
+	}
+	return lr
+}
+
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*Limiter
+	chunk    int
+}
+
+func (lr *reader) Read(p []byte) (int, error) {
+	if err := lr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) > lr.chunk {
+		p = p[:lr.chunk]
+	}
+	n, err := lr.r.Read(p)
+	for _, l := range lr.limiters {
+		if werr := l.WaitN(lr.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew_ZeroIsUnlimited(t *testing.T) {
+	if l := New(0); l != nil {
+		t.Fatalf("expected nil limiter for 0, got %+v", l)
+	}
+	var l *Limiter
+	if err := l.WaitN(context.Background(), 1<<30); err != nil {
+		t.Errorf("nil limiter WaitN: %v", err)
+	}
+}
+
+func TestNewReader_NoLimitersReturnsSource(t *testing.T) {
+	src := strings.NewReader("data")
+	if got := NewReader(context.Background(), src, nil); got != io.Reader(src) {
+		t.Error("expected the source reader back unchanged")
+	}
+}
+
+func TestReader_CapsThroughput(t *testing.T) {
+	const rate = 20000
+	payload := bytes.Repeat([]byte("x"), 6000)
+	l := New(rate)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, NewReader(context.Background(), bytes.NewReader(payload), l))
+	elapsed := time.Since(start)
+	if err != nil || n != int64(len(payload)) {
+		t.Fatalf("copy: n=%d err=%v", n, err)
+	}
+
+	// The bucket starts full, so the first burst is free.
+	minimum := time.Duration(float64(len(payload)-l.Burst()) / rate * float64(time.Second))
+	if elapsed < minimum {
+		t.Errorf("expected at least %v, took %v", minimum, elapsed)
+	}
+}
+
+func TestReader_SharedLimiterCapsCombinedRate(t *testing.T) {
+	const rate = 40000
+	shared := New(rate)
+	payload := bytes.Repeat([]byte("x"), 4000)
+
+	start := time.Now()
+	done := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := io.Copy(io.Discard, NewReader(context.Background(), bytes.NewReader(payload), nil, shared))
+			done <- err
+		}()
+	}
+	for range 2 {
+		if err := <-done; err != nil {
+			t.Fatalf("copy: %v", err)
+		}
+	}
+
+	minimum := time.Duration(float64(2*len(payload)-shared.Burst()) / rate * float64(time.Second))
+	if elapsed := time.Since(start); elapsed < minimum {
+		t.Errorf("expected at least %v for both streams, took %v", minimum, elapsed)
+	}
+}
+
+func TestReader_StopsWhenContextCancelled(t *testing.T) {
+	l := New(100)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := io.Copy(io.Discard, NewReader(ctx, strings.NewReader(strings.Repeat("x", 1000)), l))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to stop promptly, took %v", elapsed)
+	}
+}
+
+func TestLimiter_CancelRefundsTokens(t *testing.T) {
+	l := New(1000) // burst 100
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.WaitN(ctx, 1000); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Canceled, got %v", err)
+	}
+	if wait := l.reserve(l.Burst()); wait > 0 {
+		t.Errorf("expected the cancelled tokens back, still owe %v", wait)
+	}
+}
@@ -0,0 +1,359 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// BreakerOptions controls when WithCircuitBreaker stops calling a backend.
+type BreakerOptions struct {
+	// FailureRatio is the share of failed calls in a window that opens an
+	// operation's breaker, once the window has MinRequests calls. Zero
+	// means 0.5.
+	FailureRatio float64
+	MinRequests  int
+
+	// Window is how long failures are counted before the tally starts
+	// over. Zero means 30 seconds; MinRequests zero means 10.
+	Window time.Duration
+
+	// Cooldown is how long an open breaker fails calls before letting one
+	// through as a probe. Zero means 30 seconds.
+	Cooldown time.Duration
+
+	// IsFailure reports whether err says the backend is unhealthy. Nil
+	// means DefaultBreakerFailure.
+	IsFailure func(err error) bool
+}
+
+// DefaultBreakerFailure counts every error against the backend except the
+// sentinel errors, which are answers rather than outages, and a caller
+// canceling its own request. Deadlines do count: a backend too slow to
+// answer in time is what the breaker is for.
+func DefaultBreakerFailure(err error) bool {
+	switch {
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrPermission),
+		errors.Is(err, ErrExist),
+		errors.Is(err, ErrIsDirectory),
+		errors.Is(err, ErrNotEmpty),
+		errors.Is(err, ErrNotSupported),
+		errors.Is(err, ErrUnavailable),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+	return true
+}
+
+// BreakerState is the state of one operation's circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed passes calls through and counts their failures.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single probe call through after the cooldown.
+	BreakerHalfOpen
+	// BreakerOpen fails calls with ErrUnavailable without trying them.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	}
+	return "closed"
+}
+
+// Breaker operation names, as reported by BreakerStates.
+const (
+	OpList   = "list"
+	OpRead   = "read"
+	OpWrite  = "write"
+	OpStat   = "stat"
+	OpDelete = "delete"
+	OpMkdir  = "mkdir"
+	OpUsage  = "usage"
+)
+
+var breakerOps = []string{OpList, OpRead, OpWrite, OpStat, OpDelete, OpMkdir, OpUsage}
+
+// BreakerReporter is implemented by storage that trips circuit breakers,
+// so health checks and metrics can report them.
+type BreakerReporter interface {
+	BreakerStates() map[string]BreakerState
+}
+
+// WithCircuitBreaker wraps inner with a circuit breaker per operation. When
+// enough of an operation's calls fail within a window, further calls fail
+// at once with ErrUnavailable for the cooldown, instead of piling up on a
+// backend that is down; then one call is let through, and its outcome
+// closes the breaker or reopens it for another cooldown.
+//
+// Optional capabilities are forwarded and count toward the operation they
+// resemble: ListDirs as list, DeleteAll as delete, ReadSeeker, SHA256 and
+// GetMetadata as read, and Create, Replace, Rename, SetMetadata and SetModTime as write.
+// Read counts opening the file, not errors while streaming it. Wrap the
+// breaker around WithRetry, not inside it, so a call that exhausts its
+// retries counts once and an open breaker is not retried.
+func WithCircuitBreaker(inner Storage, opts BreakerOptions) Storage {
+	if opts.FailureRatio <= 0 {
+		opts.FailureRatio = 0.5
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = 10
+	}
+	if opts.Window <= 0 {
+		opts.Window = 30 * time.Second
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = DefaultBreakerFailure
+	}
+	s := &breakerStorage{Storage: inner, breakers: make(map[string]*breaker, len(breakerOps))}
+	for _, op := range breakerOps {
+		s.breakers[op] = &breaker{opts: &opts}
+	}
+	return s
+}
+
+type breakerStorage struct {
+	Storage
+	breakers map[string]*breaker
+}
+
+func (s *breakerStorage) Name() string {
+	return "breaker(" + NameOf(s.Storage) + ")"
+}
+
+// BreakerStates returns the current state of every operation's breaker.
+func (s *breakerStorage) BreakerStates() map[string]BreakerState {
+	states := make(map[string]BreakerState, len(s.breakers))
+	now := time.Now()
+	for op, b := range s.breakers {
+		states[op] = b.state(now)
+	}
+	return states
+}
+
+// do runs call through op's breaker.
+func (s *breakerStorage) do(op string, call func() error) error {
+	b := s.breakers[op]
+	probe, ok := b.allow(time.Now())
+	if !ok {
+		return ErrUnavailable
+	}
+	err := call()
+	b.record(time.Now(), probe, err)
+	return err
+}
+
+func (s *breakerStorage) List(ctx context.Context, p string) ([]FileInfo, error) {
+	var files []FileInfo
+	err := s.do(OpList, func() (err error) {
+		files, err = s.Storage.List(ctx, p)
+		return err
+	})
+	return files, err
+}
+
+func (s *breakerStorage) ListDirs(ctx context.Context, p string) ([]FileInfo, error) {
+	var dirs []FileInfo
+	err := s.do(OpList, func() (err error) {
+		dirs, err = ListDirs(ctx, s.Storage, p)
+		return err
+	})
+	return dirs, err
+}
+
+func (s *breakerStorage) Read(ctx context.Context, p string) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	err := s.do(OpRead, func() (err error) {
+		rc, err = s.Storage.Read(ctx, p)
+		return err
+	})
+	return rc, err
+}
+
+func (s *breakerStorage) ReadSeeker(ctx context.Context, p string) (io.ReadSeekCloser, error) {
+	var rs io.ReadSeekCloser
+	err := s.do(OpRead, func() (err error) {
+		rs, err = ReadSeeker(ctx, s.Storage, p)
+		return err
+	})
+	return rs, err
+}
+
+func (s *breakerStorage) Write(ctx context.Context, p string, r io.Reader) error {
+	return s.do(OpWrite, func() error { return s.Storage.Write(ctx, p, r) })
+}
+
+func (s *breakerStorage) Stat(ctx context.Context, p string) (*FileInfo, error) {
+	var info *FileInfo
+	err := s.do(OpStat, func() (err error) {
+		info, err = s.Storage.Stat(ctx, p)
+		return err
+	})
+	return info, err
+}
+
+func (s *breakerStorage) Delete(ctx context.Context, p string) error {
+	return s.do(OpDelete, func() error { return s.Storage.Delete(ctx, p) })
+}
+
+func (s *breakerStorage) DeleteAll(ctx context.Context, p string) error {
+	return s.do(OpDelete, func() error { return DeleteAll(ctx, s.Storage, p) })
+}
+
+func (s *breakerStorage) Mkdir(ctx context.Context, p string) error {
+	return s.do(OpMkdir, func() error { return s.Storage.Mkdir(ctx, p) })
+}
+
+func (s *breakerStorage) Usage(ctx context.Context, p string) (*Usage, error) {
+	var u *Usage
+	err := s.do(OpUsage, func() (err error) {
+		u, err = s.Storage.Usage(ctx, p)
+		return err
+	})
+	return u, err
+}
+
+// Create forwards to inner's atomic Create when it has one, otherwise it
+// falls back to Stat followed by Write.
+func (s *breakerStorage) Create(ctx context.Context, p string, r io.Reader) error {
+	if c, ok := s.Storage.(Creator); ok {
+		return s.do(OpWrite, func() error { return c.Create(ctx, p, r) })
+	}
+
+	_, err := s.Stat(ctx, p)
+	switch {
+	case err == nil:
+		return ErrExist
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+	return s.Write(ctx, p, r)
+}
+
+func (s *breakerStorage) Rename(ctx context.Context, from, to string) error {
+	return s.do(OpWrite, func() error { return Rename(ctx, s.Storage, from, to) })
+}
+
+func (s *breakerStorage) Replace(ctx context.Context, p string, r io.Reader) error {
+	return s.do(OpWrite, func() error { return Replace(ctx, s.Storage, p, r) })
+}
+
+func (s *breakerStorage) SHA256(ctx context.Context, p string) (string, error) {
+	var sum string
+	err := s.do(OpRead, func() (err error) {
+		sum, err = SHA256Of(ctx, s.Storage, p)
+		return err
+	})
+	return sum, err
+}
+
+func (s *breakerStorage) GetMetadata(ctx context.Context, p string) (map[string]string, error) {
+	var tags map[string]string
+	err := s.do(OpRead, func() (err error) {
+		tags, err = GetMetadata(ctx, s.Storage, p)
+		return err
+	})
+	return tags, err
+}
+
+func (s *breakerStorage) SetMetadata(ctx context.Context, p string, tags map[string]string) error {
+	return s.do(OpWrite, func() error { return SetMetadata(ctx, s.Storage, p, tags) })
+}
+
+func (s *breakerStorage) SetModTime(ctx context.Context, p string, t time.Time) error {
+	return s.do(OpWrite, func() error { return setModTime(ctx, s.Storage, p, t) })
+}
+
+func (s *breakerStorage) DiskInfo(ctx context.Context) (*DiskSpace, error) {
+	var d *DiskSpace
+	err := s.do(OpUsage, func() (err error) {
+		d, err = DiskInfo(ctx, s.Storage)
+		return err
+	})
+	return d, err
+}
+
+// breaker is one operation's circuit breaker.
+type breaker struct {
+	opts *BreakerOptions
+
+	mu          sync.Mutex
+	open        bool
+	openedAt    time.Time
+	probing     bool
+	windowStart time.Time
+	requests    int
+	failures    int
+}
+
+func (b *breaker) state(now time.Time) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case !b.open:
+		return BreakerClosed
+	case b.probing || now.Sub(b.openedAt) >= b.opts.Cooldown:
+		return BreakerHalfOpen
+	}
+	return BreakerOpen
+}
+
+// allow reports whether a call may go ahead, and whether it is the probe
+// of a half-open breaker.
+func (b *breaker) allow(now time.Time) (probe, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return false, true
+	}
+	if b.probing || now.Sub(b.openedAt) < b.opts.Cooldown {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// record counts a call's outcome, opening or closing the breaker.
+func (b *breaker) record(now time.Time, probe bool, err error) {
+	failed := err != nil && b.opts.IsFailure(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+		if failed {
+			b.openedAt = now
+			return
+		}
+		b.open = false
+		b.windowStart, b.requests, b.failures = now, 0, 0
+		return
+	}
+	if b.open {
+		// A call let through before the breaker opened.
+		return
+	}
+
+	if now.Sub(b.windowStart) >= b.opts.Window {
+		b.windowStart, b.requests, b.failures = now, 0, 0
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests >= b.opts.MinRequests && float64(b.failures) >= b.opts.FailureRatio*float64(b.requests) {
+		b.open, b.openedAt = true, now
+	}
+}
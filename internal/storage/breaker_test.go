@@ -0,0 +1,160 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// outageStorage answers Stat and Read with err while it is set, counting
+// the calls that reach it.
+type outageStorage struct {
+	storage.Storage
+	err   error
+	calls int
+}
+
+func (o *outageStorage) Stat(_ context.Context, p string) (*storage.FileInfo, error) {
+	o.calls++
+	if o.err != nil {
+		return nil, o.err
+	}
+	return &storage.FileInfo{Name: p, Path: p}, nil
+}
+
+func (o *outageStorage) Read(_ context.Context, _ string) (io.ReadCloser, error) {
+	o.calls++
+	if o.err != nil {
+		return nil, o.err
+	}
+	return io.NopCloser(strings.NewReader("ok")), nil
+}
+
+const testCooldown = 50 * time.Millisecond
+
+func newBreaker(inner storage.Storage) storage.Storage {
+	return storage.WithCircuitBreaker(inner, storage.BreakerOptions{
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		Window:       time.Minute,
+		Cooldown:     testCooldown,
+	})
+}
+
+func breakerState(s storage.Storage, op string) storage.BreakerState {
+	return s.(storage.BreakerReporter).BreakerStates()[op]
+}
+
+func statN(s storage.Storage, n int) {
+	for range n {
+		s.Stat(context.Background(), "a.txt")
+	}
+}
+
+func TestCircuitBreaker_OpensAndFailsFast(t *testing.T) {
+	inner := &outageStorage{err: errFlaky}
+	s := newBreaker(inner)
+
+	statN(s, 4)
+	if got := breakerState(s, storage.OpStat); got != storage.BreakerOpen {
+		t.Fatalf("expected the stat breaker open after 4 failures, got %s", got)
+	}
+
+	_, err := s.Stat(context.Background(), "a.txt")
+	if !errors.Is(err, storage.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+	if inner.calls != 4 {
+		t.Errorf("expected the open breaker to skip the backend, got %d calls", inner.calls)
+	}
+	if got := breakerState(s, storage.OpRead); got != storage.BreakerClosed {
+		t.Errorf("expected the read breaker unaffected, got %s", got)
+	}
+}
+
+func TestCircuitBreaker_BelowThresholdStaysClosed(t *testing.T) {
+	inner := &outageStorage{}
+	s := newBreaker(inner)
+
+	statN(s, 3)
+	inner.err = errFlaky
+	statN(s, 2)
+	if got := breakerState(s, storage.OpStat); got != storage.BreakerClosed {
+		t.Errorf("expected 2 failures in 5 calls to leave the breaker closed, got %s", got)
+	}
+}
+
+func TestCircuitBreaker_ProbeClosesAfterCooldown(t *testing.T) {
+	inner := &outageStorage{err: errFlaky}
+	s := newBreaker(inner)
+	statN(s, 4)
+
+	time.Sleep(testCooldown)
+	if got := breakerState(s, storage.OpStat); got != storage.BreakerHalfOpen {
+		t.Fatalf("expected half-open after the cooldown, got %s", got)
+	}
+
+	inner.err = nil
+	if _, err := s.Stat(context.Background(), "a.txt"); err != nil {
+		t.Fatalf("expected the probe to reach the recovered backend, got %v", err)
+	}
+	if got := breakerState(s, storage.OpStat); got != storage.BreakerClosed {
+		t.Errorf("expected a successful probe to close the breaker, got %s", got)
+	}
+	if _, err := s.Stat(context.Background(), "a.txt"); err != nil {
+		t.Errorf("expected calls to pass again, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_FailedProbeReopens(t *testing.T) {
+	inner := &outageStorage{err: errFlaky}
+	s := newBreaker(inner)
+	statN(s, 4)
+
+	time.Sleep(testCooldown)
+	if _, err := s.Stat(context.Background(), "a.txt"); !errors.Is(err, errFlaky) {
+		t.Fatalf("expected the probe to reach the backend, got %v", err)
+	}
+	if got := breakerState(s, storage.OpStat); got != storage.BreakerOpen {
+		t.Errorf("expected a failed probe to reopen the breaker, got %s", got)
+	}
+	if _, err := s.Stat(context.Background(), "a.txt"); !errors.Is(err, storage.ErrUnavailable) {
+		t.Errorf("expected a fresh cooldown, got %v", err)
+	}
+	if inner.calls != 5 {
+		t.Errorf("expected one probe call, got %d calls", inner.calls)
+	}
+}
+
+func TestCircuitBreaker_IgnoresAnswers(t *testing.T) {
+	for _, err := range []error{storage.ErrNotFound, storage.ErrPermission, storage.ErrReadOnly, context.Canceled} {
+		inner := &outageStorage{err: err}
+		s := newBreaker(inner)
+		statN(s, 10)
+		if got := breakerState(s, storage.OpStat); got != storage.BreakerClosed {
+			t.Errorf("%v: expected the breaker to stay closed, got %s", err, got)
+		}
+		if inner.calls != 10 {
+			t.Errorf("%v: expected every call to reach the backend, got %d", err, inner.calls)
+		}
+	}
+}
+
+func TestCircuitBreaker_CountsDeadlines(t *testing.T) {
+	s := newBreaker(&outageStorage{err: context.DeadlineExceeded})
+	statN(s, 4)
+	if got := breakerState(s, storage.OpStat); got != storage.BreakerOpen {
+		t.Errorf("expected timeouts to open the breaker, got %s", got)
+	}
+}
+
+func TestCircuitBreaker_NotRetried(t *testing.T) {
+	if storage.DefaultRetryable(storage.ErrUnavailable) {
+		t.Error("expected ErrUnavailable not to be retried")
+	}
+}
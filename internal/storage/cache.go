@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithCache wraps inner so Stat and List results are remembered for ttl,
+// keeping at most maxEntries of them and evicting the least recently used.
+// Write, Create, Replace, Delete, Mkdir and Rename drop the entries for the affected
+// path and its parent directory; Rename drops everything under both paths,
+// since it can move whole directories. Reads are never cached. Changes made
+// behind the decorator's back stay invisible until the entry expires.
+//
+// A ttl or maxEntries of zero or less disables caching and returns inner.
+func WithCache(inner Storage, ttl time.Duration, maxEntries int) Storage {
+	if ttl <= 0 || maxEntries <= 0 {
+		return inner
+	}
+	return &cacheStorage{
+		Storage: inner,
+		ttl:     ttl,
+		max:     maxEntries,
+		lru:     list.New(),
+		entries: make(map[cacheKey]*list.Element),
+	}
+}
+
+type cacheKind uint8
+
+const (
+	cacheStat cacheKind = iota
+	cacheList
+)
+
+type cacheKey struct {
+	kind cacheKind
+	path string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	expires time.Time
+	info    FileInfo
+	files   []FileInfo
+}
+
+type cacheStorage struct {
+	Storage
+	ttl time.Duration
+	max int
+
+	mu      sync.Mutex
+	lru     *list.List // front is most recently used
+	entries map[cacheKey]*list.Element
+	// gen counts invalidations, so a lookup that raced with a change does
+	// not store the result it fetched before the change.
+	gen uint64
+}
+
+func (s *cacheStorage) Name() string {
+	return "cache(" + NameOf(s.Storage) + ")"
+}
+
+// cachePath normalizes p so "docs", "/docs" and "docs/" share an entry.
+func cachePath(p string) string {
+	return path.Clean("/" + p)
+}
+
+func (s *cacheStorage) Stat(ctx context.Context, p string) (*FileInfo, error) {
+	key := cacheKey{cacheStat, cachePath(p)}
+	if e, ok := s.get(key); ok {
+		info := e.info
+		return &info, nil
+	}
+
+	gen := s.generation()
+	info, err := s.Storage.Stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	s.put(gen, &cacheEntry{key: key, info: *info})
+	cp := *info
+	return &cp, nil
+}
+
+func (s *cacheStorage) List(ctx context.Context, p string) ([]FileInfo, error) {
+	key := cacheKey{cacheList, cachePath(p)}
+	if e, ok := s.get(key); ok {
+		return append([]FileInfo(nil), e.files...), nil
+	}
+
+	gen := s.generation()
+	files, err := s.Storage.List(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	s.put(gen, &cacheEntry{key: key, files: append([]FileInfo(nil), files...)})
+	return files, nil
+}
+
+// ListDirs filters a cached listing of p when there is one and otherwise
+// goes to inner's DirLister, if any, without caching the result.
+func (s *cacheStorage) ListDirs(ctx context.Context, p string) ([]FileInfo, error) {
+	if e, ok := s.get(cacheKey{cacheList, cachePath(p)}); ok {
+		var dirs []FileInfo
+		for _, f := range e.files {
+			if f.IsDir {
+				dirs = append(dirs, f)
+			}
+		}
+		if dirs == nil {
+			dirs = []FileInfo{}
+		}
+		return dirs, nil
+	}
+	return ListDirs(ctx, s.Storage, p)
+}
+
+func (s *cacheStorage) Write(ctx context.Context, p string, r io.Reader) error {
+	defer s.invalidate(p, false)
+	return s.Storage.Write(ctx, p, r)
+}
+
+// Create forwards to inner's atomic Create when it has one, otherwise it
+// falls back to Stat followed by Write.
+func (s *cacheStorage) Create(ctx context.Context, p string, r io.Reader) error {
+	defer s.invalidate(p, false)
+	return Create(ctx, s.Storage, p, r)
+}
+
+func (s *cacheStorage) Delete(ctx context.Context, p string) error {
+	defer s.invalidate(p, false)
+	return s.Storage.Delete(ctx, p)
+}
+
+func (s *cacheStorage) DeleteAll(ctx context.Context, p string) error {
+	defer s.invalidate(p, true)
+	return DeleteAll(ctx, s.Storage, p)
+}
+
+func (s *cacheStorage) Mkdir(ctx context.Context, p string) error {
+	defer s.invalidate(p, false)
+	return s.Storage.Mkdir(ctx, p)
+}
+
+func (s *cacheStorage) Rename(ctx context.Context, from, to string) error {
+	defer s.invalidate(to, true)
+	defer s.invalidate(from, true)
+	return Rename(ctx, s.Storage, from, to)
+}
+
+func (s *cacheStorage) Replace(ctx context.Context, p string, r io.Reader) error {
+	defer s.invalidate(p, false)
+	return Replace(ctx, s.Storage, p, r)
+}
+
+// ReadSeeker keeps inner's SeekableReader, if any, reachable through the
+// decorator.
+func (s *cacheStorage) ReadSeeker(ctx context.Context, p string) (io.ReadSeekCloser, error) {
+	return ReadSeeker(ctx, s.Storage, p)
+}
+
+// SHA256 keeps inner's Hasher, if any, reachable through the decorator.
+func (s *cacheStorage) SHA256(ctx context.Context, p string) (string, error) {
+	return SHA256Of(ctx, s.Storage, p)
+}
+
+// GetMetadata and SetMetadata keep inner's MetadataStore, if any, reachable
+// through the decorator. Tags are not part of FileInfo, so nothing cached
+// goes stale.
+func (s *cacheStorage) GetMetadata(ctx context.Context, p string) (map[string]string, error) {
+	return GetMetadata(ctx, s.Storage, p)
+}
+
+func (s *cacheStorage) SetMetadata(ctx context.Context, p string, tags map[string]string) error {
+	return SetMetadata(ctx, s.Storage, p, tags)
+}
+
+func (s *cacheStorage) SetModTime(ctx context.Context, p string, t time.Time) error {
+	defer s.invalidate(p, false)
+	return setModTime(ctx, s.Storage, p, t)
+}
+
+// DiskInfo keeps inner's DiskReporter, if any, reachable through the
+// decorator. It is never cached.
+func (s *cacheStorage) DiskInfo(ctx context.Context) (*DiskSpace, error) {
+	return DiskInfo(ctx, s.Storage)
+}
+
+func (s *cacheStorage) generation() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gen
+}
+
+func (s *cacheStorage) get(key cacheKey) (*cacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		s.remove(key)
+		return nil, false
+	}
+	s.lru.MoveToFront(el)
+	return e, true
+}
+
+// put stores e unless an invalidation happened since gen was read.
+func (s *cacheStorage) put(gen uint64, e *cacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if gen != s.gen {
+		return
+	}
+	e.expires = time.Now().Add(s.ttl)
+	if el, ok := s.entries[e.key]; ok {
+		el.Value = e
+		s.lru.MoveToFront(el)
+		return
+	}
+	s.entries[e.key] = s.lru.PushFront(e)
+	for s.lru.Len() > s.max {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// invalidate drops the entries for p and its parent, and with subtree also
+// every entry below p.
+func (s *cacheStorage) invalidate(p string, subtree bool) {
+	p = cachePath(p)
+	parent := path.Dir(p)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen++
+	for _, dir := range []string{p, parent} {
+		s.remove(cacheKey{cacheStat, dir})
+		s.remove(cacheKey{cacheList, dir})
+	}
+	if subtree {
+		for key := range s.entries {
+			if isBelow(key.path, p) {
+				s.remove(key)
+			}
+		}
+	}
+}
+
+// remove drops key if present. The caller holds s.mu.
+func (s *cacheStorage) remove(key cacheKey) {
+	if el, ok := s.entries[key]; ok {
+		s.lru.Remove(el)
+		delete(s.entries, key)
+	}
+}
+
+// isBelow reports whether p lies strictly inside dir. Both are cleaned and
+// rooted at "/".
+func isBelow(p, dir string) bool {
+	if dir == "/" {
+		return p != "/"
+	}
+	return strings.HasPrefix(p, dir+"/")
+}
@@ -0,0 +1,207 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// countingStorage counts Stat and List calls reaching the backend.
+type countingStorage struct {
+	storage.Storage
+	mu    sync.Mutex
+	stats map[string]int
+	lists map[string]int
+}
+
+func newCountingBackend(t *testing.T) *countingStorage {
+	t.Helper()
+	inner, _ := newTeeBackend(t)
+	return &countingStorage{Storage: inner, stats: map[string]int{}, lists: map[string]int{}}
+}
+
+func (c *countingStorage) Stat(ctx context.Context, p string) (*storage.FileInfo, error) {
+	c.mu.Lock()
+	c.stats[p]++
+	c.mu.Unlock()
+	return c.Storage.Stat(ctx, p)
+}
+
+func (c *countingStorage) List(ctx context.Context, p string) ([]storage.FileInfo, error) {
+	c.mu.Lock()
+	c.lists[p]++
+	c.mu.Unlock()
+	return c.Storage.List(ctx, p)
+}
+
+func TestWithCache_StatHitsBackendOnce(t *testing.T) {
+	ctx := context.Background()
+	inner := newCountingBackend(t)
+	store := storage.WithCache(inner, time.Minute, 100)
+	if err := store.Write(ctx, "docs/a.txt", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	for range 3 {
+		info, err := store.Stat(ctx, "docs/a.txt")
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if info.Size != 5 {
+			t.Errorf("expected size 5, got %d", info.Size)
+		}
+	}
+	if n := inner.stats["docs/a.txt"]; n != 1 {
+		t.Errorf("expected 1 backend Stat, got %d", n)
+	}
+}
+
+func TestWithCache_WriteBustsEntry(t *testing.T) {
+	ctx := context.Background()
+	inner := newCountingBackend(t)
+	store := storage.WithCache(inner, time.Minute, 100)
+	store.Write(ctx, "docs/a.txt", strings.NewReader("hello"))
+
+	store.Stat(ctx, "docs/a.txt")
+	files, _ := store.List(ctx, "docs")
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(files))
+	}
+
+	store.Write(ctx, "docs/a.txt", strings.NewReader("hello, world"))
+	store.Write(ctx, "docs/b.txt", strings.NewReader("b"))
+
+	info, err := store.Stat(ctx, "docs/a.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != 12 {
+		t.Errorf("expected the new size 12, got %d", info.Size)
+	}
+	if files, _ := store.List(ctx, "docs"); len(files) != 2 {
+		t.Errorf("expected the parent listing refreshed with 2 files, got %d", len(files))
+	}
+	if n := inner.stats["docs/a.txt"]; n != 2 {
+		t.Errorf("expected 2 backend Stats, got %d", n)
+	}
+}
+
+func TestWithCache_DeleteAndRenameBustEntries(t *testing.T) {
+	ctx := context.Background()
+	inner, _ := newTeeBackend(t)
+	store := storage.WithCache(inner, time.Minute, 100)
+	store.Write(ctx, "docs/sub/a.txt", strings.NewReader("a"))
+	store.Write(ctx, "docs/b.txt", strings.NewReader("b"))
+
+	store.Stat(ctx, "docs/b.txt")
+	store.Stat(ctx, "docs/sub/a.txt")
+	store.List(ctx, "docs/sub")
+
+	if err := store.Delete(ctx, "docs/b.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "docs/b.txt"); err == nil {
+		t.Error("expected the deleted file to be gone")
+	}
+
+	if err := storage.Rename(ctx, store, "docs/sub", "moved"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if _, err := store.Stat(ctx, "docs/sub/a.txt"); err == nil {
+		t.Error("expected entries under the renamed directory to be dropped")
+	}
+	if _, err := store.List(ctx, "docs/sub"); err == nil {
+		t.Error("expected the renamed directory listing to be dropped")
+	}
+}
+
+func TestWithCache_EntriesExpire(t *testing.T) {
+	ctx := context.Background()
+	inner := newCountingBackend(t)
+	store := storage.WithCache(inner, 10*time.Millisecond, 100)
+	store.Write(ctx, "a.txt", strings.NewReader("a"))
+
+	store.Stat(ctx, "a.txt")
+	time.Sleep(20 * time.Millisecond)
+	store.Stat(ctx, "a.txt")
+
+	if n := inner.stats["a.txt"]; n != 2 {
+		t.Errorf("expected 2 backend Stats after expiry, got %d", n)
+	}
+}
+
+func TestWithCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	inner := newCountingBackend(t)
+	store := storage.WithCache(inner, time.Minute, 2)
+	for _, name := range []string{"a", "b", "c"} {
+		store.Write(ctx, name, strings.NewReader(name))
+	}
+
+	store.Stat(ctx, "a")
+	store.Stat(ctx, "b")
+	store.Stat(ctx, "a") // a is now more recent than b
+	store.Stat(ctx, "c") // evicts b
+
+	store.Stat(ctx, "a")
+	store.Stat(ctx, "b")
+	if inner.stats["a"] != 1 || inner.stats["b"] != 2 {
+		t.Errorf("expected a cached and b evicted, got %v", inner.stats)
+	}
+}
+
+func TestWithCache_ResultsAreCopies(t *testing.T) {
+	ctx := context.Background()
+	store := storage.WithCache(newCountingBackend(t), time.Minute, 100)
+	store.Write(ctx, "docs/a.txt", strings.NewReader("a"))
+
+	files, _ := store.List(ctx, "docs")
+	files[0].Path = "mutated"
+	info, _ := store.Stat(ctx, "docs/a.txt")
+	info.Path = "mutated"
+
+	files, _ = store.List(ctx, "docs")
+	info, _ = store.Stat(ctx, "docs/a.txt")
+	if files[0].Path == "mutated" || info.Path == "mutated" {
+		t.Error("expected callers' changes not to leak into the cache")
+	}
+}
+
+func TestWithCache_ConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	store := storage.WithCache(newCountingBackend(t), time.Minute, 8)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("f%d.txt", i%4)
+			for range 50 {
+				store.Write(ctx, name, strings.NewReader("x"))
+				store.Stat(ctx, name)
+				store.List(ctx, ".")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithCache_Disabled(t *testing.T) {
+	inner := newCountingBackend(t)
+	if store := storage.WithCache(inner, 0, 100); store != storage.Storage(inner) {
+		t.Error("expected a zero ttl to return inner unchanged")
+	}
+}
+
+func TestWithCache_Name(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	if got := storage.NameOf(storage.WithCache(inner, time.Minute, 10)); got != "cache(local)" {
+		t.Errorf("expected cache(local), got %q", got)
+	}
+}
@@ -0,0 +1,45 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+	"go-storage-api/internal/storage/fsadapter"
+)
+
+func TestDiskInfo_ForwardedByDecorators(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	want, err := storage.DiskInfo(ctx, inner)
+	if err != nil {
+		t.Skipf("local backend cannot report disk space here: %v", err)
+	}
+
+	var store storage.Storage = storage.WithPrefix(inner, prefixFromCtx)
+	store = storage.WithContentRouting(store)
+	store = storage.WithWriteTee(store, func(string) io.WriteCloser { return &bufferSink{} })
+	store = storage.WithRetry(store, storage.RetryPolicy{})
+	store = storage.WithCircuitBreaker(store, storage.BreakerOptions{})
+	store = storage.WithCache(store, time.Minute, 100)
+	store = storage.WithHidden(store, storage.MatchPatterns([]string{".*"}, false))
+	store = storage.WithMetrics(store)
+
+	got, err := storage.DiskInfo(ctx, store)
+	if err != nil {
+		t.Fatalf("DiskInfo through %s: %v", storage.NameOf(store), err)
+	}
+	if got.TotalBytes != want.TotalBytes {
+		t.Errorf("expected total %d, got %d", want.TotalBytes, got.TotalBytes)
+	}
+}
+
+func TestDiskInfo_NotSupported(t *testing.T) {
+	store := storage.WithMetrics(fsadapter.New(nil))
+	if _, err := storage.DiskInfo(context.Background(), store); !errors.Is(err, storage.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
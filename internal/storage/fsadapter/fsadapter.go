@@ -0,0 +1,166 @@
+// Package fsadapter serves any io/fs file system, such as embedded files,
+// a zip archive or fstest.MapFS, as a read-only storage backend.
+package fsadapter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"go-storage-api/internal/storage"
+)
+
+// Storage implements storage.Storage over an fs.FS. Listing, reading,
+// statting and usage work; every change returns storage.ErrReadOnly.
+type Storage struct {
+	fsys fs.FS
+}
+
+// New returns a read-only backend serving fsys.
+func New(fsys fs.FS) *Storage {
+	return &Storage{fsys: fsys}
+}
+
+// Name identifies this backend in diagnostics.
+func (s *Storage) Name() string {
+	return "fs"
+}
+
+func (s *Storage) List(_ context.Context, p string) ([]storage.FileInfo, error) {
+	name := fsPath(p)
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	files := make([]storage.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, mapError(err)
+		}
+		files = append(files, fileInfo(path.Join(name, e.Name()), info))
+	}
+	return files, nil
+}
+
+func (s *Storage) Read(_ context.Context, p string) (io.ReadCloser, error) {
+	f, err := s.fsys.Open(fsPath(p))
+	if err != nil {
+		return nil, mapError(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, mapError(err)
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, storage.ErrIsDirectory
+	}
+	return f, nil
+}
+
+func (s *Storage) Stat(_ context.Context, p string) (*storage.FileInfo, error) {
+	name := fsPath(p)
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	fi := fileInfo(name, info)
+	if info.IsDir() {
+		entries, err := fs.ReadDir(s.fsys, name)
+		if err != nil {
+			return nil, mapError(err)
+		}
+		fi.ChildCount = len(entries)
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			if ei, err := e.Info(); err == nil {
+				fi.TotalSize += ei.Size()
+			}
+		}
+	}
+	return &fi, nil
+}
+
+// Usage walks the subtree at p like the local backend: the starting
+// directory itself is not counted and a cancelled ctx stops the walk.
+func (s *Storage) Usage(ctx context.Context, p string) (*storage.Usage, error) {
+	root := fsPath(p)
+	var u storage.Usage
+	err := fs.WalkDir(s.fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name != root {
+				u.DirCount++
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		u.FileCount++
+		u.TotalBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &u, nil
+}
+
+func (s *Storage) Write(context.Context, string, io.Reader) error {
+	return storage.ErrReadOnly
+}
+
+func (s *Storage) Delete(context.Context, string) error {
+	return storage.ErrReadOnly
+}
+
+func (s *Storage) Mkdir(context.Context, string) error {
+	return storage.ErrReadOnly
+}
+
+// fsPath turns a client path into an fs.FS name: rooted paths lose their
+// leading slash, ".." cannot climb above the root, and the root is ".".
+func fsPath(p string) string {
+	name := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func fileInfo(name string, info fs.FileInfo) storage.FileInfo {
+	return storage.FileInfo{
+		Name:    info.Name(),
+		Path:    name,
+		Size:    info.Size(),
+		IsDir:   info.IsDir(),
+		ModTime: info.ModTime(),
+	}
+}
+
+func mapError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return storage.ErrNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return storage.ErrPermission
+	case errors.Is(err, fs.ErrInvalid):
+		return storage.ErrNotFound
+	}
+	return err
+}
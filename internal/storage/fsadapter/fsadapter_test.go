@@ -0,0 +1,150 @@
+package fsadapter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"go-storage-api/internal/storage"
+)
+
+func newTestStorage() *Storage {
+	return New(fstest.MapFS{
+		"readme.txt":       {Data: []byte("hello")},
+		"docs/guide.md":    {Data: []byte("# guide")},
+		"docs/sub/deep.go": {Data: []byte("package deep")},
+	})
+}
+
+// deniedFS refuses to open anything.
+type deniedFS struct{}
+
+func (deniedFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+}
+
+func TestList(t *testing.T) {
+	s := newTestStorage()
+
+	files, err := s.List(context.Background(), "/docs")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(files))
+	}
+	if files[0].Path != "docs/guide.md" || files[0].Size != 7 || files[0].IsDir {
+		t.Errorf("unexpected file entry %+v", files[0])
+	}
+	if files[1].Path != "docs/sub" || !files[1].IsDir {
+		t.Errorf("unexpected dir entry %+v", files[1])
+	}
+}
+
+func TestList_Root(t *testing.T) {
+	s := newTestStorage()
+	for _, p := range []string{"", "/", ".", "/.."} {
+		files, err := s.List(context.Background(), p)
+		if err != nil {
+			t.Fatalf("List(%q): %v", p, err)
+		}
+		if len(files) != 2 {
+			t.Errorf("List(%q): expected 2 entries, got %d", p, len(files))
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	s := newTestStorage()
+
+	rc, err := s.Read(context.Background(), "/docs/guide.md")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	if string(data) != "# guide" {
+		t.Errorf("expected %q, got %q", "# guide", data)
+	}
+}
+
+func TestRead_Directory(t *testing.T) {
+	_, err := newTestStorage().Read(context.Background(), "docs")
+	if !errors.Is(err, storage.ErrIsDirectory) {
+		t.Errorf("expected ErrIsDirectory, got %v", err)
+	}
+}
+
+func TestStat(t *testing.T) {
+	s := newTestStorage()
+
+	info, err := s.Stat(context.Background(), "readme.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Name != "readme.txt" || info.Path != "readme.txt" || info.Size != 5 {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	root, err := s.Stat(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Stat root: %v", err)
+	}
+	if !root.IsDir || root.Path != "." {
+		t.Errorf("unexpected root info %+v", root)
+	}
+	if root.ChildCount != 2 || root.TotalSize != 5 {
+		t.Errorf("expected 2 children of 5 bytes at the root, got %d of %d", root.ChildCount, root.TotalSize)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	u, err := newTestStorage().Usage(context.Background(), "/")
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	want := storage.Usage{TotalBytes: 5 + 7 + 12, FileCount: 3, DirCount: 2}
+	if *u != want {
+		t.Errorf("expected %+v, got %+v", want, *u)
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := newTestStorage().Stat(ctx, "missing.txt"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("missing: expected ErrNotFound, got %v", err)
+	}
+	if _, err := newTestStorage().Read(ctx, "missing.txt"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("missing read: expected ErrNotFound, got %v", err)
+	}
+	if _, err := New(deniedFS{}).Read(ctx, "a.txt"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("denied: expected ErrPermission, got %v", err)
+	}
+}
+
+func TestWritesAreRejected(t *testing.T) {
+	s := newTestStorage()
+	ctx := context.Background()
+
+	errs := map[string]error{
+		"Write":  s.Write(ctx, "new.txt", strings.NewReader("x")),
+		"Delete": s.Delete(ctx, "readme.txt"),
+		"Mkdir":  s.Mkdir(ctx, "newdir"),
+		"Rename": storage.Rename(ctx, s, "readme.txt", "moved.txt"),
+	}
+	for op, err := range errs {
+		if !errors.Is(err, storage.ErrReadOnly) || !errors.Is(err, storage.ErrPermission) {
+			t.Errorf("%s: expected ErrReadOnly wrapping ErrPermission, got %v", op, err)
+		}
+	}
+	if _, err := s.Stat(ctx, "readme.txt"); err != nil {
+		t.Errorf("expected readme.txt to survive, got %v", err)
+	}
+}
+
+var _ storage.Storage = (*Storage)(nil)
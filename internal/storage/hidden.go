@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// WithHidden makes the paths hidden reports true for look absent from
+// inner: listings leave them out, reading, statting or deleting one fails
+// with ErrNotFound, and writing one fails with ErrPermission. See
+// MatchPatterns for a hidden built from glob patterns.
+//
+// Hidden files still count toward Usage, keep a directory from being
+// deleted non-recursively, and go with their directory in DeleteAll. With
+// a nil hidden inner is returned unwrapped.
+func WithHidden(inner Storage, hidden func(p string) bool) Storage {
+	if hidden == nil {
+		return inner
+	}
+	return &hiddenStorage{inner: inner, hidden: hidden}
+}
+
+// MatchPatterns returns a func reporting whether a path matches any of
+// patterns, in path.Match syntax such as ".DS_Store", "*.tmp" or ".*".
+// Patterns match each name along the path, so ".git" also matches
+// everything below it. With fullPath they match the whole path without
+// its leading slash instead, and the paths of its parent directories.
+// No patterns returns nil.
+func MatchPatterns(patterns []string, fullPath bool) func(p string) bool {
+	if len(patterns) == 0 {
+		return nil
+	}
+	return func(p string) bool {
+		p = strings.TrimPrefix(path.Clean("/"+p), "/")
+		if p == "" {
+			return false
+		}
+		names := strings.Split(p, "/")
+		for i, name := range names {
+			if fullPath {
+				name = strings.Join(names[:i+1], "/")
+			}
+			for _, pattern := range patterns {
+				if ok, _ := path.Match(pattern, name); ok {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+type hiddenStorage struct {
+	inner  Storage
+	hidden func(p string) bool
+}
+
+func (s *hiddenStorage) Name() string {
+	return "hidden(" + NameOf(s.inner) + ")"
+}
+
+// check returns err if p is hidden.
+func (s *hiddenStorage) check(p string, err error) error {
+	if s.hidden(p) {
+		return err
+	}
+	return nil
+}
+
+// visible returns the entries of files that are not hidden, in a new
+// slice so a cached listing below is left alone.
+func (s *hiddenStorage) visible(files []FileInfo) []FileInfo {
+	out := make([]FileInfo, 0, len(files))
+	for _, f := range files {
+		if !s.hidden(f.Path) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (s *hiddenStorage) List(ctx context.Context, p string) ([]FileInfo, error) {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return nil, err
+	}
+	files, err := s.inner.List(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return s.visible(files), nil
+}
+
+func (s *hiddenStorage) ListDirs(ctx context.Context, p string) ([]FileInfo, error) {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return nil, err
+	}
+	dirs, err := ListDirs(ctx, s.inner, p)
+	if err != nil {
+		return nil, err
+	}
+	return s.visible(dirs), nil
+}
+
+func (s *hiddenStorage) Read(ctx context.Context, p string) (io.ReadCloser, error) {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return nil, err
+	}
+	return s.inner.Read(ctx, p)
+}
+
+func (s *hiddenStorage) ReadSeeker(ctx context.Context, p string) (io.ReadSeekCloser, error) {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return nil, err
+	}
+	return ReadSeeker(ctx, s.inner, p)
+}
+
+func (s *hiddenStorage) Write(ctx context.Context, p string, r io.Reader) error {
+	if err := s.check(p, ErrPermission); err != nil {
+		return err
+	}
+	return s.inner.Write(ctx, p, r)
+}
+
+// Create forwards to inner's atomic Create when it has one, otherwise it
+// falls back to Stat followed by Write.
+func (s *hiddenStorage) Create(ctx context.Context, p string, r io.Reader) error {
+	if err := s.check(p, ErrPermission); err != nil {
+		return err
+	}
+	return Create(ctx, s.inner, p, r)
+}
+
+func (s *hiddenStorage) Replace(ctx context.Context, p string, r io.Reader) error {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return err
+	}
+	return Replace(ctx, s.inner, p, r)
+}
+
+func (s *hiddenStorage) Stat(ctx context.Context, p string) (*FileInfo, error) {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return nil, err
+	}
+	return s.inner.Stat(ctx, p)
+}
+
+func (s *hiddenStorage) Delete(ctx context.Context, p string) error {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return err
+	}
+	return s.inner.Delete(ctx, p)
+}
+
+func (s *hiddenStorage) DeleteAll(ctx context.Context, p string) error {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return err
+	}
+	return DeleteAll(ctx, s.inner, p)
+}
+
+func (s *hiddenStorage) Mkdir(ctx context.Context, p string) error {
+	if err := s.check(p, ErrPermission); err != nil {
+		return err
+	}
+	return s.inner.Mkdir(ctx, p)
+}
+
+func (s *hiddenStorage) Usage(ctx context.Context, p string) (*Usage, error) {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return nil, err
+	}
+	return s.inner.Usage(ctx, p)
+}
+
+// Rename refuses hidden sources as missing and hidden targets as
+// forbidden, then uses inner's Renamer, if any.
+func (s *hiddenStorage) Rename(ctx context.Context, from, to string) error {
+	if err := s.check(from, ErrNotFound); err != nil {
+		return err
+	}
+	if err := s.check(to, ErrPermission); err != nil {
+		return err
+	}
+	return Rename(ctx, s.inner, from, to)
+}
+
+func (s *hiddenStorage) SHA256(ctx context.Context, p string) (string, error) {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return "", err
+	}
+	return SHA256Of(ctx, s.inner, p)
+}
+
+func (s *hiddenStorage) GetMetadata(ctx context.Context, p string) (map[string]string, error) {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return nil, err
+	}
+	return GetMetadata(ctx, s.inner, p)
+}
+
+func (s *hiddenStorage) SetMetadata(ctx context.Context, p string, tags map[string]string) error {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return err
+	}
+	return SetMetadata(ctx, s.inner, p, tags)
+}
+
+func (s *hiddenStorage) SetModTime(ctx context.Context, p string, t time.Time) error {
+	if err := s.check(p, ErrNotFound); err != nil {
+		return err
+	}
+	return setModTime(ctx, s.inner, p, t)
+}
+
+func (s *hiddenStorage) DiskInfo(ctx context.Context) (*DiskSpace, error) {
+	return DiskInfo(ctx, s.inner)
+}
@@ -0,0 +1,91 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+func names(files []storage.FileInfo) string {
+	var out []string
+	for _, f := range files {
+		out = append(out, f.Name)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestWithHidden(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	ctx := context.Background()
+	for _, p := range []string{".DS_Store", "a.txt", "b.tmp", "docs/c.txt", ".git/config"} {
+		if err := inner.Write(ctx, p, strings.NewReader(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := storage.WithHidden(inner, storage.MatchPatterns([]string{".DS_Store", "*.tmp", ".git"}, false))
+
+	files, err := store.List(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(files); got != "a.txt,docs" {
+		t.Errorf("expected a.txt,docs listed, got %s", got)
+	}
+
+	for _, p := range []string{".DS_Store", "b.tmp", ".git/config", "/.git/../b.tmp"} {
+		if _, err := store.Stat(ctx, p); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Stat %s: expected ErrNotFound, got %v", p, err)
+		}
+		if _, err := store.Read(ctx, p); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Read %s: expected ErrNotFound, got %v", p, err)
+		}
+		if err := store.Delete(ctx, p); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Delete %s: expected ErrNotFound, got %v", p, err)
+		}
+	}
+	if _, err := store.List(ctx, ".git"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected a hidden directory to be unlistable, got %v", err)
+	}
+	if err := store.Write(ctx, "docs/new.tmp", strings.NewReader("x")); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("expected writes to hidden paths refused, got %v", err)
+	}
+	if err := storage.Rename(ctx, store, "a.txt", "a.tmp"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("expected renames onto hidden paths refused, got %v", err)
+	}
+	if _, err := inner.Stat(ctx, ".DS_Store"); err != nil {
+		t.Errorf("expected the hidden file left in place, got %v", err)
+	}
+}
+
+func TestMatchPatterns(t *testing.T) {
+	if storage.MatchPatterns(nil, false) != nil {
+		t.Error("expected no patterns to hide nothing")
+	}
+
+	base := storage.MatchPatterns([]string{".*", "Thumbs.db"}, false)
+	full := storage.MatchPatterns([]string{"tmp", "docs/*.bak"}, true)
+	tests := []struct {
+		match func(string) bool
+		path  string
+		want  bool
+	}{
+		{base, "/.env", true},
+		{base, "photos/Thumbs.db", true},
+		{base, "a/.cache/b.txt", true},
+		{base, "a/b.txt", false},
+		{base, "/", false},
+		{full, "tmp/x.txt", true},
+		{full, "/docs/a.bak", true},
+		{full, "docs/sub/a.bak", false},
+		{full, "other/tmp", false},
+		{full, "a.bak", false},
+	}
+	for _, tt := range tests {
+		if got := tt.match(tt.path); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.path, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package local
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// fileChunk is how much one kernel-assisted copy moves before ctx is
+// checked again.
+const fileChunk = 4 << 20
+
+// copyChunked copies src into dst through dst's ReadFrom, which offloads
+// file-to-file copies to the kernel, in fileChunk pieces so a cancelled
+// ctx stops it promptly.
+func copyChunked(ctx context.Context, dst io.Writer, src *os.File) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := io.Copy(dst, &io.LimitedReader{R: src, N: fileChunk})
+		total += n
+		if err != nil || n < fileChunk {
+			return total, err
+		}
+	}
+}
+
+// contextReader stops reading from r once ctx ends.
+func contextReader(ctx context.Context, r io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		return r
+	}
+	return &ctxReader{ctx: ctx, r: r}
+}
+
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// contextFile wraps a file returned by Read so reads stop once ctx ends.
+func contextFile(ctx context.Context, f *os.File) io.ReadSeekCloser {
+	if ctx.Done() == nil {
+		return f
+	}
+	return &ctxFile{ctx: ctx, f: f}
+}
+
+// ctxFile is a cancellable *os.File. WriteTo keeps the file's sendfile
+// path for downloads, one fileChunk at a time.
+type ctxFile struct {
+	ctx context.Context
+	f   *os.File
+}
+
+func (c *ctxFile) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.f.Read(p)
+}
+
+func (c *ctxFile) WriteTo(w io.Writer) (int64, error) {
+	return copyChunked(c.ctx, w, c.f)
+}
+
+func (c *ctxFile) Close() error {
+	return c.f.Close()
+}
+
+// Seek lets callers serving several byte ranges move back without
+// reopening the file.
+func (c *ctxFile) Seek(offset int64, whence int) (int64, error) {
+	return c.f.Seek(offset, whence)
+}
@@ -0,0 +1,115 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// endlessReader yields zeros forever, like an upload far larger than the
+// test is willing to wait for.
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestWrite_CancelStopsLargeWritePromptly(t *testing.T) {
+	s, staging := newStagedStorage(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := s.Write(ctx, "huge.bin", endlessReader{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the write to stop promptly, took %v", elapsed)
+	}
+	if _, err := os.Stat(filepath.Join(s.root, "huge.bin")); !os.IsNotExist(err) {
+		t.Errorf("expected no file at the destination, got %v", err)
+	}
+	assertEmptyDir(t, staging)
+}
+
+func TestWrite_CancelledFileSourceIsNotCopied(t *testing.T) {
+	s := newTestStorage(t)
+
+	// A spooled multipart part takes the kernel copy path.
+	src, err := os.Create(filepath.Join(t.TempDir(), "part"))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer src.Close()
+	src.Truncate(16 * fileChunk)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Write(ctx, "copy.bin", src); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if off, _ := src.Seek(0, io.SeekCurrent); off != 0 {
+		t.Errorf("expected nothing read from the source, got %d bytes", off)
+	}
+	assertEmptyDir(t, s.root)
+}
+
+func TestCopyChunked(t *testing.T) {
+	src, _ := os.Create(filepath.Join(t.TempDir(), "src"))
+	defer src.Close()
+	data := bytes.Repeat([]byte("x"), fileChunk+10)
+	src.Write(data)
+	src.Seek(0, io.SeekStart)
+
+	var dst bytes.Buffer
+	n, err := copyChunked(context.Background(), &dst, src)
+	if err != nil || n != int64(len(data)) || !bytes.Equal(dst.Bytes(), data) {
+		t.Errorf("copyChunked = %d, %v; want %d bytes copied intact", n, err, len(data))
+	}
+}
+
+func TestRead_StopsAfterCancel(t *testing.T) {
+	s := newTestStorage(t)
+	os.WriteFile(filepath.Join(s.root, "a.txt"), []byte(strings.Repeat("data", 1024)), 0o644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rc, err := s.Read(ctx, "a.txt")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	defer rc.Close()
+
+	buf := make([]byte, 16)
+	if _, err := rc.Read(buf); err != nil {
+		t.Fatalf("first read: %v", err)
+	}
+	cancel()
+	if _, err := rc.Read(buf); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled after cancel, got %v", err)
+	}
+	if _, err := io.Copy(io.Discard, rc); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected WriteTo to stop with context.Canceled, got %v", err)
+	}
+}
+
+func TestRead_UncancellableContextReturnsFile(t *testing.T) {
+	s := newTestStorage(t)
+	os.WriteFile(filepath.Join(s.root, "a.txt"), []byte("data"), 0o644)
+
+	rc, err := s.Read(context.Background(), "a.txt")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	defer rc.Close()
+	if _, ok := rc.(*os.File); !ok {
+		t.Errorf("expected the plain *os.File, got %T", rc)
+	}
+}
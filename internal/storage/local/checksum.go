@@ -0,0 +1,106 @@
+package local
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// SHA256 returns the file's SHA-256, reusing a cached digest while the file's
+// modification time and size are unchanged.
+func (s *Storage) SHA256(_ context.Context, path string) (string, error) {
+	full, _, err := s.filePath(path)
+	if err != nil {
+		return "", err
+	}
+	return s.sumFile(full)
+}
+
+// sumFile hashes the file at the absolute path full, through the cache. A
+// file stored by WithCompression is hashed by its uncompressed contents.
+func (s *Storage) sumFile(full string) (string, error) {
+	f, err := os.Open(full)
+	if err != nil {
+		return "", mapError(err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", mapError(err)
+	}
+	if info.IsDir() {
+		return "", storage.ErrNotFound
+	}
+
+	key := sumKey{modTime: info.ModTime(), size: info.Size()}
+	if sum, ok := s.sums.get(full, key); ok {
+		return sum, nil
+	}
+
+	var r io.Reader = f
+	if s.compress && strings.HasSuffix(full, gzSuffix) {
+		if _, ok := readGzSize(f); ok {
+			zr, err := gzip.NewReader(f)
+			if err != nil {
+				return "", err
+			}
+			r = zr
+		}
+	}
+	sum, err := storage.HashSHA256(r)
+	if err != nil {
+		return "", mapError(err)
+	}
+	s.sums.put(full, key, sum)
+	return sum, nil
+}
+
+// sumKey identifies a version of a file well enough to reuse its digest.
+type sumKey struct {
+	modTime time.Time
+	size    int64
+}
+
+type cachedSum struct {
+	key sumKey
+	sum string
+}
+
+// sumCache maps absolute file paths to their last computed digest. The zero
+// value is ready to use.
+type sumCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedSum
+}
+
+func (c *sumCache) get(path string, key sumKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok || e.key.size != key.size || !e.key.modTime.Equal(key.modTime) {
+		return "", false
+	}
+	return e.sum, true
+}
+
+func (c *sumCache) put(path string, key sumKey, sum string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]cachedSum)
+	}
+	c.entries[path] = cachedSum{key: key, sum: sum}
+}
+
+func (c *sumCache) drop(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, path)
+}
@@ -0,0 +1,291 @@
+package local
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-storage-api/internal/storage"
+)
+
+// gzSuffix is appended to the names of files stored by WithCompression.
+const gzSuffix = ".gz"
+
+// Compressed files carry their original size in a gzip extra subfield
+// with this id. The subfield is also what marks a file as written by
+// WithCompression: other .gz files in the root are served as they are.
+var gzSizeID = [2]byte{'S', 'Z'}
+
+// gzHeaderLen covers the fixed gzip header, the extra field length and the
+// size subfield; the size is its last 8 bytes.
+const gzHeaderLen = 10 + 2 + 4 + 8
+
+// incompressibleExts are formats that are already compressed. They are
+// written in stored gzip blocks, which wrap the bytes without spending
+// time on deflate.
+var incompressibleExts = map[string]bool{
+	".gz": true, ".tgz": true, ".zip": true, ".zst": true, ".xz": true,
+	".bz2": true, ".7z": true, ".rar": true, ".br": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true,
+	".avif": true, ".heic": true,
+	".mp3": true, ".m4a": true, ".ogg": true, ".mp4": true, ".mov": true,
+	".mkv": true, ".webm": true,
+	".woff2": true, ".docx": true, ".xlsx": true, ".pptx": true, ".jar": true,
+}
+
+// WithCompression gzips files at rest. A file is stored at its path plus
+// ".gz" with its original size in the gzip header, so Stat, listings and
+// usage report the uncompressed size without decompressing anything, and
+// listings drop the suffix again. Already-compressed formats are stored
+// uncompressed inside the gzip framing. Files written before compression
+// was enabled are served as they are and compressed when next written.
+// It cannot be combined with WithDedup.
+func WithCompression() Option {
+	return func(s *Storage) {
+		s.compress = true
+	}
+}
+
+// filePath resolves path like safePath and, with WithCompression, to the
+// compressed copy of the file when there is one. compressed reports which.
+func (s *Storage) filePath(path string) (full string, compressed bool, err error) {
+	full, err = s.safePath(path)
+	if err != nil || !s.compress || full == s.root {
+		return full, false, err
+	}
+	gz := full + gzSuffix
+	if s.checkLinks(gz) == nil {
+		if _, ok := compressedSize(gz); ok {
+			return gz, true, nil
+		}
+	}
+	return full, false, nil
+}
+
+// compressedSize reports whether full is a file written by WithCompression
+// and, if so, the size of its contents.
+func compressedSize(full string) (int64, bool) {
+	f, err := os.Open(full)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	return readGzSize(f)
+}
+
+func readGzSize(r io.ReaderAt) (int64, bool) {
+	var h [gzHeaderLen]byte
+	if _, err := r.ReadAt(h[:], 0); err != nil {
+		return 0, false
+	}
+	const flagExtra = 1 << 2
+	if h[0] != 0x1f || h[1] != 0x8b || h[3]&flagExtra == 0 ||
+		binary.LittleEndian.Uint16(h[10:]) != 12 ||
+		[2]byte(h[12:14]) != gzSizeID || binary.LittleEndian.Uint16(h[14:]) != 8 {
+		return 0, false
+	}
+	return int64(binary.LittleEndian.Uint64(h[16:])), true
+}
+
+// gzExtra returns the extra field recording size.
+func gzExtra(size int64) []byte {
+	b := make([]byte, 12)
+	copy(b, gzSizeID[:])
+	binary.LittleEndian.PutUint16(b[2:], 8)
+	binary.LittleEndian.PutUint64(b[4:], uint64(size))
+	return b
+}
+
+// compressInto gzips r into f, the staged copy of full. The size is only
+// known at the end, so the header is written with zero and patched.
+func (s *Storage) compressInto(ctx context.Context, f *os.File, full string, r io.Reader) error {
+	level := gzip.DefaultCompression
+	if incompressibleExts[strings.ToLower(filepath.Ext(full))] {
+		level = gzip.NoCompression
+	}
+	zw, err := gzip.NewWriterLevel(f, level)
+	if err != nil {
+		return err
+	}
+	zw.Extra = gzExtra(0)
+	// Hide WriteTo so the copy uses a pooled buffer.
+	n, err := s.copyBuffers.Copy(zw, struct{ io.Reader }{contextReader(ctx, r)})
+	if err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(gzExtra(n)[4:], gzHeaderLen-8)
+	return err
+}
+
+// gzipFile streams the contents of a compressed file.
+type gzipFile struct {
+	*gzip.Reader
+	f io.Closer
+}
+
+func (g gzipFile) Close() error {
+	return g.f.Close()
+}
+
+// openCompressed decompresses f, stopping once ctx ends.
+func openCompressed(ctx context.Context, f *os.File) (io.ReadCloser, error) {
+	zr, err := gzip.NewReader(contextFile(ctx, f))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return gzipFile{zr, f}, nil
+}
+
+// storedEntry returns the name and size to report for the entry e in dir,
+// whose size on disk is size: with WithCompression, a compressed file's
+// name without the suffix and its original size.
+func (s *Storage) storedEntry(dir string, e fs.DirEntry, size int64) (string, int64) {
+	name := e.Name()
+	if !s.compress || !e.Type().IsRegular() {
+		return name, size
+	}
+	base, ok := strings.CutSuffix(name, gzSuffix)
+	if !ok || base == "" {
+		return name, size
+	}
+	if n, ok := compressedSize(filepath.Join(dir, name)); ok {
+		return base, n
+	}
+	return name, size
+}
+
+// writeCompressed is writeFile for WithCompression. An uncompressed file
+// left at full from before compression was enabled is replaced, and its
+// tags move to the new copy. perm, when non-zero, sets the new file's
+// permission bits.
+func (s *Storage) writeCompressed(ctx context.Context, full string, r io.Reader, excl bool, perm fs.FileMode) error {
+	gz := full + gzSuffix
+	if err := s.checkLinks(gz); err != nil {
+		return err
+	}
+	if err := s.checkDirs(filepath.Dir(full)); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return mapError(err)
+	}
+
+	plain, err := os.Lstat(full)
+	hadPlain := err == nil
+	if hadPlain && plain.IsDir() {
+		return storage.ErrIsDirectory
+	}
+	if hadPlain && excl {
+		return storage.ErrExist
+	}
+	if info, err := os.Lstat(gz); err == nil {
+		// An uncompressed file named like the compressed copy is some
+		// other file and must not be replaced.
+		if _, ok := compressedSize(gz); !ok || !info.Mode().IsRegular() || excl {
+			return storage.ErrExist
+		}
+	}
+
+	tmp, err := s.stage(ctx, full, r)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp) // no-op once renamed
+	defer s.sums.drop(gz)
+
+	if perm != 0 {
+		if err := os.Chmod(tmp, perm); err != nil {
+			return mapError(err)
+		}
+	}
+	if excl {
+		if err := os.Link(tmp, gz); err != nil {
+			return mapError(err)
+		}
+	} else if err := os.Rename(tmp, gz); err != nil {
+		return mapError(err)
+	}
+	if err := s.syncDir(filepath.Dir(gz)); err != nil {
+		return mapError(err)
+	}
+	if hadPlain {
+		s.moveMetadata(full, gz, false)
+		os.Remove(full)
+		s.sums.drop(full)
+	}
+	return nil
+}
+
+// renameTarget returns where Rename should move a file or directory to
+// reach dst under WithCompression, refusing to put a directory beside a
+// compressed file of the same name or the other way round.
+func (s *Storage) renameTarget(dst string, compressed, isDir bool) (string, error) {
+	if !s.compress {
+		return dst, nil
+	}
+	if err := s.checkDirs(filepath.Dir(dst)); err != nil {
+		return "", err
+	}
+	if isDir {
+		if _, ok := compressedSize(dst + gzSuffix); ok {
+			return "", storage.ErrExist
+		}
+		return dst, nil
+	}
+	if !compressed {
+		return dst, nil
+	}
+	if info, err := os.Stat(dst); err == nil && info.IsDir() {
+		return "", storage.ErrExist
+	}
+	gz := dst + gzSuffix
+	return gz, s.checkLinks(gz)
+}
+
+// dropTwin removes the other stored form of a file just placed at full,
+// so a rename over an uncompressed file replaces it as it would without
+// compression.
+func (s *Storage) dropTwin(full string, compressed bool) {
+	if !s.compress {
+		return
+	}
+	twin := full + gzSuffix
+	if compressed {
+		twin = strings.TrimSuffix(full, gzSuffix)
+	} else if _, ok := compressedSize(twin); !ok {
+		return
+	}
+	if info, err := os.Lstat(twin); err != nil || info.IsDir() {
+		return
+	}
+	os.Remove(twin)
+	s.sums.drop(twin)
+	s.dropMetadata(twin)
+}
+
+// checkDirs returns storage.ErrExist if creating the directory full and
+// its missing parents would put a directory beside a compressed file of
+// the same name, which would hide one of them.
+func (s *Storage) checkDirs(full string) error {
+	if !s.compress {
+		return nil
+	}
+	for p := full; p != s.root && within(s.root, p); p = filepath.Dir(p) {
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			return nil
+		}
+		if _, ok := compressedSize(p + gzSuffix); ok {
+			return storage.ErrExist
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+func newCompressedStorage(t *testing.T) *Storage {
+	t.Helper()
+	s, err := New(t.TempDir(), WithCompression())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return s
+}
+
+func TestCompression_RoundTrip(t *testing.T) {
+	s := newCompressedStorage(t)
+	ctx := context.Background()
+	content := strings.Repeat("the same line of text, over and over\n", 1000)
+
+	if err := s.Write(ctx, "docs/notes.txt", strings.NewReader(content)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if got := readString(t, s, "docs/notes.txt"); got != content {
+		t.Errorf("read back %d bytes, want the %d written", len(got), len(content))
+	}
+	disk, err := os.Stat(filepath.Join(s.root, "docs/notes.txt.gz"))
+	if err != nil {
+		t.Fatalf("expected the compressed copy on disk: %v", err)
+	}
+	if disk.Size() >= int64(len(content))/10 {
+		t.Errorf("expected repetitive text to shrink, stored %d of %d bytes", disk.Size(), len(content))
+	}
+	if _, err := os.Stat(filepath.Join(s.root, "docs/notes.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no uncompressed copy, stat err = %v", err)
+	}
+
+	info, err := s.Stat(ctx, "docs/notes.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Name != "notes.txt" || info.Path != "docs/notes.txt" || info.Size != int64(len(content)) {
+		t.Errorf("Stat = %q %q %d, want notes.txt docs/notes.txt %d", info.Name, info.Path, info.Size, len(content))
+	}
+
+	files, err := s.List(ctx, "docs")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "notes.txt" || files[0].Path != "docs/notes.txt" || files[0].Size != int64(len(content)) {
+		t.Errorf("List = %+v, want notes.txt with size %d", files, len(content))
+	}
+
+	dir, _ := s.Stat(ctx, "docs")
+	u, _ := s.Usage(ctx, "/")
+	if dir.TotalSize != int64(len(content)) || u.TotalBytes != int64(len(content)) {
+		t.Errorf("expected directory and usage totals of %d, got %d and %d", len(content), dir.TotalSize, u.TotalBytes)
+	}
+
+	want := sha256.Sum256([]byte(content))
+	if sum, err := s.SHA256(ctx, "docs/notes.txt"); err != nil || sum != hex.EncodeToString(want[:]) {
+		t.Errorf("SHA256 = %q, %v; want the digest of the uncompressed contents", sum, err)
+	}
+}
+
+func TestCompression_IncompressibleStoredRaw(t *testing.T) {
+	s := newCompressedStorage(t)
+	ctx := context.Background()
+	content := make([]byte, 4096)
+	rand.Read(content)
+
+	if err := s.Write(ctx, "photo.JPG", bytes.NewReader(content)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	disk, err := os.ReadFile(filepath.Join(s.root, "photo.JPG.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(disk, content) {
+		t.Error("expected an already-compressed format stored without deflate")
+	}
+	if got := readString(t, s, "photo.JPG"); got != string(content) {
+		t.Error("read back different contents")
+	}
+	if info, err := s.Stat(ctx, "photo.JPG"); err != nil || info.Size != int64(len(content)) {
+		t.Errorf("Stat = %+v, %v; want size %d", info, err, len(content))
+	}
+}
+
+func TestCompression_EmptyFile(t *testing.T) {
+	s := newCompressedStorage(t)
+	ctx := context.Background()
+
+	if err := s.Create(ctx, "empty.txt", strings.NewReader("")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := readString(t, s, "empty.txt"); got != "" {
+		t.Errorf("expected empty contents, got %q", got)
+	}
+	if info, err := s.Stat(ctx, "empty.txt"); err != nil || info.Size != 0 {
+		t.Errorf("Stat = %+v, %v; want size 0", info, err)
+	}
+	if err := s.Create(ctx, "empty.txt", strings.NewReader("x")); !errors.Is(err, storage.ErrExist) {
+		t.Errorf("expected ErrExist creating over a compressed file, got %v", err)
+	}
+}
+
+func TestCompression_UncompressedFilesServedAsIs(t *testing.T) {
+	s := newCompressedStorage(t)
+	ctx := context.Background()
+	os.WriteFile(filepath.Join(s.root, "old.txt"), []byte("from before"), 0o644)
+	os.WriteFile(filepath.Join(s.root, "backup.gz"), []byte("not ours"), 0o644)
+
+	files, err := s.List(ctx, "/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	names := map[string]int64{}
+	for _, f := range files {
+		names[f.Name] = f.Size
+	}
+	if len(names) != 2 || names["old.txt"] != 11 || names["backup.gz"] != 8 {
+		t.Errorf("expected both files listed unchanged, got %v", names)
+	}
+	if got := readString(t, s, "backup.gz"); got != "not ours" {
+		t.Errorf("expected a foreign .gz file read as is, got %q", got)
+	}
+	if _, err := s.Stat(ctx, "backup"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected a foreign .gz file not to answer for its base name, got %v", err)
+	}
+
+	// Rewriting an old file compresses it and removes the original.
+	if err := s.Write(ctx, "old.txt", strings.NewReader("rewritten")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.root, "old.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the uncompressed original removed, stat err = %v", err)
+	}
+	if got := readString(t, s, "old.txt"); got != "rewritten" {
+		t.Errorf("expected the new contents, got %q", got)
+	}
+	if files, _ := s.List(ctx, "/"); len(files) != 2 {
+		t.Errorf("expected 2 entries after the rewrite, got %+v", files)
+	}
+}
+
+func TestCompression_RenameAndDelete(t *testing.T) {
+	s := newCompressedStorage(t)
+	ctx := context.Background()
+	writeTestFile(t, s, "a.txt", "hello")
+	os.WriteFile(filepath.Join(s.root, "b.txt"), []byte("old"), 0o644)
+
+	if err := s.Rename(ctx, "a.txt", "b.txt"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if _, err := s.Stat(ctx, "a.txt"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected a.txt gone, got %v", err)
+	}
+	if got := readString(t, s, "b.txt"); got != "hello" {
+		t.Errorf("expected the renamed contents, got %q", got)
+	}
+	if files, _ := s.List(ctx, "/"); len(files) != 1 {
+		t.Errorf("expected the file renamed over to be replaced, got %+v", files)
+	}
+
+	if err := s.Delete(ctx, "b.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if entries, _ := os.ReadDir(s.root); len(entries) != 0 {
+		t.Errorf("expected an empty root, got %d entries", len(entries))
+	}
+}
+
+func TestCompression_DirectoryBesideFileRefused(t *testing.T) {
+	s := newCompressedStorage(t)
+	ctx := context.Background()
+	writeTestFile(t, s, "report", "x")
+
+	if err := s.Mkdir(ctx, "report"); !errors.Is(err, storage.ErrExist) {
+		t.Errorf("Mkdir: expected ErrExist, got %v", err)
+	}
+	if err := s.Write(ctx, "report/part.txt", strings.NewReader("y")); !errors.Is(err, storage.ErrExist) {
+		t.Errorf("Write below a file: expected ErrExist, got %v", err)
+	}
+	if err := s.Mkdir(ctx, "dir"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(ctx, "dir", strings.NewReader("z")); !errors.Is(err, storage.ErrIsDirectory) {
+		t.Errorf("Write over a directory: expected ErrIsDirectory, got %v", err)
+	}
+}
+
+func TestCompression_MetadataFollowsFile(t *testing.T) {
+	s := newCompressedStorage(t)
+	ctx := context.Background()
+	writeTestFile(t, s, "a.txt", "hello")
+
+	if err := s.SetMetadata(ctx, "a.txt", map[string]string{"owner": "ops"}); err != nil {
+		t.Fatalf("SetMetadata: %v", err)
+	}
+	if err := s.Rename(ctx, "a.txt", "b.txt"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if tags := getTags(t, s, "b.txt"); tags["owner"] != "ops" {
+		t.Errorf("expected tags to move with the file, got %v", tags)
+	}
+}
+
+func TestNew_CompressionWithDedupRefused(t *testing.T) {
+	if _, err := New(t.TempDir(), WithCompression(), WithDedup()); err == nil {
+		t.Error("expected an error combining compression and dedup")
+	}
+}
+
+func TestCompression_NotSeekable(t *testing.T) {
+	s := newCompressedStorage(t)
+	ctx := context.Background()
+	if err := s.Write(ctx, "notes.txt", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if _, err := s.ReadSeeker(ctx, "notes.txt"); !errors.Is(err, storage.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for a gzipped file, got %v", err)
+	}
+}
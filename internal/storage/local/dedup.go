@@ -0,0 +1,192 @@
+package local
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-storage-api/internal/storage"
+)
+
+// blobDir holds one file per distinct content when dedup is enabled, named
+// by its SHA-256 and fanned out by the first two hex digits. Visible files
+// are hard links to these blobs.
+const blobDir = ".blobs"
+
+// WithDedup stores identical contents once. Each write is hashed while it
+// is spooled into the blob store under the root; if a blob with the same
+// SHA-256 already exists the spooled copy is dropped and the path becomes
+// another hard link to it. Reads need no help. A blob is removed once the
+// last path linking to it is deleted or overwritten. The blob store is
+// hidden from listings and usage, and paths inside it are refused.
+//
+// Hard links share metadata, so every copy of a blob reports the
+// modification time of its first upload and the same permissions.
+func WithDedup() Option {
+	return func(s *Storage) {
+		s.dedup = true
+	}
+}
+
+func (s *Storage) blobRoot() string {
+	return filepath.Join(s.root, blobDir)
+}
+
+func (s *Storage) blobPath(sum string) string {
+	return filepath.Join(s.blobRoot(), sum[:2], sum)
+}
+
+// hidden reports whether full is the blob store or the metadata store,
+// which are kept out of listings.
+func (s *Storage) hidden(full string) bool {
+	return (s.dedup && full == s.blobRoot()) || full == s.metaRoot()
+}
+
+// inBlobStore reports whether full lies inside the blob store.
+func (s *Storage) inBlobStore(full string) bool {
+	root := s.blobRoot()
+	return s.dedup && (full == root || strings.HasPrefix(full, root+string(filepath.Separator)))
+}
+
+// writeDedup spools r into the blob store while hashing it, then links the
+// blob for its digest into place at full: directly when excl is set, so an
+// existing file fails with storage.ErrExist, and otherwise through a
+// temporary link renamed over full, so readers never see a missing file.
+func (s *Storage) writeDedup(ctx context.Context, full string, r io.Reader, excl bool) error {
+	if excl {
+		// Fail before consuming the upload; os.Link below is the real check.
+		if _, err := os.Lstat(full); err == nil {
+			return storage.ErrExist
+		}
+	}
+
+	if err := os.MkdirAll(s.blobRoot(), 0o755); err != nil {
+		return mapError(err)
+	}
+	tmp, err := createTemp(s.blobRoot(), ".tmp-*")
+	if err != nil {
+		return mapError(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	if _, err := s.copyInto(ctx, tmp, io.TeeReader(r, h)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := s.syncFile(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	blob := s.blobPath(sum)
+
+	// Linking and releasing blobs must not interleave, or a delete could
+	// remove a blob another write is about to link.
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(blob), 0o755); err != nil {
+		return mapError(err)
+	}
+	if err := os.Link(tmp.Name(), blob); err != nil && !errors.Is(err, fs.ErrExist) {
+		return mapError(err)
+	}
+	if err := s.syncDir(filepath.Dir(blob)); err != nil {
+		return mapError(err)
+	}
+
+	if excl {
+		if err := os.Link(blob, full); err != nil {
+			return mapError(err)
+		}
+	} else {
+		oldBlob, old := s.linkedBlob(full)
+		link, err := tempName(filepath.Dir(full), "."+filepath.Base(full)+".tmp-*")
+		if err != nil {
+			return mapError(err)
+		}
+		if err := os.Link(blob, link); err != nil {
+			return mapError(err)
+		}
+		if err := os.Rename(link, full); err != nil {
+			os.Remove(link)
+			return mapError(err)
+		}
+		s.releaseBlob(oldBlob, old)
+	}
+	if err := s.syncDir(filepath.Dir(full)); err != nil {
+		return mapError(err)
+	}
+
+	s.sums.drop(full)
+	if info, err := os.Stat(full); err == nil {
+		s.sums.put(full, sumKey{modTime: info.ModTime(), size: info.Size()}, sum)
+	}
+	return nil
+}
+
+// deleteDedup removes full and, if it was the last link to a blob, the
+// blob too.
+func (s *Storage) deleteDedup(full string) error {
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+
+	blob, info := s.linkedBlob(full)
+	if err := os.Remove(full); err != nil {
+		return mapError(err)
+	}
+	s.sums.drop(full)
+	s.dropMetadata(full)
+	s.releaseBlob(blob, info)
+	return nil
+}
+
+// linkedBlob returns the blob full may be linked to, and full's file info,
+// or "" when full is not a file with other links. It hashes the file, which
+// the digest cache usually answers for files written in dedup mode.
+func (s *Storage) linkedBlob(full string) (string, fs.FileInfo) {
+	info, err := os.Lstat(full)
+	if err != nil || !info.Mode().IsRegular() || linkCount(info) < 2 {
+		return "", nil
+	}
+	sum, err := s.sumFile(full)
+	if err != nil {
+		return "", nil
+	}
+	return s.blobPath(sum), info
+}
+
+// releaseBlob removes blob if it is the same file as the just-unlinked old
+// and nothing else links to it any more.
+func (s *Storage) releaseBlob(blob string, old fs.FileInfo) {
+	if blob == "" {
+		return
+	}
+	info, err := os.Lstat(blob)
+	if err != nil || !os.SameFile(info, old) || linkCount(info) != 1 {
+		return
+	}
+	os.Remove(blob)
+}
+
+// tempName returns an unused name in dir built from pattern, as
+// os.CreateTemp would, without leaving a file there.
+func tempName(dir, pattern string) (string, error) {
+	f, err := createTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return f.Name(), os.Remove(f.Name())
+}
@@ -0,0 +1,184 @@
+//go:build unix
+
+package local
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+func newDedupStorage(t *testing.T) *Storage {
+	t.Helper()
+	s, err := New(t.TempDir(), WithDedup())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return s
+}
+
+// blobCount returns how many blobs the store holds.
+func blobCount(t *testing.T, s *Storage) int {
+	t.Helper()
+	n := 0
+	filepath.WalkDir(s.blobRoot(), func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			n++
+		}
+		return nil
+	})
+	return n
+}
+
+func TestDedup_IdenticalUploadsShareOneBlob(t *testing.T) {
+	s := newDedupStorage(t)
+	ctx := context.Background()
+
+	if err := s.Write(ctx, "a/report.pdf", strings.NewReader("same bytes")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := s.Create(ctx, "b/copy.pdf", strings.NewReader("same bytes")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if n := blobCount(t, s); n != 1 {
+		t.Errorf("expected 1 blob, got %d", n)
+	}
+	a, _ := os.Stat(filepath.Join(s.root, "a/report.pdf"))
+	b, _ := os.Stat(filepath.Join(s.root, "b/copy.pdf"))
+	if !os.SameFile(a, b) {
+		t.Error("expected both paths to share the stored contents")
+	}
+	if got := readString(t, s, "b/copy.pdf"); got != "same bytes" {
+		t.Errorf("expected %q, got %q", "same bytes", got)
+	}
+
+	if err := s.Write(ctx, "c.txt", strings.NewReader("other bytes")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if n := blobCount(t, s); n != 2 {
+		t.Errorf("expected 2 blobs after distinct content, got %d", n)
+	}
+}
+
+func TestDedup_LastDeleteRemovesBlob(t *testing.T) {
+	s := newDedupStorage(t)
+	ctx := context.Background()
+
+	s.Write(ctx, "one.txt", strings.NewReader("shared"))
+	s.Write(ctx, "two.txt", strings.NewReader("shared"))
+
+	if err := s.Delete(ctx, "one.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if n := blobCount(t, s); n != 1 {
+		t.Errorf("expected the blob to survive while two.txt uses it, got %d blobs", n)
+	}
+	if got := readString(t, s, "two.txt"); got != "shared" {
+		t.Errorf("expected two.txt intact, got %q", got)
+	}
+
+	if err := s.Delete(ctx, "two.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if n := blobCount(t, s); n != 0 {
+		t.Errorf("expected the blob removed with its last reference, got %d blobs", n)
+	}
+}
+
+func TestDedup_OverwriteLeavesOtherCopiesAlone(t *testing.T) {
+	s := newDedupStorage(t)
+	ctx := context.Background()
+
+	s.Write(ctx, "one.txt", strings.NewReader("shared"))
+	s.Write(ctx, "two.txt", strings.NewReader("shared"))
+
+	if err := s.Write(ctx, "one.txt", strings.NewReader("changed")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := s.Replace(ctx, "two.txt", strings.NewReader("changed")); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	if got := readString(t, s, "one.txt"); got != "changed" {
+		t.Errorf("expected one.txt changed, got %q", got)
+	}
+	if n := blobCount(t, s); n != 1 {
+		t.Errorf("expected only the new blob left, got %d", n)
+	}
+
+	s.Write(ctx, "three.txt", strings.NewReader("changed"))
+	s.Write(ctx, "three.txt", strings.NewReader("private"))
+	if got := readString(t, s, "two.txt"); got != "changed" {
+		t.Errorf("expected two.txt unaffected by rewriting three.txt, got %q", got)
+	}
+}
+
+func TestDedup_RenameOverCopyReleasesBlob(t *testing.T) {
+	s := newDedupStorage(t)
+	ctx := context.Background()
+
+	s.Write(ctx, "old.txt", strings.NewReader("old"))
+	s.Write(ctx, "new.txt", strings.NewReader("new"))
+
+	if err := s.Rename(ctx, "new.txt", "old.txt"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if n := blobCount(t, s); n != 1 {
+		t.Errorf("expected the overwritten file's blob released, got %d blobs", n)
+	}
+}
+
+func TestDedup_CreateExisting(t *testing.T) {
+	s := newDedupStorage(t)
+	ctx := context.Background()
+
+	s.Write(ctx, "a.txt", strings.NewReader("first"))
+	if err := s.Create(ctx, "a.txt", strings.NewReader("first")); !errors.Is(err, storage.ErrExist) {
+		t.Errorf("expected ErrExist, got %v", err)
+	}
+}
+
+func TestDedup_BlobStoreIsHidden(t *testing.T) {
+	s := newDedupStorage(t)
+	ctx := context.Background()
+
+	s.Write(ctx, "a.txt", strings.NewReader("data"))
+
+	files, err := s.List(ctx, "/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "a.txt" {
+		t.Errorf("expected only a.txt listed, got %+v", files)
+	}
+	if u, _ := s.Usage(ctx, "/"); u.FileCount != 1 || u.DirCount != 0 {
+		t.Errorf("expected usage to skip the blob store, got %+v", u)
+	}
+	if root, _ := s.Stat(ctx, "/"); root.ChildCount != 1 {
+		t.Errorf("expected root ChildCount 1, got %d", root.ChildCount)
+	}
+	if _, err := s.List(ctx, "/.blobs"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("expected ErrPermission inside the blob store, got %v", err)
+	}
+}
+
+func TestWrite_UnlinksLeftoverHardLink(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	s.Write(ctx, "a.txt", strings.NewReader("shared"))
+	os.Link(filepath.Join(s.root, "a.txt"), filepath.Join(s.root, "b.txt"))
+
+	if err := s.Write(ctx, "a.txt", strings.NewReader("changed")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := readString(t, s, "b.txt"); got != "shared" {
+		t.Errorf("expected the other link untouched, got %q", got)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd
+
+package local
+
+import (
+	"context"
+
+	"go-storage-api/internal/storage"
+)
+
+// DiskInfo cannot ask the filesystem for its size here.
+func (s *Storage) DiskInfo(context.Context) (*storage.DiskSpace, error) {
+	return nil, storage.ErrNotSupported
+}
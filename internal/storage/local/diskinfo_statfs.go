@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+
+	"go-storage-api/internal/storage"
+)
+
+// DiskInfo reports the space on the filesystem mounted at the root
+// directory, as statfs(2) sees it.
+func (s *Storage) DiskInfo(_ context.Context) (*storage.DiskSpace, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(s.root, &st); err != nil {
+		return nil, fmt.Errorf("statfs %s: %w", s.root, err)
+	}
+	bsize := uint64(st.Bsize)
+	return &storage.DiskSpace{
+		TotalBytes: uint64(st.Blocks) * bsize,
+		FreeBytes:  uint64(st.Bavail) * bsize,
+		UsedBytes:  (uint64(st.Blocks) - uint64(st.Bfree)) * bsize,
+	}, nil
+}
@@ -0,0 +1,25 @@
+//go:build linux || darwin || freebsd
+
+package local
+
+import (
+	"context"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+func TestDiskInfo(t *testing.T) {
+	s := newTestStorage(t)
+
+	d, err := storage.DiskInfo(context.Background(), s)
+	if err != nil {
+		t.Fatalf("DiskInfo: %v", err)
+	}
+	if d.TotalBytes == 0 || d.FreeBytes == 0 {
+		t.Errorf("expected non-zero total and free space, got %+v", d)
+	}
+	if d.FreeBytes > d.TotalBytes || d.UsedBytes > d.TotalBytes {
+		t.Errorf("expected free and used within total, got %+v", d)
+	}
+}
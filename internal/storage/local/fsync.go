@@ -0,0 +1,41 @@
+package local
+
+import (
+	"os"
+)
+
+// WithFsync makes writes durable before they return: each new file is
+// flushed to disk with fsync before it is moved into place, and its
+// directory is flushed after the move so the new name survives a crash
+// too. Directories created for the file along the way are not flushed.
+//
+// Without it writes return once the data is in the page cache, which is
+// much faster; expect fsync to cost a few milliseconds per write on SSDs
+// and tens on spinning disks or network storage.
+func WithFsync() Option {
+	return func(s *Storage) {
+		s.fsync = true
+	}
+}
+
+// syncFile flushes f's contents under WithFsync.
+func (s *Storage) syncFile(f *os.File) error {
+	if !s.fsync {
+		return nil
+	}
+	return s.syncer(f)
+}
+
+// syncDir flushes the entries of directory dir under WithFsync, making
+// files just renamed or linked into it durable.
+func (s *Storage) syncDir(dir string) error {
+	if !s.fsync {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return s.syncer(d)
+}
@@ -0,0 +1,91 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordSyncs replaces s's syncer with one that records the names it is
+// given instead of flushing them.
+func recordSyncs(s *Storage) *[]string {
+	var synced []string
+	s.syncer = func(f *os.File) error {
+		synced = append(synced, f.Name())
+		return nil
+	}
+	return &synced
+}
+
+func TestWithFsync_SyncsFileAndDirectory(t *testing.T) {
+	ctx := context.Background()
+	for name, opts := range map[string][]Option{
+		"plain":      {WithFsync()},
+		"compressed": {WithFsync(), WithCompression()},
+		"dedup":      {WithFsync(), WithDedup()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, err := New(t.TempDir(), opts...)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			synced := recordSyncs(s)
+
+			if err := s.Write(ctx, "/docs/a.txt", strings.NewReader("hello")); err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+			dir := filepath.Join(s.root, "docs")
+			var file, parent bool
+			for _, name := range *synced {
+				if name == dir {
+					parent = true
+				} else if strings.Contains(filepath.Base(name), ".tmp-") {
+					file = true
+				}
+			}
+			if !file || !parent {
+				t.Errorf("expected the file and %s to be synced, got %v", dir, *synced)
+			}
+
+			*synced = nil
+			if err := s.Replace(ctx, "/docs/a.txt", strings.NewReader("again")); err != nil {
+				t.Fatalf("Replace() failed: %v", err)
+			}
+			if len(*synced) < 2 {
+				t.Errorf("expected Replace to sync the file and directory, got %v", *synced)
+			}
+		})
+	}
+}
+
+func TestWithoutFsync_NoSyncs(t *testing.T) {
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	synced := recordSyncs(s)
+
+	if err := s.Write(context.Background(), "/a.txt", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if len(*synced) != 0 {
+		t.Errorf("expected no syncs by default, got %v", *synced)
+	}
+}
+
+func TestWithFsync_SyncError(t *testing.T) {
+	s, err := New(t.TempDir(), WithFsync())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	s.syncer = func(*os.File) error { return os.ErrInvalid }
+
+	if err := s.Write(context.Background(), "/a.txt", strings.NewReader("hello")); err == nil {
+		t.Fatal("expected a failed sync to fail the write")
+	}
+	if _, err := os.Stat(filepath.Join(s.root, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected no file after a failed sync, got %v", err)
+	}
+}
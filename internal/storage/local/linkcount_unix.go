@@ -0,0 +1,16 @@
+//go:build unix
+
+package local
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// linkCount returns how many hard links point at the file behind info.
+func linkCount(info fs.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Nlink)
+	}
+	return 1
+}
@@ -2,22 +2,56 @@ package local
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"go-storage-api/internal/bufpool"
 	"go-storage-api/internal/storage"
 )
 
 // Storage implements storage.Storage against the local filesystem.
 type Storage struct {
-	root string
+	root        string
+	sums        sumCache
+	copyBuffers *bufpool.Pool
+	stagingDir  string
+
+	// dedup and blobMu implement WithDedup; see dedup.go.
+	dedup  bool
+	blobMu sync.Mutex
+
+	// noSymlinks implements WithoutSymlinks; see symlink.go.
+	noSymlinks bool
+
+	// compress implements WithCompression; see compress.go.
+	compress bool
+
+	// fsync and syncer implement WithFsync; see fsync.go.
+	fsync  bool
+	syncer func(*os.File) error
+}
+
+// Option configures a Storage.
+type Option func(*Storage)
+
+// WithCopyBuffers makes writes borrow their copy buffers from p instead of
+// allocating one per call.
+func WithCopyBuffers(p *bufpool.Pool) Option {
+	return func(s *Storage) {
+		s.copyBuffers = p
+	}
 }
 
 // New creates a local storage backend rooted at the given directory.
-func New(root string) (*Storage, error) {
+func New(root string, opts ...Option) (*Storage, error) {
 	abs, err := filepath.Abs(root)
 	if err != nil {
 		return nil, fmt.Errorf("resolve root path: %w", err)
@@ -25,10 +59,41 @@ func New(root string) (*Storage, error) {
 	if err := os.MkdirAll(abs, 0o755); err != nil {
 		return nil, fmt.Errorf("create root directory: %w", err)
 	}
-	return &Storage{root: abs}, nil
+	// Symlink checks compare resolved paths, so the root must be resolved too.
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return nil, fmt.Errorf("resolve root path: %w", err)
+	}
+	s := &Storage{root: abs, syncer: (*os.File).Sync}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.dedup && s.compress {
+		return nil, errors.New("compression cannot be combined with dedup")
+	}
+	if s.stagingDir != "" {
+		if err := s.prepareStaging(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Name identifies this backend in diagnostics.
+func (s *Storage) Name() string {
+	return "local"
 }
 
 func (s *Storage) List(_ context.Context, path string) ([]storage.FileInfo, error) {
+	return s.list(path, false)
+}
+
+// ListDirs lists only the subdirectories of path. Other entries are
+// skipped on the directory entry's type, without statting them.
+func (s *Storage) ListDirs(_ context.Context, path string) ([]storage.FileInfo, error) {
+	return s.list(path, true)
+}
+
+func (s *Storage) list(path string, dirsOnly bool) ([]storage.FileInfo, error) {
 	full, err := s.safePath(path)
 	if err != nil {
 		return nil, err
@@ -41,15 +106,19 @@ func (s *Storage) List(_ context.Context, path string) ([]storage.FileInfo, erro
 
 	files := make([]storage.FileInfo, 0, len(entries))
 	for _, e := range entries {
+		if (dirsOnly && !e.IsDir()) || s.skipEntry(full, e) {
+			continue
+		}
 		info, err := e.Info()
 		if err != nil {
 			return nil, mapError(err)
 		}
-		rel, _ := filepath.Rel(s.root, filepath.Join(full, e.Name()))
+		name, size := s.storedEntry(full, e, info.Size())
+		rel, _ := filepath.Rel(s.root, filepath.Join(full, name))
 		files = append(files, storage.FileInfo{
-			Name:    e.Name(),
+			Name:    name,
 			Path:    filepath.ToSlash(rel),
-			Size:    info.Size(),
+			Size:    size,
 			IsDir:   e.IsDir(),
 			ModTime: info.ModTime(),
 		})
@@ -57,55 +126,277 @@ func (s *Storage) List(_ context.Context, path string) ([]storage.FileInfo, erro
 	return files, nil
 }
 
-func (s *Storage) Read(_ context.Context, path string) (io.ReadCloser, error) {
-	full, err := s.safePath(path)
+// Read opens the file at path. The returned stream stops with ctx's error
+// once ctx ends, so an abandoned download stops reading the disk.
+func (s *Storage) Read(ctx context.Context, path string) (io.ReadCloser, error) {
+	f, compressed, err := s.openFile(path)
 	if err != nil {
 		return nil, err
 	}
+	if compressed {
+		return openCompressed(ctx, f)
+	}
+	return contextFile(ctx, f), nil
+}
+
+// ReadSeeker opens the file at path for random access, stopping reads once
+// ctx ends like Read. Files stored gzipped (WithCompression) cannot seek
+// and get storage.ErrNotSupported.
+func (s *Storage) ReadSeeker(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+	f, compressed, err := s.openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if compressed {
+		f.Close()
+		return nil, storage.ErrNotSupported
+	}
+	return contextFile(ctx, f), nil
+}
+
+// openFile opens the regular file stored for path, reporting whether it is
+// the gzipped copy.
+func (s *Storage) openFile(path string) (*os.File, bool, error) {
+	full, compressed, err := s.filePath(path)
+	if err != nil {
+		return nil, false, err
+	}
 
 	f, err := os.Open(full)
 	if err != nil {
-		return nil, mapError(err)
+		return nil, false, mapError(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, false, mapError(err)
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, false, storage.ErrIsDirectory
 	}
-	return f, nil
+	return f, compressed, nil
 }
 
-func (s *Storage) Write(_ context.Context, path string, r io.Reader) error {
+func (s *Storage) Write(ctx context.Context, path string, r io.Reader) error {
+	return s.writeFile(ctx, path, r, false)
+}
+
+// Create writes a new file, failing with storage.ErrExist if path is already
+// taken. The staged file is hard-linked into place, which fails instead of
+// replacing an existing file, so the check and the create are one step.
+func (s *Storage) Create(ctx context.Context, path string, r io.Reader) error {
+	return s.writeFile(ctx, path, r, true)
+}
+
+// writeFile stages r and moves the finished file to path, which must not
+// exist when excl is set. Readers see the previous file or the complete new
+// one, never a partial write.
+func (s *Storage) writeFile(ctx context.Context, path string, r io.Reader, excl bool) error {
 	full, err := s.safePath(path)
 	if err != nil {
 		return err
 	}
 
+	if s.compress {
+		return s.writeCompressed(ctx, full, r, excl, 0)
+	}
 	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
 		return mapError(err)
 	}
+	if s.dedup {
+		return s.writeDedup(ctx, full, r, excl)
+	}
+	if excl {
+		// Fail before consuming the upload; os.Link below is the real check.
+		if _, err := os.Lstat(full); err == nil {
+			return storage.ErrExist
+		}
+	}
 
-	f, err := os.Create(full)
+	tmp, err := s.stage(ctx, full, r)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp) // no-op once renamed
+	// A rewrite can land within the same mtime tick at the same size.
+	defer s.sums.drop(full)
+
+	if excl {
+		err = os.Link(tmp, full)
+	} else {
+		err = os.Rename(tmp, full)
+	}
 	if err != nil {
 		return mapError(err)
 	}
-	defer f.Close()
+	return mapError(s.syncDir(filepath.Dir(full)))
+}
 
-	if _, err := io.Copy(f, r); err != nil {
-		return fmt.Errorf("write file: %w", err)
+// Replace swaps the contents of the existing file at path the same way
+// Write does, so readers never see a partial file. The file keeps its
+// permission bits. A file deleted between the existence check and the
+// rename is recreated.
+func (s *Storage) Replace(ctx context.Context, path string, r io.Reader) error {
+	full, compressed, err := s.filePath(path)
+	if err != nil {
+		return err
 	}
-	return nil
+
+	info, err := os.Stat(full)
+	if err != nil {
+		return mapError(err)
+	}
+	if info.IsDir() {
+		return storage.ErrIsDirectory
+	}
+	if s.compress {
+		if compressed {
+			full = strings.TrimSuffix(full, gzSuffix)
+		}
+		return s.writeCompressed(ctx, full, r, false, info.Mode().Perm())
+	}
+	if s.dedup {
+		return s.writeDedup(ctx, full, r, false)
+	}
+
+	tmp, err := s.stage(ctx, full, r)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp) // no-op once renamed
+	defer s.sums.drop(full)
+
+	if err := os.Chmod(tmp, info.Mode().Perm()); err != nil {
+		return mapError(err)
+	}
+	if err := os.Rename(tmp, full); err != nil {
+		return mapError(err)
+	}
+	return mapError(s.syncDir(filepath.Dir(full)))
+}
+
+// copyInto copies r into f, stopping with ctx's error once ctx ends. When
+// r is itself a file, such as a multipart part spooled to disk, *os.File's
+// ReadFrom lets the kernel copy the data, fileChunk bytes at a time so ctx
+// is checked in between. Anything else would make ReadFrom allocate a
+// fresh buffer, so f is wrapped to hide it and the copy uses a pooled
+// buffer, checking ctx before every read.
+func (s *Storage) copyInto(ctx context.Context, f *os.File, r io.Reader) (int64, error) {
+	if src, ok := r.(*os.File); ok {
+		return copyChunked(ctx, f, src)
+	}
+	return s.copyBuffers.Copy(struct{ io.Writer }{f}, contextReader(ctx, r))
 }
 
 func (s *Storage) Delete(_ context.Context, path string) error {
-	full, err := s.safePath(path)
+	full, _, err := s.filePath(path)
 	if err != nil {
 		return err
 	}
 
+	if s.dedup {
+		return s.deleteDedup(full)
+	}
 	if err := os.Remove(full); err != nil {
 		return mapError(err)
 	}
+	s.sums.drop(full)
+	s.dropMetadata(full)
+	return nil
+}
+
+// DeleteAll removes path and, for a directory, everything below it along
+// with the tags set on it. The root itself cannot be removed. Symlinks
+// below path are removed, not followed. With WithDedup every file is
+// unlinked through the blob store first, so blobs it held alone go too.
+func (s *Storage) DeleteAll(ctx context.Context, path string) error {
+	full, _, err := s.filePath(path)
+	if err != nil {
+		return err
+	}
+	if full == s.root {
+		return storage.ErrPermission
+	}
+	info, err := os.Lstat(full)
+	if err != nil {
+		return mapError(err)
+	}
+	if !info.IsDir() {
+		return s.Delete(ctx, path)
+	}
+
+	if s.dedup {
+		err := filepath.WalkDir(full, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			return s.deleteDedup(p)
+		})
+		if err != nil {
+			return mapError(err)
+		}
+	}
+	if err := os.RemoveAll(full); err != nil {
+		return mapError(err)
+	}
+	os.RemoveAll(s.metaPath(full, ""))
+	return nil
+}
+
+// Rename moves from to to with os.Rename, creating to's parent directories,
+// and takes the tags set with SetMetadata along. The root itself cannot be
+// moved or replaced.
+func (s *Storage) Rename(_ context.Context, from, to string) error {
+	src, compressed, err := s.filePath(from)
+	if err != nil {
+		return err
+	}
+	dst, err := s.safePath(to)
+	if err != nil {
+		return err
+	}
+	if src == s.root || dst == s.root {
+		return storage.ErrPermission
+	}
+	info, err := os.Lstat(src)
+	if err != nil {
+		return mapError(err)
+	}
+	if dst, err = s.renameTarget(dst, compressed, info.IsDir()); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return mapError(err)
+	}
+	if s.dedup {
+		// Renaming over a file drops its link to a blob like a delete.
+		s.blobMu.Lock()
+		defer s.blobMu.Unlock()
+		blob, old := s.linkedBlob(dst)
+		defer s.releaseBlob(blob, old)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return mapError(err)
+	}
+	s.sums.drop(src)
+	s.sums.drop(dst)
+	s.moveMetadata(src, dst, info.IsDir())
+	if !info.IsDir() {
+		s.dropTwin(dst, compressed)
+	}
 	return nil
 }
 
 func (s *Storage) Stat(_ context.Context, path string) (*storage.FileInfo, error) {
-	full, err := s.safePath(path)
+	full, compressed, err := s.filePath(path)
 	if err != nil {
 		return nil, err
 	}
@@ -115,18 +406,121 @@ func (s *Storage) Stat(_ context.Context, path string) (*storage.FileInfo, error
 		return nil, mapError(err)
 	}
 
-	rel, _ := filepath.Rel(s.root, full)
-	return &storage.FileInfo{
-		Name:    info.Name(),
+	name, size := info.Name(), info.Size()
+	if compressed {
+		name, size = strings.TrimSuffix(name, gzSuffix), 0
+		if n, ok := compressedSize(full); ok {
+			size = n
+		}
+	}
+	rel, _ := filepath.Rel(s.root, filepath.Join(filepath.Dir(full), name))
+	fi := &storage.FileInfo{
+		Name:    name,
 		Path:    filepath.ToSlash(rel),
-		Size:    info.Size(),
+		Size:    size,
 		IsDir:   info.IsDir(),
 		ModTime: info.ModTime(),
-	}, nil
+	}
+	if info.IsDir() {
+		entries, err := os.ReadDir(full)
+		if err != nil {
+			return nil, mapError(err)
+		}
+		for _, e := range entries {
+			if s.skipEntry(full, e) {
+				continue
+			}
+			fi.ChildCount++
+			if e.IsDir() {
+				continue
+			}
+			// Entries removed since ReadDir still count but add no size.
+			if ei, err := e.Info(); err == nil {
+				_, size := s.storedEntry(full, e, ei.Size())
+				fi.TotalSize += size
+			}
+		}
+	}
+	return fi, nil
+}
+
+// SetModTime sets the modification time of path, leaving its access time
+// alone. With WithDedup, copies sharing a blob share the time too.
+func (s *Storage) SetModTime(_ context.Context, path string, t time.Time) error {
+	full, _, err := s.filePath(path)
+	if err != nil {
+		return err
+	}
+	return mapError(os.Chtimes(full, time.Time{}, t))
+}
+
+// Mkdir creates path and any missing parents. It succeeds if the directory
+// already exists and returns storage.ErrExist if a file is in the way.
+func (s *Storage) Mkdir(_ context.Context, path string) error {
+	full, err := s.safePath(path)
+	if err != nil {
+		return err
+	}
+	if err := s.checkDirs(full); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(full, 0o755); err != nil {
+		if errors.Is(err, syscall.ENOTDIR) {
+			return storage.ErrExist
+		}
+		return mapError(err)
+	}
+	return nil
+}
+
+// Usage walks the subtree at path and totals file sizes and entry counts.
+// The starting directory itself is not counted. The walk stops early if ctx
+// is cancelled.
+func (s *Storage) Usage(ctx context.Context, path string) (*storage.Usage, error) {
+	full, err := s.safePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var u storage.Usage
+	err = filepath.WalkDir(full, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if s.hidden(p) {
+				return filepath.SkipDir
+			}
+			if p != full {
+				u.DirCount++
+			}
+			return nil
+		}
+		if s.skipEntry(filepath.Dir(p), d) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		_, size := s.storedEntry(filepath.Dir(p), d, info.Size())
+		u.FileCount++
+		u.TotalBytes += size
+		return nil
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &u, nil
 }
 
 // safePath resolves the requested path against the root directory and ensures
-// the result stays within root to prevent directory traversal.
+// the result stays within root to prevent directory traversal, including
+// through symlinks; see checkLinks.
 func (s *Storage) safePath(requested string) (string, error) {
 	// Treat empty or "/" as the root directory.
 	if requested == "" || requested == "/" {
@@ -136,13 +530,17 @@ func (s *Storage) safePath(requested string) (string, error) {
 	joined := filepath.Join(s.root, filepath.FromSlash(requested))
 	cleaned := filepath.Clean(joined)
 
-	if !strings.HasPrefix(cleaned, s.root) {
+	if !within(s.root, cleaned) || s.inBlobStore(cleaned) || within(s.metaRoot(), cleaned) {
 		return "", storage.ErrPermission
 	}
+	if err := s.checkLinks(cleaned); err != nil {
+		return "", err
+	}
 	return cleaned, nil
 }
 
-// mapError converts os-level errors to storage sentinel errors.
+// mapError converts os-level errors to storage sentinel errors. A nil error
+// stays nil.
 func mapError(err error) error {
 	if os.IsNotExist(err) {
 		return storage.ErrNotFound
@@ -150,5 +548,12 @@ func mapError(err error) error {
 	if os.IsPermission(err) {
 		return storage.ErrPermission
 	}
+	// os.IsExist also matches ENOTEMPTY.
+	if errors.Is(err, syscall.ENOTEMPTY) {
+		return storage.ErrNotEmpty
+	}
+	if os.IsExist(err) {
+		return storage.ErrExist
+	}
 	return err
 }
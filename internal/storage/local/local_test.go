@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"go-storage-api/internal/storage"
 )
@@ -21,6 +22,17 @@ func newTestStorage(t *testing.T) *Storage {
 	return s
 }
 
+func readString(t *testing.T, s *Storage, path string) string {
+	t.Helper()
+	rc, err := s.Read(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Read(%s): %v", path, err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	return string(data)
+}
+
 // --- List ---
 
 func TestList_EmptyDir(t *testing.T) {
@@ -96,6 +108,33 @@ func TestList_NotFound(t *testing.T) {
 	}
 }
 
+func TestListDirs_MixedDir(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	os.MkdirAll(filepath.Join(s.root, "docs", "b"), 0o755)
+	os.MkdirAll(filepath.Join(s.root, "docs", "a"), 0o755)
+	os.WriteFile(filepath.Join(s.root, "docs", "readme.md"), []byte("hi"), 0o644)
+	os.WriteFile(filepath.Join(s.root, "docs", "a", "inner.txt"), []byte("x"), 0o644)
+
+	dirs, err := s.ListDirs(ctx, "docs")
+	if err != nil {
+		t.Fatalf("ListDirs: %v", err)
+	}
+	if len(dirs) != 2 || dirs[0].Path != "docs/a" || dirs[1].Path != "docs/b" {
+		t.Fatalf("expected docs/a and docs/b, got %+v", dirs)
+	}
+	for _, d := range dirs {
+		if !d.IsDir || d.ModTime.IsZero() {
+			t.Errorf("expected a directory with a modtime, got %+v", d)
+		}
+	}
+
+	if _, err := s.ListDirs(ctx, "nonexistent"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
 // --- Read ---
 
 func TestRead_Success(t *testing.T) {
@@ -130,6 +169,50 @@ func TestRead_NotFound(t *testing.T) {
 	}
 }
 
+func TestRead_Directory(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	os.Mkdir(filepath.Join(s.root, "docs"), 0o755)
+
+	_, err := s.Read(ctx, "docs")
+	if !errors.Is(err, storage.ErrIsDirectory) {
+		t.Errorf("expected ErrIsDirectory, got %v", err)
+	}
+}
+
+func TestReadSeeker(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	os.WriteFile(filepath.Join(s.root, "test.txt"), []byte("0123456789"), 0o644)
+
+	rs, err := s.ReadSeeker(ctx, "test.txt")
+	if err != nil {
+		t.Fatalf("ReadSeeker: %v", err)
+	}
+	defer rs.Close()
+
+	if _, err := rs.Seek(-4, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(rs); string(data) != "6789" {
+		t.Errorf("expected 6789 after seeking, got %q", data)
+	}
+	rs.Seek(2, io.SeekStart)
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(rs, buf); err != nil || string(buf) != "234" {
+		t.Errorf("expected 234 after seeking back, got %q (%v)", buf, err)
+	}
+
+	if _, err := s.ReadSeeker(ctx, "missing.txt"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	os.Mkdir(filepath.Join(s.root, "docs"), 0o755)
+	if _, err := s.ReadSeeker(ctx, "docs"); !errors.Is(err, storage.ErrIsDirectory) {
+		t.Errorf("expected ErrIsDirectory, got %v", err)
+	}
+}
+
 // --- Write ---
 
 func TestWrite_NewFile(t *testing.T) {
@@ -182,6 +265,42 @@ func TestWrite_Overwrite(t *testing.T) {
 	}
 }
 
+// --- Create ---
+
+func TestCreate_NewFile(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	if err := s.Create(ctx, "nested/new.txt", strings.NewReader("fresh")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.root, "nested", "new.txt"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(data) != "fresh" {
+		t.Errorf("expected %q, got %q", "fresh", string(data))
+	}
+}
+
+func TestCreate_ExistingFile(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	os.WriteFile(filepath.Join(s.root, "keep.txt"), []byte("original"), 0o644)
+
+	err := s.Create(ctx, "keep.txt", strings.NewReader("clobber"))
+	if !errors.Is(err, storage.ErrExist) {
+		t.Errorf("expected ErrExist, got %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(s.root, "keep.txt"))
+	if string(data) != "original" {
+		t.Errorf("expected original content preserved, got %q", string(data))
+	}
+}
+
 // --- Delete ---
 
 func TestDelete_Success(t *testing.T) {
@@ -209,6 +328,66 @@ func TestDelete_NotFound(t *testing.T) {
 	}
 }
 
+func TestDelete_Directory(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	os.Mkdir(filepath.Join(s.root, "empty"), 0o755)
+	writeTestFile(t, s, "full/a.txt", "a")
+
+	if err := s.Delete(ctx, "empty"); err != nil {
+		t.Errorf("empty directory: %v", err)
+	}
+	if err := s.Delete(ctx, "full"); !errors.Is(err, storage.ErrNotEmpty) {
+		t.Errorf("non-empty directory: expected ErrNotEmpty, got %v", err)
+	}
+}
+
+func TestDeleteAll_RemovesSubtree(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	writeTestFile(t, s, "docs/a.txt", "a")
+	writeTestFile(t, s, "docs/sub/b.txt", "b")
+	writeTestFile(t, s, "keep.txt", "k")
+	if err := s.SetMetadata(ctx, "docs/sub/b.txt", map[string]string{"owner": "ops"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteAll(ctx, "docs"); err != nil {
+		t.Fatalf("DeleteAll: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.root, "docs")); !os.IsNotExist(err) {
+		t.Error("expected the directory removed")
+	}
+	if _, err := os.Stat(s.metaPath(filepath.Join(s.root, "docs"), "")); !os.IsNotExist(err) {
+		t.Error("expected the subtree's tags removed")
+	}
+	if _, err := os.Stat(filepath.Join(s.root, "keep.txt")); err != nil {
+		t.Errorf("expected files outside the subtree kept: %v", err)
+	}
+
+	if err := s.DeleteAll(ctx, "keep.txt"); err != nil {
+		t.Errorf("DeleteAll of a file: %v", err)
+	}
+	if err := s.DeleteAll(ctx, "docs"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("missing path: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDeleteAll_StaysInsideRoot(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	writeTestFile(t, s, "a.txt", "a")
+
+	for _, p := range []string{"/", "", "../.."} {
+		if err := s.DeleteAll(ctx, p); !errors.Is(err, storage.ErrPermission) {
+			t.Errorf("DeleteAll(%q): expected ErrPermission, got %v", p, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(s.root, "a.txt")); err != nil {
+		t.Errorf("expected the root left intact: %v", err)
+	}
+}
+
 // --- Stat ---
 
 func TestStat_File(t *testing.T) {
@@ -248,6 +427,45 @@ func TestStat_Dir(t *testing.T) {
 	if !fi.IsDir {
 		t.Error("expected IsDir=true")
 	}
+	if fi.ChildCount != 0 || fi.TotalSize != 0 {
+		t.Errorf("expected an empty directory, got %d children of %d bytes", fi.ChildCount, fi.TotalSize)
+	}
+}
+
+func TestStat_DirChildCounts(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	dir := filepath.Join(s.root, "mydir")
+	os.Mkdir(dir, 0o755)
+	for name, data := range map[string]string{"a.txt": "1", "b.txt": "22", "c.txt": "333"} {
+		os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644)
+	}
+
+	fi, err := s.Stat(ctx, "mydir")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fi.ChildCount != 3 {
+		t.Errorf("expected ChildCount 3, got %d", fi.ChildCount)
+	}
+	if fi.TotalSize != 6 {
+		t.Errorf("expected TotalSize 6, got %d", fi.TotalSize)
+	}
+
+	// Subdirectories count as children but add nothing to the size, and
+	// their contents are not read.
+	os.MkdirAll(filepath.Join(dir, "sub", "deep"), 0o755)
+	os.WriteFile(filepath.Join(dir, "sub", "deep", "big.bin"), make([]byte, 100), 0o644)
+	fi, _ = s.Stat(ctx, "mydir")
+	if fi.ChildCount != 4 || fi.TotalSize != 6 {
+		t.Errorf("expected 4 children of 6 bytes, got %d of %d", fi.ChildCount, fi.TotalSize)
+	}
+
+	file, _ := s.Stat(ctx, "mydir/a.txt")
+	if file.ChildCount != 0 || file.TotalSize != 0 {
+		t.Errorf("expected no counts on a file, got %+v", file)
+	}
 }
 
 func TestStat_NotFound(t *testing.T) {
@@ -309,3 +527,287 @@ func TestSafePath_AllowsValid(t *testing.T) {
 // --- Interface compliance ---
 
 var _ storage.Storage = (*Storage)(nil)
+
+// --- Mkdir ---
+
+func TestMkdir_CreatesNested(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	if err := s.Mkdir(ctx, "a/b/c"); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(s.root, "a", "b", "c"))
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if !info.IsDir() {
+		t.Error("expected a directory")
+	}
+}
+
+func TestMkdir_Idempotent(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	if err := s.Mkdir(ctx, "dir"); err != nil {
+		t.Fatalf("first Mkdir: %v", err)
+	}
+	if err := s.Mkdir(ctx, "dir"); err != nil {
+		t.Errorf("second Mkdir should succeed, got %v", err)
+	}
+}
+
+func TestMkdir_FileInTheWay(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	os.WriteFile(filepath.Join(s.root, "file.txt"), []byte("x"), 0o644)
+
+	err := s.Mkdir(ctx, "file.txt")
+	if !errors.Is(err, storage.ErrExist) {
+		t.Errorf("expected ErrExist, got %v", err)
+	}
+}
+
+// --- Usage ---
+
+func TestUsage_Subtree(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	os.MkdirAll(filepath.Join(s.root, "docs", "sub"), 0o755)
+	os.WriteFile(filepath.Join(s.root, "top.txt"), []byte("12345"), 0o644)
+	os.WriteFile(filepath.Join(s.root, "docs", "a.txt"), []byte("abc"), 0o644)
+	os.WriteFile(filepath.Join(s.root, "docs", "sub", "b.txt"), []byte("de"), 0o644)
+
+	u, err := s.Usage(ctx, "/")
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if u.TotalBytes != 10 || u.FileCount != 3 || u.DirCount != 2 {
+		t.Errorf("root usage = %+v, want {10 3 2}", *u)
+	}
+
+	u, err = s.Usage(ctx, "docs")
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if u.TotalBytes != 5 || u.FileCount != 2 || u.DirCount != 1 {
+		t.Errorf("docs usage = %+v, want {5 2 1}", *u)
+	}
+}
+
+func TestUsage_NotFound(t *testing.T) {
+	s := newTestStorage(t)
+
+	_, err := s.Usage(context.Background(), "missing")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUsage_Cancelled(t *testing.T) {
+	s := newTestStorage(t)
+	os.WriteFile(filepath.Join(s.root, "a.txt"), []byte("a"), 0o644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.Usage(ctx, "/")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// --- SHA256 ---
+
+const helloSHA256 = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+func TestSHA256(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	s.Write(ctx, "a.txt", strings.NewReader("hello world"))
+
+	sum, err := s.SHA256(ctx, "a.txt")
+	if err != nil {
+		t.Fatalf("SHA256: %v", err)
+	}
+	if sum != helloSHA256 {
+		t.Errorf("expected %s, got %s", helloSHA256, sum)
+	}
+}
+
+func TestSHA256_CachedUntilFileChanges(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	s.Write(ctx, "a.txt", strings.NewReader("hello world"))
+	if _, err := s.SHA256(ctx, "a.txt"); err != nil {
+		t.Fatalf("SHA256: %v", err)
+	}
+
+	// Change the bytes behind the backend's back but keep size and mtime:
+	// the cached digest should be served.
+	full := filepath.Join(s.root, "a.txt")
+	info, _ := os.Stat(full)
+	os.WriteFile(full, []byte("HELLO WORLD"), 0o644)
+	os.Chtimes(full, info.ModTime(), info.ModTime())
+
+	sum, _ := s.SHA256(ctx, "a.txt")
+	if sum != helloSHA256 {
+		t.Errorf("expected cached digest, got %s", sum)
+	}
+
+	// Writing through the backend invalidates the entry.
+	s.Write(ctx, "a.txt", strings.NewReader("HELLO WORLD"))
+	sum, _ = s.SHA256(ctx, "a.txt")
+	if sum == helloSHA256 {
+		t.Error("expected a fresh digest after Write")
+	}
+}
+
+func TestSHA256_NotFound(t *testing.T) {
+	s := newTestStorage(t)
+
+	if _, err := s.SHA256(context.Background(), "missing.txt"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// --- Replace ---
+
+func TestReplace_Existing(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	full := filepath.Join(s.root, "conf", "app.json")
+	os.MkdirAll(filepath.Dir(full), 0o755)
+	os.WriteFile(full, []byte(`{"old":true,"padding":"xxxxxxxx"}`), 0o600)
+
+	if err := s.Replace(ctx, "conf/app.json", strings.NewReader(`{"new":true}`)); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	data, _ := os.ReadFile(full)
+	if string(data) != `{"new":true}` {
+		t.Errorf("expected new contents, got %q", data)
+	}
+	info, _ := os.Stat(full)
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600 kept, got %v", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(full))
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left, got %d entries", len(entries))
+	}
+}
+
+func TestReplace_Missing(t *testing.T) {
+	s := newTestStorage(t)
+
+	err := s.Replace(context.Background(), "missing.json", strings.NewReader("{}"))
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.root, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("expected no file created, stat err = %v", err)
+	}
+}
+
+func TestReplace_Directory(t *testing.T) {
+	s := newTestStorage(t)
+	os.Mkdir(filepath.Join(s.root, "docs"), 0o755)
+
+	err := s.Replace(context.Background(), "docs", strings.NewReader("{}"))
+	if !errors.Is(err, storage.ErrIsDirectory) {
+		t.Errorf("expected ErrIsDirectory, got %v", err)
+	}
+}
+
+// errReader fails every read, like a client that disconnects mid-body.
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestReplace_FailedCopyKeepsOriginal(t *testing.T) {
+	s := newTestStorage(t)
+	full := filepath.Join(s.root, "a.txt")
+	os.WriteFile(full, []byte("original"), 0o644)
+
+	err := s.Replace(context.Background(), "a.txt", io.MultiReader(strings.NewReader("partial"), errReader{}))
+	if err == nil {
+		t.Fatal("expected the copy error")
+	}
+	data, _ := os.ReadFile(full)
+	if string(data) != "original" {
+		t.Errorf("expected the original contents kept, got %q", data)
+	}
+	entries, _ := os.ReadDir(s.root)
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left, got %d entries", len(entries))
+	}
+}
+
+// --- Rename ---
+
+func TestRename_CreatesParents(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	s.Write(ctx, "/a.txt", strings.NewReader("hello"))
+
+	if err := s.Rename(ctx, "/a.txt", "/deep/dir/b.txt"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if _, err := s.Stat(ctx, "/a.txt"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected source gone, got %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(s.root, "deep", "dir", "b.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("expected moved contents %q, got %q (%v)", "hello", data, err)
+	}
+}
+
+func TestRename_Root(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	s.Write(ctx, "/a.txt", strings.NewReader("hello"))
+
+	if err := s.Rename(ctx, "/", "/moved"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("moving root: expected ErrPermission, got %v", err)
+	}
+	if err := s.Rename(ctx, "/a.txt", "/"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("replacing root: expected ErrPermission, got %v", err)
+	}
+}
+
+func TestRename_NotFound(t *testing.T) {
+	s := newTestStorage(t)
+
+	err := s.Rename(context.Background(), "/missing.txt", "/b.txt")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSetModTime(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	s.Write(ctx, "docs/a.txt", strings.NewReader("a"))
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, p := range []string{"docs/a.txt", "docs"} {
+		if err := s.SetModTime(ctx, p, when); err != nil {
+			t.Fatalf("SetModTime(%s): %v", p, err)
+		}
+		info, err := s.Stat(ctx, p)
+		if err != nil {
+			t.Fatalf("Stat(%s): %v", p, err)
+		}
+		if !info.ModTime.Equal(when) {
+			t.Errorf("%s: expected ModTime %s, got %s", p, when, info.ModTime)
+		}
+	}
+
+	if err := s.SetModTime(ctx, "missing.txt", when); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("missing file: expected ErrNotFound, got %v", err)
+	}
+}
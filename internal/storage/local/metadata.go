@@ -0,0 +1,135 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go-storage-api/internal/storage"
+)
+
+// metaDir holds the tags set with SetMetadata, one JSON sidecar per file at
+// the file's path plus ".json", so a directory's tags live under the
+// matching directory here and move with it. Like the blob store it is
+// hidden from listings and cannot be addressed through the API.
+const metaDir = ".meta"
+
+func (s *Storage) metaRoot() string {
+	return filepath.Join(s.root, metaDir)
+}
+
+// metaPath returns the sidecar for full, or for a directory the directory
+// holding its files' sidecars when suffix is empty.
+func (s *Storage) metaPath(full, suffix string) string {
+	rel, _ := filepath.Rel(s.root, full)
+	return filepath.Join(s.metaRoot(), rel) + suffix
+}
+
+// GetMetadata returns the tags of the file at path, or an empty map if it
+// has none.
+func (s *Storage) GetMetadata(_ context.Context, path string) (map[string]string, error) {
+	full, err := s.metaTarget(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]string{}
+	data, err := os.ReadFile(s.metaPath(full, ".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return tags, nil
+	}
+	if err != nil {
+		return nil, mapError(err)
+	}
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// SetMetadata replaces the tags of the file at path. The sidecar is written
+// to a temporary file and renamed into place, so readers never see a
+// partial set.
+func (s *Storage) SetMetadata(_ context.Context, path string, tags map[string]string) error {
+	if err := storage.ValidateMetadata(tags); err != nil {
+		return err
+	}
+	full, err := s.metaTarget(path)
+	if err != nil {
+		return err
+	}
+
+	sidecar := s.metaPath(full, ".json")
+	if len(tags) == 0 {
+		if err := os.Remove(sidecar); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return mapError(err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(sidecar), 0o755); err != nil {
+		return mapError(err)
+	}
+	tmp, err := createTemp(filepath.Dir(sidecar), "."+filepath.Base(sidecar)+".tmp-*")
+	if err != nil {
+		return mapError(err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return mapError(err)
+	}
+	return mapError(os.Rename(tmp.Name(), sidecar))
+}
+
+// metaTarget resolves path and checks it names an existing file.
+func (s *Storage) metaTarget(path string) (string, error) {
+	full, _, err := s.filePath(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return "", mapError(err)
+	}
+	if info.IsDir() {
+		return "", storage.ErrIsDirectory
+	}
+	return full, nil
+}
+
+// dropMetadata removes the tags of the deleted file full. Failures only
+// leave an orphaned sidecar behind, so they are ignored.
+func (s *Storage) dropMetadata(full string) {
+	os.Remove(s.metaPath(full, ".json"))
+}
+
+// moveMetadata carries tags along when src is renamed to dst: the sidecar
+// of a file, or every sidecar below a directory. Tags of a file replaced
+// at dst go away with it.
+func (s *Storage) moveMetadata(src, dst string, isDir bool) {
+	suffix := ".json"
+	if isDir {
+		suffix = ""
+	}
+	from, to := s.metaPath(src, suffix), s.metaPath(dst, suffix)
+	if _, err := os.Lstat(from); err != nil {
+		if !isDir {
+			os.Remove(to)
+		}
+		return
+	}
+	if os.MkdirAll(filepath.Dir(to), 0o755) == nil {
+		os.Rename(from, to)
+	}
+}
@@ -0,0 +1,171 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+func writeTestFile(t *testing.T, s *Storage, path, content string) {
+	t.Helper()
+	if err := s.Write(context.Background(), path, strings.NewReader(content)); err != nil {
+		t.Fatalf("Write(%s): %v", path, err)
+	}
+}
+
+func getTags(t *testing.T, s *Storage, path string) map[string]string {
+	t.Helper()
+	tags, err := s.GetMetadata(context.Background(), path)
+	if err != nil {
+		t.Fatalf("GetMetadata(%s): %v", path, err)
+	}
+	return tags
+}
+
+func TestMetadata_RoundTrip(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	writeTestFile(t, s, "docs/a.txt", "a")
+
+	if tags := getTags(t, s, "docs/a.txt"); tags == nil || len(tags) != 0 {
+		t.Errorf("expected an empty map before any tags are set, got %#v", tags)
+	}
+
+	want := map[string]string{"owner": "alice", "label": "draft"}
+	if err := s.SetMetadata(ctx, "docs/a.txt", want); err != nil {
+		t.Fatalf("SetMetadata: %v", err)
+	}
+	if got := getTags(t, s, "docs/a.txt"); !maps.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// A set replaces the previous one; overwriting the file keeps it.
+	want = map[string]string{"owner": "bob"}
+	s.SetMetadata(ctx, "docs/a.txt", want)
+	writeTestFile(t, s, "docs/a.txt", "new contents")
+	if got := getTags(t, s, "docs/a.txt"); !maps.Equal(got, want) {
+		t.Errorf("expected %v after replace and overwrite, got %v", want, got)
+	}
+
+	if err := s.SetMetadata(ctx, "docs/a.txt", map[string]string{}); err != nil {
+		t.Fatalf("SetMetadata({}): %v", err)
+	}
+	if got := getTags(t, s, "docs/a.txt"); len(got) != 0 {
+		t.Errorf("expected {} to clear the tags, got %v", got)
+	}
+}
+
+func TestMetadata_Errors(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	writeTestFile(t, s, "docs/a.txt", "a")
+	tags := map[string]string{"owner": "alice"}
+
+	if err := s.SetMetadata(ctx, "missing.txt", tags); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("missing file: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetMetadata(ctx, "docs"); !errors.Is(err, storage.ErrIsDirectory) {
+		t.Errorf("directory: expected ErrIsDirectory, got %v", err)
+	}
+	if err := s.SetMetadata(ctx, "docs/a.txt", map[string]string{"etag": "x"}); !errors.Is(err, storage.ErrInvalidMetadata) {
+		t.Errorf("reserved key: expected ErrInvalidMetadata, got %v", err)
+	}
+}
+
+func TestMetadata_RemovedWithFile(t *testing.T) {
+	for _, dedup := range []bool{false, true} {
+		name := "plain"
+		opts := []Option{}
+		if dedup {
+			name = "dedup"
+			opts = append(opts, WithDedup())
+		}
+		t.Run(name, func(t *testing.T) {
+			s, err := New(t.TempDir(), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			writeTestFile(t, s, "a.txt", "a")
+			s.SetMetadata(ctx, "a.txt", map[string]string{"owner": "alice"})
+
+			if err := s.Delete(ctx, "a.txt"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			full, _ := s.safePath("a.txt")
+			if _, err := os.Stat(s.metaPath(full, ".json")); !os.IsNotExist(err) {
+				t.Errorf("expected the sidecar to be removed, stat err = %v", err)
+			}
+
+			// A new file at the same path starts without tags.
+			writeTestFile(t, s, "a.txt", "again")
+			if got := getTags(t, s, "a.txt"); len(got) != 0 {
+				t.Errorf("expected no tags on a new file, got %v", got)
+			}
+		})
+	}
+}
+
+func TestMetadata_FollowsRename(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	writeTestFile(t, s, "a.txt", "a")
+	writeTestFile(t, s, "b.txt", "b")
+	writeTestFile(t, s, "dir/c.txt", "c")
+	s.SetMetadata(ctx, "a.txt", map[string]string{"from": "a"})
+	s.SetMetadata(ctx, "b.txt", map[string]string{"from": "b"})
+	s.SetMetadata(ctx, "dir/c.txt", map[string]string{"from": "c"})
+
+	if err := s.Rename(ctx, "a.txt", "moved/a.txt"); err != nil {
+		t.Fatalf("Rename file: %v", err)
+	}
+	if got := getTags(t, s, "moved/a.txt"); got["from"] != "a" {
+		t.Errorf("expected tags to follow the file, got %v", got)
+	}
+
+	// Renaming over b.txt replaces b's tags with the moved file's (none).
+	writeTestFile(t, s, "plain.txt", "p")
+	if err := s.Rename(ctx, "plain.txt", "b.txt"); err != nil {
+		t.Fatalf("Rename over file: %v", err)
+	}
+	if got := getTags(t, s, "b.txt"); len(got) != 0 {
+		t.Errorf("expected the replaced file's tags to go, got %v", got)
+	}
+
+	if err := s.Rename(ctx, "dir", "renamed"); err != nil {
+		t.Fatalf("Rename dir: %v", err)
+	}
+	if got := getTags(t, s, "renamed/c.txt"); got["from"] != "c" {
+		t.Errorf("expected tags to follow the directory, got %v", got)
+	}
+}
+
+func TestMetadata_StoreHidden(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	writeTestFile(t, s, "a.txt", "a")
+	s.SetMetadata(ctx, "a.txt", map[string]string{"owner": "alice"})
+
+	files, err := s.List(ctx, "/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "a.txt" {
+		t.Errorf("expected only a.txt in the listing, got %+v", files)
+	}
+	u, err := s.Usage(ctx, "/")
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if u.FileCount != 1 || u.DirCount != 0 {
+		t.Errorf("expected the metadata store left out of usage, got %+v", u)
+	}
+	if _, err := s.Read(ctx, ".meta/a.txt.json"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("expected ErrPermission reading the metadata store, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// WithStagingDir makes writes stream into temporary files in dir rather
+// than beside their destination, keeping unfinished uploads out of
+// listings. dir must be on the same filesystem as the root, because
+// finished files are renamed into place; New creates it and checks this.
+func WithStagingDir(dir string) Option {
+	return func(s *Storage) {
+		s.stagingDir = dir
+	}
+}
+
+// prepareStaging resolves and creates the staging directory and makes sure
+// files can be renamed from it into the root.
+func (s *Storage) prepareStaging() error {
+	abs, err := filepath.Abs(s.stagingDir)
+	if err != nil {
+		return fmt.Errorf("resolve staging directory: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return fmt.Errorf("create staging directory: %w", err)
+	}
+	s.stagingDir = abs
+
+	probe, err := createTemp(abs, ".probe-*")
+	if err != nil {
+		return fmt.Errorf("create staging directory: %w", err)
+	}
+	probe.Close()
+	defer os.Remove(probe.Name())
+
+	moved := filepath.Join(s.root, filepath.Base(probe.Name()))
+	if err := os.Rename(probe.Name(), moved); err != nil {
+		if errors.Is(err, syscall.EXDEV) {
+			return fmt.Errorf("staging directory %s is not on the same filesystem as %s", abs, s.root)
+		}
+		return fmt.Errorf("check staging directory: %w", err)
+	}
+	return os.Remove(moved)
+}
+
+// stage streams r into a new file in the staging directory, or beside full
+// when none is configured, and returns its name for the caller to move into
+// place, compressed under WithCompression. The file is removed if the copy
+// fails or ctx ends first.
+func (s *Storage) stage(ctx context.Context, full string, r io.Reader) (string, error) {
+	dir := s.stagingDir
+	if dir == "" {
+		dir = filepath.Dir(full)
+	}
+	f, err := createTemp(dir, "."+filepath.Base(full)+".tmp-*")
+	if err != nil {
+		return "", mapError(err)
+	}
+
+	if s.compress {
+		err = s.compressInto(ctx, f, full, r)
+	} else {
+		_, err = s.copyInto(ctx, f, r)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		err = s.syncFile(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// createTemp is os.CreateTemp with the permissions os.Create uses, so a
+// staged file ends up with the same mode as one written in place.
+func createTemp(dir, pattern string) (*os.File, error) {
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	for range 10000 {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix)
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o666)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return f, err
+	}
+	return nil, &fs.PathError{Op: "createtemp", Path: filepath.Join(dir, pattern), Err: fs.ErrExist}
+}
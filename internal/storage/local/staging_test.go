@@ -0,0 +1,137 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cancellingReader yields chunk, cancels the write's context once after
+// the first read, then keeps yielding chunk forever.
+type cancellingReader struct {
+	chunk  string
+	cancel context.CancelFunc
+	reads  int
+}
+
+func (r *cancellingReader) Read(p []byte) (int, error) {
+	r.reads++
+	if r.reads == 2 {
+		r.cancel()
+	}
+	return copy(p, r.chunk), nil
+}
+
+func newStagedStorage(t *testing.T) (*Storage, string) {
+	t.Helper()
+	staging := filepath.Join(t.TempDir(), "staging")
+	s, err := New(t.TempDir(), WithStagingDir(staging))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return s, staging
+}
+
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected %s empty, found %d entries", dir, len(entries))
+	}
+}
+
+func TestWrite_CancelledMidWriteLeavesNothing(t *testing.T) {
+	s, staging := newStagedStorage(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := s.Write(ctx, "inbox/big.bin", &cancellingReader{chunk: "partial", cancel: cancel})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.root, "inbox", "big.bin")); !os.IsNotExist(err) {
+		t.Errorf("expected no file at the destination, got %v", err)
+	}
+	assertEmptyDir(t, staging)
+}
+
+func TestWrite_CancelledOverwriteKeepsOriginal(t *testing.T) {
+	s := newTestStorage(t)
+	full := filepath.Join(s.root, "a.txt")
+	os.WriteFile(full, []byte("original"), 0o644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Write(ctx, "a.txt", &cancellingReader{chunk: "partial", cancel: cancel}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	data, _ := os.ReadFile(full)
+	if string(data) != "original" {
+		t.Errorf("expected the original contents kept, got %q", data)
+	}
+	entries, _ := os.ReadDir(s.root)
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left, got %d entries", len(entries))
+	}
+}
+
+func TestWrite_StagesOutsideDestination(t *testing.T) {
+	s, staging := newStagedStorage(t)
+	ctx := context.Background()
+
+	// Inspect the staging directory while the upload is still streaming.
+	var seen []os.DirEntry
+	r := io.MultiReader(strings.NewReader("hello "), readerFunc(func([]byte) (int, error) {
+		seen, _ = os.ReadDir(staging)
+		return 0, io.EOF
+	}), strings.NewReader("world"))
+
+	if err := s.Write(ctx, "greeting.txt", r); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if len(seen) != 1 || !strings.HasPrefix(seen[0].Name(), ".greeting.txt.tmp-") {
+		t.Errorf("expected the upload staged in %s, saw %v", staging, seen)
+	}
+	data, _ := os.ReadFile(filepath.Join(s.root, "greeting.txt"))
+	if string(data) != "hello world" {
+		t.Errorf("expected %q, got %q", "hello world", data)
+	}
+	assertEmptyDir(t, staging)
+}
+
+func TestWrite_FailedCreateLeavesNothing(t *testing.T) {
+	s, staging := newStagedStorage(t)
+
+	err := s.Create(context.Background(), "a.txt", io.MultiReader(strings.NewReader("partial"), errReader{}))
+	if err == nil {
+		t.Fatal("expected the copy error")
+	}
+	assertEmptyDir(t, s.root)
+	assertEmptyDir(t, staging)
+}
+
+func TestWrite_StagedFileMode(t *testing.T) {
+	s, _ := newStagedStorage(t)
+	s.Write(context.Background(), "a.txt", strings.NewReader("x"))
+
+	ref := filepath.Join(t.TempDir(), "ref")
+	f, _ := os.Create(ref)
+	f.Close()
+	want, _ := os.Stat(ref)
+	got, err := os.Stat(filepath.Join(s.root, "a.txt"))
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if got.Mode().Perm() != want.Mode().Perm() {
+		t.Errorf("expected mode %v like os.Create, got %v", want.Mode().Perm(), got.Mode().Perm())
+	}
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
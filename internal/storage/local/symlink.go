@@ -0,0 +1,112 @@
+package local
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"go-storage-api/internal/storage"
+)
+
+// maxLinkHops bounds how many dangling symlinks resolveExisting follows,
+// matching the limit filepath.EvalSymlinks applies to chains that exist.
+const maxLinkHops = 255
+
+// WithoutSymlinks stops the backend following symbolic links. Any path
+// that passes through a symlink is reported as storage.ErrNotFound, and
+// symlinks are left out of listings, directory counts and usage.
+func WithoutSymlinks() Option {
+	return func(s *Storage) {
+		s.noSymlinks = true
+	}
+}
+
+// within reports whether p is root or lies below it.
+func within(root, p string) bool {
+	return p == root || strings.HasPrefix(p, root+string(filepath.Separator))
+}
+
+// checkLinks makes sure full, a cleaned path under the root, does not
+// reach outside the root through a symlink. With WithoutSymlinks any
+// symlink along the path is refused as storage.ErrNotFound; otherwise
+// links are followed and the target must stay inside the root, or the
+// result is storage.ErrPermission. Links can change between this check
+// and the operation that follows, so it protects against links planted
+// ahead of time rather than ones raced in alongside a request.
+func (s *Storage) checkLinks(full string) error {
+	if s.noSymlinks {
+		return s.rejectLinks(full)
+	}
+	real, err := resolveExisting(full, maxLinkHops)
+	if err != nil {
+		return mapError(err)
+	}
+	if !within(s.root, real) {
+		return storage.ErrPermission
+	}
+	return nil
+}
+
+// rejectLinks returns storage.ErrNotFound if any existing component of
+// full below the root is a symlink.
+func (s *Storage) rejectLinks(full string) error {
+	rel, err := filepath.Rel(s.root, full)
+	if err != nil || rel == "." {
+		return err
+	}
+	p := s.root
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		p = filepath.Join(p, name)
+		info, err := os.Lstat(p)
+		if err != nil {
+			// Missing components cannot be links; let the operation report it.
+			return nil
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return storage.ErrNotFound
+		}
+	}
+	return nil
+}
+
+// resolveExisting is filepath.EvalSymlinks for paths that may not exist
+// yet: it resolves the deepest existing ancestor and appends the rest.
+// Dangling links are followed to where their target would be created.
+func resolveExisting(p string, hops int) (string, error) {
+	rest := ""
+	for {
+		real, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(real, rest), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, syscall.ENOTDIR) {
+			return "", err
+		}
+
+		if target, err := os.Readlink(p); err == nil {
+			if hops == 0 {
+				return "", &fs.PathError{Op: "resolve", Path: p, Err: errors.New("too many links")}
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(p), target)
+			}
+			return resolveExisting(filepath.Join(target, rest), hops-1)
+		}
+
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(p, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
+// skipEntry reports whether the directory entry e in dir is left out of
+// listings: the dedup blob store, and symlinks under WithoutSymlinks.
+func (s *Storage) skipEntry(dir string, e fs.DirEntry) bool {
+	return s.hidden(filepath.Join(dir, e.Name())) || (s.noSymlinks && e.Type()&fs.ModeSymlink != 0)
+}
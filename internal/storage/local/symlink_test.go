@@ -0,0 +1,150 @@
+//go:build unix
+
+package local
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// newLinkFixture returns a store rooted in a fresh directory next to an
+// "outside" directory holding secret.txt, with links planted in the root:
+// leak -> outside/secret.txt, out -> outside, dangling -> outside/new.txt
+// and alias -> docs, a directory inside the root holding a.txt.
+func newLinkFixture(t *testing.T, opts ...Option) (*Storage, string) {
+	t.Helper()
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "docs"), outside} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644)
+	os.WriteFile(filepath.Join(root, "docs", "a.txt"), []byte("inside"), 0o644)
+
+	links := map[string]string{
+		"leak":     filepath.Join(outside, "secret.txt"),
+		"out":      outside,
+		"dangling": filepath.Join(outside, "new.txt"),
+		"alias":    "docs",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := New(root, opts...)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return s, outside
+}
+
+func TestSymlinks_EscapingRootRefused(t *testing.T) {
+	s, outside := newLinkFixture(t)
+	ctx := context.Background()
+
+	if _, err := s.Read(ctx, "leak"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("Read through escaping file link: expected ErrPermission, got %v", err)
+	}
+	if _, err := s.Stat(ctx, "out/secret.txt"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("Stat through escaping dir link: expected ErrPermission, got %v", err)
+	}
+	if _, err := s.List(ctx, "out"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("List escaping dir link: expected ErrPermission, got %v", err)
+	}
+	if err := s.Write(ctx, "out/planted.txt", strings.NewReader("x")); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("Write through escaping dir link: expected ErrPermission, got %v", err)
+	}
+	if err := s.Mkdir(ctx, "out/sub/dir"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("Mkdir through escaping dir link: expected ErrPermission, got %v", err)
+	}
+	if err := s.Write(ctx, "dangling", strings.NewReader("x")); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("Write to dangling escaping link: expected ErrPermission, got %v", err)
+	}
+	if err := s.Rename(ctx, "docs/a.txt", "out/a.txt"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("Rename into escaping dir link: expected ErrPermission, got %v", err)
+	}
+
+	for _, name := range []string{"planted.txt", "sub", "new.txt", "a.txt"} {
+		if _, err := os.Lstat(filepath.Join(outside, name)); err == nil {
+			t.Errorf("%s was created outside the root", name)
+		}
+	}
+}
+
+func TestSymlinks_InsideRootFollowed(t *testing.T) {
+	s, _ := newLinkFixture(t)
+
+	if got := readString(t, s, "alias/a.txt"); got != "inside" {
+		t.Errorf("expected %q through the link, got %q", "inside", got)
+	}
+	if err := s.Write(context.Background(), "alias/b.txt", strings.NewReader("new")); err != nil {
+		t.Fatalf("Write through internal link: %v", err)
+	}
+	if got := readString(t, s, "docs/b.txt"); got != "new" {
+		t.Errorf("expected the write to land in docs, got %q", got)
+	}
+}
+
+func TestSymlinks_SiblingOfRootRefused(t *testing.T) {
+	base := t.TempDir()
+	os.MkdirAll(filepath.Join(base, "data2"), 0o755)
+	os.WriteFile(filepath.Join(base, "data2", "f.txt"), []byte("x"), 0o644)
+	s, err := New(filepath.Join(base, "data"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	if _, err := s.Read(context.Background(), "../data2/f.txt"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("expected ErrPermission for a root-prefixed sibling, got %v", err)
+	}
+}
+
+func TestWithoutSymlinks(t *testing.T) {
+	s, _ := newLinkFixture(t, WithoutSymlinks())
+	ctx := context.Background()
+
+	for _, p := range []string{"alias/a.txt", "alias", "leak", "out/secret.txt"} {
+		if _, err := s.Stat(ctx, p); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Stat(%s): expected ErrNotFound, got %v", p, err)
+		}
+	}
+	if err := s.Write(ctx, "alias/b.txt", strings.NewReader("x")); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Write through link: expected ErrNotFound, got %v", err)
+	}
+	if got := readString(t, s, "docs/a.txt"); got != "inside" {
+		t.Errorf("expected plain paths to keep working, got %q", got)
+	}
+
+	files, err := s.List(ctx, "/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "docs" {
+		t.Errorf("expected only docs in the listing, got %+v", files)
+	}
+	info, err := s.Stat(ctx, "/")
+	if err != nil {
+		t.Fatalf("Stat root: %v", err)
+	}
+	if info.ChildCount != 1 {
+		t.Errorf("expected links left out of the child count, got %d", info.ChildCount)
+	}
+	u, err := s.Usage(ctx, "/")
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if u.FileCount != 1 || u.DirCount != 1 {
+		t.Errorf("expected 1 file and 1 dir, got %+v", u)
+	}
+}
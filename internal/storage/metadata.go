@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidMetadata is wrapped by ValidateMetadata's errors.
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// Limits ValidateMetadata enforces on a file's tags. MaxMetadataBytes
+// counts every key and value together and fits S3's 2 KB user-metadata
+// allowance.
+const (
+	MaxMetadataBytes  = 2048
+	MaxMetadataKeyLen = 128
+)
+
+// reservedMetadataPrefixes are key prefixes kept for the service and for
+// object-store system metadata.
+var reservedMetadataPrefixes = []string{"x-", "storage.", "content-", "cache-"}
+
+// reservedMetadataKeys are names that would collide with HTTP or object
+// metadata the service sets itself.
+var reservedMetadataKeys = map[string]bool{
+	"etag":          true,
+	"checksum":      true,
+	"last-modified": true,
+	"expires":       true,
+}
+
+// MetadataStore is implemented by backends that can attach string key/value
+// tags to files. GetMetadata returns an empty map for a file without tags.
+// SetMetadata replaces the whole set; an empty map clears it. Both return
+// ErrNotFound for a missing file and ErrIsDirectory for a directory, and
+// deleting a file removes its tags.
+type MetadataStore interface {
+	GetMetadata(ctx context.Context, path string) (map[string]string, error)
+	SetMetadata(ctx context.Context, path string, tags map[string]string) error
+}
+
+// GetMetadata returns the tags of the file at path, or ErrNotSupported if
+// the backend has no MetadataStore.
+func GetMetadata(ctx context.Context, s Storage, path string) (map[string]string, error) {
+	if m, ok := s.(MetadataStore); ok {
+		return m.GetMetadata(ctx, path)
+	}
+	return nil, ErrNotSupported
+}
+
+// SetMetadata replaces the tags of the file at path, or returns
+// ErrNotSupported if the backend has no MetadataStore. Tags are not
+// validated here; see ValidateMetadata.
+func SetMetadata(ctx context.Context, s Storage, path string, tags map[string]string) error {
+	if m, ok := s.(MetadataStore); ok {
+		return m.SetMetadata(ctx, path, tags)
+	}
+	return ErrNotSupported
+}
+
+// ValidateMetadata checks tags against the limits every backend accepts.
+// Keys are lowercase letters, digits, '-', '_' and '.', starting with a
+// letter or digit, and must not be reserved. Values must be printable
+// UTF-8 without control characters.
+func ValidateMetadata(tags map[string]string) error {
+	total := 0
+	for k, v := range tags {
+		if err := validateMetadataKey(k); err != nil {
+			return err
+		}
+		for _, r := range v {
+			if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+				return fmt.Errorf("%w: value of %q contains control or invalid characters", ErrInvalidMetadata, k)
+			}
+		}
+		total += len(k) + len(v)
+	}
+	if total > MaxMetadataBytes {
+		return fmt.Errorf("%w: %d bytes of keys and values exceeds the %d byte limit", ErrInvalidMetadata, total, MaxMetadataBytes)
+	}
+	return nil
+}
+
+func validateMetadataKey(k string) error {
+	if k == "" || len(k) > MaxMetadataKeyLen {
+		return fmt.Errorf("%w: keys must be 1 to %d bytes", ErrInvalidMetadata, MaxMetadataKeyLen)
+	}
+	for i, r := range k {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case i > 0 && (r == '-' || r == '_' || r == '.'):
+		default:
+			return fmt.Errorf("%w: key %q must be lowercase letters, digits, '-', '_' or '.'", ErrInvalidMetadata, k)
+		}
+	}
+	if reservedMetadataKeys[k] {
+		return fmt.Errorf("%w: key %q is reserved", ErrInvalidMetadata, k)
+	}
+	for _, prefix := range reservedMetadataPrefixes {
+		if strings.HasPrefix(k, prefix) {
+			return fmt.Errorf("%w: keys starting with %q are reserved", ErrInvalidMetadata, prefix)
+		}
+	}
+	return nil
+}
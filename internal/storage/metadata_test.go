@@ -0,0 +1,87 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go-storage-api/internal/storage"
+	"go-storage-api/internal/storage/fsadapter"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		ok   bool
+	}{
+		{"empty", map[string]string{}, true},
+		{"simple", map[string]string{"owner": "alice", "label": "Q3 report", "build.id": "42"}, true},
+		{"uppercase key", map[string]string{"Owner": "alice"}, false},
+		{"empty key", map[string]string{"": "x"}, false},
+		{"leading dash", map[string]string{"-owner": "x"}, false},
+		{"space in key", map[string]string{"the owner": "x"}, false},
+		{"key too long", map[string]string{strings.Repeat("k", storage.MaxMetadataKeyLen+1): "x"}, false},
+		{"reserved key", map[string]string{"etag": "x"}, false},
+		{"reserved prefix", map[string]string{"x-amz-meta-owner": "x"}, false},
+		{"content prefix", map[string]string{"content-type": "text/html"}, false},
+		{"control in value", map[string]string{"note": "a\nb"}, false},
+		{"invalid utf8 value", map[string]string{"note": "\xff"}, false},
+		{"at size limit", map[string]string{"k": strings.Repeat("v", storage.MaxMetadataBytes-1)}, true},
+		{"over size limit", map[string]string{"k": strings.Repeat("v", storage.MaxMetadataBytes)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := storage.ValidateMetadata(tt.tags)
+			if tt.ok && err != nil {
+				t.Errorf("expected valid, got %v", err)
+			}
+			if !tt.ok && !errors.Is(err, storage.ErrInvalidMetadata) {
+				t.Errorf("expected ErrInvalidMetadata, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMetadata_NotSupported(t *testing.T) {
+	store := fsadapter.New(fstest.MapFS{"a.txt": {Data: []byte("a")}})
+	ctx := context.Background()
+
+	if _, err := storage.GetMetadata(ctx, store, "a.txt"); !errors.Is(err, storage.ErrNotSupported) {
+		t.Errorf("GetMetadata: expected ErrNotSupported, got %v", err)
+	}
+	if err := storage.SetMetadata(ctx, store, "a.txt", map[string]string{"k": "v"}); !errors.Is(err, storage.ErrNotSupported) {
+		t.Errorf("SetMetadata: expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestMetadata_ForwardedByDecorators(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	if err := inner.Write(ctx, "users/alice/a.txt", strings.NewReader("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	var store storage.Storage = storage.WithPrefix(inner, prefixFromCtx)
+	store = storage.WithContentRouting(store)
+	store = storage.WithWriteTee(store, func(string) io.WriteCloser { return &bufferSink{} })
+	store = storage.WithRetry(store, storage.RetryPolicy{})
+	store = storage.WithCache(store, time.Minute, 100)
+
+	tags := map[string]string{"owner": "alice"}
+	if err := storage.SetMetadata(ctx, store, "a.txt", tags); err != nil {
+		t.Fatalf("SetMetadata through %s: %v", storage.NameOf(store), err)
+	}
+	got, err := storage.GetMetadata(ctx, inner, "users/alice/a.txt")
+	if err != nil || !maps.Equal(got, tags) {
+		t.Errorf("expected %v on the inner path, got %v (%v)", tags, got, err)
+	}
+	if got, err := storage.GetMetadata(ctx, store, "a.txt"); err != nil || !maps.Equal(got, tags) {
+		t.Errorf("expected %v through the decorators, got %v (%v)", tags, got, err)
+	}
+}
@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WithMetrics wraps inner so the latency and failures of List, Read,
+// Write, Delete and Stat, and the bytes read and written, are recorded as
+// Prometheus metrics labeled by operation:
+//
+//   - storage_operation_duration_seconds{operation}
+//   - storage_operation_errors_total{operation,kind}, kind being
+//     not_found, permission or other
+//   - storage_operation_bytes_total{operation}, for read and write
+//
+// Read and ReadSeeker are timed until the file is open; their bytes are
+// counted as the stream is consumed. Write, Create and Replace are timed
+// to completion and count the bytes taken from the source. ListDirs and
+// DeleteAll count as list and delete. Other optional capabilities are
+// forwarded untimed.
+//
+// The returned Storage is a prometheus.Collector; NewRouter registers it
+// with the /metrics registry when it is the store the router is given.
+// Name reports inner's name, since the decorator does not change behavior.
+func WithMetrics(inner Storage) Storage {
+	return &metricsStorage{
+		Storage: inner,
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "storage_operation_duration_seconds",
+			Help:    "Storage backend call latency by operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "storage_operation_errors_total",
+			Help: "Failed storage backend calls by operation and error kind.",
+		}, []string{"operation", "kind"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "storage_operation_bytes_total",
+			Help: "Bytes read from and written to the storage backend.",
+		}, []string{"operation"}),
+	}
+}
+
+type metricsStorage struct {
+	Storage
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	bytes    *prometheus.CounterVec
+}
+
+func (s *metricsStorage) Name() string {
+	return NameOf(s.Storage)
+}
+
+func (s *metricsStorage) Describe(ch chan<- *prometheus.Desc) {
+	s.duration.Describe(ch)
+	s.errors.Describe(ch)
+	s.bytes.Describe(ch)
+}
+
+func (s *metricsStorage) Collect(ch chan<- prometheus.Metric) {
+	s.duration.Collect(ch)
+	s.errors.Collect(ch)
+	s.bytes.Collect(ch)
+}
+
+// errorKind buckets err for the kind label.
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrPermission):
+		return "permission"
+	}
+	return "other"
+}
+
+// observe times call as op and counts its error, if any. ErrNotSupported
+// is not counted: callers fall back to another operation.
+func (s *metricsStorage) observe(op string, call func() error) error {
+	start := time.Now()
+	err := call()
+	s.duration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, ErrNotSupported) {
+		s.errors.WithLabelValues(op, errorKind(err)).Inc()
+	}
+	return err
+}
+
+func (s *metricsStorage) List(ctx context.Context, p string) ([]FileInfo, error) {
+	var files []FileInfo
+	err := s.observe(OpList, func() (err error) {
+		files, err = s.Storage.List(ctx, p)
+		return err
+	})
+	return files, err
+}
+
+func (s *metricsStorage) ListDirs(ctx context.Context, p string) ([]FileInfo, error) {
+	var dirs []FileInfo
+	err := s.observe(OpList, func() (err error) {
+		dirs, err = ListDirs(ctx, s.Storage, p)
+		return err
+	})
+	return dirs, err
+}
+
+func (s *metricsStorage) Read(ctx context.Context, p string) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	err := s.observe(OpRead, func() (err error) {
+		rc, err = s.Storage.Read(ctx, p)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &countingReadCloser{ReadCloser: rc, counter: s.bytes.WithLabelValues(OpRead)}, nil
+}
+
+func (s *metricsStorage) ReadSeeker(ctx context.Context, p string) (io.ReadSeekCloser, error) {
+	if _, ok := s.Storage.(SeekableReader); !ok {
+		return nil, ErrNotSupported
+	}
+	var rs io.ReadSeekCloser
+	err := s.observe(OpRead, func() (err error) {
+		rs, err = ReadSeeker(ctx, s.Storage, p)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &countingReadSeekCloser{
+		countingReadCloser: countingReadCloser{ReadCloser: rs, counter: s.bytes.WithLabelValues(OpRead)},
+		seeker:             rs,
+	}, nil
+}
+
+func (s *metricsStorage) Write(ctx context.Context, p string, r io.Reader) error {
+	return s.observe(OpWrite, func() error {
+		return s.Storage.Write(ctx, p, s.countWrite(r))
+	})
+}
+
+func (s *metricsStorage) Create(ctx context.Context, p string, r io.Reader) error {
+	return s.observe(OpWrite, func() error {
+		return Create(ctx, s.Storage, p, s.countWrite(r))
+	})
+}
+
+func (s *metricsStorage) Replace(ctx context.Context, p string, r io.Reader) error {
+	return s.observe(OpWrite, func() error {
+		return Replace(ctx, s.Storage, p, s.countWrite(r))
+	})
+}
+
+func (s *metricsStorage) Stat(ctx context.Context, p string) (*FileInfo, error) {
+	var info *FileInfo
+	err := s.observe(OpStat, func() (err error) {
+		info, err = s.Storage.Stat(ctx, p)
+		return err
+	})
+	return info, err
+}
+
+func (s *metricsStorage) Delete(ctx context.Context, p string) error {
+	return s.observe(OpDelete, func() error { return s.Storage.Delete(ctx, p) })
+}
+
+func (s *metricsStorage) DeleteAll(ctx context.Context, p string) error {
+	return s.observe(OpDelete, func() error { return DeleteAll(ctx, s.Storage, p) })
+}
+
+// Rename keeps inner's Renamer, if any, reachable through the decorator.
+func (s *metricsStorage) Rename(ctx context.Context, from, to string) error {
+	return Rename(ctx, s.Storage, from, to)
+}
+
+// SHA256 keeps inner's Hasher, if any, reachable through the decorator.
+func (s *metricsStorage) SHA256(ctx context.Context, p string) (string, error) {
+	return SHA256Of(ctx, s.Storage, p)
+}
+
+// GetMetadata and SetMetadata keep inner's MetadataStore, if any,
+// reachable through the decorator.
+func (s *metricsStorage) GetMetadata(ctx context.Context, p string) (map[string]string, error) {
+	return GetMetadata(ctx, s.Storage, p)
+}
+
+func (s *metricsStorage) SetMetadata(ctx context.Context, p string, tags map[string]string) error {
+	return SetMetadata(ctx, s.Storage, p, tags)
+}
+
+// SetModTime keeps inner's ModTimeSetter, if any, reachable through the
+// decorator.
+func (s *metricsStorage) SetModTime(ctx context.Context, p string, t time.Time) error {
+	return setModTime(ctx, s.Storage, p, t)
+}
+
+// DiskInfo keeps inner's DiskReporter, if any, reachable through the
+// decorator.
+func (s *metricsStorage) DiskInfo(ctx context.Context) (*DiskSpace, error) {
+	return DiskInfo(ctx, s.Storage)
+}
+
+// countWrite wraps a write source so the bytes taken from it are counted,
+// keeping io.Seeker for decorators that rewind it, such as WithRetry.
+func (s *metricsStorage) countWrite(r io.Reader) io.Reader {
+	counter := s.bytes.WithLabelValues(OpWrite)
+	if rs, ok := r.(io.ReadSeeker); ok {
+		return &countingReadSeekCloser{
+			countingReadCloser: countingReadCloser{ReadCloser: io.NopCloser(rs), counter: counter},
+			seeker:             rs,
+		}
+	}
+	return &countingReadCloser{ReadCloser: io.NopCloser(r), counter: counter}
+}
+
+// countingReadCloser adds the bytes read through it to counter.
+type countingReadCloser struct {
+	io.ReadCloser
+	counter prometheus.Counter
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.counter.Add(float64(n))
+	return n, err
+}
+
+// WriteTo keeps the stream's own WriteTo, such as the local backend's
+// sendfile path, when it has one.
+func (c *countingReadCloser) WriteTo(w io.Writer) (int64, error) {
+	wt, ok := c.ReadCloser.(io.WriterTo)
+	if !ok {
+		return io.Copy(w, struct{ io.Reader }{c})
+	}
+	n, err := wt.WriteTo(w)
+	c.counter.Add(float64(n))
+	return n, err
+}
+
+type countingReadSeekCloser struct {
+	countingReadCloser
+	seeker io.Seeker
+}
+
+func (c *countingReadSeekCloser) Seek(offset int64, whence int) (int64, error) {
+	return c.seeker.Seek(offset, whence)
+}
@@ -0,0 +1,90 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"go-storage-api/internal/storage"
+)
+
+func TestWithMetrics_CountsBytes(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	store := storage.WithMetrics(inner)
+	ctx := context.Background()
+
+	if err := store.Write(ctx, "a.txt", strings.NewReader("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := store.Read(ctx, "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bytes.Buffer's ReadFrom makes io.Copy use the stream's WriteTo, if
+	// any, and plain Read otherwise; both are counted.
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, rc); err != nil || buf.String() != "0123456789" {
+		t.Fatalf("unexpected read %q (%v)", buf.String(), err)
+	}
+	rc.Close()
+
+	expected := `
+# HELP storage_operation_bytes_total Bytes read from and written to the storage backend.
+# TYPE storage_operation_bytes_total counter
+storage_operation_bytes_total{operation="read"} 10
+storage_operation_bytes_total{operation="write"} 10
+`
+	c := store.(prometheus.Collector)
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "storage_operation_bytes_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(c, "storage_operation_duration_seconds"); n != 2 {
+		t.Errorf("expected read and write latencies, got %d series", n)
+	}
+	if got := storage.NameOf(store); got != storage.NameOf(inner) {
+		t.Errorf("expected the backend name %q, got %q", storage.NameOf(inner), got)
+	}
+}
+
+func TestWithMetrics_CountsErrorsByKind(t *testing.T) {
+	inner := &outageStorage{}
+	store := storage.WithMetrics(inner)
+	ctx := context.Background()
+
+	for _, err := range []error{storage.ErrNotFound, storage.ErrNotFound, storage.ErrPermission, errFlaky, nil} {
+		inner.err = err
+		if _, got := store.Stat(ctx, "a.txt"); !errors.Is(got, err) {
+			t.Fatalf("expected %v passed through, got %v", err, got)
+		}
+	}
+	inner.err = errFlaky
+	store.Read(ctx, "a.txt")
+
+	expected := `
+# HELP storage_operation_errors_total Failed storage backend calls by operation and error kind.
+# TYPE storage_operation_errors_total counter
+storage_operation_errors_total{kind="not_found",operation="stat"} 2
+storage_operation_errors_total{kind="other",operation="read"} 1
+storage_operation_errors_total{kind="other",operation="stat"} 1
+storage_operation_errors_total{kind="permission",operation="stat"} 1
+`
+	if err := testutil.CollectAndCompare(store.(prometheus.Collector), strings.NewReader(expected), "storage_operation_errors_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithMetrics_NotSupportedIsNotAnError(t *testing.T) {
+	store := storage.WithMetrics(&outageStorage{})
+	if _, err := storage.ReadSeeker(context.Background(), store, "a.txt"); !errors.Is(err, storage.ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+	if n := testutil.CollectAndCount(store.(prometheus.Collector), "storage_operation_errors_total"); n != 0 {
+		t.Errorf("expected no errors counted, got %d series", n)
+	}
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// WithPrefix confines every operation on inner to a subtree chosen per call
+// by prefix, typically from identity stored in ctx. Incoming paths are
+// cleaned as if rooted at "/" before the prefix is joined on, so ".." can
+// never climb out of the subtree, and FileInfo.Path values coming back are
+// made relative to the subtree again. If prefix fails, the operation returns
+// its error without touching inner.
+func WithPrefix(inner Storage, prefix func(ctx context.Context) (string, error)) Storage {
+	return &prefixStorage{inner: inner, prefix: prefix}
+}
+
+type prefixStorage struct {
+	inner  Storage
+	prefix func(ctx context.Context) (string, error)
+}
+
+func (s *prefixStorage) Name() string {
+	return "prefix(" + NameOf(s.inner) + ")"
+}
+
+// resolve maps a client path into the subtree and returns the subtree root
+// for stripping results.
+func (s *prefixStorage) resolve(ctx context.Context, p string) (full, root string, err error) {
+	root, err = s.prefix(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	root = path.Clean("/" + root)
+	return path.Join(root, path.Clean("/"+p)), root, nil
+}
+
+// strip rewrites a backend-reported path relative to root. Backends differ
+// on leading slashes, so both sides are compared without them.
+func strip(p, root string) string {
+	p = strings.TrimPrefix(p, "/")
+	root = strings.TrimPrefix(root, "/")
+	if p == root {
+		return "."
+	}
+	return strings.TrimPrefix(p, root+"/")
+}
+
+func (s *prefixStorage) List(ctx context.Context, p string) ([]FileInfo, error) {
+	full, root, err := s.resolve(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.inner.List(ctx, full)
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		files[i].Path = strip(files[i].Path, root)
+	}
+	return files, nil
+}
+
+// ListDirs maps p into the subtree and uses inner's DirLister, if any.
+func (s *prefixStorage) ListDirs(ctx context.Context, p string) ([]FileInfo, error) {
+	full, root, err := s.resolve(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := ListDirs(ctx, s.inner, full)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dirs {
+		dirs[i].Path = strip(dirs[i].Path, root)
+	}
+	return dirs, nil
+}
+
+func (s *prefixStorage) Read(ctx context.Context, p string) (io.ReadCloser, error) {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return s.inner.Read(ctx, full)
+}
+
+func (s *prefixStorage) ReadSeeker(ctx context.Context, p string) (io.ReadSeekCloser, error) {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return ReadSeeker(ctx, s.inner, full)
+}
+
+func (s *prefixStorage) Write(ctx context.Context, p string, r io.Reader) error {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return err
+	}
+	return s.inner.Write(ctx, full, r)
+}
+
+// Create forwards to inner's atomic Create when it has one, otherwise it
+// falls back to Stat followed by Write.
+func (s *prefixStorage) Create(ctx context.Context, p string, r io.Reader) error {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return err
+	}
+	return Create(ctx, s.inner, full, r)
+}
+
+func (s *prefixStorage) Delete(ctx context.Context, p string) error {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return err
+	}
+	return s.inner.Delete(ctx, full)
+}
+
+// DeleteAll maps p into the subtree and uses inner's RecursiveDeleter, if
+// any. The subtree root itself cannot be removed.
+func (s *prefixStorage) DeleteAll(ctx context.Context, p string) error {
+	full, root, err := s.resolve(ctx, p)
+	if err != nil {
+		return err
+	}
+	if full == root {
+		return ErrPermission
+	}
+	return DeleteAll(ctx, s.inner, full)
+}
+
+// Rename maps both paths into the subtree and uses inner's Renamer, if any.
+func (s *prefixStorage) Rename(ctx context.Context, from, to string) error {
+	src, _, err := s.resolve(ctx, from)
+	if err != nil {
+		return err
+	}
+	dst, _, err := s.resolve(ctx, to)
+	if err != nil {
+		return err
+	}
+	return Rename(ctx, s.inner, src, dst)
+}
+
+// Replace maps p into the subtree and uses inner's Replacer, if any.
+func (s *prefixStorage) Replace(ctx context.Context, p string, r io.Reader) error {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return err
+	}
+	return Replace(ctx, s.inner, full, r)
+}
+
+// GetMetadata maps p into the subtree and uses inner's MetadataStore, if any.
+func (s *prefixStorage) GetMetadata(ctx context.Context, p string) (map[string]string, error) {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return GetMetadata(ctx, s.inner, full)
+}
+
+// SetMetadata maps p into the subtree and uses inner's MetadataStore, if any.
+func (s *prefixStorage) SetMetadata(ctx context.Context, p string, tags map[string]string) error {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return err
+	}
+	return SetMetadata(ctx, s.inner, full, tags)
+}
+
+// SetModTime maps p into the subtree and uses inner's ModTimeSetter, if any.
+func (s *prefixStorage) SetModTime(ctx context.Context, p string, t time.Time) error {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return err
+	}
+	return setModTime(ctx, s.inner, full, t)
+}
+
+// DiskInfo reports inner's filesystem, which the subtree shares.
+func (s *prefixStorage) DiskInfo(ctx context.Context) (*DiskSpace, error) {
+	return DiskInfo(ctx, s.inner)
+}
+
+func (s *prefixStorage) Stat(ctx context.Context, p string) (*FileInfo, error) {
+	full, root, err := s.resolve(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.inner.Stat(ctx, full)
+	if err != nil {
+		return nil, err
+	}
+	info.Path = strip(info.Path, root)
+	return info, nil
+}
+
+func (s *prefixStorage) Mkdir(ctx context.Context, p string) error {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return err
+	}
+	return s.inner.Mkdir(ctx, full)
+}
+
+func (s *prefixStorage) Usage(ctx context.Context, p string) (*Usage, error) {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return s.inner.Usage(ctx, full)
+}
+
+// SHA256 keeps inner's Hasher, if any, reachable through the decorator.
+func (s *prefixStorage) SHA256(ctx context.Context, p string) (string, error) {
+	full, _, err := s.resolve(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	return SHA256Of(ctx, s.inner, full)
+}
@@ -0,0 +1,148 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+type userKey struct{}
+
+// prefixFromCtx scopes to /users/<user> and fails without a user.
+func prefixFromCtx(ctx context.Context) (string, error) {
+	user, _ := ctx.Value(userKey{}).(string)
+	if user == "" {
+		return "", storage.ErrPermission
+	}
+	return "/users/" + user, nil
+}
+
+func TestWithPrefix_ConfinesPaths(t *testing.T) {
+	inner, root := newTeeBackend(t)
+	store := storage.WithPrefix(inner, prefixFromCtx)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+
+	for _, p := range []string{"a.txt", "/../../escape.txt"} {
+		if err := store.Write(ctx, p, strings.NewReader(p)); err != nil {
+			t.Fatalf("Write(%q): %v", p, err)
+		}
+	}
+
+	for _, rel := range []string{"users/alice/a.txt", "users/alice/escape.txt"} {
+		if _, err := os.Stat(filepath.Join(root, rel)); err != nil {
+			t.Errorf("expected %s: %v", rel, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("write escaped the prefix, stat err = %v", err)
+	}
+}
+
+func TestWithPrefix_StripsResultPaths(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	store := storage.WithPrefix(inner, prefixFromCtx)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	store.Write(ctx, "docs/a.txt", strings.NewReader("a"))
+
+	files, err := store.List(ctx, "/docs")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "docs/a.txt" {
+		t.Errorf("List = %+v, want one entry with path docs/a.txt", files)
+	}
+
+	info, err := store.Stat(ctx, "/")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Path != "." {
+		t.Errorf("Stat(/).Path = %q, want %q", info.Path, ".")
+	}
+}
+
+func TestWithPrefix_ListDirs(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	store := storage.WithPrefix(inner, prefixFromCtx)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	store.Write(ctx, "docs/a.txt", strings.NewReader("a"))
+	store.Write(ctx, "docs/sub/b.txt", strings.NewReader("b"))
+
+	dirs, err := storage.ListDirs(ctx, store, "/docs")
+	if err != nil {
+		t.Fatalf("ListDirs: %v", err)
+	}
+	if len(dirs) != 1 || dirs[0].Path != "docs/sub" {
+		t.Errorf("ListDirs = %+v, want one entry with path docs/sub", dirs)
+	}
+}
+
+func TestWithPrefix_DeleteAll(t *testing.T) {
+	inner, root := newTeeBackend(t)
+	store := storage.WithPrefix(inner, prefixFromCtx)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	store.Write(ctx, "docs/sub/a.txt", strings.NewReader("a"))
+
+	if err := storage.DeleteAll(ctx, store, "/"); !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("subtree root: expected ErrPermission, got %v", err)
+	}
+	if err := storage.DeleteAll(ctx, store, "docs"); err != nil {
+		t.Fatalf("DeleteAll: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "alice", "docs")); !os.IsNotExist(err) {
+		t.Errorf("expected docs removed, stat err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "alice")); err != nil {
+		t.Errorf("expected the subtree root kept: %v", err)
+	}
+}
+
+func TestWithPrefix_PrefixError(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	store := storage.WithPrefix(inner, prefixFromCtx)
+
+	_, err := store.List(context.Background(), "/")
+	if !errors.Is(err, storage.ErrPermission) {
+		t.Errorf("List without user err = %v, want ErrPermission", err)
+	}
+}
+
+func TestWithPrefix_Replace(t *testing.T) {
+	inner, root := newTeeBackend(t)
+	store := storage.WithPrefix(inner, prefixFromCtx)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	store.Write(ctx, "a.txt", strings.NewReader("old"))
+
+	if err := storage.Replace(ctx, store, "a.txt", strings.NewReader("new")); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "users", "alice", "a.txt"))
+	if string(data) != "new" {
+		t.Errorf("expected new contents, got %q", data)
+	}
+	if err := storage.Replace(ctx, store, "b.txt", strings.NewReader("x")); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("missing file: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestWithPrefix_Rename(t *testing.T) {
+	inner, root := newTeeBackend(t)
+	store := storage.WithPrefix(inner, prefixFromCtx)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	store.Write(ctx, "a.txt", strings.NewReader("a"))
+
+	if err := storage.Rename(ctx, store, "a.txt", "/../b.txt"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "alice", "b.txt")); err != nil {
+		t.Errorf("expected users/alice/b.txt: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "users", "alice", "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected users/alice/a.txt to be gone, stat err = %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+func TestReadSeeker_ForwardedByDecorators(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	if err := inner.Write(ctx, "users/alice/a.txt", strings.NewReader("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+
+	var store storage.Storage = storage.WithPrefix(inner, prefixFromCtx)
+	store = storage.WithContentRouting(store)
+	store = storage.WithWriteTee(store, func(string) io.WriteCloser { return &bufferSink{} })
+	store = storage.WithRetry(store, storage.RetryPolicy{})
+	store = storage.WithCircuitBreaker(store, storage.BreakerOptions{})
+	store = storage.WithCache(store, time.Minute, 100)
+	store = storage.WithMetrics(store)
+
+	rs, err := storage.ReadSeeker(ctx, store, "a.txt")
+	if err != nil {
+		t.Fatalf("ReadSeeker through %s: %v", storage.NameOf(store), err)
+	}
+	defer rs.Close()
+	if _, err := rs.Seek(7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(rs); string(got) != "789" {
+		t.Errorf("expected 789, got %q", got)
+	}
+
+	if _, err := storage.ReadSeeker(ctx, store, "missing.txt"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestReadSeeker_NotSupported(t *testing.T) {
+	if _, err := storage.ReadSeeker(context.Background(), &outageStorage{}, "a.txt"); !errors.Is(err, storage.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported without a SeekableReader, got %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how WithRetry retries a failing call.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first. Values
+	// below 2 disable retries.
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the second attempt; it doubles after
+	// every further failure up to MaxDelay, if set. The actual wait is drawn
+	// uniformly from [0, backoff) ("full jitter") so that clients failing
+	// together do not retry together.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Retryable reports whether err is worth another attempt. Nil means
+	// DefaultRetryable.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryable treats every error as transient except the sentinel
+// errors, which retrying cannot change (ErrUnavailable included: an open
+// circuit breaker stays open for its cooldown), and context cancellation.
+func DefaultRetryable(err error) bool {
+	switch {
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrPermission),
+		errors.Is(err, ErrExist),
+		errors.Is(err, ErrIsDirectory),
+		errors.Is(err, ErrNotEmpty),
+		errors.Is(err, ErrUnavailable),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	return true
+}
+
+// WithRetry wraps inner so List, Read, Write, Stat and Delete are retried on
+// retryable errors with exponential backoff and jitter. Retries stop as soon
+// as ctx is done or the next wait would run past its deadline; the last
+// error is returned.
+//
+// Read retries only opening the file, not errors while streaming it. Write
+// is retried only when the source implements io.Seeker, so it can be rewound
+// to where the first attempt started; other sources get a single attempt.
+// A Delete whose first attempt succeeded but reported an error may come
+// back as ErrNotFound. Create, Replace, Rename, Mkdir and Usage are not
+// retried.
+func WithRetry(inner Storage, policy RetryPolicy) Storage {
+	if policy.Retryable == nil {
+		policy.Retryable = DefaultRetryable
+	}
+	return &retryStorage{Storage: inner, policy: policy}
+}
+
+type retryStorage struct {
+	Storage
+	policy RetryPolicy
+}
+
+func (s *retryStorage) Name() string {
+	return "retry(" + NameOf(s.Storage) + ")"
+}
+
+func (s *retryStorage) List(ctx context.Context, p string) ([]FileInfo, error) {
+	var files []FileInfo
+	err := s.do(ctx, func() (err error) {
+		files, err = s.Storage.List(ctx, p)
+		return err
+	})
+	return files, err
+}
+
+func (s *retryStorage) ListDirs(ctx context.Context, p string) ([]FileInfo, error) {
+	var dirs []FileInfo
+	err := s.do(ctx, func() (err error) {
+		dirs, err = ListDirs(ctx, s.Storage, p)
+		return err
+	})
+	return dirs, err
+}
+
+func (s *retryStorage) Read(ctx context.Context, p string) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	err := s.do(ctx, func() (err error) {
+		rc, err = s.Storage.Read(ctx, p)
+		return err
+	})
+	return rc, err
+}
+
+// ReadSeeker keeps inner's SeekableReader, if any, reachable through the
+// decorator, retrying opening the file like Read.
+func (s *retryStorage) ReadSeeker(ctx context.Context, p string) (io.ReadSeekCloser, error) {
+	var rs io.ReadSeekCloser
+	err := s.do(ctx, func() (err error) {
+		rs, err = ReadSeeker(ctx, s.Storage, p)
+		return err
+	})
+	return rs, err
+}
+
+func (s *retryStorage) Write(ctx context.Context, p string, r io.Reader) error {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return s.Storage.Write(ctx, p, r)
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return s.Storage.Write(ctx, p, r)
+	}
+
+	first := true
+	return s.do(ctx, func() error {
+		if !first {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return &noRetryError{err}
+			}
+		}
+		first = false
+		return s.Storage.Write(ctx, p, r)
+	})
+}
+
+func (s *retryStorage) Stat(ctx context.Context, p string) (*FileInfo, error) {
+	var info *FileInfo
+	err := s.do(ctx, func() (err error) {
+		info, err = s.Storage.Stat(ctx, p)
+		return err
+	})
+	return info, err
+}
+
+func (s *retryStorage) Delete(ctx context.Context, p string) error {
+	return s.do(ctx, func() error {
+		return s.Storage.Delete(ctx, p)
+	})
+}
+
+// DeleteAll keeps inner's RecursiveDeleter, if any, reachable through the
+// decorator.
+func (s *retryStorage) DeleteAll(ctx context.Context, p string) error {
+	return s.do(ctx, func() error {
+		return DeleteAll(ctx, s.Storage, p)
+	})
+}
+
+// Create forwards to inner's atomic Create when it has one, otherwise it
+// falls back to Stat followed by Write.
+func (s *retryStorage) Create(ctx context.Context, p string, r io.Reader) error {
+	if c, ok := s.Storage.(Creator); ok {
+		return c.Create(ctx, p, r)
+	}
+
+	_, err := s.Stat(ctx, p)
+	switch {
+	case err == nil:
+		return ErrExist
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+	return s.Write(ctx, p, r)
+}
+
+// Rename keeps inner's Renamer, if any, reachable through the decorator.
+func (s *retryStorage) Rename(ctx context.Context, from, to string) error {
+	return Rename(ctx, s.Storage, from, to)
+}
+
+// Replace keeps inner's Replacer, if any, reachable through the decorator.
+func (s *retryStorage) Replace(ctx context.Context, p string, r io.Reader) error {
+	return Replace(ctx, s.Storage, p, r)
+}
+
+// SHA256 keeps inner's Hasher, if any, reachable through the decorator.
+func (s *retryStorage) SHA256(ctx context.Context, p string) (string, error) {
+	return SHA256Of(ctx, s.Storage, p)
+}
+
+// GetMetadata keeps inner's MetadataStore, if any, reachable through the
+// decorator.
+func (s *retryStorage) GetMetadata(ctx context.Context, p string) (map[string]string, error) {
+	return GetMetadata(ctx, s.Storage, p)
+}
+
+// SetMetadata keeps inner's MetadataStore, if any, reachable through the
+// decorator.
+func (s *retryStorage) SetMetadata(ctx context.Context, p string, tags map[string]string) error {
+	return SetMetadata(ctx, s.Storage, p, tags)
+}
+
+// SetModTime keeps inner's ModTimeSetter, if any, reachable through the
+// decorator.
+func (s *retryStorage) SetModTime(ctx context.Context, p string, t time.Time) error {
+	return setModTime(ctx, s.Storage, p, t)
+}
+
+// DiskInfo keeps inner's DiskReporter, if any, reachable through the
+// decorator.
+func (s *retryStorage) DiskInfo(ctx context.Context) (*DiskSpace, error) {
+	return DiskInfo(ctx, s.Storage)
+}
+
+// noRetryError marks a failure inside the retry loop itself, such as a
+// failed rewind, that must end the loop whatever the policy says.
+type noRetryError struct{ err error }
+
+func (e *noRetryError) Error() string { return e.err.Error() }
+func (e *noRetryError) Unwrap() error { return e.err }
+
+// do runs op until it succeeds, fails with a non-retryable error, runs out
+// of attempts or ctx would expire before the next one.
+func (s *retryStorage) do(ctx context.Context, op func() error) error {
+	backoff := s.capDelay(s.policy.BaseDelay)
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		var stop *noRetryError
+		if errors.As(err, &stop) {
+			return stop.err
+		}
+		if attempt >= s.policy.MaxAttempts || !s.policy.Retryable(err) {
+			return err
+		}
+
+		wait := jitter(backoff)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		if backoff < math.MaxInt64/2 {
+			backoff = s.capDelay(backoff * 2)
+		}
+	}
+}
+
+// capDelay limits d to MaxDelay when one is set.
+func (s *retryStorage) capDelay(d time.Duration) time.Duration {
+	if s.policy.MaxDelay > 0 && d > s.policy.MaxDelay {
+		return s.policy.MaxDelay
+	}
+	return d
+}
+
+// jitter returns a random duration in [0, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
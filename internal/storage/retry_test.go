@@ -0,0 +1,201 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+var errFlaky = errors.New("503 service unavailable")
+
+// flakyStorage fails the first failures calls of every operation with err,
+// then succeeds against an in-memory file map.
+type flakyStorage struct {
+	storage.Storage
+	failures int
+	err      error
+	calls    int
+	files    map[string]string
+}
+
+func newFlaky(failures int, err error) *flakyStorage {
+	return &flakyStorage{failures: failures, err: err, files: map[string]string{"a.txt": "hello"}}
+}
+
+func (f *flakyStorage) fail() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *flakyStorage) List(ctx context.Context, p string) ([]storage.FileInfo, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	return []storage.FileInfo{{Name: "a.txt", Path: "a.txt"}}, nil
+}
+
+func (f *flakyStorage) Read(ctx context.Context, p string) (io.ReadCloser, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader(f.files[p])), nil
+}
+
+// Write consumes part of the source before failing, like a connection that
+// drops mid-upload.
+func (f *flakyStorage) Write(ctx context.Context, p string, r io.Reader) error {
+	if err := f.fail(); err != nil {
+		io.CopyN(io.Discard, r, 3)
+		return err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	f.files[p] = string(b)
+	return nil
+}
+
+func (f *flakyStorage) Stat(ctx context.Context, p string) (*storage.FileInfo, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	return &storage.FileInfo{Name: p, Path: p}, nil
+}
+
+func (f *flakyStorage) Delete(ctx context.Context, p string) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	delete(f.files, p)
+	return nil
+}
+
+var fastRetry = storage.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+func TestWithRetry_SucceedsAfterTransientFailures(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		op   func(s storage.Storage) error
+	}{
+		{"List", func(s storage.Storage) error { _, err := s.List(ctx, "."); return err }},
+		{"Read", func(s storage.Storage) error {
+			rc, err := s.Read(ctx, "a.txt")
+			if err == nil {
+				rc.Close()
+			}
+			return err
+		}},
+		{"Write", func(s storage.Storage) error { return s.Write(ctx, "b.txt", strings.NewReader("data")) }},
+		{"Stat", func(s storage.Storage) error { _, err := s.Stat(ctx, "a.txt"); return err }},
+		{"Delete", func(s storage.Storage) error { return s.Delete(ctx, "a.txt") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := newFlaky(2, errFlaky)
+			if err := tt.op(storage.WithRetry(flaky, fastRetry)); err != nil {
+				t.Fatalf("expected success, got %v", err)
+			}
+			if flaky.calls != 3 {
+				t.Errorf("expected 3 calls, got %d", flaky.calls)
+			}
+		})
+	}
+}
+
+func TestWithRetry_WriteRewindsSource(t *testing.T) {
+	flaky := newFlaky(2, errFlaky)
+	store := storage.WithRetry(flaky, fastRetry)
+
+	if err := store.Write(context.Background(), "b.txt", strings.NewReader("full contents")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := flaky.files["b.txt"]; got != "full contents" {
+		t.Errorf("expected the full body after retries, got %q", got)
+	}
+}
+
+func TestWithRetry_WriteNotRetriedWithoutSeeker(t *testing.T) {
+	flaky := newFlaky(2, errFlaky)
+	store := storage.WithRetry(flaky, fastRetry)
+
+	src := struct{ io.Reader }{strings.NewReader("data")}
+	if err := store.Write(context.Background(), "b.txt", src); !errors.Is(err, errFlaky) {
+		t.Fatalf("expected the first error, got %v", err)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("expected 1 call, got %d", flaky.calls)
+	}
+}
+
+func TestWithRetry_StopsOnPermanentErrors(t *testing.T) {
+	for _, want := range []error{storage.ErrNotFound, storage.ErrPermission} {
+		t.Run(want.Error(), func(t *testing.T) {
+			flaky := newFlaky(5, want)
+			_, err := storage.WithRetry(flaky, fastRetry).Stat(context.Background(), "a.txt")
+			if !errors.Is(err, want) {
+				t.Fatalf("expected %v, got %v", want, err)
+			}
+			if flaky.calls != 1 {
+				t.Errorf("expected 1 call, got %d", flaky.calls)
+			}
+		})
+	}
+}
+
+func TestWithRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	flaky := newFlaky(5, errFlaky)
+	_, err := storage.WithRetry(flaky, fastRetry).Stat(context.Background(), "a.txt")
+	if !errors.Is(err, errFlaky) {
+		t.Fatalf("expected the last error, got %v", err)
+	}
+	if flaky.calls != fastRetry.MaxAttempts {
+		t.Errorf("expected %d calls, got %d", fastRetry.MaxAttempts, flaky.calls)
+	}
+}
+
+func TestWithRetry_HonorsContextDeadline(t *testing.T) {
+	flaky := newFlaky(5, errFlaky)
+	store := storage.WithRetry(flaky, storage.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := store.Stat(ctx, "a.txt")
+	if !errors.Is(err, errFlaky) {
+		t.Fatalf("expected the last error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to give up before waiting, took %v", elapsed)
+	}
+}
+
+func TestWithRetry_CustomRetryable(t *testing.T) {
+	flaky := newFlaky(2, errFlaky)
+	policy := fastRetry
+	policy.Retryable = func(error) bool { return false }
+
+	_, err := storage.WithRetry(flaky, policy).Stat(context.Background(), "a.txt")
+	if !errors.Is(err, errFlaky) {
+		t.Fatalf("expected the first error, got %v", err)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("expected 1 call, got %d", flaky.calls)
+	}
+}
+
+func TestWithRetry_Name(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	if got := storage.NameOf(storage.WithRetry(inner, fastRetry)); got != "retry(local)" {
+		t.Errorf("expected retry(local), got %q", got)
+	}
+}
@@ -0,0 +1,354 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sniffLen is how many leading bytes http.DetectContentType inspects.
+const sniffLen = 512
+
+// ContentRule sends writes that match it to Backend. A rule matches when the
+// sniffed content type starts with ContentTypePrefix (empty matches any) and
+// the content is at least MinSize bytes long (zero matches any size).
+type ContentRule struct {
+	ContentTypePrefix string
+	MinSize           int64
+	Backend           Storage
+}
+
+// WithContentRouting returns a Storage that places each written file on the
+// backend of the first matching rule, or on fallback if none match. The
+// routing decision buffers the first max(512, largest MinSize) bytes of each
+// write, so keep MinSize thresholds modest.
+//
+// File locations are tracked in an in-memory index. Paths missing from the
+// index (for example after a restart) are located by asking fallback and then
+// each rule backend in order, so reads keep working without persistent state.
+func WithContentRouting(fallback Storage, rules ...ContentRule) Storage {
+	peek := int64(sniffLen)
+	backends := []Storage{fallback}
+	for _, r := range rules {
+		if r.MinSize > peek {
+			peek = r.MinSize
+		}
+		backends = appendUnique(backends, r.Backend)
+	}
+	return &routingStorage{
+		fallback: fallback,
+		rules:    rules,
+		backends: backends,
+		peekLen:  int(peek),
+		index:    make(map[string]Storage),
+	}
+}
+
+type routingStorage struct {
+	fallback Storage
+	rules    []ContentRule
+	backends []Storage
+	peekLen  int
+
+	mu    sync.RWMutex
+	index map[string]Storage
+}
+
+func (s *routingStorage) Name() string {
+	names := make([]string, len(s.backends))
+	for i, b := range s.backends {
+		names[i] = NameOf(b)
+	}
+	return "route(" + strings.Join(names, ",") + ")"
+}
+
+func (s *routingStorage) List(ctx context.Context, path string) ([]FileInfo, error) {
+	return s.merge(func(b Storage) ([]FileInfo, error) { return b.List(ctx, path) })
+}
+
+// ListDirs merges the subdirectories of path on every backend, using each
+// one's DirLister where it has one.
+func (s *routingStorage) ListDirs(ctx context.Context, path string) ([]FileInfo, error) {
+	return s.merge(func(b Storage) ([]FileInfo, error) { return ListDirs(ctx, b, path) })
+}
+
+// merge combines list's results over every backend, earlier backends
+// winning on name clashes. It returns ErrNotFound only if no backend has
+// the directory.
+func (s *routingStorage) merge(list func(Storage) ([]FileInfo, error)) ([]FileInfo, error) {
+	var merged []FileInfo
+	seen := make(map[string]bool)
+	found := false
+	for _, b := range s.backends {
+		files, err := list(b)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, f := range files {
+			if !seen[f.Name] {
+				seen[f.Name] = true
+				merged = append(merged, f)
+			}
+		}
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	if merged == nil {
+		merged = []FileInfo{}
+	}
+	return merged, nil
+}
+
+func (s *routingStorage) Read(ctx context.Context, path string) (io.ReadCloser, error) {
+	b, err := s.locate(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return b.Read(ctx, path)
+}
+
+func (s *routingStorage) ReadSeeker(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+	b, err := s.locate(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return ReadSeeker(ctx, b, path)
+}
+
+func (s *routingStorage) Write(ctx context.Context, path string, r io.Reader) error {
+	target, body, err := s.route(r)
+	if err != nil {
+		return err
+	}
+
+	previous, _ := s.locate(ctx, path)
+	if err := target.Write(ctx, path, body); err != nil {
+		return err
+	}
+	s.remember(path, target)
+
+	// The file moved backends; drop the stale copy so reads stay unambiguous.
+	if previous != nil && previous != target {
+		previous.Delete(ctx, path)
+	}
+	return nil
+}
+
+// Create writes path only if no backend already holds it.
+func (s *routingStorage) Create(ctx context.Context, path string, r io.Reader) error {
+	if _, err := s.locate(ctx, path); err == nil {
+		return ErrExist
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	target, body, err := s.route(r)
+	if err != nil {
+		return err
+	}
+	if c, ok := target.(Creator); ok {
+		err = c.Create(ctx, path, body)
+	} else {
+		err = target.Write(ctx, path, body)
+	}
+	if err != nil {
+		return err
+	}
+	s.remember(path, target)
+	return nil
+}
+
+func (s *routingStorage) Delete(ctx context.Context, path string) error {
+	b, err := s.locate(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := b.Delete(ctx, path); err != nil {
+		return err
+	}
+	s.forget(path)
+	return nil
+}
+
+func (s *routingStorage) Stat(ctx context.Context, path string) (*FileInfo, error) {
+	for _, b := range s.candidates(path) {
+		info, err := b.Stat(ctx, path)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err == nil && !info.IsDir {
+			s.remember(path, b)
+		}
+		if err == nil && info.IsDir && len(s.backends) > 1 {
+			return s.statMergedDir(ctx, path, info)
+		}
+		return info, err
+	}
+	return nil, ErrNotFound
+}
+
+// statMergedDir recounts a directory's children from the merged listing,
+// since the backend that answered Stat only sees its own share of them.
+func (s *routingStorage) statMergedDir(ctx context.Context, path string, info *FileInfo) (*FileInfo, error) {
+	files, err := s.List(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	merged := *info
+	merged.ChildCount, merged.TotalSize = len(files), 0
+	for _, f := range files {
+		if !f.IsDir {
+			merged.TotalSize += f.Size
+		}
+	}
+	return &merged, nil
+}
+
+// GetMetadata reads tags from the backend holding path.
+func (s *routingStorage) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	b, err := s.locate(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return GetMetadata(ctx, b, path)
+}
+
+// SetMetadata stores tags on the backend holding path.
+func (s *routingStorage) SetMetadata(ctx context.Context, path string, tags map[string]string) error {
+	b, err := s.locate(ctx, path)
+	if err != nil {
+		return err
+	}
+	return SetMetadata(ctx, b, path, tags)
+}
+
+// SetModTime changes the time on the backend holding path.
+func (s *routingStorage) SetModTime(ctx context.Context, path string, t time.Time) error {
+	b, err := s.locate(ctx, path)
+	if err != nil {
+		return err
+	}
+	return setModTime(ctx, b, path, t)
+}
+
+// DiskInfo reports the fallback backend's filesystem, where files land
+// when no rule matches.
+func (s *routingStorage) DiskInfo(ctx context.Context) (*DiskSpace, error) {
+	return DiskInfo(ctx, s.fallback)
+}
+
+// Mkdir creates directories on the fallback backend, which always takes part
+// in listings.
+func (s *routingStorage) Mkdir(ctx context.Context, path string) error {
+	return s.fallback.Mkdir(ctx, path)
+}
+
+// Usage sums usage for path across every backend that has it.
+func (s *routingStorage) Usage(ctx context.Context, path string) (*Usage, error) {
+	var total Usage
+	found := false
+	for _, b := range s.backends {
+		u, err := b.Usage(ctx, path)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		total.TotalBytes += u.TotalBytes
+		total.FileCount += u.FileCount
+		total.DirCount += u.DirCount
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return &total, nil
+}
+
+// route peeks at r to pick a backend and returns a reader that still yields
+// the full content.
+func (s *routingStorage) route(r io.Reader) (Storage, io.Reader, error) {
+	br := bufio.NewReaderSize(r, s.peekLen)
+	head, err := br.Peek(s.peekLen)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, nil, err
+	}
+
+	// size is exact when the content ended inside the peek window and a
+	// lower bound otherwise, which is all MinSize comparisons need.
+	size := int64(len(head))
+	ct := http.DetectContentType(head[:min(len(head), sniffLen)])
+
+	for _, rule := range s.rules {
+		if strings.HasPrefix(ct, rule.ContentTypePrefix) && size >= rule.MinSize {
+			return rule.Backend, br, nil
+		}
+	}
+	return s.fallback, br, nil
+}
+
+// locate finds the backend holding the file at path.
+func (s *routingStorage) locate(ctx context.Context, path string) (Storage, error) {
+	for _, b := range s.candidates(path) {
+		_, err := b.Stat(ctx, path)
+		if err == nil {
+			s.remember(path, b)
+			return b, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+	}
+	s.forget(path)
+	return nil, ErrNotFound
+}
+
+// candidates lists backends to try for path, the indexed one first.
+func (s *routingStorage) candidates(path string) []Storage {
+	s.mu.RLock()
+	known := s.index[path]
+	s.mu.RUnlock()
+	if known == nil {
+		return s.backends
+	}
+	return appendUnique([]Storage{known}, s.backends...)
+}
+
+func (s *routingStorage) remember(path string, b Storage) {
+	s.mu.Lock()
+	s.index[path] = b
+	s.mu.Unlock()
+}
+
+func (s *routingStorage) forget(path string) {
+	s.mu.Lock()
+	delete(s.index, path)
+	s.mu.Unlock()
+}
+
+func appendUnique(list []Storage, items ...Storage) []Storage {
+	for _, item := range items {
+		dup := false
+		for _, existing := range list {
+			if existing == item {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,178 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+)
+
+// pngHeader is enough of a PNG signature for http.DetectContentType.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func newRoutingStores(t *testing.T) (storage.Storage, string, string) {
+	t.Helper()
+	fast, fastRoot := newTeeBackend(t)
+	bulk, bulkRoot := newTeeBackend(t)
+	store := storage.WithContentRouting(fast,
+		storage.ContentRule{ContentTypePrefix: "image/", MinSize: 1024, Backend: bulk},
+	)
+	return store, fastRoot, bulkRoot
+}
+
+func TestWithContentRouting_LargeImageGoesToRuleBackend(t *testing.T) {
+	store, fastRoot, bulkRoot := newRoutingStores(t)
+	ctx := context.Background()
+
+	img := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 4096)...)
+	if err := store.Write(ctx, "media/big.png", bytes.NewReader(img)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(bulkRoot, "media", "big.png"))
+	if err != nil {
+		t.Fatalf("expected file on bulk backend: %v", err)
+	}
+	if !bytes.Equal(got, img) {
+		t.Error("bulk backend content does not match what was written")
+	}
+	if _, err := os.Stat(filepath.Join(fastRoot, "media", "big.png")); !os.IsNotExist(err) {
+		t.Errorf("file should not be on fallback backend, stat err = %v", err)
+	}
+
+	rc, err := store.Read(ctx, "media/big.png")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	defer rc.Close()
+	read, _ := io.ReadAll(rc)
+	if !bytes.Equal(read, img) {
+		t.Error("Read returned different content")
+	}
+}
+
+func TestWithContentRouting_SmallTextStaysOnFallback(t *testing.T) {
+	store, fastRoot, bulkRoot := newRoutingStores(t)
+	ctx := context.Background()
+
+	if err := store.Write(ctx, "notes.txt", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(fastRoot, "notes.txt")); err != nil {
+		t.Errorf("expected file on fallback backend: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(bulkRoot, "notes.txt")); !os.IsNotExist(err) {
+		t.Errorf("file should not be on bulk backend, stat err = %v", err)
+	}
+
+	// A small image does not meet MinSize either.
+	if err := store.Write(ctx, "icon.png", bytes.NewReader(pngHeader)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(fastRoot, "icon.png")); err != nil {
+		t.Errorf("expected small image on fallback backend: %v", err)
+	}
+}
+
+func TestWithContentRouting_LocatesFilesWithoutIndex(t *testing.T) {
+	fast, _ := newTeeBackend(t)
+	bulk, _ := newTeeBackend(t)
+	ctx := context.Background()
+
+	// Written directly, as if by a previous process whose index is gone.
+	if err := bulk.Write(ctx, "old.bin", strings.NewReader("legacy")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	store := storage.WithContentRouting(fast, storage.ContentRule{MinSize: 1 << 20, Backend: bulk})
+
+	rc, err := store.Read(ctx, "old.bin")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "legacy" {
+		t.Errorf("content = %q, want %q", got, "legacy")
+	}
+
+	if err := store.Delete(ctx, "old.bin"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := bulk.Stat(ctx, "old.bin"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Stat after Delete err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestWithContentRouting_OverwriteMovesFile(t *testing.T) {
+	store, fastRoot, bulkRoot := newRoutingStores(t)
+	ctx := context.Background()
+
+	if err := store.Write(ctx, "pic.png", bytes.NewReader(pngHeader)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	big := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 2048)...)
+	if err := store.Write(ctx, "pic.png", bytes.NewReader(big)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(fastRoot, "pic.png")); !os.IsNotExist(err) {
+		t.Errorf("stale copy left on fallback backend, stat err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(bulkRoot, "pic.png")); err != nil {
+		t.Errorf("expected file on bulk backend: %v", err)
+	}
+}
+
+func TestWithContentRouting_CreateChecksAllBackends(t *testing.T) {
+	store, _, _ := newRoutingStores(t)
+	ctx := context.Background()
+
+	big := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 2048)...)
+	c := store.(storage.Creator)
+	if err := c.Create(ctx, "x.png", bytes.NewReader(big)); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	err := c.Create(ctx, "x.png", strings.NewReader("small"))
+	if !errors.Is(err, storage.ErrExist) {
+		t.Errorf("second Create err = %v, want ErrExist", err)
+	}
+}
+
+func TestWithContentRouting_ListMergesBackends(t *testing.T) {
+	store, _, _ := newRoutingStores(t)
+	ctx := context.Background()
+
+	big := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 2048)...)
+	store.Write(ctx, "a.png", bytes.NewReader(big))
+	store.Write(ctx, "b.txt", strings.NewReader("text"))
+
+	files, err := store.List(ctx, "/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	names := map[string]bool{}
+	for _, f := range files {
+		names[f.Name] = true
+	}
+	if !names["a.png"] || !names["b.txt"] {
+		t.Errorf("List = %v, want a.png and b.txt", files)
+	}
+
+	root, err := store.Stat(ctx, "/")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if root.ChildCount != 2 || root.TotalSize != int64(len(big)+4) {
+		t.Errorf("expected Stat to count both backends' files, got %d children of %d bytes", root.ChildCount, root.TotalSize)
+	}
+
+	if got := storage.NameOf(store); got != "route(local,local)" {
+		t.Errorf("NameOf = %q", got)
+	}
+}
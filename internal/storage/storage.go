@@ -2,22 +2,66 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"time"
 )
 
 var (
 	ErrNotFound   = errors.New("file not found")
 	ErrPermission = errors.New("permission denied")
+	ErrExist      = errors.New("file already exists")
+
+	// ErrIsDirectory is returned by Read when path names a directory.
+	ErrIsDirectory = errors.New("path is a directory")
+
+	// ErrNotEmpty is returned by Delete for a directory that still has
+	// entries; see DeleteAll.
+	ErrNotEmpty = errors.New("directory not empty")
+
+	// ErrReadOnly is returned by read-only backends for every change. It
+	// wraps ErrPermission, so callers that only know that error still
+	// treat it as a refusal.
+	ErrReadOnly = fmt.Errorf("read-only backend: %w", ErrPermission)
+
+	// ErrNotSupported is returned for optional operations the backend
+	// cannot perform.
+	ErrNotSupported = errors.New("operation not supported by this storage backend")
+
+	// ErrUnavailable is returned without calling the backend while a
+	// circuit breaker is open; see WithCircuitBreaker.
+	ErrUnavailable = errors.New("storage backend unavailable")
 )
 
 type FileInfo struct {
 	Name    string    `json:"name"`
 	Path    string    `json:"path"`
 	Size    int64     `json:"size"`
-	IsDir   bool      `json:"isDir"`
-	ModTime time.Time `json:"modTime"`
+	IsDir   bool      `json:"is_dir"`
+	ModTime time.Time `json:"mod_time"`
+
+	// Checksum is the lowercase hex SHA-256 of the contents. It is only
+	// filled in when a caller asks for it, since hashing reads the file.
+	Checksum string `json:"checksum,omitempty"`
+
+	// ChildCount and TotalSize describe a directory's immediate entries:
+	// how many there are and the summed size of the files among them. Stat
+	// fills them in for directories from a single-level read; they stay
+	// zero for files and in listings.
+	ChildCount int   `json:"child_count,omitempty"`
+	TotalSize  int64 `json:"total_size,omitempty"`
+}
+
+// Usage summarizes the space consumed by a subtree.
+type Usage struct {
+	TotalBytes int64 `json:"total_bytes"`
+	FileCount  int64 `json:"file_count"`
+	DirCount   int64 `json:"dir_count"`
 }
 
 type Storage interface {
@@ -26,4 +70,271 @@ type Storage interface {
 	Write(ctx context.Context, path string, r io.Reader) error
 	Delete(ctx context.Context, path string) error
 	Stat(ctx context.Context, path string) (*FileInfo, error)
+	Mkdir(ctx context.Context, path string) error
+	Usage(ctx context.Context, path string) (*Usage, error)
+}
+
+// Creator is implemented by backends that can atomically create a file only
+// when nothing exists at path yet, returning ErrExist otherwise. Callers fall
+// back to Stat followed by Write for backends that do not implement it.
+type Creator interface {
+	Create(ctx context.Context, path string, r io.Reader) error
+}
+
+// Create writes a new file at path, returning ErrExist if something is
+// already there. It uses the backend's Creator when it has one and Stat
+// followed by Write otherwise. The fallback is not atomic.
+func Create(ctx context.Context, s Storage, path string, r io.Reader) error {
+	if c, ok := s.(Creator); ok {
+		return c.Create(ctx, path, r)
+	}
+	_, err := s.Stat(ctx, path)
+	switch {
+	case err == nil:
+		return ErrExist
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+	return s.Write(ctx, path, r)
+}
+
+// Renamer is implemented by backends that can move a file in one step.
+// Missing parents of to are created and an existing file at to is replaced.
+type Renamer interface {
+	Rename(ctx context.Context, from, to string) error
+}
+
+// Rename moves the file at from to to, using the backend's Renamer when it
+// has one and copying then deleting otherwise. The fallback only handles
+// files, not directories.
+func Rename(ctx context.Context, s Storage, from, to string) error {
+	if rn, ok := s.(Renamer); ok {
+		return rn.Rename(ctx, from, to)
+	}
+	rc, err := s.Read(ctx, from)
+	if err != nil {
+		return err
+	}
+	err = s.Write(ctx, to, rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	return s.Delete(ctx, from)
+}
+
+// Replacer is implemented by backends that can swap an existing file's
+// contents in one step, so readers see either the old or the new contents
+// and never a partial write. It returns ErrNotFound when nothing exists at
+// path and ErrIsDirectory for a directory.
+type Replacer interface {
+	Replace(ctx context.Context, path string, r io.Reader) error
+}
+
+// Replace overwrites the contents of the existing file at path, using the
+// backend's Replacer when it has one and Stat followed by Write otherwise.
+// The fallback is not atomic.
+func Replace(ctx context.Context, s Storage, path string, r io.Reader) error {
+	if rp, ok := s.(Replacer); ok {
+		return rp.Replace(ctx, path, r)
+	}
+	info, err := s.Stat(ctx, path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir {
+		return ErrIsDirectory
+	}
+	return s.Write(ctx, path, r)
+}
+
+// Namer is implemented by backends and decorators that can describe
+// themselves. Decorators report their chain, e.g. "cache(local)".
+type Namer interface {
+	Name() string
+}
+
+// NameOf returns s.Name() when s implements Namer, or "" otherwise.
+func NameOf(s Storage) string {
+	if n, ok := s.(Namer); ok {
+		return n.Name()
+	}
+	return ""
+}
+
+// Hasher is implemented by backends that can produce a file's SHA-256 more
+// cheaply than streaming it through Read, for example by caching digests.
+// The result is lowercase hex.
+type Hasher interface {
+	SHA256(ctx context.Context, path string) (string, error)
+}
+
+// SHA256Of returns the lowercase hex SHA-256 of the file at path, using the
+// backend's Hasher when it has one and hashing a full Read otherwise.
+func SHA256Of(ctx context.Context, s Storage, path string) (string, error) {
+	if h, ok := s.(Hasher); ok {
+		return h.SHA256(ctx, path)
+	}
+	rc, err := s.Read(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	return HashSHA256(rc)
+}
+
+// HashSHA256 returns the lowercase hex SHA-256 of everything r yields.
+func HashSHA256(r io.Reader) (string, error) {
+	sum := sha256.New()
+	if _, err := io.Copy(sum, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// RecursiveDeleter is implemented by backends that can remove a directory
+// and everything below it in one call. Like Delete, it also removes a
+// single file.
+type RecursiveDeleter interface {
+	DeleteAll(ctx context.Context, path string) error
+}
+
+// DeleteAll removes path and, for a directory, everything below it, using
+// the backend's RecursiveDeleter when it has one. Otherwise the subtree is
+// emptied depth first through List and Delete; an error stops it part way.
+func DeleteAll(ctx context.Context, s Storage, path string) error {
+	if d, ok := s.(RecursiveDeleter); ok {
+		return d.DeleteAll(ctx, path)
+	}
+	info, err := s.Stat(ctx, path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir {
+		files, err := s.List(ctx, path)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := DeleteAll(ctx, s, f.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Delete(ctx, path)
+}
+
+// SeekableReader is implemented by backends that can open a file for
+// random access, so callers can serve byte ranges by seeking instead of
+// skipping through a stream. A backend may return ErrNotSupported for
+// files it cannot seek, such as ones it stores compressed.
+type SeekableReader interface {
+	ReadSeeker(ctx context.Context, path string) (io.ReadSeekCloser, error)
+}
+
+// ReadSeeker opens the file at path for random access through the
+// backend's SeekableReader, or returns ErrNotSupported when it has none;
+// callers then fall back to Read.
+func ReadSeeker(ctx context.Context, s Storage, path string) (io.ReadSeekCloser, error) {
+	if rs, ok := s.(SeekableReader); ok {
+		return rs.ReadSeeker(ctx, path)
+	}
+	return nil, ErrNotSupported
+}
+
+// DirLister is implemented by backends that can list just the
+// subdirectories of a directory more cheaply than List, for example
+// without statting the files.
+type DirLister interface {
+	ListDirs(ctx context.Context, path string) ([]FileInfo, error)
+}
+
+// ListDirs returns the entries of the directory at path that are
+// directories, using the backend's DirLister when it has one and filtering
+// List otherwise.
+func ListDirs(ctx context.Context, s Storage, path string) ([]FileInfo, error) {
+	if d, ok := s.(DirLister); ok {
+		return d.ListDirs(ctx, path)
+	}
+	files, err := s.List(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(files, func(f FileInfo) bool { return !f.IsDir }), nil
+}
+
+// ModTimeSetter is implemented by backends that can change a file's or
+// directory's modification time.
+type ModTimeSetter interface {
+	SetModTime(ctx context.Context, path string, t time.Time) error
+}
+
+// setModTime uses s's ModTimeSetter, or returns ErrNotSupported. It lets
+// decorators forward the capability without hiding its absence.
+func setModTime(ctx context.Context, s Storage, path string, t time.Time) error {
+	if m, ok := s.(ModTimeSetter); ok {
+		return m.SetModTime(ctx, path, t)
+	}
+	return ErrNotSupported
+}
+
+// Touch sets the modification time of path to t, or to the current time
+// when t is zero, creating an empty file first if nothing exists there. It
+// reports whether it created the file. Backends without a ModTimeSetter
+// can only touch a file they have just created, to the current time;
+// anything else returns ErrNotSupported.
+func Touch(ctx context.Context, s Storage, path string, t time.Time) (created bool, err error) {
+	_, err = s.Stat(ctx, path)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		err = Create(ctx, s, path, strings.NewReader(""))
+		created = err == nil
+		// Someone else created it first; touch theirs.
+		if errors.Is(err, ErrExist) {
+			err = nil
+		}
+		if err != nil {
+			return false, err
+		}
+	case err != nil:
+		return false, err
+	}
+
+	now := t.IsZero()
+	if now {
+		t = time.Now()
+	}
+	err = setModTime(ctx, s, path, t)
+	// A file created just now already has the current time.
+	if errors.Is(err, ErrNotSupported) && created && now {
+		err = nil
+	}
+	return created, err
+}
+
+// DiskSpace describes the filesystem holding a backend's files. FreeBytes
+// is what an unprivileged writer can still use, so with blocks reserved
+// for root UsedBytes and FreeBytes add up to less than TotalBytes.
+type DiskSpace struct {
+	TotalBytes uint64 `json:"total_bytes"`
+	FreeBytes  uint64 `json:"free_bytes"`
+	UsedBytes  uint64 `json:"used_bytes"`
+}
+
+// DiskReporter is implemented by backends stored on a filesystem whose
+// size they can report. Object stores have no such limit and leave it out.
+type DiskReporter interface {
+	DiskInfo(ctx context.Context) (*DiskSpace, error)
+}
+
+// DiskInfo returns the space on the filesystem holding s, or
+// ErrNotSupported if s has no DiskReporter.
+func DiskInfo(ctx context.Context, s Storage) (*DiskSpace, error) {
+	if d, ok := s.(DiskReporter); ok {
+		return d.DiskInfo(ctx)
+	}
+	return nil, ErrNotSupported
 }
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// TeeOption customizes WithWriteTee.
+type TeeOption func(*teeStorage)
+
+// TeeBestEffort makes sink failures non-fatal: the primary write proceeds
+// and onErr, if non-nil, is told about the failure. By default any sink
+// error fails the Write.
+func TeeBestEffort(onErr func(path string, err error)) TeeOption {
+	return func(t *teeStorage) {
+		t.bestEffort = true
+		t.onErr = onErr
+	}
+}
+
+// WithWriteTee wraps inner so every Write (and Create) streams the content to
+// both inner and the WriteCloser returned by sink for that path, in a single
+// pass over the source reader. This suits append-only audit or backup
+// archives. All other operations go straight to inner.
+func WithWriteTee(inner Storage, sink func(path string) io.WriteCloser, opts ...TeeOption) Storage {
+	t := &teeStorage{Storage: inner, sink: sink}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+type teeStorage struct {
+	Storage
+	sink       func(path string) io.WriteCloser
+	bestEffort bool
+	onErr      func(path string, err error)
+}
+
+func (t *teeStorage) Name() string {
+	return "tee(" + NameOf(t.Storage) + ")"
+}
+
+func (t *teeStorage) Write(ctx context.Context, path string, r io.Reader) error {
+	return t.tee(path, r, func(r io.Reader) error {
+		return t.Storage.Write(ctx, path, r)
+	})
+}
+
+// Create forwards to inner's atomic Create when it has one, otherwise it
+// falls back to Stat followed by Write.
+func (t *teeStorage) Create(ctx context.Context, path string, r io.Reader) error {
+	if c, ok := t.Storage.(Creator); ok {
+		return t.tee(path, r, func(r io.Reader) error {
+			return c.Create(ctx, path, r)
+		})
+	}
+
+	_, err := t.Storage.Stat(ctx, path)
+	switch {
+	case err == nil:
+		return ErrExist
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+	return t.Write(ctx, path, r)
+}
+
+// Replace tees the new contents like Write. It forwards to inner's atomic
+// Replace when it has one, otherwise it falls back to Stat followed by Write.
+func (t *teeStorage) Replace(ctx context.Context, path string, r io.Reader) error {
+	if rp, ok := t.Storage.(Replacer); ok {
+		return t.tee(path, r, func(r io.Reader) error {
+			return rp.Replace(ctx, path, r)
+		})
+	}
+
+	info, err := t.Storage.Stat(ctx, path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir {
+		return ErrIsDirectory
+	}
+	return t.Write(ctx, path, r)
+}
+
+// GetMetadata and SetMetadata go to inner's MetadataStore, if any; tags
+// are not teed.
+func (t *teeStorage) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	return GetMetadata(ctx, t.Storage, path)
+}
+
+func (t *teeStorage) SetMetadata(ctx context.Context, path string, tags map[string]string) error {
+	return SetMetadata(ctx, t.Storage, path, tags)
+}
+
+// DeleteAll goes to inner's RecursiveDeleter, if any.
+func (t *teeStorage) DeleteAll(ctx context.Context, path string) error {
+	return DeleteAll(ctx, t.Storage, path)
+}
+
+// ReadSeeker goes to inner's SeekableReader, if any.
+func (t *teeStorage) ReadSeeker(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+	return ReadSeeker(ctx, t.Storage, path)
+}
+
+// ListDirs goes to inner's DirLister, if any.
+func (t *teeStorage) ListDirs(ctx context.Context, path string) ([]FileInfo, error) {
+	return ListDirs(ctx, t.Storage, path)
+}
+
+// SetModTime goes to inner's ModTimeSetter, if any.
+func (t *teeStorage) SetModTime(ctx context.Context, path string, mt time.Time) error {
+	return setModTime(ctx, t.Storage, path, mt)
+}
+
+// DiskInfo goes to inner's DiskReporter, if any.
+func (t *teeStorage) DiskInfo(ctx context.Context) (*DiskSpace, error) {
+	return DiskInfo(ctx, t.Storage)
+}
+
+// tee runs write with a reader that copies everything it yields into the
+// sink for path.
+func (t *teeStorage) tee(path string, r io.Reader, write func(io.Reader) error) error {
+	sink := t.sink(path)
+	var dst io.Writer = sink
+	var lenient *lenientWriter
+	if t.bestEffort {
+		lenient = &lenientWriter{w: sink}
+		dst = lenient
+	}
+
+	writeErr := write(io.TeeReader(r, dst))
+	closeErr := sink.Close()
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	sinkErr := closeErr
+	if lenient != nil && lenient.err != nil {
+		sinkErr = lenient.err
+	}
+	if sinkErr == nil {
+		return nil
+	}
+	if t.bestEffort {
+		if t.onErr != nil {
+			t.onErr(path, sinkErr)
+		}
+		return nil
+	}
+	return fmt.Errorf("tee sink: %w", sinkErr)
+}
+
+// lenientWriter records the first error from w and then discards further
+// writes, so a failing sink never interrupts the primary stream.
+type lenientWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (l *lenientWriter) Write(p []byte) (int, error) {
+	if l.err == nil {
+		if _, err := l.w.Write(p); err != nil {
+			l.err = err
+		}
+	}
+	return len(p), nil
+}
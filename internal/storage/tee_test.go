@@ -0,0 +1,137 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/storage"
+	"go-storage-api/internal/storage/local"
+)
+
+// bufferSink collects tee output per path.
+type bufferSink struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferSink) Close() error {
+	b.closed = true
+	return nil
+}
+
+// failingSink errors on every write.
+type failingSink struct{}
+
+func (failingSink) Write([]byte) (int, error) { return 0, errors.New("archive offline") }
+func (failingSink) Close() error              { return nil }
+
+func newTeeBackend(t *testing.T) (*local.Storage, string) {
+	t.Helper()
+	root := t.TempDir()
+	s, err := local.New(root)
+	if err != nil {
+		t.Fatalf("local.New: %v", err)
+	}
+	return s, root
+}
+
+func TestWithWriteTee_SinkReceivesIdenticalBytes(t *testing.T) {
+	inner, root := newTeeBackend(t)
+	sinks := map[string]*bufferSink{}
+	store := storage.WithWriteTee(inner, func(path string) io.WriteCloser {
+		s := &bufferSink{}
+		sinks[path] = s
+		return s
+	})
+
+	content := strings.Repeat("audit me ", 10000)
+	if err := store.Write(context.Background(), "docs/a.txt", strings.NewReader(content)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	primary, err := os.ReadFile(filepath.Join(root, "docs", "a.txt"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	sink := sinks["docs/a.txt"]
+	if sink == nil {
+		t.Fatal("sink was not opened for path")
+	}
+	if !sink.closed {
+		t.Error("expected sink to be closed")
+	}
+	if sink.String() != string(primary) || sink.String() != content {
+		t.Errorf("sink and primary differ: sink %d bytes, primary %d bytes", sink.Len(), len(primary))
+	}
+}
+
+func TestWithWriteTee_CreateIsTeedAndAtomic(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	var sink bufferSink
+	store := storage.WithWriteTee(inner, func(string) io.WriteCloser { return &sink })
+
+	c, ok := store.(storage.Creator)
+	if !ok {
+		t.Fatal("expected tee store to implement storage.Creator")
+	}
+	ctx := context.Background()
+	if err := c.Create(ctx, "new.txt", strings.NewReader("first")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if sink.String() != "first" {
+		t.Errorf("expected sink %q, got %q", "first", sink.String())
+	}
+
+	err := c.Create(ctx, "new.txt", strings.NewReader("second"))
+	if !errors.Is(err, storage.ErrExist) {
+		t.Errorf("expected ErrExist, got %v", err)
+	}
+}
+
+func TestWithWriteTee_SinkFailureFatalByDefault(t *testing.T) {
+	inner, root := newTeeBackend(t)
+	store := storage.WithWriteTee(inner, func(string) io.WriteCloser { return failingSink{} })
+
+	err := store.Write(context.Background(), "a.txt", strings.NewReader("data"))
+	if err == nil {
+		t.Fatal("expected sink failure to fail the write")
+	}
+	if _, statErr := os.Stat(filepath.Join(root, "a.txt")); !os.IsNotExist(statErr) {
+		t.Error("expected no primary file after fatal sink failure")
+	}
+}
+
+func TestWithWriteTee_SinkFailureBestEffort(t *testing.T) {
+	inner, root := newTeeBackend(t)
+	var reported error
+	store := storage.WithWriteTee(inner,
+		func(string) io.WriteCloser { return failingSink{} },
+		storage.TeeBestEffort(func(_ string, err error) { reported = err }),
+	)
+
+	if err := store.Write(context.Background(), "a.txt", strings.NewReader("data")); err != nil {
+		t.Fatalf("expected best-effort write to succeed, got %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "a.txt"))
+	if string(data) != "data" {
+		t.Errorf("expected primary %q, got %q", "data", string(data))
+	}
+	if reported == nil {
+		t.Error("expected sink failure to be reported")
+	}
+}
+
+func TestWithWriteTee_Name(t *testing.T) {
+	inner, _ := newTeeBackend(t)
+	store := storage.WithWriteTee(inner, func(string) io.WriteCloser { return &bufferSink{} })
+
+	if got := storage.NameOf(store); got != "tee(local)" {
+		t.Errorf("expected name tee(local), got %q", got)
+	}
+}
@@ -0,0 +1,45 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go-storage-api/internal/storage"
+	"go-storage-api/internal/storage/fsadapter"
+)
+
+func TestTouch(t *testing.T) {
+	store, _ := newTeeBackend(t)
+	ctx := context.Background()
+	when := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	created, err := storage.Touch(ctx, store, "new.txt", when)
+	if err != nil || !created {
+		t.Fatalf("expected the file to be created, got created=%v err=%v", created, err)
+	}
+	info, _ := store.Stat(ctx, "new.txt")
+	if info.Size != 0 || !info.ModTime.Equal(when) {
+		t.Errorf("expected an empty file dated %s, got size %d at %s", when, info.Size, info.ModTime)
+	}
+
+	before := time.Now().Add(-time.Second)
+	created, err = storage.Touch(ctx, store, "new.txt", time.Time{})
+	if err != nil || created {
+		t.Fatalf("expected an existing file to be touched, got created=%v err=%v", created, err)
+	}
+	info, _ = store.Stat(ctx, "new.txt")
+	if info.ModTime.Before(before) {
+		t.Errorf("expected a zero time to mean now, got %s", info.ModTime)
+	}
+}
+
+func TestTouch_NotSupported(t *testing.T) {
+	store := fsadapter.New(fstest.MapFS{"a.txt": {Data: []byte("a")}})
+
+	if _, err := storage.Touch(context.Background(), store, "a.txt", time.Time{}); !errors.Is(err, storage.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
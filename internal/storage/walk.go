@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultListConcurrency is how many directories ListRecursive lists at once
+// when the caller does not say.
+const DefaultListConcurrency = 8
+
+// ListRecursive returns every file and directory below root, sorted by
+// Path. Up to concurrency directory listings run at once, which hides most
+// of the round-trip latency of remote backends; a concurrency of one walks
+// serially and zero or less uses DefaultListConcurrency. The first error,
+// or ctx ending, stops the walk and is returned.
+func ListRecursive(ctx context.Context, s Storage, root string, concurrency int) ([]FileInfo, error) {
+	return walk(ctx, s, root, concurrency, nil)
+}
+
+// Search walks the subtree below root like ListRecursive and returns the
+// entries match accepts, sorted by Path and cut to the first limit (zero
+// or less keeps all). Only matches are held in memory, and the whole tree
+// is visited so the result does not depend on listing order.
+func Search(ctx context.Context, s Storage, root string, match func(FileInfo) bool, limit, concurrency int) ([]FileInfo, error) {
+	files, err := walk(ctx, s, root, concurrency, match)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(files) > limit {
+		files = files[:limit]
+	}
+	return files, nil
+}
+
+// walk collects the entries below root that keep accepts, or all of them
+// if keep is nil, sorted by Path.
+func walk(ctx context.Context, s Storage, root string, concurrency int, keep func(FileInfo) bool) ([]FileInfo, error) {
+	files := []FileInfo{}
+	err := Walk(ctx, s, root, concurrency, func(f FileInfo) error {
+		if keep == nil || keep(f) {
+			files = append(files, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(files, func(a, b FileInfo) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return files, nil
+}
+
+// Walk calls fn for every file and directory below root as the directory
+// listings arrive, in no particular order, without holding the tree in
+// memory. Listings run concurrently as in ListRecursive, but fn is called
+// on the calling goroutine, one entry at a time; while it blocks, the
+// workers stop after at most one listing each. An error from fn, the first
+// listing error or ctx ending stops the walk and is returned.
+func Walk(ctx context.Context, s Storage, root string, concurrency int, fn func(FileInfo) error) error {
+	if concurrency <= 0 {
+		concurrency = DefaultListConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := &walker{ctx: ctx, cancel: cancel, store: s, out: make(chan []FileInfo)}
+	w.cond = sync.NewCond(&w.mu)
+	w.push(root)
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(w.out)
+	}()
+
+	var fnErr error
+	for batch := range w.out {
+		for _, f := range batch {
+			if fnErr == nil {
+				fnErr = fn(f)
+			}
+		}
+		if fnErr != nil {
+			// Keep draining so the workers can see the cancel and exit.
+			cancel()
+		}
+	}
+
+	if fnErr != nil {
+		return fnErr
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return ctx.Err()
+}
+
+// walker hands directories to Walk's workers. pending counts directories
+// queued or being listed; the walk is over when it reaches zero. Each
+// listing is sent on out.
+type walker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	store  Storage
+	out    chan []FileInfo
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []string
+	pending int
+	err     error
+}
+
+func (w *walker) push(dir string) {
+	w.queue = append(w.queue, dir)
+	w.pending++
+}
+
+// next blocks until there is a directory to list, returning false once the
+// walk is finished or has failed.
+func (w *walker) next() (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.queue) == 0 && w.pending > 0 && w.err == nil {
+		w.cond.Wait()
+	}
+	if len(w.queue) == 0 || w.err != nil {
+		return "", false
+	}
+	dir := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	return dir, true
+}
+
+func (w *walker) work() {
+	for {
+		dir, ok := w.next()
+		if !ok {
+			return
+		}
+
+		files, err := w.store.List(w.ctx, dir)
+		if err == nil {
+			err = w.ctx.Err()
+		}
+
+		w.mu.Lock()
+		if err != nil && w.err == nil {
+			w.err = err
+			w.cancel()
+		}
+		failed := w.err != nil
+		if !failed {
+			for _, f := range files {
+				if f.IsDir {
+					w.push(f.Path)
+				}
+			}
+		}
+		w.pending--
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		if failed || len(files) == 0 {
+			continue
+		}
+		select {
+		case w.out <- files:
+		case <-w.ctx.Done():
+		}
+	}
+}
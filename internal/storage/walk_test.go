@@ -0,0 +1,240 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-storage-api/internal/storage"
+)
+
+// treeStorage serves List from a synthetic tree: every directory above
+// depth holds fanout subdirectories and one file. Each List sleeps for
+// latency to mimic a remote backend.
+type treeStorage struct {
+	storage.Storage
+	depth, fanout int
+	latency       time.Duration
+	failAt        string
+	inFlight, max atomic.Int32
+}
+
+func (s *treeStorage) List(ctx context.Context, p string) ([]storage.FileInfo, error) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		m := s.max.Load()
+		if n <= m || s.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+
+	select {
+	case <-time.After(s.latency):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if p == s.failAt {
+		return nil, errors.New("backend unavailable")
+	}
+
+	p = strings.Trim(p, "/")
+	level := 0
+	if p != "" {
+		level = strings.Count(p, "/") + 1
+	}
+	if level >= s.depth {
+		return []storage.FileInfo{}, nil
+	}
+	var files []storage.FileInfo
+	for i := range s.fanout {
+		name := fmt.Sprintf("d%d", i)
+		files = append(files, storage.FileInfo{Name: name, Path: path.Join(p, name), IsDir: true})
+	}
+	files = append(files, storage.FileInfo{Name: "f.txt", Path: path.Join(p, "f.txt")})
+	return files, nil
+}
+
+// treeSize is how many entries ListRecursive should find below the root.
+func treeSize(depth, fanout int) int {
+	total, dirs := 0, 1
+	for range depth {
+		total += dirs * (fanout + 1)
+		dirs *= fanout
+	}
+	return total
+}
+
+func TestListRecursive_FindsEverythingSorted(t *testing.T) {
+	for _, concurrency := range []int{1, 4, 0} {
+		t.Run(fmt.Sprint(concurrency), func(t *testing.T) {
+			store := &treeStorage{depth: 3, fanout: 3}
+			files, err := storage.ListRecursive(context.Background(), store, "/", concurrency)
+			if err != nil {
+				t.Fatalf("ListRecursive: %v", err)
+			}
+			if len(files) != treeSize(3, 3) {
+				t.Errorf("expected %d entries, got %d", treeSize(3, 3), len(files))
+			}
+			if !slices.IsSortedFunc(files, func(a, b storage.FileInfo) int { return strings.Compare(a.Path, b.Path) }) {
+				t.Error("expected results sorted by path")
+			}
+		})
+	}
+}
+
+func TestListRecursive_BoundsConcurrency(t *testing.T) {
+	store := &treeStorage{depth: 3, fanout: 6, latency: time.Millisecond}
+	if _, err := storage.ListRecursive(context.Background(), store, "/", 4); err != nil {
+		t.Fatalf("ListRecursive: %v", err)
+	}
+	if got := store.max.Load(); got > 4 {
+		t.Errorf("expected at most 4 concurrent lists, saw %d", got)
+	}
+}
+
+func TestListRecursive_StopsOnError(t *testing.T) {
+	store := &treeStorage{depth: 3, fanout: 3, failAt: "d1/d2"}
+	if _, err := storage.ListRecursive(context.Background(), store, "/", 4); err == nil {
+		t.Fatal("expected the listing error")
+	}
+}
+
+func TestListRecursive_HonorsCancellation(t *testing.T) {
+	store := &treeStorage{depth: 6, fanout: 6, latency: 10 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := storage.ListRecursive(ctx, store, "/", 4)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to stop promptly, took %v", elapsed)
+	}
+}
+
+func TestListRecursive_Local(t *testing.T) {
+	ctx := context.Background()
+	inner, _ := newTeeBackend(t)
+	for _, p := range []string{"b.txt", "a/x.txt", "a/sub/y.txt"} {
+		if err := inner.Write(ctx, p, strings.NewReader(p)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	files, err := storage.ListRecursive(ctx, inner, "/", 2)
+	if err != nil {
+		t.Fatalf("ListRecursive: %v", err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	want := []string{"a", "a/sub", "a/sub/y.txt", "a/x.txt", "b.txt"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("expected %v, got %v", want, paths)
+	}
+}
+
+func TestListDirs_FiltersList(t *testing.T) {
+	store := &treeStorage{depth: 1, fanout: 3}
+
+	dirs, err := storage.ListDirs(context.Background(), store, "/")
+	if err != nil {
+		t.Fatalf("ListDirs: %v", err)
+	}
+	if len(dirs) != 3 {
+		t.Fatalf("expected the 3 subdirectories, got %+v", dirs)
+	}
+	for _, d := range dirs {
+		if !d.IsDir {
+			t.Errorf("expected only directories, got %+v", d)
+		}
+	}
+}
+
+func TestWalk_StreamsEveryEntry(t *testing.T) {
+	store := &treeStorage{depth: 2, fanout: 3}
+	seen := map[string]bool{}
+
+	err := storage.Walk(context.Background(), store, "/", 4, func(f storage.FileInfo) error {
+		if seen[f.Path] {
+			t.Errorf("%s visited twice", f.Path)
+		}
+		seen[f.Path] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	// Each of the 1+3 directories above depth holds 3 dirs and a file.
+	if len(seen) != 4*4 {
+		t.Errorf("expected 16 entries, got %d", len(seen))
+	}
+}
+
+func TestWalk_StopsOnCallbackError(t *testing.T) {
+	store := &treeStorage{depth: 4, fanout: 4}
+	stop := errors.New("client went away")
+	calls := 0
+
+	err := storage.Walk(context.Background(), store, "/", 4, func(storage.FileInfo) error {
+		calls++
+		if calls == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("expected the callback's error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected no calls after the error, got %d", calls)
+	}
+}
+
+func BenchmarkListRecursive(b *testing.B) {
+	for _, concurrency := range []int{1, 8, 32} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			store := &treeStorage{depth: 3, fanout: 5, latency: time.Millisecond}
+			for range b.N {
+				if _, err := storage.ListRecursive(context.Background(), store, "/", concurrency); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSearch_FiltersSortsAndLimits(t *testing.T) {
+	store := &treeStorage{depth: 3, fanout: 3}
+	isFile := func(f storage.FileInfo) bool { return !f.IsDir }
+
+	all, err := storage.Search(context.Background(), store, "/", isFile, 0, 4)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(all) != 1+3+9 {
+		t.Fatalf("expected 13 files, got %d", len(all))
+	}
+
+	// Truncation keeps the first paths in sorted order, whatever order the
+	// directories were listed in.
+	for range 5 {
+		top, err := storage.Search(context.Background(), store, "/", isFile, 3, 4)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if !slices.Equal(top, all[:3]) {
+			t.Fatalf("expected %v, got %v", all[:3], top)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Package version holds build metadata stamped in at link time:
+//
+//	go build -ldflags "-X go-storage-api/internal/version.Version=v1.4.0 \
+//	  -X go-storage-api/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X go-storage-api/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+//
+// Unstamped builds report "dev" and "unknown".
+package version
+
+import "runtime"
+
+// Set with -ldflags "-X"; they must stay plain string variables.
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build metadata of the running binary.
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
@@ -0,0 +1,16 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGet_Defaults(t *testing.T) {
+	info := Get()
+	if info.Version != "dev" || info.Commit != "unknown" || info.BuildTime != "unknown" {
+		t.Errorf("expected unstamped defaults, got %+v", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("expected go_version %q, got %q", runtime.Version(), info.GoVersion)
+	}
+}
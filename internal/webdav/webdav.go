@@ -0,0 +1,373 @@
+// Package webdav exposes a storage.Storage over a minimal WebDAV (RFC 4918
+// class 1) surface so it can be mounted as a network drive by davfs2,
+// Windows Explorer or macOS Finder. Locking is not implemented; clients
+// that insist on LOCK will mount read-only.
+package webdav
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"go-storage-api/internal/bufpool"
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/storage"
+)
+
+const allowedMethods = "OPTIONS, PROPFIND, GET, HEAD, PUT, DELETE, MKCOL, MOVE"
+
+// Handler serves WebDAV requests under prefix by delegating to a Storage.
+type Handler struct {
+	store         storage.Storage
+	prefix        string
+	maxUploadSize int64
+	pathLimits    middleware.PathLimits
+	copyBuffers   *bufpool.Pool
+}
+
+// New returns a Handler for requests whose URL path starts with prefix
+// (e.g. "/webdav"). PUT bodies are capped at maxUploadSize bytes and
+// resource paths are validated with middleware.CleanPath using pathLimits.
+// GET bodies are copied with buffers from copyBuffers, which may be nil.
+func New(store storage.Storage, prefix string, maxUploadSize int64, pathLimits middleware.PathLimits, copyBuffers *bufpool.Pool) *Handler {
+	return &Handler{
+		store:         store,
+		prefix:        strings.TrimSuffix(prefix, "/"),
+		maxUploadSize: maxUploadSize,
+		pathLimits:    pathLimits,
+		copyBuffers:   copyBuffers,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p, err := h.resourcePath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodOptions:
+		h.options(w)
+	case "PROPFIND":
+		h.propfind(w, r, p)
+	case http.MethodGet, http.MethodHead:
+		h.get(w, r, p)
+	case http.MethodPut:
+		h.put(w, r, p)
+	case http.MethodDelete:
+		h.delete(w, r, p)
+	case "MKCOL":
+		h.mkcol(w, r, p)
+	case "MOVE":
+		h.move(w, r, p)
+	default:
+		w.Header().Set("Allow", allowedMethods)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// resourcePath maps a request URL path to a validated storage path.
+func (h *Handler) resourcePath(urlPath string) (string, error) {
+	rest, ok := strings.CutPrefix(urlPath, h.prefix)
+	if !ok {
+		return "", errors.New("path outside WebDAV root")
+	}
+	return middleware.CleanPath("/"+rest, h.pathLimits)
+}
+
+// href builds the URL path clients should use for p.
+func (h *Handler) href(p string, isDir bool) string {
+	u := url.URL{Path: path.Join(h.prefix, p)}
+	href := u.EscapedPath()
+	if isDir && !strings.HasSuffix(href, "/") {
+		href += "/"
+	}
+	return href
+}
+
+func (h *Handler) options(w http.ResponseWriter) {
+	w.Header().Set("Allow", allowedMethods)
+	w.Header().Set("DAV", "1")
+	w.Header().Set("MS-Author-Via", "DAV")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) propfind(w http.ResponseWriter, r *http.Request, p string) {
+	// Only Depth 0 and 1 are supported; RFC 4918 lets servers refuse
+	// "infinity", which could otherwise walk the whole tree.
+	depth := r.Header.Get("Depth")
+	if depth == "" || strings.EqualFold(depth, "infinity") {
+		depth = "1"
+	}
+	if depth != "0" && depth != "1" {
+		http.Error(w, "invalid Depth header", http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.store.Stat(r.Context(), p)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	ms := multistatus{XMLNS: "DAV:", Responses: []response{h.propResponse(p, info)}}
+	if info.IsDir && depth == "1" {
+		children, err := h.store.List(r.Context(), p)
+		if err != nil {
+			writeStorageError(w, err)
+			return
+		}
+		for i := range children {
+			ms.Responses = append(ms.Responses, h.propResponse(path.Join(p, children[i].Name), &children[i]))
+		}
+	}
+
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(ms)
+}
+
+func (h *Handler) propResponse(p string, info *storage.FileInfo) response {
+	name := info.Name
+	if p == "/" {
+		name = ""
+	}
+	pr := prop{
+		DisplayName:  name,
+		LastModified: info.ModTime.UTC().Format(http.TimeFormat),
+	}
+	if info.IsDir {
+		pr.ResourceType.Collection = &struct{}{}
+	} else {
+		size := info.Size
+		pr.ContentLength = &size
+		pr.ContentType = mime.TypeByExtension(path.Ext(p))
+	}
+	return response{
+		Href: h.href(p, info.IsDir),
+		PropStat: propstat{
+			Prop:   pr,
+			Status: "HTTP/1.1 200 OK",
+		},
+	}
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, p string) {
+	info, err := h.store.Stat(r.Context(), p)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	if info.IsDir {
+		w.Header().Set("Allow", "OPTIONS, PROPFIND, DELETE, MKCOL, MOVE")
+		http.Error(w, "cannot GET a collection; use PROPFIND", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if ct := mime.TypeByExtension(path.Ext(p)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	rc, err := h.store.Read(r.Context(), p)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	defer rc.Close()
+	h.copyBuffers.Copy(w, rc)
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, p string) {
+	if r.ContentLength > h.maxUploadSize {
+		http.Error(w, "upload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	existed, err := h.exists(r, p)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	if err := h.store.Write(r.Context(), p, r.Body); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "upload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeStorageError(w, err)
+		return
+	}
+	writeCreated(w, existed)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, p string) {
+	if p == "/" {
+		http.Error(w, "cannot delete the root collection", http.StatusForbidden)
+		return
+	}
+	if err := h.store.Delete(r.Context(), p); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) mkcol(w http.ResponseWriter, r *http.Request, p string) {
+	if r.ContentLength > 0 {
+		http.Error(w, "MKCOL request bodies are not supported", http.StatusUnsupportedMediaType)
+		return
+	}
+	existed, err := h.exists(r, p)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	if existed {
+		http.Error(w, "resource already exists", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.store.Mkdir(r.Context(), p); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// move relocates a file to the Destination with storage.Rename, which
+// copies and deletes on backends without a Renamer. Collections are not
+// supported.
+func (h *Handler) move(w http.ResponseWriter, r *http.Request, src string) {
+	dest, err := h.destination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dest == src {
+		http.Error(w, "source and destination are the same", http.StatusForbidden)
+		return
+	}
+
+	info, err := h.store.Stat(r.Context(), src)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	if info.IsDir {
+		http.Error(w, "moving collections is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	existed, err := h.exists(r, dest)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	if existed && strings.EqualFold(r.Header.Get("Overwrite"), "F") {
+		http.Error(w, "destination exists", http.StatusPreconditionFailed)
+		return
+	}
+
+	if err := storage.Rename(r.Context(), h.store, src, dest); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	writeCreated(w, existed)
+}
+
+// destination parses the MOVE Destination header, which may be an absolute
+// URL or an absolute path, into a validated storage path.
+func (h *Handler) destination(r *http.Request) (string, error) {
+	raw := r.Header.Get("Destination")
+	if raw == "" {
+		return "", errors.New("Destination header is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", errors.New("invalid Destination header")
+	}
+	if u.Host != "" && u.Host != r.Host {
+		return "", errors.New("Destination must be on this server")
+	}
+	return h.resourcePath(u.Path)
+}
+
+func (h *Handler) exists(r *http.Request, p string) (bool, error) {
+	_, err := h.store.Stat(r.Context(), p)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, storage.ErrNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// writeCreated answers a PUT or MOVE: 204 when it replaced an existing
+// resource, 201 when it made a new one.
+func writeCreated(w http.ResponseWriter, replaced bool) {
+	if replaced {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func writeStorageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, storage.ErrPermission):
+		http.Error(w, "permission denied", http.StatusForbidden)
+	case errors.Is(err, storage.ErrExist), errors.Is(err, storage.ErrNotEmpty):
+		http.Error(w, "conflict", http.StatusConflict)
+	default:
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// multistatus and friends model the subset of the DAV: XML schema used in
+// PROPFIND responses.
+type multistatus struct {
+	XMLName   xml.Name   `xml:"D:multistatus"`
+	XMLNS     string     `xml:"xmlns:D,attr"`
+	Responses []response `xml:"D:response"`
+}
+
+type response struct {
+	Href     string   `xml:"D:href"`
+	PropStat propstat `xml:"D:propstat"`
+}
+
+type propstat struct {
+	Prop   prop   `xml:"D:prop"`
+	Status string `xml:"D:status"`
+}
+
+type prop struct {
+	DisplayName   string       `xml:"D:displayname"`
+	ResourceType  resourceType `xml:"D:resourcetype"`
+	ContentLength *int64       `xml:"D:getcontentlength,omitempty"`
+	ContentType   string       `xml:"D:getcontenttype,omitempty"`
+	LastModified  string       `xml:"D:getlastmodified"`
+}
+
+type resourceType struct {
+	Collection *struct{} `xml:"D:collection,omitempty"`
+}
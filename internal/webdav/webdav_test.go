@@ -0,0 +1,161 @@
+package webdav
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-storage-api/internal/middleware"
+	"go-storage-api/internal/storage/local"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	store, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("local.New: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/webdav/", New(store, "/webdav", 1024, middleware.PathLimits{}, nil))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(t *testing.T, method, url string, body io.Reader, headers ...string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func expectStatus(t *testing.T, resp *http.Response, want int) {
+	t.Helper()
+	if resp.StatusCode != want {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s %s: expected %d, got %d: %s", resp.Request.Method, resp.Request.URL.Path, want, resp.StatusCode, body)
+	}
+}
+
+// davResponse decodes the parts of a PROPFIND response the tests check.
+type davResponse struct {
+	Href     string `xml:"href"`
+	PropStat struct {
+		Prop struct {
+			ResourceType struct {
+				Collection *struct{} `xml:"collection"`
+			} `xml:"resourcetype"`
+			ContentLength string `xml:"getcontentlength"`
+			LastModified  string `xml:"getlastmodified"`
+		} `xml:"prop"`
+	} `xml:"propstat"`
+}
+
+func propfind(t *testing.T, url, depth string) []davResponse {
+	t.Helper()
+	resp := do(t, "PROPFIND", url, nil, "Depth", depth)
+	expectStatus(t, resp, http.StatusMultiStatus)
+
+	var ms struct {
+		Responses []davResponse `xml:"response"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		t.Fatalf("decode multistatus: %v", err)
+	}
+	return ms.Responses
+}
+
+func TestWebDAV_Lifecycle(t *testing.T) {
+	srv := newTestServer(t)
+	base := srv.URL + "/webdav"
+
+	expectStatus(t, do(t, "MKCOL", base+"/docs", nil), http.StatusCreated)
+	expectStatus(t, do(t, "MKCOL", base+"/docs", nil), http.StatusMethodNotAllowed)
+	expectStatus(t, do(t, http.MethodPut, base+"/docs/a.txt", strings.NewReader("hello")), http.StatusCreated)
+	expectStatus(t, do(t, http.MethodPut, base+"/docs/a.txt", strings.NewReader("hello!")), http.StatusNoContent)
+
+	resp := do(t, http.MethodGet, base+"/docs/a.txt", nil)
+	expectStatus(t, resp, http.StatusOK)
+	if body, _ := io.ReadAll(resp.Body); string(body) != "hello!" {
+		t.Errorf("GET body = %q", body)
+	}
+
+	responses := propfind(t, base+"/docs/", "1")
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 PROPFIND responses, got %d", len(responses))
+	}
+	if responses[0].Href != "/webdav/docs/" || responses[0].PropStat.Prop.ResourceType.Collection == nil {
+		t.Errorf("unexpected collection entry %+v", responses[0])
+	}
+	file := responses[1]
+	if file.Href != "/webdav/docs/a.txt" || file.PropStat.Prop.ContentLength != "6" || file.PropStat.Prop.LastModified == "" {
+		t.Errorf("unexpected file entry %+v", file)
+	}
+
+	if got := propfind(t, base+"/docs/", "0"); len(got) != 1 {
+		t.Errorf("Depth 0: expected 1 response, got %d", len(got))
+	}
+
+	expectStatus(t, do(t, "MOVE", base+"/docs/a.txt", nil, "Destination", base+"/docs/b.txt"), http.StatusCreated)
+	expectStatus(t, do(t, http.MethodGet, base+"/docs/a.txt", nil), http.StatusNotFound)
+	expectStatus(t, do(t, http.MethodHead, base+"/docs/b.txt", nil), http.StatusOK)
+
+	expectStatus(t, do(t, http.MethodDelete, base+"/docs/b.txt", nil), http.StatusNoContent)
+	expectStatus(t, do(t, http.MethodDelete, base+"/docs/b.txt", nil), http.StatusNotFound)
+}
+
+func TestWebDAV_MoveWithoutOverwrite(t *testing.T) {
+	srv := newTestServer(t)
+	base := srv.URL + "/webdav"
+
+	do(t, http.MethodPut, base+"/a.txt", strings.NewReader("a"))
+	do(t, http.MethodPut, base+"/b.txt", strings.NewReader("b"))
+
+	resp := do(t, "MOVE", base+"/a.txt", nil, "Destination", "/webdav/b.txt", "Overwrite", "F")
+	expectStatus(t, resp, http.StatusPreconditionFailed)
+}
+
+func TestWebDAV_Options(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := do(t, http.MethodOptions, srv.URL+"/webdav/", nil)
+	expectStatus(t, resp, http.StatusOK)
+	if resp.Header.Get("DAV") != "1" || !strings.Contains(resp.Header.Get("Allow"), "PROPFIND") {
+		t.Errorf("unexpected headers %v", resp.Header)
+	}
+}
+
+func TestWebDAV_PutTooLarge(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := do(t, http.MethodPut, srv.URL+"/webdav/big.bin", strings.NewReader(strings.Repeat("x", 2048)))
+	expectStatus(t, resp, http.StatusRequestEntityTooLarge)
+}
+
+func TestWebDAV_RejectsBadPaths(t *testing.T) {
+	srv := newTestServer(t)
+
+	// Encoded so the client does not normalize them away.
+	for _, p := range []string{"/webdav/%2e%2e/secret", "/webdav/a%5cb.txt", "/webdav/a%00.txt"} {
+		resp := do(t, http.MethodGet, srv.URL+p, nil)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET %s: expected 400, got %d", p, resp.StatusCode)
+		}
+	}
+
+	resp := do(t, "MOVE", srv.URL+"/webdav/a.txt", nil, "Destination", "http://elsewhere.example/webdav/a.txt")
+	expectStatus(t, resp, http.StatusBadRequest)
+}
@@ -2,16 +2,28 @@ package integration
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/base64"
 	"encoding/json"
 	"io"
 	"log/slog"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 
 	"go-storage-api/internal/api"
 	"go-storage-api/internal/storage"
+	"go-storage-api/internal/storage/fsadapter"
 	"go-storage-api/internal/storage/local"
 )
 
@@ -26,7 +38,7 @@ func newTestServer(t *testing.T) *httptest.Server {
 	}
 
 	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
-	router := api.NewRouter(store, 10<<20, logger)
+	router := api.NewRouter(store, api.Options{MaxUploadSize: 10 << 20}, logger)
 	return httptest.NewServer(router)
 }
 
@@ -275,7 +287,7 @@ func TestMethodNotAllowed(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
 
-	req, err := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/files", nil)
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/files", nil)
 	if err != nil {
 		t.Fatalf("create request: %v", err)
 	}
@@ -286,7 +298,7 @@ func TestMethodNotAllowed(t *testing.T) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusOK {
-		t.Error("expected non-200 for unsupported method PUT on /api/v1/files")
+		t.Error("expected non-200 for unsupported method POST on /api/v1/files")
 	}
 }
 
@@ -307,6 +319,96 @@ func TestDownload_NonexistentFile(t *testing.T) {
 	}
 }
 
+func TestDownload_MultipleRanges(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	uploadFile(t, srv.URL, "/data.txt", "0123456789")
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/files/download?path=/data.txt", nil)
+	req.Header.Set("Range", "bytes=6-7,0-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", resp.StatusCode)
+	}
+	mt, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mt != "multipart/byteranges" {
+		t.Fatalf("expected multipart/byteranges, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	var got []string
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next part: %v", err)
+		}
+		b, _ := io.ReadAll(part)
+		got = append(got, part.Header.Get("Content-Range")+" "+string(b))
+	}
+	want := []string{"bytes 6-7/10 67", "bytes 0-1/10 01"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected parts %q, got %q", want, got)
+	}
+}
+
+func TestDownload_MalformedRangeServesFullBody(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	uploadFile(t, srv.URL, "/data.txt", "0123456789")
+
+	for _, header := range []string{"bytes=abc", "bytes=5-2", "items=0-1"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/files/download?path=/data.txt", nil)
+		req.Header.Set("Range", header)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || string(body) != "0123456789" {
+			t.Errorf("Range %q: expected 200 with the full body, got %d %q", header, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestDownload_Directory(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/mkdir?path=/docs"); code != http.StatusCreated {
+		t.Fatalf("mkdir: expected 201, got %d", code)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/v1/files/download?path=/docs")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	var body api.ErrorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body.Code != api.CodeIsDirectory {
+		t.Errorf("expected code %s, got %q", api.CodeIsDirectory, body.Code)
+	}
+	if !strings.Contains(body.Error, "/api/v1/files/archive") {
+		t.Errorf("expected the error to point at the archive endpoint, got %q", body.Error)
+	}
+}
+
 func TestDelete_NonexistentFile(t *testing.T) {
 	srv := newTestServer(t)
 	defer srv.Close()
@@ -325,3 +427,1076 @@ func TestDelete_NonexistentFile(t *testing.T) {
 		t.Errorf("expected 404, got %d", resp.StatusCode)
 	}
 }
+
+// --- Overwrite Protection ---
+
+func TestUpload_ExistingFileConflict(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := uploadFile(t, srv.URL, "/dup.txt", "first")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("first upload: expected 201, got %d", resp.StatusCode)
+	}
+
+	resp = uploadFile(t, srv.URL, "/dup.txt", "second")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("second upload: expected 409, got %d", resp.StatusCode)
+	}
+
+	resp = uploadFile(t, srv.URL, "/dup.txt&overwrite=true", "third")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("overwrite upload: expected 201, got %d", resp.StatusCode)
+	}
+}
+
+// --- Raw PUT ---
+
+func TestPut_RawBody(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/files?path=/raw/notes.txt", strings.NewReader("raw body"))
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("put request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/v1/files/download?path=/raw/notes.txt")
+	if err != nil {
+		t.Fatalf("download request: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if string(data) != "raw body" {
+		t.Errorf("expected %q, got %q", "raw body", string(data))
+	}
+}
+
+func TestPut_ContentMD5MismatchKeepsExistingFile(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	put := func(body, query, md5sum string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/files?path=/good.txt"+query, strings.NewReader(body))
+		if md5sum != "" {
+			req.Header.Set("Content-MD5", md5sum)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := put("good copy", "", ""); code != http.StatusCreated {
+		t.Fatalf("put: expected 201, got %d", code)
+	}
+	sum := md5.Sum([]byte("something else"))
+	if code := put("corrupted", "&overwrite=true", base64.StdEncoding.EncodeToString(sum[:])); code != http.StatusBadRequest {
+		t.Fatalf("mismatched overwrite: expected 400, got %d", code)
+	}
+	if got := download(t, srv.URL, "/good.txt"); got != "good copy" {
+		t.Errorf("expected the existing file kept, got %q", got)
+	}
+}
+
+func TestPut_TooLargeLeavesNoFile(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	// Hide the length so the limit trips mid-stream rather than up front.
+	body := io.MultiReader(strings.NewReader(strings.Repeat("x", 11<<20)))
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/files?path=/big.bin", body)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("put request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/v1/files/stat?path=/big.bin")
+	if err != nil {
+		t.Fatalf("stat request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected partial file to be removed, stat returned %d", resp.StatusCode)
+	}
+}
+
+// --- Per-user scoping ---
+
+const scopeSecret = "integration-secret"
+
+func newScopedServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	store, err := local.New(root)
+	if err != nil {
+		t.Fatalf("create local storage: %v", err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	router := api.NewRouter(store, api.Options{
+		MaxUploadSize: 10 << 20,
+		JWTSecret:     []byte(scopeSecret),
+		ScopeToUser:   true,
+	}, logger)
+	return httptest.NewServer(router), root
+}
+
+// scopedRequest sends an authenticated request as subject sub.
+func scopedRequest(t *testing.T, method, url, sub string, body io.Reader) *http.Response {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": sub,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(scopeSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	req, _ := http.NewRequest(method, url, body)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	return resp
+}
+
+func TestScopeToUser_Isolation(t *testing.T) {
+	srv, root := newScopedServer(t)
+	defer srv.Close()
+
+	resp := scopedRequest(t, http.MethodPut, srv.URL+"/api/v1/files?path=/foo.txt", "alice", strings.NewReader("alice's"))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("alice put: expected 201, got %d", resp.StatusCode)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "users", "alice", "foo.txt"))
+	if err != nil || string(data) != "alice's" {
+		t.Fatalf("expected file under users/alice, got %q, %v", data, err)
+	}
+
+	// Bob sees neither the file nor alice's directory, even via "..".
+	for _, p := range []string{"/foo.txt", "/../alice/foo.txt"} {
+		resp = scopedRequest(t, http.MethodGet, srv.URL+"/api/v1/files/download?path="+p, "bob", nil)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Errorf("bob download %s: expected failure, got 200", p)
+		}
+	}
+
+	// Bob writing the same path gets his own copy.
+	resp = scopedRequest(t, http.MethodPut, srv.URL+"/api/v1/files?path=/foo.txt", "bob", strings.NewReader("bob's"))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("bob put: expected 201, got %d", resp.StatusCode)
+	}
+
+	resp = scopedRequest(t, http.MethodGet, srv.URL+"/api/v1/files/download?path=/foo.txt", "alice", nil)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "alice's" {
+		t.Errorf("alice download: expected her own content, got %q", body)
+	}
+}
+
+func TestScopeToUser_ResponsesHidePrefix(t *testing.T) {
+	srv, _ := newScopedServer(t)
+	defer srv.Close()
+
+	resp := scopedRequest(t, http.MethodPut, srv.URL+"/api/v1/files?path=/docs/a.txt", "alice", strings.NewReader("a"))
+	resp.Body.Close()
+
+	resp = scopedRequest(t, http.MethodGet, srv.URL+"/api/v1/files?path=/docs", "alice", nil)
+	var files []storage.FileInfo
+	json.NewDecoder(resp.Body).Decode(&files)
+	resp.Body.Close()
+	if len(files) != 1 || files[0].Path != "docs/a.txt" {
+		t.Errorf("list: expected path docs/a.txt, got %+v", files)
+	}
+
+	resp = scopedRequest(t, http.MethodGet, srv.URL+"/api/v1/files/stat?path=/docs/a.txt", "alice", nil)
+	var info storage.FileInfo
+	json.NewDecoder(resp.Body).Decode(&info)
+	resp.Body.Close()
+	if info.Path != "docs/a.txt" {
+		t.Errorf("stat: expected path docs/a.txt, got %q", info.Path)
+	}
+}
+
+func TestScopeToUser_ReadyNeedsNoToken(t *testing.T) {
+	srv, _ := newScopedServer(t)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/ready")
+	if err != nil {
+		t.Fatalf("ready: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("ready: expected 200, got %d", resp.StatusCode)
+	}
+}
+
+// --- Soft delete ---
+
+// doRequest sends a bodiless request and returns the status code.
+func doRequest(t *testing.T, method, url string) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSoftDelete_Restore(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := uploadFile(t, srv.URL, "/docs/a.txt", "keep me")
+	resp.Body.Close()
+
+	if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/docs/a.txt&soft=true"); code != http.StatusOK {
+		t.Fatalf("soft delete: expected 200, got %d", code)
+	}
+	if code := doRequest(t, http.MethodGet, srv.URL+"/api/v1/files/stat?path=/docs/a.txt"); code != http.StatusNotFound {
+		t.Fatalf("stat after soft delete: expected 404, got %d", code)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/v1/files?path=/&hideTrash=true")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var files []storage.FileInfo
+	json.NewDecoder(resp.Body).Decode(&files)
+	resp.Body.Close()
+	for _, f := range files {
+		if f.Name == ".trash" {
+			t.Errorf("hideTrash listing still shows .trash: %+v", files)
+		}
+	}
+
+	if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/restore?path=/docs/a.txt"); code != http.StatusOK {
+		t.Fatalf("restore: expected 200, got %d", code)
+	}
+	resp, err = http.Get(srv.URL + "/api/v1/files/download?path=/docs/a.txt")
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != "keep me" {
+		t.Errorf("expected restored contents %q, got %q", "keep me", data)
+	}
+
+	// Nothing left in the trash for this path.
+	if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/restore?path=/docs/a.txt"); code != http.StatusNotFound {
+		t.Errorf("second restore: expected 404, got %d", code)
+	}
+}
+
+func TestSoftDelete_Directory(t *testing.T) {
+	store, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("create local storage: %v", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	for name, tt := range map[string]struct {
+		backend  storage.Storage
+		wantCode int
+	}{
+		"rename": {store, http.StatusOK},
+		// Hides local's Renamer, so the directory cannot be moved.
+		"no rename": {struct{ storage.Storage }{store}, http.StatusConflict},
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(api.NewRouter(tt.backend, api.Options{MaxUploadSize: 10 << 20, SoftDelete: true}, logger))
+			defer srv.Close()
+			uploadTree(t, srv.URL)
+			defer doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/src&recursive=true&purge=true")
+
+			if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/src&recursive=true"); code != tt.wantCode {
+				t.Fatalf("soft delete: expected %d, got %d", tt.wantCode, code)
+			}
+			if tt.wantCode != http.StatusOK {
+				checkTree(t, srv.URL, "/src")
+				return
+			}
+			if code := doRequest(t, http.MethodGet, srv.URL+"/api/v1/files/stat?path=/src"); code != http.StatusNotFound {
+				t.Fatalf("stat after soft delete: expected 404, got %d", code)
+			}
+			if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/restore?path=/src"); code != http.StatusOK {
+				t.Fatalf("restore: expected 200, got %d", code)
+			}
+			checkTree(t, srv.URL, "/src")
+		})
+	}
+}
+
+func TestDelete_DirectoryNeedsRecursive(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/mkdir?path=/empty"); code != http.StatusCreated {
+		t.Fatalf("mkdir: expected 201, got %d", code)
+	}
+	if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/empty"); code != http.StatusOK {
+		t.Errorf("empty directory: expected 200, got %d", code)
+	}
+
+	resp := uploadFile(t, srv.URL, "/docs/sub/a.txt", "a")
+	resp.Body.Close()
+	if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/docs"); code != http.StatusConflict {
+		t.Errorf("non-empty directory without recursive: expected 409, got %d", code)
+	}
+	if code := doRequest(t, http.MethodGet, srv.URL+"/api/v1/files/stat?path=/docs/sub/a.txt"); code != http.StatusOK {
+		t.Fatalf("expected the file kept after the refused delete, got %d", code)
+	}
+	if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/docs&recursive=true"); code != http.StatusOK {
+		t.Errorf("non-empty directory with recursive: expected 200, got %d", code)
+	}
+	if code := doRequest(t, http.MethodGet, srv.URL+"/api/v1/files/stat?path=/docs"); code != http.StatusNotFound {
+		t.Errorf("expected the directory gone, got %d", code)
+	}
+}
+
+func TestSoftDelete_Purge(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := uploadFile(t, srv.URL, "/gone.txt", "bye")
+	resp.Body.Close()
+
+	if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/gone.txt&purge=true"); code != http.StatusOK {
+		t.Fatalf("purge: expected 200, got %d", code)
+	}
+	if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/restore?path=/gone.txt"); code != http.StatusNotFound {
+		t.Errorf("restore after purge: expected 404, got %d", code)
+	}
+}
+
+func TestList_Recursive(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	for _, p := range []string{"/b.txt", "/a/x.txt", "/a/sub/y.txt", "/gone.txt"} {
+		resp := uploadFile(t, srv.URL, p, "data")
+		resp.Body.Close()
+	}
+	if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/gone.txt&soft=true"); code != http.StatusOK {
+		t.Fatalf("soft delete: expected 200, got %d", code)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/v1/files?path=/&recursive=true&hideTrash=true")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var files []storage.FileInfo
+	json.NewDecoder(resp.Body).Decode(&files)
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	want := "a a/sub a/sub/y.txt a/x.txt b.txt"
+	if got := strings.Join(paths, " "); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestReadOnlyFSBackend(t *testing.T) {
+	store := fsadapter.New(fstest.MapFS{
+		"docs/readme.txt": {Data: []byte("bundled")},
+	})
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	srv := httptest.NewServer(api.NewRouter(store, api.Options{MaxUploadSize: 10 << 20}, logger))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/files/download?path=/docs/readme.txt")
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(data) != "bundled" {
+		t.Fatalf("download: expected 200 %q, got %d %q", "bundled", resp.StatusCode, data)
+	}
+
+	resp = uploadFile(t, srv.URL, "/docs/new.txt", "nope")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("upload: expected 403, got %d", resp.StatusCode)
+	}
+	var body api.ErrorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body.Code != api.CodePermissionDenied || !strings.Contains(body.Error, "read-only") {
+		t.Errorf("expected a read-only permission_denied error, got %+v", body)
+	}
+
+	if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/docs/readme.txt"); code != http.StatusForbidden {
+		t.Errorf("delete: expected 403, got %d", code)
+	}
+}
+
+func TestPatch_EditsInPlace(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := uploadFile(t, srv.URL, "/conf/app.json", `{"debug":false}`)
+	resp.Body.Close()
+
+	patch := func(path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPatch, srv.URL+"/api/v1/files?path="+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("patch: %v", err)
+		}
+		return resp
+	}
+
+	resp = patch("/conf/app.json", `{"debug":true}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("patch: expected 200, got %d", resp.StatusCode)
+	}
+	var info storage.FileInfo
+	json.NewDecoder(resp.Body).Decode(&info)
+	if info.Size != int64(len(`{"debug":true}`)) {
+		t.Errorf("expected the new size in the response, got %+v", info)
+	}
+
+	get, err := http.Get(srv.URL + "/api/v1/files/download?path=/conf/app.json")
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	data, _ := io.ReadAll(get.Body)
+	get.Body.Close()
+	if string(data) != `{"debug":true}` {
+		t.Errorf("expected edited contents, got %q", data)
+	}
+
+	missing := patch("/conf/other.json", "{}")
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Errorf("patch missing: expected 404, got %d", missing.StatusCode)
+	}
+	if code := doRequest(t, http.MethodGet, srv.URL+"/api/v1/files/stat?path=/conf/other.json"); code != http.StatusNotFound {
+		t.Errorf("expected patch not to create the file, stat got %d", code)
+	}
+}
+
+func TestDownload_HTMLIsAttachment(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp := uploadFile(t, srv.URL, "/pages/evil.html", "<script>alert(document.cookie)</script>")
+	resp.Body.Close()
+	resp = uploadFile(t, srv.URL, "/notes.txt", "plain")
+	resp.Body.Close()
+
+	html, err := http.Get(srv.URL + "/api/v1/files/download?path=/pages/evil.html")
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	html.Body.Close()
+	if got := html.Header.Get("Content-Disposition"); got != "attachment; filename=evil.html" {
+		t.Errorf("expected html as attachment, got %q", got)
+	}
+	if got := html.Header.Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("expected nosniff, got %q", got)
+	}
+	if got := html.Header.Get("Content-Security-Policy"); got == "" {
+		t.Error("expected a Content-Security-Policy")
+	}
+
+	text, err := http.Get(srv.URL + "/api/v1/files/download?path=/notes.txt")
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	text.Body.Close()
+	if got := text.Header.Get("Content-Disposition"); got != "inline; filename=notes.txt" {
+		t.Errorf("expected text inline, got %q", got)
+	}
+	if got := text.Header.Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("expected nosniff, got %q", got)
+	}
+}
+
+func TestSearch_ByName(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	for _, p := range []string{"/docs/Q1-report.pdf", "/docs/notes.txt", "/docs/2024/q2-REPORT.pdf", "/docs/2024/report.txt"} {
+		resp := uploadFile(t, srv.URL, p, "x")
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(srv.URL + "/api/v1/files/search?path=/docs&q=report&ext=.pdf")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var files []storage.FileInfo
+	json.NewDecoder(resp.Body).Decode(&files)
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	if want := "docs/2024/q2-REPORT.pdf,docs/Q1-report.pdf"; strings.Join(paths, ",") != want {
+		t.Errorf("expected %s, got %v", want, paths)
+	}
+
+	if code := doRequest(t, http.MethodGet, srv.URL+"/api/v1/files/search?path=/docs"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 without q, got %d", code)
+	}
+}
+
+func TestMetadata_SetGetAndDelete(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	uploadFile(t, srv.URL, "/docs/a.txt", "a").Body.Close()
+	metaURL := srv.URL + "/api/v1/files/metadata?path=/docs/a.txt"
+
+	req, _ := http.NewRequest(http.MethodPut, metaURL, strings.NewReader(`{"owner":"alice","label":"draft"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("put metadata: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	getTags := func() map[string]string {
+		t.Helper()
+		resp, err := http.Get(metaURL)
+		if err != nil {
+			t.Fatalf("get metadata: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		var tags map[string]string
+		json.NewDecoder(resp.Body).Decode(&tags)
+		return tags
+	}
+	if tags := getTags(); tags["owner"] != "alice" || tags["label"] != "draft" || len(tags) != 2 {
+		t.Errorf("expected the stored tags back, got %v", tags)
+	}
+
+	if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/docs/a.txt"); code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d", code)
+	}
+	if code := doRequest(t, http.MethodGet, metaURL); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted file, got %d", code)
+	}
+	uploadFile(t, srv.URL, "/docs/a.txt", "again").Body.Close()
+	if tags := getTags(); len(tags) != 0 {
+		t.Errorf("expected a recreated file to start without tags, got %v", tags)
+	}
+}
+
+func TestTouch_SetsAndUpdatesModTime(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	touch := func(query string) (int, storage.FileInfo) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/files/touch?"+query, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("touch: %v", err)
+		}
+		defer resp.Body.Close()
+		var info storage.FileInfo
+		json.NewDecoder(resp.Body).Decode(&info)
+		return resp.StatusCode, info
+	}
+
+	preserved := time.Date(2019, 11, 30, 8, 15, 0, 0, time.UTC)
+	code, info := touch("path=/backup/a.txt&mtime=" + preserved.Format(time.RFC3339))
+	if code != http.StatusCreated {
+		t.Fatalf("expected 201 creating the file, got %d", code)
+	}
+	if !info.ModTime.Equal(preserved) || info.Size != 0 {
+		t.Errorf("expected an empty file dated %s, got %d bytes at %s", preserved, info.Size, info.ModTime)
+	}
+
+	before := time.Now().Add(-time.Second)
+	code, info = touch("path=/backup/a.txt")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 touching the file, got %d", code)
+	}
+	if info.ModTime.Before(before) {
+		t.Errorf("expected the time updated to now, got %s", info.ModTime)
+	}
+}
+
+func TestDedup_IdenticalUploads(t *testing.T) {
+	root := t.TempDir()
+	store, err := local.New(root, local.WithDedup())
+	if err != nil {
+		t.Fatalf("create local storage: %v", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	srv := httptest.NewServer(api.NewRouter(store, api.Options{MaxUploadSize: 10 << 20}, logger))
+	defer srv.Close()
+
+	for _, p := range []string{"/a/report.pdf", "/b/report-copy.pdf"} {
+		resp := uploadFile(t, srv.URL, p, "identical contents")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("upload %s: expected 201, got %d", p, resp.StatusCode)
+		}
+	}
+
+	blobs, _ := filepath.Glob(filepath.Join(root, ".blobs", "*", "*"))
+	if len(blobs) != 1 {
+		t.Errorf("expected one stored blob, got %v", blobs)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/v1/files?path=/")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var files []storage.FileInfo
+	json.NewDecoder(resp.Body).Decode(&files)
+	resp.Body.Close()
+	if len(files) != 2 {
+		t.Errorf("expected a and b listed without .blobs, got %+v", files)
+	}
+
+	for _, p := range []string{"/a/report.pdf", "/b/report-copy.pdf"} {
+		if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path="+p); code != http.StatusOK {
+			t.Fatalf("delete %s: expected 200, got %d", p, code)
+		}
+	}
+	if blobs, _ := filepath.Glob(filepath.Join(root, ".blobs", "*", "*")); len(blobs) != 0 {
+		t.Errorf("expected the blob removed with the last copy, got %v", blobs)
+	}
+}
+
+// tusRequest sends a tus 1.0.0 request with the given headers.
+func tusRequest(t *testing.T, method, url string, body io.Reader, headers map[string]string) *http.Response {
+	t.Helper()
+
+	req, _ := http.NewRequest(method, url, body)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestResumableUpload_ResumeAfterInterruption(t *testing.T) {
+	root := t.TempDir()
+	store, err := local.New(root)
+	if err != nil {
+		t.Fatalf("create local storage: %v", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	srv := httptest.NewServer(api.NewRouter(store, api.Options{
+		MaxUploadSize:      10 << 20,
+		ResumableUploadDir: t.TempDir(),
+	}, logger))
+	defer srv.Close()
+
+	content := strings.Repeat("0123456789", 100)
+	resp := tusRequest(t, http.MethodPost, srv.URL+"/api/v1/uploads?path=/docs/big.txt", nil, map[string]string{"Upload-Length": "1000"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d", resp.StatusCode)
+	}
+	upload := srv.URL + resp.Header.Get("Location")
+
+	chunk := func(offset, end int) *http.Response {
+		return tusRequest(t, http.MethodPatch, upload, strings.NewReader(content[offset:end]), map[string]string{
+			"Content-Type":  "application/offset+octet-stream",
+			"Upload-Offset": strconv.Itoa(offset),
+		})
+	}
+	if resp := chunk(0, 400); resp.StatusCode != http.StatusNoContent || resp.Header.Get("Upload-Offset") != "400" {
+		t.Fatalf("first chunk: expected 204 at offset 400, got %d at %q", resp.StatusCode, resp.Header.Get("Upload-Offset"))
+	}
+
+	// The client lost track of what was sent and asks where to resume.
+	resp = tusRequest(t, http.MethodHead, upload, nil, nil)
+	offset, _ := strconv.Atoi(resp.Header.Get("Upload-Offset"))
+	if offset != 400 {
+		t.Fatalf("expected to resume at 400, got %q", resp.Header.Get("Upload-Offset"))
+	}
+	if _, err := os.Stat(filepath.Join(root, "docs", "big.txt")); err == nil {
+		t.Fatal("file stored before the upload finished")
+	}
+
+	if resp := chunk(offset, len(content)); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("final chunk: expected 204, got %d", resp.StatusCode)
+	}
+	got, err := os.ReadFile(filepath.Join(root, "docs", "big.txt"))
+	if err != nil || string(got) != content {
+		t.Errorf("expected the whole upload stored, got %d bytes (err %v)", len(got), err)
+	}
+}
+
+// --- Copy and move ---
+
+// download returns the contents of the file at p, failing the test on
+// anything but 200.
+func download(t *testing.T, baseURL, p string) string {
+	t.Helper()
+	resp, err := http.Get(baseURL + "/api/v1/files/download?path=" + p)
+	if err != nil {
+		t.Fatalf("download %s: %v", p, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("download %s: expected 200, got %d", p, resp.StatusCode)
+	}
+	return string(data)
+}
+
+var nestedTree = map[string]string{
+	"/src/a.txt":            "a",
+	"/src/sub/b.txt":        "b",
+	"/src/sub/deep/c.txt":   "c",
+	"/src/other/d.txt":      "d",
+	"/src/other/deep/.keep": "",
+}
+
+func uploadTree(t *testing.T, baseURL string) {
+	t.Helper()
+	for p, content := range nestedTree {
+		resp := uploadFile(t, baseURL, p, content)
+		resp.Body.Close()
+	}
+	if code := doRequest(t, http.MethodPost, baseURL+"/api/v1/files/mkdir?path=/src/empty"); code != http.StatusCreated {
+		t.Fatalf("mkdir: expected 201, got %d", code)
+	}
+}
+
+// checkTree verifies that the nested tree sits under root.
+func checkTree(t *testing.T, baseURL, root string) {
+	t.Helper()
+	for p, content := range nestedTree {
+		p = root + strings.TrimPrefix(p, "/src")
+		if got := download(t, baseURL, p); got != content {
+			t.Errorf("%s: expected %q, got %q", p, content, got)
+		}
+	}
+	if code := doRequest(t, http.MethodGet, baseURL+"/api/v1/files/stat?path="+root+"/empty"); code != http.StatusOK {
+		t.Errorf("expected the empty directory at %s/empty, got %d", root, code)
+	}
+}
+
+func TestCopy_NestedDirectory(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	uploadTree(t, srv.URL)
+
+	if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/copy?path=/src&to=/backup/src"); code != http.StatusCreated {
+		t.Fatalf("copy: expected 201, got %d", code)
+	}
+	checkTree(t, srv.URL, "/backup/src")
+	checkTree(t, srv.URL, "/src")
+
+	if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/copy?path=/src&to=/backup/src"); code != http.StatusConflict {
+		t.Errorf("copy onto an existing directory: expected 409, got %d", code)
+	}
+	if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/copy?path=/src&to=/src/sub/loop"); code != http.StatusBadRequest {
+		t.Errorf("copy into itself: expected 400, got %d", code)
+	}
+}
+
+func TestCopy_PreserveTimes(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	uploadTree(t, srv.URL)
+
+	old := time.Date(2019, 11, 30, 8, 15, 0, 0, time.UTC)
+	if code := doRequest(t, http.MethodPut, srv.URL+"/api/v1/files/touch?path=/src/a.txt&mtime="+old.Format(time.RFC3339)); code != http.StatusOK {
+		t.Fatalf("touch: expected 200, got %d", code)
+	}
+
+	modTime := func(p string) time.Time {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/api/v1/files/stat?path=" + p)
+		if err != nil {
+			t.Fatalf("stat: %v", err)
+		}
+		defer resp.Body.Close()
+		var info storage.FileInfo
+		json.NewDecoder(resp.Body).Decode(&info)
+		return info.ModTime
+	}
+
+	before := time.Now().Add(-time.Second)
+	for _, q := range []string{"path=/src&to=/kept&preserve_times=true", "path=/src/a.txt&to=/kept.txt&preserve_times=true", "path=/src/a.txt&to=/fresh.txt"} {
+		if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/copy?"+q); code != http.StatusCreated {
+			t.Fatalf("copy %s: expected 201, got %d", q, code)
+		}
+	}
+	for _, p := range []string{"/kept/a.txt", "/kept.txt"} {
+		if got := modTime(p); !got.Equal(old) {
+			t.Errorf("%s: expected the source time %s, got %s", p, old, got)
+		}
+	}
+	if got := modTime("/fresh.txt"); got.Before(before) {
+		t.Errorf("expected a copy without preserve_times to get the current time, got %s", got)
+	}
+}
+
+func TestMove_NestedDirectory(t *testing.T) {
+	store, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("create local storage: %v", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	for name, backend := range map[string]storage.Storage{
+		"rename": store,
+		// Hides local's Renamer, so the move copies and deletes.
+		"copy": struct{ storage.Storage }{store},
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(api.NewRouter(backend, api.Options{MaxUploadSize: 10 << 20}, logger))
+			defer srv.Close()
+			uploadTree(t, srv.URL)
+			defer doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/moved&recursive=true&purge=true")
+
+			if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/move?path=/src&to=/moved"); code != http.StatusOK {
+				t.Fatalf("move: expected 200, got %d", code)
+			}
+			checkTree(t, srv.URL, "/moved")
+			if code := doRequest(t, http.MethodGet, srv.URL+"/api/v1/files/stat?path=/src"); code != http.StatusNotFound {
+				t.Errorf("expected the source gone, got %d", code)
+			}
+		})
+	}
+}
+
+func TestTransfer_DirectoryOntoFileRefused(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	uploadTree(t, srv.URL)
+	resp := uploadFile(t, srv.URL, "/target.txt", "keep")
+	resp.Body.Close()
+
+	for _, op := range []string{"copy", "move"} {
+		if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/"+op+"?path=/src&to=/target.txt&overwrite=true"); code != http.StatusConflict {
+			t.Errorf("%s a directory onto a file: expected 409, got %d", op, code)
+		}
+	}
+	checkTree(t, srv.URL, "/src")
+	if got := download(t, srv.URL, "/target.txt"); got != "keep" {
+		t.Errorf("expected the file kept, got %q", got)
+	}
+}
+
+// --- Dry run ---
+
+// dryRun sends a dry-run request and returns the paths it reports.
+func dryRun(t *testing.T, method, url string) []string {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("dry run %s: expected 200, got %d", url, resp.StatusCode)
+	}
+	var body api.DryRunResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.WouldAffect != len(body.Paths) {
+		t.Errorf("would_affect %d does not match %d paths", body.WouldAffect, len(body.Paths))
+	}
+	return body.Paths
+}
+
+func TestDryRun_LeavesStoreUnchanged(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	uploadTree(t, srv.URL)
+
+	tree := []string{
+		"/src", "/src/a.txt", "/src/empty", "/src/other", "/src/other/d.txt",
+		"/src/other/deep", "/src/other/deep/.keep", "/src/sub", "/src/sub/b.txt",
+		"/src/sub/deep", "/src/sub/deep/c.txt",
+	}
+	moved := make([]string, len(tree))
+	for i, p := range tree {
+		moved[i] = "/dst" + strings.TrimPrefix(p, "/src")
+	}
+
+	tests := []struct {
+		name, method, url string
+		want              []string
+	}{
+		{"delete file", http.MethodDelete, "/api/v1/files?path=/src/a.txt&dry_run=true", []string{"/src/a.txt"}},
+		{"delete tree", http.MethodDelete, "/api/v1/files?path=/src&recursive=true&dry_run=true", tree},
+		{"soft delete tree", http.MethodDelete, "/api/v1/files?path=/src&recursive=true&soft=true&dry_run=true", tree},
+		{"copy file", http.MethodPost, "/api/v1/files/copy?path=/src/a.txt&to=/dst.txt&dry_run=true", []string{"/dst.txt"}},
+		{"copy tree", http.MethodPost, "/api/v1/files/copy?path=/src&to=/dst&dry_run=true", moved},
+		{"move tree", http.MethodPost, "/api/v1/files/move?path=/src&to=/dst&dry_run=true", append(append([]string{}, tree...), moved...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dryRun(t, tt.method, srv.URL+tt.url)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			checkTree(t, srv.URL, "/src")
+			for _, p := range []string{"/dst", "/dst.txt"} {
+				if code := doRequest(t, http.MethodGet, srv.URL+"/api/v1/files/stat?path="+p); code != http.StatusNotFound {
+					t.Errorf("expected nothing at %s, got %d", p, code)
+				}
+			}
+		})
+	}
+
+	// A dry run fails where the real request would.
+	if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/src&dry_run=true"); code != http.StatusConflict {
+		t.Errorf("non-recursive delete of a directory: expected 409, got %d", code)
+	}
+	if code := doRequest(t, http.MethodPost, srv.URL+"/api/v1/files/copy?path=/src&to=/src/sub&dry_run=true"); code != http.StatusBadRequest {
+		t.Errorf("copy into itself: expected 400, got %d", code)
+	}
+	if code := doRequest(t, http.MethodDelete, srv.URL+"/api/v1/files?path=/src&dry_run=maybe"); code != http.StatusBadRequest {
+		t.Errorf("invalid dry_run: expected 400, got %d", code)
+	}
+}
+
+// --- Verify ---
+
+func TestVerify_StoredFile(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	uploadFile(t, srv.URL, "/backup/data.txt", "hello world").Body.Close()
+
+	check := func(algo, expected string) api.VerifyResponse {
+		t.Helper()
+		body := `{"algo":"` + algo + `","expected":"` + expected + `"}`
+		resp, err := http.Post(srv.URL+"/api/v1/files/verify?path=/backup/data.txt", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("verify %s: expected 200, got %d", algo, resp.StatusCode)
+		}
+		var out api.VerifyResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return out
+	}
+
+	const sha = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if got := check("sha256", sha); !got.Match {
+		t.Errorf("expected sha256 to match, got %+v", got)
+	}
+	if got := check("md5", "00000000000000000000000000000000"); got.Match || got.Actual != "5eb63bbbe01eeed093cb22bb8f5acdc3" {
+		t.Errorf("expected an md5 mismatch with the real digest, got %+v", got)
+	}
+}
+
+// --- Upload quota ---
+
+func TestUploadQuota_RejectsPastQuota(t *testing.T) {
+	store, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("create local storage: %v", err)
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	srv := httptest.NewServer(api.NewRouter(store, api.Options{
+		MaxUploadSize:     10 << 20,
+		UploadQuota:       1000,
+		UploadQuotaWindow: time.Hour,
+	}, logger))
+	defer srv.Close()
+
+	put := func(p string, body io.Reader) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/files?path="+p, body)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("put %s: %v", p, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Sent chunked, so the size is only known once read; the upload in
+	// progress finishes and its bytes count.
+	resp := put("/big.bin", io.MultiReader(strings.NewReader(strings.Repeat("x", 1500))))
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("first upload: expected 201, got %d", resp.StatusCode)
+	}
+
+	resp = put("/small.bin", strings.NewReader("y"))
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("upload past the quota: expected 429, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+	if code := doRequest(t, http.MethodGet, srv.URL+"/api/v1/files/stat?path=/small.bin"); code != http.StatusNotFound {
+		t.Errorf("expected the rejected upload not stored, got %d", code)
+	}
+}